@@ -8,6 +8,8 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -21,6 +23,7 @@ import (
 	"github.com/txplain/txplain/internal/mcp"
 	"github.com/txplain/txplain/internal/models"
 	"github.com/txplain/txplain/internal/rpc"
+	"github.com/txplain/txplain/internal/sigdb"
 	"github.com/txplain/txplain/internal/tools"
 )
 
@@ -46,6 +49,19 @@ func main() {
 		debugToken  = flag.String("debug-token", "", "Debug specific token contract (address)")
 		txHash      = flag.String("tx", "", "Transaction hash to explain")
 		networkID   = flag.Int64("network", 1, "Network ID (1=Ethereum, 137=Polygon, 42161=Arbitrum)")
+
+		importSignatures = flag.String("import-signatures", "", "Comma-separated 4byte/openchain signature dumps to import into the offline signature database")
+		signatureKind    = flag.String("import-signatures-kind", "", "Signature kind for dumps that don't specify it: function or event (default: guessed from file name)")
+		signatureScore   = flag.Int("import-signatures-score", 1, "Popularity score given to imported signatures (higher ranks first on collisions)")
+		signatureDB      = flag.String("signature-db", "", "Signature database file to update (defaults to SIGNATURE_DB_PATH, or the embedded database source)")
+
+		importTokens      = flag.String("import-tokens", "", "Comma-separated Uniswap token lists (.json) or token CSVs (files or URLs) to import into the static token data")
+		importTokensChain = flag.Int64("import-tokens-chain", 1, "Chain ID for imported CSV rows without a chain_id column")
+		tokensDir         = flag.String("tokens-dir", "", "Directory of per-chain token CSVs written by -import-tokens (default: tokens/ in STATIC_DATA_DIR, or "+tools.DefaultTokensDir+")")
+
+		importScreening     = flag.String("import-screening", "", "Comma-separated address lists (OFAC sdn.csv, JSON address arrays or CSVs; files or URLs) to import into a screening list")
+		importScreeningList = flag.String("import-screening-list", "", "Screening list to import into: "+strings.Join(tools.ScreeningLists, ", "))
+		screeningDir        = flag.String("screening-dir", "", "Directory of screening list CSVs written by -import-screening (default: "+tools.ScreeningDir+"/ in STATIC_DATA_DIR, or data/"+tools.ScreeningDir+")")
 	)
 	flag.Parse()
 
@@ -71,6 +87,24 @@ func main() {
 		return
 	}
 
+	// Handle signature import mode
+	if *importSignatures != "" {
+		importSignatureDumps(*importSignatures, *signatureKind, *signatureScore, *signatureDB)
+		return
+	}
+
+	// Handle token list import mode
+	if *importTokens != "" {
+		importTokenLists(*importTokens, *importTokensChain, *tokensDir)
+		return
+	}
+
+	// Handle screening list import mode
+	if *importScreening != "" {
+		importScreeningLists(*importScreening, *importScreeningList, *screeningDir)
+		return
+	}
+
 	// Initialize cache from DATABASE_URL
 	var cache tools.Cache
 	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
@@ -452,3 +486,99 @@ func debugTokenContract(contractAddress string, networkID int64) {
 		}
 	}
 }
+
+// importSignatureDumps merges signature dumps into the offline signature database file
+func importSignatureDumps(paths, kind string, score int, dbPath string) {
+	if dbPath == "" {
+		dbPath = os.Getenv("SIGNATURE_DB_PATH")
+	}
+	if dbPath == "" {
+		dbPath = sigdb.EmbeddedPath
+	}
+
+	// Start from the existing database so imports are additive
+	db := sigdb.New()
+	if err := db.LoadFile(dbPath); err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("Failed to load signature database %s: %v", dbPath, err)
+		}
+		if err := db.LoadEmbedded(); err != nil {
+			log.Fatalf("Failed to load embedded signature database: %v", err)
+		}
+	}
+	functionsBefore, eventsBefore := db.Len()
+
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		count, err := db.ImportFile(path, kind, score)
+		if err != nil {
+			log.Fatalf("Failed to import %s: %v", path, err)
+		}
+		fmt.Printf("Imported %d signatures from %s\n", count, path)
+	}
+
+	if err := db.WriteFile(dbPath); err != nil {
+		log.Fatalf("Failed to write signature database %s: %v", dbPath, err)
+	}
+
+	functions, events := db.Len()
+	fmt.Printf("Signature database %s: %d functions (+%d), %d events (+%d)\n", dbPath, functions, functions-functionsBefore, events, events-eventsBefore)
+}
+
+// importTokenLists merges token lists and CSVs into the per-chain token CSVs loaded by the static context provider
+func importTokenLists(paths string, defaultChainID int64, dir string) {
+	if dir == "" {
+		dir = tools.DefaultTokensDir
+		if dataDir := os.Getenv("STATIC_DATA_DIR"); dataDir != "" {
+			dir = filepath.Join(dataDir, "tokens")
+		}
+	}
+
+	var sources []string
+	for _, path := range strings.Split(paths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			sources = append(sources, path)
+		}
+	}
+
+	added, err := tools.ImportTokenLists(sources, dir, defaultChainID)
+	if err != nil {
+		log.Fatalf("Failed to import tokens: %v", err)
+	}
+
+	chainIDs := make([]int64, 0, len(added))
+	for chainID := range added {
+		chainIDs = append(chainIDs, chainID)
+	}
+	sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+	for _, chainID := range chainIDs {
+		fmt.Printf("Chain %d: %d new tokens -> %s\n", chainID, added[chainID], filepath.Join(dir, fmt.Sprintf("%d.csv", chainID)))
+	}
+}
+
+// importScreeningLists refreshes a sanctions, scam or denylist screening list from address lists
+func importScreeningLists(paths, list, dir string) {
+	if dir == "" {
+		dataDir := os.Getenv("STATIC_DATA_DIR")
+		if dataDir == "" {
+			dataDir = tools.DefaultStaticDataDir
+		}
+		dir = filepath.Join(dataDir, tools.ScreeningDir)
+	}
+
+	var sources []string
+	for _, path := range strings.Split(paths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			sources = append(sources, path)
+		}
+	}
+
+	added, removed, err := tools.ImportScreeningLists(list, sources, dir)
+	if err != nil {
+		log.Fatalf("Failed to import screening list: %v", err)
+	}
+	fmt.Printf("Screening list %s: %d new addresses, %d removed -> %s\n", list, added, removed, filepath.Join(dir, list+".csv"))
+}
@@ -3,7 +3,11 @@ package agent
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/tmc/langchaingo/agents"
 	"github.com/tmc/langchaingo/chains"
@@ -24,6 +28,10 @@ type TxplainAgent struct {
 	executor     *chains.SequentialChain
 	priceService txtools.PriceService
 	cache        txtools.Cache
+	abiRegistry  *txtools.LocalABIRegistry
+	iconStore    *txtools.IconStore
+	staticData   *txtools.StaticContextProvider
+	stopWatchers context.CancelFunc
 	verbose      bool
 }
 
@@ -59,12 +67,23 @@ func NewTxplainAgent(openaiAPIKey string, cache txtools.Cache, verbose bool) (*T
 	traceDecoder := txtools.NewTraceDecoder(cache, verbose) // Will be enhanced per request
 	logDecoder := txtools.NewLogDecoder(cache, verbose)     // Will be enhanced per request
 
-	// Initialize static context provider first (needed by transaction explainer)
-	staticProvider := txtools.NewStaticContextProvider(verbose)
+	// Static knowledge (tokens, addresses, protocols, tags) is loaded once, shared by all requests and
+	// reloaded when its CSVs change
+	staticProvider := txtools.NewStaticContextProvider("", verbose)
 
 	// Initialize transaction explainer (now uses baggage pipeline with RAG)
 	explainer := txtools.NewTransactionExplainer(llm, staticProvider, verbose)
 
+	// Local ABI registry is shared across requests and watched for new or changed files
+	abiRegistry := txtools.NewLocalABIRegistry("", verbose)
+	watchCtx, stopWatchers := context.WithCancel(context.Background())
+	go abiRegistry.Watch(watchCtx, 10*time.Second)
+	go staticProvider.Watch(watchCtx, 10*time.Second)
+	go reloadOnSignal(watchCtx, staticProvider, abiRegistry)
+
+	// Icon store is shared with the API server, which serves the downloaded icons
+	iconStore := txtools.NewIconStore(cache, verbose)
+
 	agent := &TxplainAgent{
 		llm:          llm,
 		rpcClients:   rpcClients,
@@ -73,12 +92,36 @@ func NewTxplainAgent(openaiAPIKey string, cache txtools.Cache, verbose bool) (*T
 		explainer:    explainer,
 		priceService: priceService,
 		cache:        cache,
+		abiRegistry:  abiRegistry,
+		iconStore:    iconStore,
+		staticData:   staticProvider,
+		stopWatchers: stopWatchers,
 		verbose:      verbose,
 	}
 
 	return agent, nil
 }
 
+// reloadOnSignal reloads the static knowledge base and the local ABI registry on SIGHUP
+func reloadOnSignal(ctx context.Context, staticProvider *txtools.StaticContextProvider, abiRegistry *txtools.LocalABIRegistry) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			fmt.Println("SIGHUP received, reloading static data and local ABIs")
+			staticProvider.Reload()
+			if err := abiRegistry.Reload(); err != nil {
+				fmt.Printf("Warning: failed to reload local ABI registry: %v\n", err)
+			}
+		}
+	}
+}
+
 // ExplainTransaction processes a transaction with enhanced baggage pipeline
 func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.TransactionRequest) (*models.ExplanationResult, error) {
 	fmt.Println("\n" + strings.Repeat("🌟", 40))
@@ -115,12 +158,13 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 	// Step 2: Initialize baggage with raw transaction data
 	baggage := map[string]interface{}{
 		"raw_data": map[string]interface{}{
-			"tx_hash":    rawData.TxHash,
-			"network_id": float64(rawData.NetworkID),
-			"trace":      rawData.Trace,
-			"logs":       rawData.Logs,
-			"receipt":    rawData.Receipt,
-			"block":      rawData.Block,
+			"tx_hash":     rawData.TxHash,
+			"network_id":  float64(rawData.NetworkID),
+			"transaction": rawData.Transaction,
+			"trace":       rawData.Trace,
+			"logs":        rawData.Logs,
+			"receipt":     rawData.Receipt,
+			"block":       rawData.Block,
 		},
 	}
 	fmt.Printf("✅ Baggage initialized with %d items\n", len(baggage))
@@ -132,9 +176,9 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 
 	fmt.Println("   📥 Adding pipeline processors...")
 
-	// Add static context provider first (loads CSV data - tokens, protocols, addresses)
+	// Add static context provider first (shared CSV data - tokens, protocols, addresses)
 	fmt.Println("      • Static Context Provider (CSV data loader)")
-	staticContextProvider := txtools.NewStaticContextProvider(true) // Enable verbose for debugging token data loading
+	staticContextProvider := a.staticData
 	if err := pipeline.AddProcessor(staticContextProvider); err != nil {
 		return nil, fmt.Errorf("failed to add static context provider: %w", err)
 	}
@@ -149,7 +193,7 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 
 	// Add ABI resolver (runs early - fetches contract ABIs from Etherscan)
 	fmt.Println("      • ABI Resolver (Etherscan)")
-	abiResolver := txtools.NewABIResolver(a.cache, a.verbose)
+	abiResolver := txtools.NewABIResolverWithRPC(client, a.abiRegistry, a.cache, a.verbose)
 	if err := pipeline.AddProcessor(abiResolver); err != nil {
 		return nil, fmt.Errorf("failed to add ABI resolver: %w", err)
 	}
@@ -179,6 +223,38 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 	}
 	contextProviders = append(contextProviders, signatureResolver)
 
+	// Add revert decoder (explains failed calls and why the transaction failed)
+	fmt.Println("      • Revert Decoder")
+	revertDecoder := txtools.NewRevertDecoder(a.verbose)
+	if err := pipeline.AddProcessor(revertDecoder); err != nil {
+		return nil, fmt.Errorf("failed to add revert decoder: %w", err)
+	}
+	contextProviders = append(contextProviders, revertDecoder)
+
+	// Add call unwrapper (multicall, Safe MultiSend and Universal Router calldata as a call tree)
+	fmt.Println("      • Call Unwrapper")
+	callUnwrapper := txtools.NewCallUnwrapper(a.verbose)
+	if err := pipeline.AddProcessor(callUnwrapper); err != nil {
+		return nil, fmt.Errorf("failed to add call unwrapper: %w", err)
+	}
+	contextProviders = append(contextProviders, callUnwrapper)
+
+	// Add UserOperation decoder (ERC-4337 bundles as each smart account's calls)
+	fmt.Println("      • UserOperation Decoder")
+	userOperationDecoder := txtools.NewUserOperationDecoder(a.verbose)
+	if err := pipeline.AddProcessor(userOperationDecoder); err != nil {
+		return nil, fmt.Errorf("failed to add user operation decoder: %w", err)
+	}
+	contextProviders = append(contextProviders, userOperationDecoder)
+
+	// Add Safe transaction decoder (execTransaction with the owners who signed it)
+	fmt.Println("      • Safe Transaction Decoder")
+	safeTransactionDecoder := txtools.NewSafeTransactionDecoder(client, a.verbose)
+	if err := pipeline.AddProcessor(safeTransactionDecoder); err != nil {
+		return nil, fmt.Errorf("failed to add safe transaction decoder: %w", err)
+	}
+	contextProviders = append(contextProviders, safeTransactionDecoder)
+
 	// Add token transfer extractor (extracts transfers from events)
 	fmt.Println("      • Token Transfer Extractor")
 	transferExtractor := txtools.NewTokenTransferExtractor()
@@ -198,11 +274,28 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 	// Add token metadata enricher
 	fmt.Println("      • Token Metadata Enricher")
 	tokenMetadata := txtools.NewTokenMetadataEnricher(a.cache, a.verbose, client)
+	tokenMetadata.SetStaticContextProvider(staticContextProvider)
 	if err := pipeline.AddProcessor(tokenMetadata); err != nil {
 		return nil, fmt.Errorf("failed to add token metadata enricher: %w", err)
 	}
 	contextProviders = append(contextProviders, tokenMetadata)
 
+	// Add pool resolver (verifies pools with their factory or registry)
+	fmt.Println("      • Pool Resolver")
+	poolResolver := txtools.NewPoolResolver(client, staticContextProvider, a.cache, a.verbose)
+	if err := pipeline.AddProcessor(poolResolver); err != nil {
+		return nil, fmt.Errorf("failed to add pool resolver: %w", err)
+	}
+	contextProviders = append(contextProviders, poolResolver)
+
+	// Add NFT sale detector (marketplace events and NFT-for-payment transfer patterns)
+	fmt.Println("      • NFT Sale Detector")
+	nftSaleDetector := txtools.NewNFTSaleDetector(a.verbose)
+	if err := pipeline.AddProcessor(nftSaleDetector); err != nil {
+		return nil, fmt.Errorf("failed to add NFT sale detector: %w", err)
+	}
+	contextProviders = append(contextProviders, nftSaleDetector)
+
 	// Add amounts finder (NEW - uses LLM to detect ALL relevant amounts generically)
 	fmt.Println("      • Amounts Finder (AI-powered)")
 	amountsFinder := txtools.NewAmountsFinder(a.llm, a.verbose)
@@ -214,6 +307,7 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 	// Add icon resolver (discovers token icons from various sources)
 	fmt.Println("      • Icon Resolver")
 	iconResolver := txtools.NewIconResolver(staticContextProvider, a.cache, a.verbose)
+	iconResolver.SetIconStore(a.iconStore)
 	if err := pipeline.AddProcessor(iconResolver); err != nil {
 		return nil, fmt.Errorf("failed to add icon resolver: %w", err)
 	}
@@ -257,6 +351,7 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 	// Add protocol resolver (probabilistic protocol detection with RAG)
 	fmt.Println("      • Protocol Resolver (AI-powered)")
 	protocolResolver := txtools.NewProtocolResolver(a.llm, a.verbose, 0.6)
+	protocolResolver.SetStaticContextProvider(staticContextProvider)
 	if err := pipeline.AddProcessor(protocolResolver); err != nil {
 		return nil, fmt.Errorf("failed to add protocol resolver: %w", err)
 	}
@@ -265,11 +360,52 @@ func (a *TxplainAgent) ExplainTransaction(ctx context.Context, request *models.T
 	// Add tag resolver (probabilistic tag detection with RAG)
 	fmt.Println("      • Tag Resolver (AI-powered)")
 	tagResolver := txtools.NewTagResolver(a.llm, a.verbose, 0.6)
+	tagResolver.SetStaticContextProvider(staticContextProvider)
 	if err := pipeline.AddProcessor(tagResolver); err != nil {
 		return nil, fmt.Errorf("failed to add tag resolver: %w", err)
 	}
 	contextProviders = append(contextProviders, tagResolver)
 
+	// Add action builder (structured actions, LLM only when decoders cannot explain the transaction)
+	fmt.Println("      • Action Builder")
+	actionBuilder := txtools.NewActionBuilder(a.llm, a.verbose)
+	if err := pipeline.AddProcessor(actionBuilder); err != nil {
+		return nil, fmt.Errorf("failed to add action builder: %w", err)
+	}
+	contextProviders = append(contextProviders, actionBuilder)
+
+	// Add approval analyzer (allowances and permits with risk flags)
+	fmt.Println("      • Approval Analyzer")
+	approvalAnalyzer := txtools.NewApprovalAnalyzer(client, a.verbose)
+	if err := pipeline.AddProcessor(approvalAnalyzer); err != nil {
+		return nil, fmt.Errorf("failed to add approval analyzer: %w", err)
+	}
+	contextProviders = append(contextProviders, approvalAnalyzer)
+
+	// Add address poisoning detector (lookalike addresses and fake tokens)
+	fmt.Println("      • Address Poisoning Detector")
+	poisoningDetector := txtools.NewAddressPoisoningDetector(client, staticContextProvider, a.verbose)
+	if err := pipeline.AddProcessor(poisoningDetector); err != nil {
+		return nil, fmt.Errorf("failed to add address poisoning detector: %w", err)
+	}
+	contextProviders = append(contextProviders, poisoningDetector)
+
+	// Add address screener (local sanctions, scam and denylist lists)
+	fmt.Println("      • Address Screener")
+	addressScreener := txtools.NewAddressScreener(staticContextProvider, a.verbose)
+	if err := pipeline.AddProcessor(addressScreener); err != nil {
+		return nil, fmt.Errorf("failed to add address screener: %w", err)
+	}
+	contextProviders = append(contextProviders, addressScreener)
+
+	// Add MEV detector (sandwiches, arbitrage, liquidations and JIT liquidity in the block)
+	fmt.Println("      • MEV Detector")
+	mevDetector := txtools.NewMEVDetector(client, a.verbose)
+	if err := pipeline.AddProcessor(mevDetector); err != nil {
+		return nil, fmt.Errorf("failed to add MEV detector: %w", err)
+	}
+	contextProviders = append(contextProviders, mevDetector)
+
 	// Add context providers to baggage for transaction explainer
 	baggage["context_providers"] = contextProviders
 
@@ -403,12 +539,13 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	// Step 2: Initialize baggage with raw transaction data
 	baggage := map[string]interface{}{
 		"raw_data": map[string]interface{}{
-			"tx_hash":    rawData.TxHash,
-			"network_id": float64(rawData.NetworkID),
-			"trace":      rawData.Trace,
-			"logs":       rawData.Logs,
-			"receipt":    rawData.Receipt,
-			"block":      rawData.Block,
+			"tx_hash":     rawData.TxHash,
+			"network_id":  float64(rawData.NetworkID),
+			"transaction": rawData.Transaction,
+			"trace":       rawData.Trace,
+			"logs":        rawData.Logs,
+			"receipt":     rawData.Receipt,
+			"block":       rawData.Block,
 		},
 	}
 
@@ -419,8 +556,8 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	// Send progress update for pipeline setup
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding data processing tools...")
 
-	// Add static context provider first (loads CSV data - tokens, protocols, addresses)
-	staticContextProvider := txtools.NewStaticContextProvider(a.verbose)
+	// Add static context provider first (shared CSV data - tokens, protocols, addresses)
+	staticContextProvider := a.staticData
 	if err := pipeline.AddProcessor(staticContextProvider); err != nil {
 		progressTracker.SendError(fmt.Errorf("failed to add static context provider: %w", err))
 		return nil, fmt.Errorf("failed to add static context provider: %w", err)
@@ -437,7 +574,7 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 
 	// Add ABI resolver (runs early - fetches contract ABIs from Etherscan)
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding ABI resolver...")
-	abiResolver := txtools.NewABIResolver(a.cache, a.verbose)
+	abiResolver := txtools.NewABIResolverWithRPC(client, a.abiRegistry, a.cache, a.verbose)
 	if err := pipeline.AddProcessor(abiResolver); err != nil {
 		progressTracker.SendError(fmt.Errorf("failed to add ABI resolver: %w", err))
 		return nil, fmt.Errorf("failed to add ABI resolver: %w", err)
@@ -471,6 +608,42 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	}
 	contextProviders = append(contextProviders, signatureResolver)
 
+	// Add revert decoder (explains failed calls and why the transaction failed)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding revert decoder...")
+	revertDecoder := txtools.NewRevertDecoder(a.verbose)
+	if err := pipeline.AddProcessor(revertDecoder); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add revert decoder: %w", err))
+		return nil, fmt.Errorf("failed to add revert decoder: %w", err)
+	}
+	contextProviders = append(contextProviders, revertDecoder)
+
+	// Add call unwrapper (multicall, Safe MultiSend and Universal Router calldata as a call tree)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding call unwrapper...")
+	callUnwrapper := txtools.NewCallUnwrapper(a.verbose)
+	if err := pipeline.AddProcessor(callUnwrapper); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add call unwrapper: %w", err))
+		return nil, fmt.Errorf("failed to add call unwrapper: %w", err)
+	}
+	contextProviders = append(contextProviders, callUnwrapper)
+
+	// Add UserOperation decoder (ERC-4337 bundles as each smart account's calls)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding user operation decoder...")
+	userOperationDecoder := txtools.NewUserOperationDecoder(a.verbose)
+	if err := pipeline.AddProcessor(userOperationDecoder); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add user operation decoder: %w", err))
+		return nil, fmt.Errorf("failed to add user operation decoder: %w", err)
+	}
+	contextProviders = append(contextProviders, userOperationDecoder)
+
+	// Add Safe transaction decoder (execTransaction with the owners who signed it)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding safe transaction decoder...")
+	safeTransactionDecoder := txtools.NewSafeTransactionDecoder(client, a.verbose)
+	if err := pipeline.AddProcessor(safeTransactionDecoder); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add safe transaction decoder: %w", err))
+		return nil, fmt.Errorf("failed to add safe transaction decoder: %w", err)
+	}
+	contextProviders = append(contextProviders, safeTransactionDecoder)
+
 	// Add token transfer extractor (extracts transfers from events)
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding transfer extractor...")
 	transferExtractor := txtools.NewTokenTransferExtractor()
@@ -492,12 +665,31 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	// Add token metadata enricher
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding token metadata enricher...")
 	tokenMetadata := txtools.NewTokenMetadataEnricher(a.cache, a.verbose, client)
+	tokenMetadata.SetStaticContextProvider(staticContextProvider)
 	if err := pipeline.AddProcessor(tokenMetadata); err != nil {
 		progressTracker.SendError(fmt.Errorf("failed to add token metadata enricher: %w", err))
 		return nil, fmt.Errorf("failed to add token metadata enricher: %w", err)
 	}
 	contextProviders = append(contextProviders, tokenMetadata)
 
+	// Add pool resolver (verifies pools with their factory or registry)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding pool resolver...")
+	poolResolver := txtools.NewPoolResolver(client, staticContextProvider, a.cache, a.verbose)
+	if err := pipeline.AddProcessor(poolResolver); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add pool resolver: %w", err))
+		return nil, fmt.Errorf("failed to add pool resolver: %w", err)
+	}
+	contextProviders = append(contextProviders, poolResolver)
+
+	// Add NFT sale detector (marketplace events and NFT-for-payment transfer patterns)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding NFT sale detector...")
+	nftSaleDetector := txtools.NewNFTSaleDetector(a.verbose)
+	if err := pipeline.AddProcessor(nftSaleDetector); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add NFT sale detector: %w", err))
+		return nil, fmt.Errorf("failed to add NFT sale detector: %w", err)
+	}
+	contextProviders = append(contextProviders, nftSaleDetector)
+
 	// Add amounts finder (NEW - uses LLM to detect ALL relevant amounts generically)
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding AI amounts finder...")
 	amountsFinder := txtools.NewAmountsFinder(a.llm, a.verbose)
@@ -510,6 +702,7 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	// Add icon resolver (discovers token icons from various sources)
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding icon resolver...")
 	iconResolver := txtools.NewIconResolver(staticContextProvider, a.cache, a.verbose)
+	iconResolver.SetIconStore(a.iconStore)
 	if err := pipeline.AddProcessor(iconResolver); err != nil {
 		progressTracker.SendError(fmt.Errorf("failed to add icon resolver: %w", err))
 		return nil, fmt.Errorf("failed to add icon resolver: %w", err)
@@ -556,6 +749,7 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	// Add protocol resolver (probabilistic protocol detection with RAG)
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding protocol resolver...")
 	protocolResolver := txtools.NewProtocolResolver(a.llm, a.verbose, 0.6)
+	protocolResolver.SetStaticContextProvider(staticContextProvider)
 	if err := pipeline.AddProcessor(protocolResolver); err != nil {
 		progressTracker.SendError(fmt.Errorf("failed to add protocol resolver: %w", err))
 		return nil, fmt.Errorf("failed to add protocol resolver: %w", err)
@@ -565,12 +759,58 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	// Add tag resolver (probabilistic tag detection with RAG)
 	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding tag resolver...")
 	tagResolver := txtools.NewTagResolver(a.llm, a.verbose, 0.6)
+	tagResolver.SetStaticContextProvider(staticContextProvider)
 	if err := pipeline.AddProcessor(tagResolver); err != nil {
 		progressTracker.SendError(fmt.Errorf("failed to add tag resolver: %w", err))
 		return nil, fmt.Errorf("failed to add tag resolver: %w", err)
 	}
 	contextProviders = append(contextProviders, tagResolver)
 
+	// Add action builder (structured actions, LLM only when decoders cannot explain the transaction)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding action builder...")
+	actionBuilder := txtools.NewActionBuilder(a.llm, a.verbose)
+	if err := pipeline.AddProcessor(actionBuilder); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add action builder: %w", err))
+		return nil, fmt.Errorf("failed to add action builder: %w", err)
+	}
+	contextProviders = append(contextProviders, actionBuilder)
+
+	// Add approval analyzer (allowances and permits with risk flags)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding approval analyzer...")
+	approvalAnalyzer := txtools.NewApprovalAnalyzer(client, a.verbose)
+	if err := pipeline.AddProcessor(approvalAnalyzer); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add approval analyzer: %w", err))
+		return nil, fmt.Errorf("failed to add approval analyzer: %w", err)
+	}
+	contextProviders = append(contextProviders, approvalAnalyzer)
+
+	// Add address poisoning detector (lookalike addresses and fake tokens)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding address poisoning detector...")
+	poisoningDetector := txtools.NewAddressPoisoningDetector(client, staticContextProvider, a.verbose)
+	if err := pipeline.AddProcessor(poisoningDetector); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add address poisoning detector: %w", err))
+		return nil, fmt.Errorf("failed to add address poisoning detector: %w", err)
+	}
+	contextProviders = append(contextProviders, poisoningDetector)
+
+	// Add address screener (local sanctions, scam and denylist lists)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding address screener...")
+	addressScreener := txtools.NewAddressScreener(staticContextProvider, a.verbose)
+	if err := pipeline.AddProcessor(addressScreener); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add address screener: %w", err))
+		return nil, fmt.Errorf("failed to add address screener: %w", err)
+	}
+	contextProviders = append(contextProviders, addressScreener)
+
+	// Add MEV detector (sandwiches, arbitrage, liquidations and JIT liquidity in the block)
+	progressTracker.UpdateComponent("pipeline_setup", models.ComponentGroupData, "Configuring Pipeline", models.ComponentStatusRunning, "Adding MEV detector...")
+	mevDetector := txtools.NewMEVDetector(client, a.verbose)
+	if err := pipeline.AddProcessor(mevDetector); err != nil {
+		progressTracker.SendError(fmt.Errorf("failed to add MEV detector: %w", err))
+		return nil, fmt.Errorf("failed to add MEV detector: %w", err)
+	}
+	contextProviders = append(contextProviders, mevDetector)
+
 	// Add context providers to baggage for transaction explainer
 	baggage["context_providers"] = contextProviders
 
@@ -648,6 +888,58 @@ func (a *TxplainAgent) ExplainTransactionWithProgress(ctx context.Context, reque
 	return explanation, nil
 }
 
+// ExplainSignature explains what signing an EIP-712 typed-data payload would authorize
+func (a *TxplainAgent) ExplainSignature(ctx context.Context, request *models.SignatureRequest) (*models.SignatureExplanation, error) {
+	fmt.Printf("\n✍️  ANALYZING SIGNATURE REQUEST: %s on network %d\n", request.TypedData.PrimaryType, request.NetworkID)
+
+	// Validate input
+	if !models.IsValidNetwork(request.NetworkID) {
+		return nil, fmt.Errorf("unsupported network ID: %d", request.NetworkID)
+	}
+
+	// Get RPC client for the network
+	client, exists := a.rpcClients[request.NetworkID]
+	if !exists {
+		return nil, fmt.Errorf("no RPC client available for network %d", request.NetworkID)
+	}
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(request.NetworkID),
+		},
+		"typed_data": &request.TypedData,
+		"signature":  request.Signature,
+	}
+
+	// There is no transaction for the ABI resolver and decoders, so the tools run in dependency order directly
+	typedDataDecoder := txtools.NewTypedDataDecoder(a.verbose)
+	tokenMetadata := txtools.NewTokenMetadataEnricher(a.cache, a.verbose, client)
+	tokenMetadata.SetStaticContextProvider(a.staticData)
+	signatureExplainer := txtools.NewSignatureExplainer(client, a.staticData, a.verbose)
+	for _, tool := range []txtools.Tool{typedDataDecoder, tokenMetadata, signatureExplainer} {
+		if err := tool.Process(ctx, baggage); err != nil {
+			return nil, fmt.Errorf("%s failed: %w", tool.Name(), err)
+		}
+	}
+
+	explanation, ok := txtools.GetSignatureExplanation(baggage)
+	if !ok {
+		return nil, fmt.Errorf("invalid signature explanation format")
+	}
+
+	// The summary is deterministic, so annotations are a best-effort addition
+	baggage["context_providers"] = []interface{}{tokenMetadata, signatureExplainer}
+	annotations, err := txtools.NewAnnotationGenerator(a.llm, a.verbose).Annotate(ctx, explanation.Summary, baggage, request.NetworkID)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to annotate signature explanation: %v\n", err)
+	} else {
+		explanation.Annotations = annotations
+	}
+
+	fmt.Printf("✅ SIGNATURE ANALYSIS COMPLETE: %s\n", explanation.Summary)
+	return explanation, nil
+}
+
 // CreateLangChainAgent creates a LangChain agent with registered tools (alternative approach)
 func (a *TxplainAgent) CreateLangChainAgent() (*agents.Executor, error) {
 	// For now, return a simple implementation - the full LangChain integration
@@ -660,8 +952,27 @@ func (a *TxplainAgent) GetSupportedNetworks() map[int64]models.Network {
 	return models.SupportedNetworks
 }
 
+// GetLocalABIRegistry returns the shared on-disk ABI registry
+func (a *TxplainAgent) GetLocalABIRegistry() *txtools.LocalABIRegistry {
+	return a.abiRegistry
+}
+
+// GetStaticContextProvider returns the shared static knowledge base
+func (a *TxplainAgent) GetStaticContextProvider() *txtools.StaticContextProvider {
+	return a.staticData
+}
+
+// GetIconStore returns the shared icon store
+func (a *TxplainAgent) GetIconStore() *txtools.IconStore {
+	return a.iconStore
+}
+
 // Close cleans up resources
 func (a *TxplainAgent) Close() error {
+	// Stop background file watchers
+	if a.stopWatchers != nil {
+		a.stopWatchers()
+	}
 	// Close RPC clients if needed
 	return nil
 }
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxABIUploadSize caps ABI and artifact uploads (Foundry artifacts with metadata can be large)
+const maxABIUploadSize = 10 << 20
+
+// adminAuthMiddleware protects admin endpoints with the ADMIN_API_TOKEN bearer token.
+// Admin endpoints are disabled entirely when no token is configured.
+func (s *Server) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("ADMIN_API_TOKEN")
+		if token == "" {
+			s.writeErrorResponse(w, http.StatusForbidden, "Admin API is disabled (ADMIN_API_TOKEN not set)", nil)
+			return
+		}
+
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			s.writeErrorResponse(w, http.StatusUnauthorized, "Invalid or missing admin token", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleListABIs lists all ABIs in the local registry (without the ABI bodies)
+func (s *Server) handleListABIs(w http.ResponseWriter, r *http.Request) {
+	entries := s.agent.GetLocalABIRegistry().List()
+
+	var abis []map[string]interface{}
+	for _, entry := range entries {
+		abis = append(abis, map[string]interface{}{
+			"chain_id":         entry.ChainID,
+			"address":          entry.Address,
+			"contract_name":    entry.ContractName,
+			"compiler_version": entry.CompilerVersion,
+			"format":           entry.Format,
+			"updated_at":       entry.UpdatedAt,
+		})
+	}
+
+	response := map[string]interface{}{
+		"abis":  abis,
+		"count": len(abis),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetABI returns a single ABI from the local registry
+func (s *Server) handleGetABI(w http.ResponseWriter, r *http.Request) {
+	networkID, address, ok := s.parseABIPath(w, r)
+	if !ok {
+		return
+	}
+
+	entry, exists := s.agent.GetLocalABIRegistry().Lookup(networkID, address)
+	if !exists {
+		s.writeErrorResponse(w, http.StatusNotFound, "No local ABI for this contract", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// handleUploadABI stores an ABI JSON array or a Hardhat/Foundry artifact for a contract
+func (s *Server) handleUploadABI(w http.ResponseWriter, r *http.Request) {
+	networkID, address, ok := s.parseABIPath(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxABIUploadSize))
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	entry, err := s.agent.GetLocalABIRegistry().Save(networkID, address, body)
+	if err != nil {
+		// Validation errors are safe to return - they only describe the uploaded file
+		s.writeErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid ABI: %v", err), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// handleDeleteABI removes a contract's ABI from the local registry
+func (s *Server) handleDeleteABI(w http.ResponseWriter, r *http.Request) {
+	networkID, address, ok := s.parseABIPath(w, r)
+	if !ok {
+		return
+	}
+
+	if _, exists := s.agent.GetLocalABIRegistry().Lookup(networkID, address); !exists {
+		s.writeErrorResponse(w, http.StatusNotFound, "No local ABI for this contract", nil)
+		return
+	}
+
+	if err := s.agent.GetLocalABIRegistry().Delete(networkID, address); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete ABI", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseABIPath extracts and validates the network and address path variables.
+// Any chain ID is accepted so that private chains can be registered.
+func (s *Server) parseABIPath(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	vars := mux.Vars(r)
+
+	networkID, err := strconv.ParseInt(vars["network"], 10, 64)
+	if err != nil || networkID <= 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid network ID", err)
+		return 0, "", false
+	}
+
+	address := strings.ToLower(vars["address"])
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid contract address", nil)
+		return 0, "", false
+	}
+
+	return networkID, address, true
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/tools"
+)
+
+// iconCacheMaxAge lets browsers reuse icons for a day; the ETag makes revalidation cheap afterwards
+const iconCacheMaxAge = 24 * time.Hour
+
+// handleGetIcon serves a token icon as a PNG. Icons are downloaded into the icon store the first time they
+// are requested (or discovered during an explanation); tokens without one get a generated identicon.
+func (s *Server) handleGetIcon(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	networkID, err := strconv.ParseInt(vars["network"], 10, 64)
+	if err != nil || networkID <= 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid network ID", err)
+		return
+	}
+	// Both are checked before anything is fetched or stored, so the endpoint cannot grow the icon store at will
+	if !models.IsValidNetwork(networkID) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Unsupported network ID", nil)
+		return
+	}
+
+	address := strings.ToLower(vars["address"])
+	if !isHexAddress(address) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid contract address", nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	data, source := []byte(nil), "identicon"
+	icon, err := s.agent.GetIconStore().Ensure(ctx, networkID, address, tools.TrustWalletIconURLs(networkID, address))
+	if err != nil {
+		log.Printf("Icon fetch failed for %s on network %d: %v", address, networkID, err)
+	} else if !icon.Missing {
+		data, source = icon.Data, "store"
+	}
+	if data == nil {
+		data = tools.Identicon(address)
+	}
+
+	etag := tools.IconETag(data)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(iconCacheMaxAge.Seconds())))
+	w.Header().Set("X-Icon-Source", source)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
+
+// isHexAddress reports whether a lowercase string is 0x followed by exactly 40 hex characters
+func isHexAddress(address string) bool {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return false
+	}
+	_, err := hex.DecodeString(address[2:])
+	return err == nil
+}
+
+// etagMatches checks an If-None-Match header (which may list several tags or be "*") against an ETag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/txplain/txplain/internal/tools"
+)
+
+// maxKnowledgeEntrySize caps the JSON body of a single static knowledge entry
+const maxKnowledgeEntrySize = 64 << 10
+
+// handleReloadKnowledge re-reads the static data directory
+func (s *Server) handleReloadKnowledge(w http.ResponseWriter, r *http.Request) {
+	store := s.agent.GetStaticContextProvider()
+	store.Reload()
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"dir":       store.Dir(),
+		"tokens":    len(store.ListTokens(0)),
+		"addresses": len(store.ListAddresses(0)),
+		"protocols": len(store.Protocols()),
+		"tags":      len(store.Tags()),
+	})
+}
+
+// handleListKnowledgeTokens lists known tokens, optionally filtered with ?chain_id=
+func (s *Server) handleListKnowledgeTokens(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := s.parseChainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	tokens := s.agent.GetStaticContextProvider().ListTokens(chainID)
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tokens": tokens,
+		"count":  len(tokens),
+	})
+}
+
+// handleSaveKnowledgeToken adds or replaces a token; the path decides its chain and address
+func (s *Server) handleSaveKnowledgeToken(w http.ResponseWriter, r *http.Request) {
+	chainID, address, ok := s.parseKnowledgePath(w, r, false)
+	if !ok {
+		return
+	}
+
+	var entry tools.TokenListEntry
+	if !s.decodeKnowledgeEntry(w, r, &entry) {
+		return
+	}
+	entry.ChainID = chainID
+	entry.Address = address
+
+	if err := s.agent.GetStaticContextProvider().SaveToken(entry); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, entry)
+}
+
+// handleDeleteKnowledgeToken removes a token
+func (s *Server) handleDeleteKnowledgeToken(w http.ResponseWriter, r *http.Request) {
+	chainID, address, ok := s.parseKnowledgePath(w, r, false)
+	if !ok {
+		return
+	}
+
+	if err := s.agent.GetStaticContextProvider().DeleteToken(chainID, address); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListKnowledgeAddresses lists address labels, optionally filtered with ?chain_id=
+func (s *Server) handleListKnowledgeAddresses(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := s.parseChainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	addresses := s.agent.GetStaticContextProvider().ListAddresses(chainID)
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"addresses": addresses,
+		"count":     len(addresses),
+	})
+}
+
+// handleSaveKnowledgeAddress adds or replaces an address label. Network 0 labels the address on every chain.
+func (s *Server) handleSaveKnowledgeAddress(w http.ResponseWriter, r *http.Request) {
+	chainID, address, ok := s.parseKnowledgePath(w, r, true)
+	if !ok {
+		return
+	}
+
+	var entry tools.StaticAddress
+	if !s.decodeKnowledgeEntry(w, r, &entry) {
+		return
+	}
+	entry.ChainID = chainID
+	entry.Address = address
+
+	if err := s.agent.GetStaticContextProvider().SaveAddress(entry); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, entry)
+}
+
+// handleDeleteKnowledgeAddress removes an address label
+func (s *Server) handleDeleteKnowledgeAddress(w http.ResponseWriter, r *http.Request) {
+	chainID, address, ok := s.parseKnowledgePath(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := s.agent.GetStaticContextProvider().DeleteAddress(chainID, address); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListKnowledgeProtocols lists the curated protocols
+func (s *Server) handleListKnowledgeProtocols(w http.ResponseWriter, r *http.Request) {
+	protocols := s.agent.GetStaticContextProvider().Protocols()
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"protocols": protocols,
+		"count":     len(protocols),
+	})
+}
+
+// handleSaveKnowledgeProtocol adds or replaces a protocol by name
+func (s *Server) handleSaveKnowledgeProtocol(w http.ResponseWriter, r *http.Request) {
+	var protocol tools.ProtocolKnowledge
+	if !s.decodeKnowledgeEntry(w, r, &protocol) {
+		return
+	}
+	protocol.Name = mux.Vars(r)["name"]
+
+	if err := s.agent.GetStaticContextProvider().SaveProtocol(protocol); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, protocol)
+}
+
+// handleDeleteKnowledgeProtocol removes a protocol by name
+func (s *Server) handleDeleteKnowledgeProtocol(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.GetStaticContextProvider().DeleteProtocol(mux.Vars(r)["name"]); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListKnowledgeTags lists the curated transaction tags
+func (s *Server) handleListKnowledgeTags(w http.ResponseWriter, r *http.Request) {
+	tags := s.agent.GetStaticContextProvider().Tags()
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tags":  tags,
+		"count": len(tags),
+	})
+}
+
+// handleSaveKnowledgeTag adds or replaces a tag
+func (s *Server) handleSaveKnowledgeTag(w http.ResponseWriter, r *http.Request) {
+	var tag tools.TagKnowledge
+	if !s.decodeKnowledgeEntry(w, r, &tag) {
+		return
+	}
+	tag.Tag = mux.Vars(r)["tag"]
+
+	if err := s.agent.GetStaticContextProvider().SaveTag(tag); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, tag)
+}
+
+// handleDeleteKnowledgeTag removes a tag
+func (s *Server) handleDeleteKnowledgeTag(w http.ResponseWriter, r *http.Request) {
+	if err := s.agent.GetStaticContextProvider().DeleteTag(mux.Vars(r)["tag"]); err != nil {
+		s.writeKnowledgeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseKnowledgePath extracts the network and address path variables. Chain 0 is only accepted where an
+// entry can apply to every chain.
+func (s *Server) parseKnowledgePath(w http.ResponseWriter, r *http.Request, allowAllChains bool) (int64, string, bool) {
+	vars := mux.Vars(r)
+
+	chainID, err := strconv.ParseInt(vars["network"], 10, 64)
+	if err != nil || chainID < 0 || (chainID == 0 && !allowAllChains) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid network ID", err)
+		return 0, "", false
+	}
+
+	address := strings.ToLower(vars["address"])
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid address", nil)
+		return 0, "", false
+	}
+
+	return chainID, address, true
+}
+
+// parseChainFilter reads the optional chain_id query parameter (0 = all chains)
+func (s *Server) parseChainFilter(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	value := r.URL.Query().Get("chain_id")
+	if value == "" {
+		return 0, true
+	}
+
+	chainID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || chainID < 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid chain_id", err)
+		return 0, false
+	}
+	return chainID, true
+}
+
+// decodeKnowledgeEntry decodes a JSON request body into an entry
+func (s *Server) decodeKnowledgeEntry(w http.ResponseWriter, r *http.Request, entry interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxKnowledgeEntrySize)).Decode(entry); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return false
+	}
+	return true
+}
+
+// writeKnowledgeError maps static store errors to HTTP status codes
+func (s *Server) writeKnowledgeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, tools.ErrInvalidStaticEntry):
+		// Validation errors are safe to return - they only describe the submitted entry
+		s.writeErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, os.ErrNotExist):
+		s.writeErrorResponse(w, http.StatusNotFound, "Entry not found", nil)
+	default:
+		s.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update static data", err)
+	}
+}
+
+// writeJSON writes a JSON response
+func (s *Server) writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
@@ -62,12 +62,43 @@ func (s *Server) setupRoutes() {
 	// Transaction explanation with Server-Sent Events
 	v1.HandleFunc("/explain-sse", s.handleExplainTransactionSSE).Methods("POST")
 
+	// EIP-712 typed-data signature explanation (before the user signs)
+	v1.HandleFunc("/explain-signature", s.handleExplainSignature).Methods("POST")
+
 	// Get supported networks
 	v1.HandleFunc("/networks", s.handleGetNetworks).Methods("GET")
 
 	// Transaction details (without explanation)
 	v1.HandleFunc("/transaction/{network}/{hash}", s.handleGetTransactionDetails).Methods("GET")
 
+	// Token icons served from the local icon store (identicon fallback)
+	v1.HandleFunc("/icons/{network}/{address}", s.handleGetIcon).Methods("GET", "HEAD")
+
+	// Local ABI registry management (admin token required)
+	abis := v1.PathPrefix("/abis").Subrouter()
+	abis.Use(s.adminAuthMiddleware)
+	abis.HandleFunc("", s.handleListABIs).Methods("GET")
+	abis.HandleFunc("/{network}/{address}", s.handleGetABI).Methods("GET")
+	abis.HandleFunc("/{network}/{address}", s.handleUploadABI).Methods("PUT", "POST")
+	abis.HandleFunc("/{network}/{address}", s.handleDeleteABI).Methods("DELETE")
+
+	// Static knowledge base management (admin token required)
+	knowledge := v1.PathPrefix("/knowledge").Subrouter()
+	knowledge.Use(s.adminAuthMiddleware)
+	knowledge.HandleFunc("/reload", s.handleReloadKnowledge).Methods("POST")
+	knowledge.HandleFunc("/tokens", s.handleListKnowledgeTokens).Methods("GET")
+	knowledge.HandleFunc("/tokens/{network}/{address}", s.handleSaveKnowledgeToken).Methods("PUT")
+	knowledge.HandleFunc("/tokens/{network}/{address}", s.handleDeleteKnowledgeToken).Methods("DELETE")
+	knowledge.HandleFunc("/addresses", s.handleListKnowledgeAddresses).Methods("GET")
+	knowledge.HandleFunc("/addresses/{network}/{address}", s.handleSaveKnowledgeAddress).Methods("PUT")
+	knowledge.HandleFunc("/addresses/{network}/{address}", s.handleDeleteKnowledgeAddress).Methods("DELETE")
+	knowledge.HandleFunc("/protocols", s.handleListKnowledgeProtocols).Methods("GET")
+	knowledge.HandleFunc("/protocols/{name}", s.handleSaveKnowledgeProtocol).Methods("PUT")
+	knowledge.HandleFunc("/protocols/{name}", s.handleDeleteKnowledgeProtocol).Methods("DELETE")
+	knowledge.HandleFunc("/tags", s.handleListKnowledgeTags).Methods("GET")
+	knowledge.HandleFunc("/tags/{tag}", s.handleSaveKnowledgeTag).Methods("PUT")
+	knowledge.HandleFunc("/tags/{tag}", s.handleDeleteKnowledgeTag).Methods("DELETE")
+
 	// Serve static assets (CSS, JS, etc.) - must come before SPA handler
 	s.router.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir("./web/dist/assets/"))))
 
@@ -160,6 +191,47 @@ func (s *Server) handleExplainTransaction(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// handleExplainSignature handles EIP-712 typed-data signature explanation requests
+func (s *Server) handleExplainSignature(w http.ResponseWriter, r *http.Request) {
+	// Parse request body
+	var request models.SignatureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	// Validate request
+	if request.TypedData.PrimaryType == "" || len(request.TypedData.Types) == 0 {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Typed data with types and primaryType is required", nil)
+		return
+	}
+
+	if !models.IsValidNetwork(request.NetworkID) {
+		s.writeErrorResponse(w, http.StatusBadRequest, "Unsupported network ID", nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+
+	explanation, err := s.agent.ExplainSignature(ctx, &request)
+	if err != nil {
+		log.Printf("ExplainSignature failed: %v", err)
+		if ctx.Err() != nil {
+			s.writeErrorResponse(w, http.StatusRequestTimeout, "Signature analysis timed out", err)
+		} else {
+			s.writeErrorResponse(w, http.StatusUnprocessableEntity, "Failed to explain signature", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if encodeErr := json.NewEncoder(w).Encode(explanation); encodeErr != nil {
+		log.Printf("FAILED to encode signature explanation response as JSON: %v", encodeErr)
+	}
+}
+
 // handleExplainTransactionSSE handles transaction explanation with Server-Sent Events
 func (s *Server) handleExplainTransactionSSE(w http.ResponseWriter, r *http.Request) {
 	// Parse request body FIRST before any SSE setup
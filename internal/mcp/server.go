@@ -88,6 +88,8 @@ func (s *Server) handleMCPRequest(w http.ResponseWriter, r *http.Request) {
 	switch request.Method {
 	case "txplain.explain":
 		s.handleExplainMethod(w, &request)
+	case "txplain.explain_signature":
+		s.handleExplainSignatureMethod(w, &request)
 	case "txplain.networks":
 		s.handleNetworksMethod(w, &request)
 	case "txplain.capabilities":
@@ -147,6 +149,60 @@ func (s *Server) handleExplainMethod(w http.ResponseWriter, request *MCPRequest)
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleExplainSignatureMethod handles EIP-712 typed-data signature explanation requests via MCP
+func (s *Server) handleExplainSignatureMethod(w http.ResponseWriter, request *MCPRequest) {
+	typedData, ok := request.Params["typed_data"]
+	if !ok {
+		s.writeErrorResponse(w, request.ID, -32602, "Invalid params", "typed_data is required")
+		return
+	}
+
+	networkIDFloat, ok := request.Params["network_id"].(float64)
+	if !ok {
+		s.writeErrorResponse(w, request.ID, -32602, "Invalid params", "network_id is required")
+		return
+	}
+	networkID := int64(networkIDFloat)
+
+	// Validate network
+	if !models.IsValidNetwork(networkID) {
+		s.writeErrorResponse(w, request.ID, -32602, "Invalid params", "Unsupported network ID")
+		return
+	}
+
+	// typed_data is the eth_signTypedData_v4 JSON string or its object; as an object, uint256 values above
+	// 2^53 must be strings to survive the round trip
+	signatureRequest := &models.SignatureRequest{NetworkID: networkID}
+	signatureRequest.Signature, _ = request.Params["signature"].(string)
+	encoded, err := json.Marshal(typedData)
+	if err == nil {
+		err = json.Unmarshal(encoded, &signatureRequest.TypedData)
+	}
+	if err != nil || signatureRequest.TypedData.PrimaryType == "" {
+		s.writeErrorResponse(w, request.ID, -32602, "Invalid params", "typed_data must be an EIP-712 payload with types and primaryType")
+		return
+	}
+
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	explanation, err := s.agent.ExplainSignature(ctx, signatureRequest)
+	if err != nil {
+		s.writeErrorResponse(w, request.ID, -32000, "Processing error", err.Error())
+		return
+	}
+
+	response := MCPResponse{
+		Result: explanation,
+		ID:     request.ID,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleNetworksMethod handles network list requests
 func (s *Server) handleNetworksMethod(w http.ResponseWriter, request *MCPRequest) {
 	networks := s.agent.GetSupportedNetworks()
@@ -177,6 +233,7 @@ func (s *Server) handleCapabilitiesMethod(w http.ResponseWriter, request *MCPReq
 		"version": "1.0.0",
 		"methods": []string{
 			"txplain.explain",
+			"txplain.explain_signature",
 			"txplain.networks",
 			"txplain.capabilities",
 		},
@@ -191,6 +248,7 @@ func (s *Server) handleCapabilitiesMethod(w http.ResponseWriter, request *MCPReq
 			"multi_network_support",
 			"token_transfer_detection",
 			"defi_protocol_recognition",
+			"signature_explanation",
 		},
 	}
 
@@ -224,6 +282,7 @@ func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
 		"version": "1.0.0",
 		"methods": []string{
 			"txplain.explain",
+			"txplain.explain_signature",
 			"txplain.networks",
 			"txplain.capabilities",
 		},
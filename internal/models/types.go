@@ -1,6 +1,7 @@
 package models
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -191,12 +192,13 @@ type TransactionRequest struct {
 
 // RawTransactionData contains the raw blockchain data
 type RawTransactionData struct {
-	TxHash    string                 `json:"tx_hash"`
-	NetworkID int64                  `json:"network_id"`
-	Trace     map[string]interface{} `json:"trace"`
-	Logs      []interface{}          `json:"logs"`
-	Receipt   map[string]interface{} `json:"receipt"`
-	Block     map[string]interface{} `json:"block"`
+	TxHash      string                 `json:"tx_hash"`
+	NetworkID   int64                  `json:"network_id"`
+	Transaction map[string]interface{} `json:"transaction"`
+	Trace       map[string]interface{} `json:"trace"`
+	Logs        []interface{}          `json:"logs"`
+	Receipt     map[string]interface{} `json:"receipt"`
+	Block       map[string]interface{} `json:"block"`
 }
 
 // Call represents a decoded contract method invocation
@@ -209,7 +211,8 @@ type Call struct {
 	CallType    string                 `json:"call_type"` // call, delegatecall, staticcall, etc.
 	Success     bool                   `json:"success"`
 	ErrorReason string                 `json:"error_reason,omitempty"`
-	Depth       int                    `json:"depth"` // Call depth for nested calls
+	RevertData  string                 `json:"revert_data,omitempty"` // Raw output of a reverted call
+	Depth       int                    `json:"depth"`                 // Call depth for nested calls
 }
 
 // Event represents a decoded emitted event
@@ -350,6 +353,315 @@ type ExplanationResult struct {
 	Tags         []string               `json:"tags,omitempty"`  // Transaction categorization tags
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
 	Annotations  []Annotation           `json:"annotations,omitempty"` // Interactive annotations for the UI
+	Failure      *Failure               `json:"failure,omitempty"`     // Why the transaction reverted
+	Actions      []Action               `json:"actions,omitempty"`     // What the transaction did, as structured steps
+	CallTree     *DecodedCall           `json:"call_tree,omitempty"`   // Calls unwrapped from batch calldata
+
+	UserOperations   []UserOperation   `json:"user_operations,omitempty"`   // ERC-4337 operations bundled in the transaction
+	SafeTransactions []SafeTransaction `json:"safe_transactions,omitempty"` // Safe multisig executions with their signers
+	Approvals        []Approval        `json:"approvals,omitempty"`         // Allowances and permits granted or revoked
+	MEV              []MEV             `json:"mev,omitempty"`               // Sandwiches, arbitrage and JIT liquidity around the transaction
+}
+
+// Batch kinds of a DecodedCall that wraps other calls
+const (
+	BatchMulticall       = "multicall"        // multicall(bytes[]) and Multicall3 aggregate variants
+	BatchSafe            = "safe"             // Safe execTransaction
+	BatchMultiSend       = "multisend"        // Safe MultiSend packed transactions
+	BatchUniversalRouter = "universal_router" // Uniswap Universal Router execute(commands, inputs)
+	BatchAccount         = "account"          // Smart account execute/executeBatch (ERC-4337 and ERC-7579)
+)
+
+// DecodedCall is a call decoded from calldata rather than from a trace frame. Batch calls carry the calls
+// they wrap, so a multicall inside a Safe MultiSend becomes a tree.
+type DecodedCall struct {
+	Contract     string                 `json:"contract"`
+	Method       string                 `json:"method,omitempty"`
+	Signature    string                 `json:"signature,omitempty"`
+	Arguments    map[string]interface{} `json:"arguments,omitempty"`
+	Value        string                 `json:"value,omitempty"`         // Wei, decimal
+	Operation    string                 `json:"operation"`               // call, delegatecall or command (Universal Router)
+	Batch        string                 `json:"batch,omitempty"`         // Set when the call wraps other calls
+	AllowFailure bool                   `json:"allow_failure,omitempty"` // The batch carries on if this call reverts
+	Calls        []DecodedCall          `json:"calls,omitempty"`
+}
+
+// UserOperation is an ERC-4337 operation a bundler submitted through an EntryPoint. The smart account
+// (Sender) is the actor; the bundler only relays it.
+type UserOperation struct {
+	Hash          string       `json:"hash,omitempty"`
+	EntryPoint    string       `json:"entry_point"`
+	Sender        string       `json:"sender"` // Smart account executing the operation
+	Nonce         string       `json:"nonce"`
+	Paymaster     string       `json:"paymaster,omitempty"` // Sponsor of the gas, empty when the account pays
+	Factory       string       `json:"factory,omitempty"`   // Set when the operation deploys the account
+	Bundler       string       `json:"bundler,omitempty"`   // Transaction sender
+	Beneficiary   string       `json:"beneficiary,omitempty"`
+	Success       bool         `json:"success"`
+	ActualGasCost string       `json:"actual_gas_cost,omitempty"` // Wei, decimal
+	ActualGasUsed string       `json:"actual_gas_used,omitempty"`
+	RevertReason  string       `json:"revert_reason,omitempty"`
+	Call          *DecodedCall `json:"call,omitempty"` // The operation's callData decoded against the account
+}
+
+// How a Safe owner approved a transaction
+const (
+	SafeSignatureECDSA        = "ecdsa"         // Signed the Safe transaction hash (EIP-712)
+	SafeSignatureEthSign      = "eth_sign"      // Signed the hash as an eth_sign message
+	SafeSignatureApprovedHash = "approved_hash" // Called approveHash on the Safe, or is the executor
+	SafeSignatureContract     = "contract"      // Contract owner validating through EIP-1271
+)
+
+// SafeSignature is one owner approval recovered from execTransaction's packed signatures
+type SafeSignature struct {
+	Owner   string `json:"owner"`
+	Type    string `json:"type"`
+	IsOwner bool   `json:"is_owner"` // The address is among the Safe's owners (false when owners are unknown)
+}
+
+// SafeTransaction is a Safe multisig execution: the transaction the Safe ran and who approved it
+type SafeTransaction struct {
+	Safe       string          `json:"safe"`
+	SafeTxHash string          `json:"safe_tx_hash,omitempty"`
+	Threshold  int             `json:"threshold,omitempty"`
+	Owners     []string        `json:"owners,omitempty"`
+	Signers    []SafeSignature `json:"signers"`
+	Executor   string          `json:"executor"` // Account that submitted execTransaction
+	Relayed    bool            `json:"relayed"`  // Submitted by an account that is not an owner
+	Success    bool            `json:"success"`
+	Payment    string          `json:"payment,omitempty"` // Gas refund the Safe paid to the executor or refund receiver
+	Call       *DecodedCall    `json:"call,omitempty"`    // The inner transaction (to, value, data, operation)
+}
+
+// Approval kinds
+const (
+	ApprovalKindAllowance       = "allowance"        // ERC20 approve/increaseAllowance
+	ApprovalKindToken           = "token"            // ERC721 approval of a single token
+	ApprovalKindOperator        = "operator"         // setApprovalForAll over a whole collection
+	ApprovalKindPermit          = "permit"           // EIP-2612 (or DAI-style) permit signature
+	ApprovalKindPermit2         = "permit2"          // Permit2 allowance, set by approve or a permit signature
+	ApprovalKindPermit2Transfer = "permit2_transfer" // Permit2 one-time signature transfer
+)
+
+// Spender types of an approval
+const (
+	SpenderTypeEOA      = "eoa"
+	SpenderTypeContract = "contract"
+)
+
+// Approval is an allowance, operator approval or permit granted (or revoked) in the transaction
+type Approval struct {
+	Kind        string       `json:"kind"`
+	Owner       string       `json:"owner"`
+	Spender     string       `json:"spender"`
+	SpenderName string       `json:"spender_name,omitempty"` // Verified contract, protocol or ENS name
+	SpenderType string       `json:"spender_type,omitempty"` // eoa or contract; empty when unknown
+	Allowance   ActionAmount `json:"allowance"`              // Token, and amount ("unlimited", "all") or token ID
+	Unlimited   bool         `json:"unlimited"`
+	Revoked     bool         `json:"revoked,omitempty"`    // Allowance set to zero or operator removed
+	Increase    bool         `json:"increase,omitempty"`   // Allowance raised by the amount rather than set to it
+	Expiration  *time.Time   `json:"expiration,omitempty"` // When a Permit2 allowance expires
+	Deadline    *time.Time   `json:"deadline,omitempty"`   // Until when the permit signature was valid
+	Source      string       `json:"source"`               // event, calldata or signature (not yet signed)
+	Risks       []string     `json:"risks,omitempty"`
+}
+
+// MEV kinds
+const (
+	MEVKindSandwich    = "sandwich"      // A searcher swapped the same pool right before and right after other swaps
+	MEVKindArbitrage   = "arbitrage"     // Swaps through pools in a cycle that ends with more of the starting token
+	MEVKindJIT         = "jit_liquidity" // Liquidity added to a pool right before a swap and removed right after
+	MEVKindLiquidation = "liquidation"   // An undercollateralized loan repaid in exchange for its collateral at a discount
+)
+
+// Roles of the transaction in a MEV pattern
+const (
+	MEVRoleVictim     = "victim"     // The sandwiched swap, or the swap a JIT position filled
+	MEVRoleFrontRun   = "front_run"  // The sandwich's first leg
+	MEVRoleBackRun    = "back_run"   // The sandwich's last leg
+	MEVRoleSearcher   = "searcher"   // The arbitrage itself
+	MEVRoleJITMint    = "jit_mint"   // The liquidity added by a JIT position
+	MEVRoleJITBurn    = "jit_burn"   // The liquidity removed by a JIT position
+	MEVRoleLiquidator = "liquidator" // The liquidation itself
+)
+
+// MEV is a maximal extractable value pattern the transaction took part in, found among the transactions of its
+// block
+type MEV struct {
+	Kind        string         `json:"kind"` // MEVKind*
+	Role        string         `json:"role"` // MEVRole*
+	Searcher    string         `json:"searcher"`
+	Pools       []string       `json:"pools"`
+	FrontRun    string         `json:"front_run,omitempty"` // Sandwich front-run or JIT mint transaction hash
+	BackRun     string         `json:"back_run,omitempty"`  // Sandwich back-run or JIT burn transaction hash
+	Victims     []string       `json:"victims,omitempty"`   // Transaction hashes of the swaps traded against
+	Borrower    string         `json:"borrower,omitempty"`  // Account whose loan was liquidated
+	Extracted   []ActionAmount `json:"extracted,omitempty"` // Searcher profit, fees earned by the JIT position or liquidation bonus
+	Description string         `json:"description"`
+}
+
+// SignatureRequest asks what an EIP-712 typed-data signature would authorize
+type SignatureRequest struct {
+	TypedData TypedData `json:"typed_data" validate:"required"`
+	NetworkID int64     `json:"network_id" validate:"required"`
+	Signature string    `json:"signature,omitempty"` // Optional: the signature, to recover who signed
+}
+
+// TypedData is an EIP-712 payload as passed to eth_signTypedData_v4
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// TypedDataField is a member of an EIP-712 struct type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// UnmarshalJSON accepts the payload as an object or, as wallets pass it, a JSON string. Numbers are kept
+// as json.Number so uint256 values survive.
+func (t *TypedData) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		data = []byte(encoded)
+	}
+
+	type typedData TypedData
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode((*typedData)(t))
+}
+
+// Typed-data schemas recognized by the signature explainer
+const (
+	SignatureSchemaPermit          = "erc2612_permit"   // EIP-2612 Permit (and DAI-style permit)
+	SignatureSchemaPermit2         = "permit2"          // Permit2 PermitSingle / PermitBatch allowance
+	SignatureSchemaPermit2Transfer = "permit2_transfer" // Permit2 one-time signature transfer (possibly with a witness)
+	SignatureSchemaSeaport         = "seaport_order"    // Seaport OrderComponents
+	SignatureSchemaCowSwap         = "cowswap_order"    // CoW Protocol GPv2Order
+	SignatureSchemaUnknown         = "unknown"
+)
+
+// SignatureDomain is the EIP-712 domain of a typed-data signature
+type SignatureDomain struct {
+	Name                  string `json:"name,omitempty"`
+	Version               string `json:"version,omitempty"`
+	ChainID               int64  `json:"chain_id,omitempty"`
+	VerifyingContract     string `json:"verifying_contract,omitempty"`
+	VerifyingContractName string `json:"verifying_contract_name,omitempty"` // Token or protocol at the verifying contract
+}
+
+// SignedOrder is a trade order a signature authorizes, seen from the maker's side
+type SignedOrder struct {
+	Protocol          string         `json:"protocol"`
+	Maker             string         `json:"maker"`
+	Receiver          string         `json:"receiver,omitempty"` // Who receives what the maker buys, when not the maker
+	Gives             []ActionAmount `json:"gives"`
+	Receives          []ActionAmount `json:"receives"`
+	Fees              []ActionAmount `json:"fees,omitempty"` // Paid to others out of the trade (marketplace fees, royalties, solver fees)
+	ValidFrom         *time.Time     `json:"valid_from,omitempty"`
+	ValidUntil        *time.Time     `json:"valid_until,omitempty"`
+	PartiallyFillable bool           `json:"partially_fillable,omitempty"`
+}
+
+// SignatureExplanation explains what signing a typed-data payload authorizes
+type SignatureExplanation struct {
+	NetworkID   int64           `json:"network_id"`
+	Schema      string          `json:"schema"`
+	PrimaryType string          `json:"primary_type"`
+	Domain      SignatureDomain `json:"domain"`
+	Hash        string          `json:"hash,omitempty"`   // EIP-712 digest the wallet signs
+	Signer      string          `json:"signer,omitempty"` // Recovered from the signature, when given
+	Summary     string          `json:"summary"`
+	Approvals   []Approval      `json:"approvals,omitempty"` // Allowances the signature grants
+	Order       *SignedOrder    `json:"order,omitempty"`
+	Risks       []string        `json:"risks,omitempty"`
+	Annotations []Annotation    `json:"annotations,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// Action types
+const (
+	ActionTypeSwap     = "swap"
+	ActionTypeTransfer = "transfer"
+	ActionTypeApprove  = "approve"
+	ActionTypeMint     = "mint"
+	ActionTypeBurn     = "burn"
+	ActionTypeWrap     = "wrap"
+	ActionTypeUnwrap   = "unwrap"
+	ActionTypeSupply   = "supply"
+	ActionTypeWithdraw = "withdraw"
+	ActionTypeStake    = "stake"
+	ActionTypeUnstake  = "unstake"
+	ActionTypeBorrow   = "borrow"
+	ActionTypeRepay    = "repay"
+	ActionTypeClaim    = "claim"
+	ActionTypeBridge   = "bridge"
+	ActionTypeNFTBuy   = "nft_buy"
+	ActionTypeNFTSell  = "nft_sell"
+	ActionTypeOther    = "other"
+)
+
+// ActionTypes lists every action type, in the order they are documented to the LLM
+var ActionTypes = []string{
+	ActionTypeSwap, ActionTypeTransfer, ActionTypeApprove, ActionTypeMint, ActionTypeBurn,
+	ActionTypeWrap, ActionTypeUnwrap, ActionTypeSupply, ActionTypeWithdraw, ActionTypeStake,
+	ActionTypeUnstake, ActionTypeBorrow, ActionTypeRepay, ActionTypeClaim, ActionTypeBridge,
+	ActionTypeNFTBuy, ActionTypeNFTSell, ActionTypeOther,
+}
+
+// Action sources
+const (
+	ActionSourceDecoded = "decoded" // Derived deterministically from decoded events, transfers and traces
+	ActionSourceLLM     = "llm"     // Inferred by the LLM when decoders could not explain the transaction
+)
+
+// Action is one step of what a transaction did, seen from the actor's side: TokensOut left the actor,
+// TokensIn reached it. For approvals TokensOut holds the allowance granted to the counterparty.
+type Action struct {
+	Type         string         `json:"type"`
+	Protocol     string         `json:"protocol,omitempty"` // e.g. "Uniswap v3"
+	Actor        string         `json:"actor,omitempty"`
+	Counterparty string         `json:"counterparty,omitempty"` // Recipient, spender, seller, buyer or pool
+	Contract     string         `json:"contract,omitempty"`     // Contract the action happened on
+	TokensIn     []ActionAmount `json:"tokens_in,omitempty"`
+	TokensOut    []ActionAmount `json:"tokens_out,omitempty"`
+	Source       string         `json:"source"`
+}
+
+// ActionAmount is an amount of a token, or a single NFT, moved by an action
+type ActionAmount struct {
+	Token     string `json:"token"` // Contract address, or "native"
+	Symbol    string `json:"symbol,omitempty"`
+	Amount    string `json:"amount,omitempty"`     // Human-readable amount (e.g. "1.5"), "unlimited" for max approvals
+	RawAmount string `json:"raw_amount,omitempty"` // Decimal amount in the token's smallest unit
+	TokenID   string `json:"token_id,omitempty"`
+	AmountUSD string `json:"amount_usd,omitempty"`
+}
+
+// Failure kinds
+const (
+	FailureKindErrorString    = "error_string"    // require/revert with a message: Error(string)
+	FailureKindPanic          = "panic"           // Compiler-inserted check: Panic(uint256)
+	FailureKindCustomError    = "custom_error"    // Solidity custom error
+	FailureKindEmpty          = "empty"           // Reverted without data
+	FailureKindExecutionError = "execution_error" // Out of gas, invalid opcode, ...
+	FailureKindUnknown        = "unknown"         // No trace available to tell why
+)
+
+// Failure explains why a transaction reverted, from the frame where the revert originated
+type Failure struct {
+	Kind      string                 `json:"kind"`
+	Reason    string                 `json:"reason"`              // Human-readable reason
+	Error     string                 `json:"error,omitempty"`     // Error signature, e.g. "Error(string)" or "InsufficientBalance(uint256,uint256)"
+	Arguments map[string]interface{} `json:"arguments,omitempty"` // Decoded error arguments by name
+	Contract  string                 `json:"contract,omitempty"`  // Contract whose call reverted first
+	Method    string                 `json:"method,omitempty"`
+	Depth     int                    `json:"depth"`
+	Data      string                 `json:"data,omitempty"` // Raw revert data
 }
 
 // DecodedData contains the processed transaction data
@@ -236,9 +236,14 @@ func (c *Client) tryFetchTokenLikeInfo(ctx context.Context, contractAddress stri
 
 // supportsInterface checks if contract supports a given interface
 func (c *Client) supportsInterface(ctx context.Context, contractAddress, interfaceID string) bool {
-	// Call supportsInterface(bytes4)
-	callData := ERC721_SUPPORTS_INTERFACE + padLeft(interfaceID[2:], 64)
-	result, err := c.ethCall(ctx, contractAddress, callData)
+	return c.supportsInterfaceAt(ctx, contractAddress, interfaceID, "latest")
+}
+
+// supportsInterfaceAt checks if contract supported a given interface at a block
+func (c *Client) supportsInterfaceAt(ctx context.Context, contractAddress, interfaceID, block string) bool {
+	// Call supportsInterface(bytes4); bytes4 arguments are left-aligned in their word
+	callData := ERC721_SUPPORTS_INTERFACE + interfaceID[2:] + strings.Repeat("0", 56)
+	result, err := c.CallContractAt(ctx, contractAddress, callData, block)
 	if err != nil {
 		return false
 	}
@@ -303,14 +308,65 @@ func (c *Client) GetNFTOwner(ctx context.Context, contractAddress, tokenID strin
 	return "", fmt.Errorf("failed to decode owner address")
 }
 
+// GetTokenURI fetches the metadata URI of an NFT at a given block ("latest" if empty).
+// ERC721 uses tokenURI(uint256); ERC1155 uses uri(uint256) with {id} substituted per EIP-1155.
+func (c *Client) GetTokenURI(ctx context.Context, contractAddress, tokenID, standard, block string) (string, error) {
+	id, ok := parseTokenID(tokenID)
+	if !ok {
+		return "", fmt.Errorf("invalid token ID %q", tokenID)
+	}
+	idHex := padLeft(hex.EncodeToString(id.Bytes()), 64)
+
+	selector := ERC721_TOKEN_URI
+	if standard == "ERC1155" {
+		selector = ERC1155_URI
+	}
+
+	result, err := c.CallContractAt(ctx, contractAddress, selector+idHex, block)
+	if err != nil {
+		return "", err
+	}
+
+	uri := c.decodeString(result)
+	if uri == "" {
+		return "", fmt.Errorf("empty token URI")
+	}
+
+	if standard == "ERC1155" {
+		uri = strings.ReplaceAll(uri, "{id}", idHex)
+	}
+	return uri, nil
+}
+
+// parseTokenID parses a token ID given in decimal or 0x-prefixed hex
+func parseTokenID(tokenID string) (*big.Int, bool) {
+	if strings.HasPrefix(tokenID, "0x") {
+		return new(big.Int).SetString(tokenID[2:], 16)
+	}
+	return new(big.Int).SetString(tokenID, 10)
+}
+
+// CallContractAt makes an eth_call against a specific block (hex number or tag; "latest" if empty)
+func (c *Client) CallContractAt(ctx context.Context, to, data, block string) (string, error) {
+	if block == "" {
+		block = "latest"
+	}
+	return c.ethCallAt(ctx, to, data, block)
+}
+
 // ethCall makes a contract call using eth_call
 func (c *Client) ethCall(ctx context.Context, to, data string) (string, error) {
+	return c.ethCallAt(ctx, to, data, "latest")
+}
+
+// ethCallAt makes a contract call using eth_call at the given block
+func (c *Client) ethCallAt(ctx context.Context, to, data, block string) (string, error) {
 	params := []interface{}{
 		map[string]interface{}{
 			"to":   to,
 			"data": data,
 		},
-		"latest",
+		block,
 	}
 
 	result, err := c.call(ctx, "eth_call", params)
@@ -328,7 +384,15 @@ func (c *Client) ethCall(ctx context.Context, to, data string) (string, error) {
 
 // getCode fetches contract bytecode
 func (c *Client) getCode(ctx context.Context, address string) (string, error) {
-	result, err := c.call(ctx, "eth_getCode", []string{address, "latest"})
+	return c.GetCodeAt(ctx, address, "latest")
+}
+
+// GetCodeAt fetches contract bytecode at a specific block (hex number or tag; "latest" if empty)
+func (c *Client) GetCodeAt(ctx context.Context, address, block string) (string, error) {
+	if block == "" {
+		block = "latest"
+	}
+	result, err := c.call(ctx, "eth_getCode", []string{address, block})
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,467 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NameRegistry describes a deployment of an ENS-compatible name registry
+type NameRegistry struct {
+	Name          string // Display name, e.g. "ENS" or "Basenames"
+	NetworkID     int64  // Chain the registry lives on
+	Registry      string // Registry contract address
+	ReverseSuffix string // Reverse namespace that address records live under
+}
+
+// Known registries. Basenames keeps its reverse records under the ENSIP-11 coin type of Base (0x80000000 | 8453).
+var (
+	ENSRegistry = NameRegistry{
+		Name:          "ENS",
+		NetworkID:     1,
+		Registry:      "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e",
+		ReverseSuffix: "addr.reverse",
+	}
+	BasenamesRegistry = NameRegistry{
+		Name:          "Basenames",
+		NetworkID:     8453,
+		Registry:      "0xb94704422c2a1e396835a571837aa5ae53285a95",
+		ReverseSuffix: "80002105.reverse",
+	}
+)
+
+// ENS function signatures
+var (
+	ENS_RESOLVER     = "0x0178b8bf" // resolver(bytes32) on the registry
+	ENS_ADDR         = "0x3b3b57de" // addr(bytes32)
+	ENS_NAME         = "0x691f3431" // name(bytes32)
+	ENS_TEXT         = "0x59d1d43c" // text(bytes32,string)
+	ENS_RESOLVE      = "0x9061b923" // resolve(bytes,bytes) - ENSIP-10 extended resolver, also its interface ID
+	EIP3668_OFFCHAIN = "0x556f1830" // OffchainLookup(address,string[],bytes,bytes4,bytes) revert
+)
+
+// ENSTextRecordKeys are the text records shown alongside resolved names
+var ENSTextRecordKeys = []string{"avatar", "url", "com.twitter"}
+
+const (
+	maxCCIPReadLookups = 4                // EIP-3668 clients should cap the number of chained lookups
+	ccipReadTimeout    = 10 * time.Second // Per gateway request
+)
+
+// ResolveENSName resolves the current primary ENS name of an address on Ethereum mainnet (reverse lookup).
+// The name is only returned if its forward record points back to the address.
+func (c *Client) ResolveENSName(ctx context.Context, address string) (string, error) {
+	// Only resolve on Ethereum mainnet
+	if c.network.ID != ENSRegistry.NetworkID {
+		return "", nil
+	}
+	return c.LookupName(ctx, ENSRegistry, address, "")
+}
+
+// LookupName performs a verified reverse lookup of an address against a registry on this client's chain.
+// Anyone can set any reverse name, so names whose forward record does not resolve back to the address are dropped.
+// block pins every registry and resolver call to a block (hex number or tag; "latest" if empty). Historical
+// blocks need an archive node, and CCIP-read gateways always answer with their current data.
+func (c *Client) LookupName(ctx context.Context, registry NameRegistry, address, block string) (string, error) {
+	if c.network.ID != registry.NetworkID {
+		return "", fmt.Errorf("%s registry is on network %d, client is on %d", registry.Name, registry.NetworkID, c.network.ID)
+	}
+	if len(address) != 42 || address[:2] != "0x" {
+		return "", fmt.Errorf("invalid address format")
+	}
+
+	// For address 0x5c0a...89a0 the reverse name is 5c0a...89a0.addr.reverse
+	reverseName := strings.ToLower(address[2:]) + "." + registry.ReverseSuffix
+	result, err := c.resolveRecord(ctx, registry, reverseName, ENS_NAME+namehash(reverseName)[2:], block)
+	if err != nil {
+		return "", err
+	}
+
+	name := c.decodeString(result)
+	if name == "" {
+		return "", nil
+	}
+
+	forward, err := c.ResolveName(ctx, registry, name, block)
+	if err != nil {
+		return "", fmt.Errorf("forward verification of %s failed: %w", name, err)
+	}
+	if !strings.EqualFold(forward, address) {
+		return "", nil // Spoofed or stale reverse record
+	}
+	return name, nil
+}
+
+// ResolveName resolves a name to an address (forward lookup) at a block, following ENSIP-10 wildcards and CCIP-read
+func (c *Client) ResolveName(ctx context.Context, registry NameRegistry, name, block string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	result, err := c.resolveRecord(ctx, registry, name, ENS_ADDR+namehash(name)[2:], block)
+	if err != nil {
+		return "", err
+	}
+	if len(result) < 66 {
+		return "", nil
+	}
+
+	address := "0x" + result[26:66]
+	if address == "0x0000000000000000000000000000000000000000" {
+		return "", nil
+	}
+	return address, nil
+}
+
+// GetTextRecords fetches text records (avatar, url, com.twitter, ...) for a name at a block; missing records are omitted
+func (c *Client) GetTextRecords(ctx context.Context, registry NameRegistry, name string, keys []string, block string) (map[string]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	node := namehash(name)[2:]
+
+	records := make(map[string]string)
+	for _, key := range keys {
+		callData := ENS_TEXT + node + encodeABIWord(64) + encodeABIBytes([]byte(key))
+		result, err := c.resolveRecord(ctx, registry, name, callData, block)
+		if err != nil {
+			if ctx.Err() != nil {
+				return records, ctx.Err()
+			}
+			continue // A broken text record should not hide the others
+		}
+		if value := c.decodeString(result); value != "" {
+			records[key] = value
+		}
+	}
+	return records, nil
+}
+
+// resolveRecord finds the resolver for a name and calls it with the given resolver calldata.
+// Resolvers implementing ENSIP-10 are called through resolve(bytes,bytes), which also covers names
+// that only exist under a parent's wildcard resolver. Returns "0x" if no resolver applies.
+func (c *Client) resolveRecord(ctx context.Context, registry NameRegistry, name, callData, block string) (string, error) {
+	resolver, exact, err := c.findResolver(ctx, registry, name, block)
+	if err != nil || resolver == "" {
+		return "0x", err
+	}
+
+	if c.supportsInterfaceAt(ctx, resolver, ENS_RESOLVE, block) {
+		inner, err := hex.DecodeString(strings.TrimPrefix(callData, "0x"))
+		if err != nil {
+			return "", err
+		}
+		result, err := c.callWithCCIPRead(ctx, resolver, ENS_RESOLVE+encodeABIBytesArgs(dnsEncode(name), inner), block)
+		if err != nil {
+			return "", err
+		}
+		decoded, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+		if err != nil {
+			return "", err
+		}
+		unwrapped, err := decodeABIBytes(decoded, 0)
+		if err != nil {
+			return "", err
+		}
+		return "0x" + hex.EncodeToString(unwrapped), nil
+	}
+
+	if !exact {
+		return "0x", nil // A parent's resolver only answers for subnames if it supports wildcards
+	}
+	return c.callWithCCIPRead(ctx, resolver, callData, block)
+}
+
+// findResolver returns the resolver of a name or, failing that, of its closest ancestor (ENSIP-10).
+// exact reports whether the resolver is set on the name itself.
+func (c *Client) findResolver(ctx context.Context, registry NameRegistry, name, block string) (resolver string, exact bool, err error) {
+	labels := strings.Split(name, ".")
+	for i := range labels {
+		result, err := c.CallContractAt(ctx, registry.Registry, ENS_RESOLVER+namehash(strings.Join(labels[i:], "."))[2:], block)
+		if err != nil {
+			return "", false, err
+		}
+		if len(result) < 66 {
+			continue
+		}
+		address := "0x" + result[len(result)-40:]
+		if address != "0x0000000000000000000000000000000000000000" {
+			return address, i == 0, nil
+		}
+	}
+	return "", false, nil
+}
+
+// callWithCCIPRead makes an eth_call and follows EIP-3668 OffchainLookup reverts through the gateway URLs
+func (c *Client) callWithCCIPRead(ctx context.Context, to, data, block string) (string, error) {
+	for lookup := 0; lookup <= maxCCIPReadLookups; lookup++ {
+		result, err := c.CallContractAt(ctx, to, data, block)
+		if err == nil {
+			return result, nil
+		}
+
+		revert, ok := revertData(err)
+		if !ok || !strings.HasPrefix(revert, EIP3668_OFFCHAIN) {
+			return "", err
+		}
+
+		offchain, decodeErr := decodeOffchainLookup(revert)
+		if decodeErr != nil {
+			return "", decodeErr
+		}
+		if !strings.EqualFold(offchain.sender, to) {
+			return "", fmt.Errorf("offchain lookup sender %s does not match resolver %s", offchain.sender, to)
+		}
+
+		response, fetchErr := c.fetchCCIPRead(ctx, offchain)
+		if fetchErr != nil {
+			return "", fetchErr
+		}
+		data = offchain.callback + encodeABIBytesArgs(response, offchain.extraData)
+	}
+	return "", fmt.Errorf("too many offchain lookups")
+}
+
+// offchainLookup is a decoded EIP-3668 OffchainLookup revert
+type offchainLookup struct {
+	sender    string
+	urls      []string
+	callData  []byte
+	callback  string // 0x-prefixed bytes4 selector
+	extraData []byte
+}
+
+// decodeOffchainLookup decodes OffchainLookup(address sender, string[] urls, bytes callData, bytes4 callbackFunction, bytes extraData)
+func decodeOffchainLookup(revert string) (*offchainLookup, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(revert, EIP3668_OFFCHAIN))
+	if err != nil || len(data) < 5*32 {
+		return nil, fmt.Errorf("malformed OffchainLookup revert")
+	}
+
+	lookup := &offchainLookup{
+		sender:   "0x" + hex.EncodeToString(data[12:32]),
+		callback: "0x" + hex.EncodeToString(data[96:100]),
+	}
+	if lookup.callData, err = decodeABIBytes(data, 64); err != nil {
+		return nil, err
+	}
+	if lookup.extraData, err = decodeABIBytes(data, 128); err != nil {
+		return nil, err
+	}
+
+	urlsOffset, ok := abiInt(data, 32)
+	if !ok {
+		return nil, fmt.Errorf("malformed OffchainLookup urls")
+	}
+	count, ok := abiInt(data, urlsOffset)
+	if !ok {
+		return nil, fmt.Errorf("malformed OffchainLookup urls")
+	}
+	for i := 0; i < count; i++ {
+		relative, ok := abiInt(data, urlsOffset+32+i*32)
+		if !ok {
+			return nil, fmt.Errorf("malformed OffchainLookup url %d", i)
+		}
+		url, err := decodeABIBytesAt(data, urlsOffset+32+relative)
+		if err != nil {
+			return nil, err
+		}
+		lookup.urls = append(lookup.urls, string(url))
+	}
+	return lookup, nil
+}
+
+// fetchCCIPRead queries the gateways in order. URLs containing {data} are fetched with GET, others with
+// a JSON POST. Per EIP-3668 a 4xx response ends the lookup while 5xx and network errors try the next gateway.
+func (c *Client) fetchCCIPRead(ctx context.Context, lookup *offchainLookup) ([]byte, error) {
+	sender := strings.ToLower(lookup.sender)
+	callData := "0x" + hex.EncodeToString(lookup.callData)
+
+	gatewayClient := c.gatewayClient
+	if gatewayClient == nil {
+		gatewayClient = NewPublicHTTPClient(ccipReadTimeout)
+	}
+
+	var lastErr error = fmt.Errorf("no gateway URLs")
+	for _, gateway := range lookup.urls {
+		// The URLs come from the contract's revert, so only HTTPS gateways on public hosts are contacted
+		if !strings.HasPrefix(strings.ToLower(gateway), "https://") {
+			lastErr = fmt.Errorf("gateway %s does not use https", gateway)
+			continue
+		}
+		requestCtx, cancel := context.WithTimeout(ctx, ccipReadTimeout)
+
+		var req *http.Request
+		var err error
+		url := strings.ReplaceAll(gateway, "{sender}", sender)
+		if strings.Contains(url, "{data}") {
+			req, err = http.NewRequestWithContext(requestCtx, "GET", strings.ReplaceAll(url, "{data}", callData), nil)
+		} else {
+			body, _ := json.Marshal(map[string]string{"data": callData, "sender": sender})
+			req, err = http.NewRequestWithContext(requestCtx, "POST", url, bytes.NewReader(body))
+			if req != nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+		}
+		if err != nil {
+			cancel()
+			lastErr = err
+			continue
+		}
+
+		resp, err := gatewayClient.Do(req)
+		if err != nil {
+			cancel()
+			lastErr = fmt.Errorf("gateway request failed: %w", err)
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, fmt.Errorf("gateway %s returned status %d", gateway, resp.StatusCode)
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("gateway %s returned status %d", gateway, resp.StatusCode)
+			continue
+		}
+
+		var response struct {
+			Data string `json:"data"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			lastErr = fmt.Errorf("invalid gateway response: %w", err)
+			continue
+		}
+		return hex.DecodeString(strings.TrimPrefix(response.Data, "0x"))
+	}
+	return nil, lastErr
+}
+
+// revertData extracts the revert payload from an eth_call error
+func revertData(err error) (string, bool) {
+	var rpcErr *JSONRPCError
+	if !errors.As(err, &rpcErr) || len(rpcErr.Data) == 0 {
+		return "", false
+	}
+
+	var data string
+	if json.Unmarshal(rpcErr.Data, &data) == nil {
+		return strings.ToLower(data), strings.HasPrefix(data, "0x")
+	}
+	// Some nodes nest it: {"data": "0x..."}
+	var nested struct {
+		Data string `json:"data"`
+	}
+	if json.Unmarshal(rpcErr.Data, &nested) == nil && strings.HasPrefix(nested.Data, "0x") {
+		return strings.ToLower(nested.Data), true
+	}
+	return "", false
+}
+
+// namehash implements the ENS namehash algorithm
+func namehash(name string) string {
+	// Start with 32 zero bytes
+	node := make([]byte, 32)
+	if name == "" {
+		return "0x" + hex.EncodeToString(node)
+	}
+
+	// Split the name into labels (e.g., "vitalik.eth" -> ["vitalik", "eth"])
+	labels := strings.Split(name, ".")
+
+	// Process labels in reverse order (from right to left)
+	for i := len(labels) - 1; i >= 0; i-- {
+		// Calculate keccak256 of the label
+		labelHash := sha3.NewLegacyKeccak256()
+		labelHash.Write([]byte(labels[i]))
+
+		// Calculate keccak256 of (current_node + label_hash)
+		nodeHash := sha3.NewLegacyKeccak256()
+		nodeHash.Write(node)
+		nodeHash.Write(labelHash.Sum(nil))
+		node = nodeHash.Sum(nil)
+	}
+
+	return "0x" + hex.EncodeToString(node)
+}
+
+// dnsEncode encodes a name in DNS wire format as ENSIP-10 resolve() expects
+func dnsEncode(name string) []byte {
+	var encoded []byte
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 255 {
+			continue
+		}
+		encoded = append(encoded, byte(len(label)))
+		encoded = append(encoded, label...)
+	}
+	return append(encoded, 0)
+}
+
+// encodeABIWord encodes an integer as a 32-byte hex word
+func encodeABIWord(value int) string {
+	return padLeft(fmt.Sprintf("%x", value), 64)
+}
+
+// encodeABIBytes encodes the length and right-padded content of a bytes/string value
+func encodeABIBytes(value []byte) string {
+	encoded := hex.EncodeToString(value)
+	if padding := len(encoded) % 64; padding != 0 {
+		encoded += strings.Repeat("0", 64-padding)
+	}
+	return encodeABIWord(len(value)) + encoded
+}
+
+// encodeABIBytesArgs encodes a list of dynamic bytes arguments (heads then tails)
+func encodeABIBytesArgs(values ...[]byte) string {
+	var head, tail strings.Builder
+	offset := 32 * len(values)
+	for _, value := range values {
+		head.WriteString(encodeABIWord(offset))
+		encoded := encodeABIBytes(value)
+		tail.WriteString(encoded)
+		offset += len(encoded) / 2
+	}
+	return head.String() + tail.String()
+}
+
+// abiInt reads a word at a byte offset as an offset or length, rejecting values outside the payload
+func abiInt(data []byte, offset int) (int, bool) {
+	if offset < 0 || offset+32 > len(data) {
+		return 0, false
+	}
+	value := new(big.Int).SetBytes(data[offset : offset+32])
+	if !value.IsInt64() || value.Int64() > int64(len(data)) {
+		return 0, false
+	}
+	return int(value.Int64()), true
+}
+
+// decodeABIBytes decodes a dynamic bytes value whose offset is stored in the head word at headOffset
+func decodeABIBytes(data []byte, headOffset int) ([]byte, error) {
+	offset, ok := abiInt(data, headOffset)
+	if !ok {
+		return nil, fmt.Errorf("invalid ABI bytes offset")
+	}
+	return decodeABIBytesAt(data, offset)
+}
+
+// decodeABIBytesAt decodes the length-prefixed bytes value starting at offset
+func decodeABIBytesAt(data []byte, offset int) ([]byte, error) {
+	length, ok := abiInt(data, offset)
+	if !ok || offset+32+length > len(data) {
+		return nil, fmt.Errorf("invalid ABI bytes length")
+	}
+	return data[offset+32 : offset+32+length], nil
+}
@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+func TestNamehash(t *testing.T) {
+	require.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000000", namehash(""))
+	require.Equal(t, "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae", namehash("eth"))
+	require.Equal(t, "0xee6c4522aab0003e8d14cd40a6af439055fd2577951148c14b6cea9a53475835", namehash("vitalik.eth"))
+	require.Equal(t, []byte("\x07vitalik\x03eth\x00"), dnsEncode("vitalik.eth"))
+}
+
+// fakeENSNode serves eth_call for a registry, a plain resolver and a CCIP-read wildcard resolver
+type fakeENSNode struct {
+	t         *testing.T
+	resolvers map[string]string // node -> resolver
+	names     map[string]string // reverse node -> name
+	addrs     map[string]string // node -> address
+	gateway   string
+	blocks    map[string]bool // block params seen
+}
+
+const (
+	testRegistry        = "0x00000000000c2e074ec69a0dfb2997ba6c7d2e1e"
+	testResolver        = "0x4976fb03c32e5b8cfe2b6ccb31c09ba78ebaba41"
+	testOffchainResolve = "0x1111111111111111111111111111111111111111"
+	testCallback        = "0xdeadbeef"
+)
+
+func (n *fakeENSNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Params []json.RawMessage `json:"params"`
+	}
+	require.NoError(n.t, json.NewDecoder(r.Body).Decode(&req))
+	var call struct {
+		To   string `json:"to"`
+		Data string `json:"data"`
+	}
+	require.NoError(n.t, json.Unmarshal(req.Params[0], &call))
+	var block string
+	require.NoError(n.t, json.Unmarshal(req.Params[1], &block))
+	n.blocks[block] = true
+	to, data := strings.ToLower(call.To), strings.ToLower(call.Data)
+
+	respond := func(result string) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}
+	word := func(address string) string { return "0x" + padLeft(strings.TrimPrefix(address, "0x"), 64) }
+
+	switch {
+	case to == testRegistry && strings.HasPrefix(data, ENS_RESOLVER):
+		respond(word(n.resolvers["0x"+data[10:]]))
+	case strings.HasPrefix(data, ERC721_SUPPORTS_INTERFACE):
+		supported := to == testOffchainResolve && strings.HasPrefix(data[10:], ENS_RESOLVE[2:])
+		if supported {
+			respond(word("1"))
+		} else {
+			respond(word("0"))
+		}
+	case to == testResolver && strings.HasPrefix(data, ENS_NAME):
+		name := n.names["0x"+data[10:]]
+		respond("0x" + encodeABIWord(32) + encodeABIBytes([]byte(name)))
+	case to == testResolver && strings.HasPrefix(data, ENS_ADDR):
+		respond(word(n.addrs["0x"+data[10:]]))
+	case to == testOffchainResolve && strings.HasPrefix(data, ENS_RESOLVE):
+		// Revert with OffchainLookup(sender, [gateway], callData, callback, extraData)
+		url := encodeABIBytes([]byte(n.gateway + "/{sender}/{data}.json"))
+		callData := encodeABIBytes([]byte{0xca, 0x11})
+		urlsSize := 64 + len(url)/2
+		revert := EIP3668_OFFCHAIN +
+			padLeft(testOffchainResolve[2:], 64) +
+			encodeABIWord(160) +
+			encodeABIWord(160+urlsSize) +
+			testCallback[2:] + strings.Repeat("0", 56) +
+			encodeABIWord(160+urlsSize+len(callData)/2) +
+			encodeABIWord(1) + encodeABIWord(32) + url +
+			callData +
+			encodeABIBytes([]byte("extra"))
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{
+			"code": 3, "message": "execution reverted", "data": revert,
+		}})
+	case to == testOffchainResolve && strings.HasPrefix(data, testCallback):
+		payload, err := hex.DecodeString(data[10:])
+		require.NoError(n.t, err)
+		response, err := decodeABIBytes(payload, 0)
+		require.NoError(n.t, err)
+		extra, err := decodeABIBytes(payload, 32)
+		require.NoError(n.t, err)
+		require.Equal(n.t, "extra", string(extra))
+		// resolve() returns the inner record as bytes
+		respond("0x" + encodeABIWord(32) + encodeABIBytes(response))
+	default:
+		respond("0x")
+	}
+}
+
+func TestLookupNameVerifiesForwardRecord(t *testing.T) {
+	alice := "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	spoofer := "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	carol := "0xcccccccccccccccccccccccccccccccccccccccc"
+	vitalik := "0xd8da6bf26964af9d7eed9e03e53415d37aa96045"
+
+	gateway := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/"+testOffchainResolve+"/0xca11.json", r.URL.Path)
+		w.Write([]byte(`{"data":"0x` + padLeft(carol[2:], 64) + `"}`))
+	}))
+	defer gateway.Close()
+
+	node := &fakeENSNode{
+		t:         t,
+		resolvers: map[string]string{},
+		names:     map[string]string{},
+		addrs:     map[string]string{},
+		gateway:   gateway.URL,
+		blocks:    map[string]bool{},
+	}
+	for address, name := range map[string]string{alice: "alice.eth", spoofer: "vitalik.eth", carol: "carol.offchain.eth"} {
+		reverse := namehash(address[2:] + ".addr.reverse")
+		node.resolvers[reverse] = testResolver
+		node.names[reverse] = name
+	}
+	node.resolvers[namehash("alice.eth")] = testResolver
+	node.addrs[namehash("alice.eth")] = alice
+	node.resolvers[namehash("vitalik.eth")] = testResolver
+	node.addrs[namehash("vitalik.eth")] = vitalik
+	node.resolvers[namehash("offchain.eth")] = testOffchainResolve
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+	client := &Client{httpClient: http.DefaultClient, gatewayClient: gateway.Client(), network: models.Network{ID: 1, RPCUrl: server.URL}}
+	ctx := context.Background()
+
+	name, err := client.ResolveENSName(ctx, alice)
+	require.NoError(t, err)
+	require.Equal(t, "alice.eth", name)
+
+	name, err = client.ResolveENSName(ctx, spoofer)
+	require.NoError(t, err)
+	require.Empty(t, name, "reverse record claiming someone else's name must be rejected")
+
+	name, err = client.ResolveENSName(ctx, carol)
+	require.NoError(t, err)
+	require.Equal(t, "carol.offchain.eth", name, "forward record resolved through wildcard resolver and CCIP-read")
+
+	_, err = client.LookupName(ctx, BasenamesRegistry, alice, "")
+	require.Error(t, err, "registry on another chain")
+	require.Equal(t, map[string]bool{"latest": true}, node.blocks)
+
+	node.blocks = map[string]bool{}
+	name, err = client.LookupName(ctx, ENSRegistry, carol, "0x12d687")
+	require.NoError(t, err)
+	require.Equal(t, "carol.offchain.eth", name)
+	require.Equal(t, map[string]bool{"0x12d687": true}, node.blocks, "every registry and resolver call is pinned to the block")
+}
+
+func TestFetchCCIPReadOnlyContactsPublicHTTPSGateways(t *testing.T) {
+	gateway := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("gateway on a private address was contacted: %s", r.URL)
+	}))
+	defer gateway.Close()
+
+	// Gateway URLs come from the contract's revert
+	lookup := &offchainLookup{sender: testOffchainResolve, callData: []byte{0xca, 0x11}}
+	client := &Client{httpClient: http.DefaultClient}
+
+	lookup.urls = []string{"http://gateway.example/{sender}/{data}.json"}
+	_, err := client.fetchCCIPRead(context.Background(), lookup)
+	require.ErrorContains(t, err, "does not use https")
+
+	lookup.urls = []string{gateway.URL + "/{sender}/{data}.json", "https://169.254.169.254/latest/meta-data/"}
+	_, err = client.fetchCCIPRead(context.Background(), lookup)
+	require.ErrorContains(t, err, "non-public address")
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "10.0.0.1", "172.16.5.4", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1"} {
+		require.False(t, IsPublicIP(net.ParseIP(address)), address)
+	}
+	for _, address := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		require.True(t, IsPublicIP(net.ParseIP(address)), address)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/txplain/txplain/internal/sigdb"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -46,14 +48,15 @@ func NewSignatureResolver(rpcClient *Client, use4ByteAPI bool) *SignatureResolve
 		},
 		rpcClient:   rpcClient,
 		cache:       make(map[string]*SignatureInfo),
-		use4ByteAPI: use4ByteAPI,
+		use4ByteAPI: use4ByteAPI && os.Getenv("SIGNATURE_LOOKUP_OFFLINE") != "true",
 	}
 }
 
 // Signature resolution should rely on:
 // 1. ABI data from verified contracts (primary source)
-// 2. 4byte.directory API (secondary source)
-// 3. Generic fallback (no hardcoded assumptions)
+// 2. Offline signature database (no network)
+// 3. 4byte.directory API
+// 4. Generic fallback (no hardcoded assumptions)
 
 // ResolveFunctionSignature resolves a function signature to its human-readable form
 func (sr *SignatureResolver) ResolveFunctionSignature(ctx context.Context, signature string) (*SignatureInfo, error) {
@@ -68,6 +71,17 @@ func (sr *SignatureResolver) ResolveFunctionSignature(ctx context.Context, signa
 		return cached, nil
 	}
 
+	// Offline signature database
+	if candidates := sigdb.Default().LookupFunction(sig); len(candidates) > 0 {
+		info := &SignatureInfo{
+			Signature: candidates[0].TextSignature,
+			Name:      extractFunctionName(candidates[0].TextSignature),
+			Type:      "function",
+		}
+		sr.cache[sig] = info
+		return info, nil
+	}
+
 	// Try 4byte API if enabled (generic approach - no hardcoded assumptions)
 	if sr.use4ByteAPI {
 		if info, err := sr.resolve4Byte(ctx, sig); err == nil && info != nil {
@@ -99,6 +113,17 @@ func (sr *SignatureResolver) ResolveEventSignature(ctx context.Context, signatur
 		return cached, nil
 	}
 
+	// Offline signature database
+	if candidates := sigdb.Default().LookupEvent(sig); len(candidates) > 0 {
+		info := &SignatureInfo{
+			Signature: candidates[0].TextSignature,
+			Name:      extractEventName(candidates[0].TextSignature),
+			Type:      "event",
+		}
+		sr.cache[sig] = info
+		return info, nil
+	}
+
 	// Try 4byte API if enabled for event signatures (generic approach)
 	if sr.use4ByteAPI {
 		if info, err := sr.resolveEventSignature4Byte(ctx, sig); err == nil && info != nil {
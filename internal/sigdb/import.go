@@ -0,0 +1,231 @@
+package sigdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fourByteSignature is a single record in 4byte.directory API responses and JSON exports
+type fourByteSignature struct {
+	TextSignature string `json:"text_signature"`
+	HexSignature  string `json:"hex_signature"`
+}
+
+// openchainEntry is a single record in openchain.xyz signature lookup/export responses
+type openchainEntry struct {
+	Name     string `json:"name"`
+	Filtered bool   `json:"filtered"`
+}
+
+// ImportFile merges a signature dump into the database and returns how many signatures were read.
+// Supported formats:
+//   - this package's TSV database (optionally gzip-compressed)
+//   - 4byte.directory JSON: an array of records or an API page with "results"
+//   - openchain JSON: {"function": {...}, "event": {...}}, optionally wrapped in {"result": ...}
+//   - plain text or CSV with one text signature per line
+//
+// kind ("function" or "event") applies to formats that don't say; when empty it is
+// guessed from the file name. Every signature is re-hashed, so mismatched records are dropped.
+func (db *DB) ImportFile(path, kind string, score int) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if kind == "" {
+		kind = KindFunction
+		if strings.Contains(strings.ToLower(filepath.Base(path)), "event") {
+			kind = KindEvent
+		}
+	}
+	if score <= 0 {
+		score = 1
+	}
+
+	// Our own database format (gzip magic or kind/hash/signature TSV)
+	if len(content) > 2 && content[0] == 0x1f && content[1] == 0x8b {
+		return db.importDatabase(content)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return db.importJSON(trimmed, kind, score)
+	}
+
+	return db.importText(trimmed, kind, score)
+}
+
+// importDatabase merges another database file, counting the signatures it contained that hash to their
+// recorded selector or topic
+func (db *DB) importDatabase(content []byte) (int, error) {
+	other := New()
+	if err := other.Load(bytes.NewReader(content)); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	for _, index := range []map[string][]*Entry{other.functions, other.events} {
+		for _, entries := range index {
+			for _, entry := range entries {
+				if db.addVerified(entry.TextSignature, entry.Hash, entry.Kind, entry.Score) {
+					count++
+				}
+			}
+		}
+	}
+	return count, nil
+}
+
+// importJSON handles 4byte.directory and openchain JSON dumps
+func (db *DB) importJSON(content []byte, kind string, score int) (int, error) {
+	// 4byte export: [{text_signature, hex_signature}, ...]
+	if content[0] == '[' {
+		var records []fourByteSignature
+		if err := json.Unmarshal(content, &records); err != nil {
+			return 0, fmt.Errorf("failed to parse 4byte JSON: %w", err)
+		}
+		return db.importFourByte(records, kind, score), nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(content, &object); err != nil {
+		return 0, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	// 4byte API page: {count, next, results: [...]}
+	if raw, ok := object["results"]; ok {
+		var records []fourByteSignature
+		if err := json.Unmarshal(raw, &records); err != nil {
+			return 0, fmt.Errorf("failed to parse 4byte results: %w", err)
+		}
+		return db.importFourByte(records, kind, score), nil
+	}
+
+	// openchain lookup response: {ok, result: {function: {...}, event: {...}}}
+	if raw, ok := object["result"]; ok {
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return 0, fmt.Errorf("failed to parse openchain result: %w", err)
+		}
+	}
+
+	count := 0
+	for key, sigKind := range map[string]string{"function": KindFunction, "event": KindEvent} {
+		raw, ok := object[key]
+		if !ok {
+			continue
+		}
+		var byHash map[string][]openchainEntry
+		if err := json.Unmarshal(raw, &byHash); err != nil {
+			return count, fmt.Errorf("failed to parse openchain %s signatures: %w", key, err)
+		}
+		for hash, entries := range byHash {
+			for _, entry := range entries {
+				if entry.Filtered {
+					continue // openchain marks known spam/collisions as filtered
+				}
+				if db.addVerified(entry.Name, hash, sigKind, score) {
+					count++
+				}
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// importFourByte adds 4byte records, inferring the kind from the hex signature length
+func (db *DB) importFourByte(records []fourByteSignature, kind string, score int) int {
+	count := 0
+	for _, record := range records {
+		recordKind := kind
+		switch len(record.HexSignature) {
+		case 10:
+			recordKind = KindFunction
+		case 66:
+			recordKind = KindEvent
+		}
+		if db.addVerified(record.TextSignature, record.HexSignature, recordKind, score) {
+			count++
+		}
+	}
+	return count
+}
+
+// importText reads one signature per line, tolerating CSV/TSV columns around it
+func (db *DB) importText(content []byte, kind string, score int) (int, error) {
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineKind := kind
+		if fields := strings.Split(line, "\t"); len(fields) >= 3 && (fields[0] == "f" || fields[0] == "e") {
+			lineKind = KindFunction
+			if fields[0] == "e" {
+				lineKind = KindEvent
+			}
+		}
+
+		signature := extractSignature(line)
+		if signature == "" {
+			continue
+		}
+		if db.Add(signature, lineKind, score) == nil {
+			count++
+		}
+	}
+	return count, scanner.Err()
+}
+
+// addVerified adds a signature only if it hashes to the hash claimed by the dump
+func (db *DB) addVerified(textSignature, hash, kind string, score int) bool {
+	textSignature = NormalizeSignature(textSignature)
+	if !isWellFormed(textSignature) {
+		return false
+	}
+
+	expected := fmt.Sprintf("0x%x", keccak(textSignature))
+	if kind != KindEvent {
+		expected = expected[:10]
+	}
+	if hash != "" && normalizeHash(hash) != expected {
+		return false
+	}
+
+	return db.Add(textSignature, kind, score) == nil
+}
+
+// extractSignature finds the "name(types)" token in a CSV/TSV/plain line
+func extractSignature(line string) string {
+	open := strings.Index(line, "(")
+	if open <= 0 {
+		return ""
+	}
+
+	start := strings.LastIndexAny(line[:open], ",\t;\" ") + 1
+
+	depth := 0
+	for i := open; i < len(line); i++ {
+		switch line[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return line[start : i+1]
+			}
+		}
+	}
+	return ""
+}
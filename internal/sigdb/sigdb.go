@@ -0,0 +1,440 @@
+// Package sigdb provides an offline database of function and event signatures.
+//
+// A compressed database of common signatures is embedded in the binary so that
+// selector lookups work without network access. Additional signatures can be
+// imported from 4byte.directory or openchain dumps and loaded at runtime via
+// SIGNATURE_DB_PATH.
+package sigdb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/sha3"
+)
+
+//go:embed data/signatures.tsv.gz
+var embeddedFS embed.FS
+
+// EmbeddedPath is the source path of the embedded database, used by the import command
+const EmbeddedPath = "internal/sigdb/data/signatures.tsv.gz"
+
+// Signature kinds
+const (
+	KindFunction = "function"
+	KindEvent    = "event"
+)
+
+// Entry is a single text signature stored in the database
+type Entry struct {
+	Hash          string `json:"hash"` // 4-byte selector for functions, 32-byte topic for events
+	TextSignature string `json:"text_signature"`
+	Kind          string `json:"kind"`
+	Score         int    `json:"score"` // Popularity - higher means more commonly seen
+}
+
+// DB is a concurrency-safe in-memory signature database
+type DB struct {
+	mu        sync.RWMutex
+	functions map[string][]*Entry // selector -> candidates
+	events    map[string][]*Entry // topic -> candidates
+}
+
+var (
+	defaultDB   *DB
+	defaultOnce sync.Once
+)
+
+// New creates an empty database
+func New() *DB {
+	return &DB{
+		functions: make(map[string][]*Entry),
+		events:    make(map[string][]*Entry),
+	}
+}
+
+// Default returns the shared database: the embedded signatures, overlaid with
+// the file at SIGNATURE_DB_PATH if one is configured
+func Default() *DB {
+	defaultOnce.Do(func() {
+		defaultDB = New()
+		if err := defaultDB.LoadEmbedded(); err != nil {
+			fmt.Printf("Warning: failed to load embedded signature database: %v\n", err)
+		}
+		if path := os.Getenv("SIGNATURE_DB_PATH"); path != "" {
+			if err := defaultDB.LoadFile(path); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("Warning: failed to load signature database %s: %v\n", path, err)
+			}
+		}
+	})
+	return defaultDB
+}
+
+// LoadEmbedded loads the signatures bundled with the binary
+func (db *DB) LoadEmbedded() error {
+	content, err := embeddedFS.ReadFile("data/signatures.tsv.gz")
+	if err != nil {
+		return err
+	}
+	return db.Load(bytes.NewReader(content))
+}
+
+// LoadFile loads a database file (gzip-compressed or plain TSV)
+func (db *DB) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return db.Load(file)
+}
+
+// Load reads the TSV database format: kind<TAB>hash<TAB>text_signature<TAB>score.
+// Gzip-compressed input is detected automatically.
+func (db *DB) Load(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			continue
+		}
+
+		kind := KindFunction
+		if fields[0] == "e" {
+			kind = KindEvent
+		}
+		score := 1
+		if len(fields) > 3 {
+			if parsed, err := strconv.Atoi(fields[3]); err == nil {
+				score = parsed
+			}
+		}
+
+		db.put(&Entry{Hash: strings.ToLower(fields[1]), TextSignature: fields[2], Kind: kind, Score: score})
+	}
+
+	return scanner.Err()
+}
+
+// Add inserts a text signature after normalising it and computing its hash.
+// If the signature already exists its score is raised to the higher of the two.
+func (db *DB) Add(textSignature, kind string, score int) error {
+	textSignature = NormalizeSignature(textSignature)
+	if !isWellFormed(textSignature) {
+		return fmt.Errorf("malformed signature: %q", textSignature)
+	}
+
+	digest := keccak(textSignature)
+	hash := "0x" + hex.EncodeToString(digest)
+	if kind != KindEvent {
+		kind = KindFunction
+		hash = hash[:10]
+	}
+
+	db.put(&Entry{Hash: hash, TextSignature: textSignature, Kind: kind, Score: score})
+	return nil
+}
+
+// LookupFunction returns the ranked candidates for a 4-byte selector
+func (db *DB) LookupFunction(selector string) []Entry {
+	return db.lookup(db.functions, normalizeHash(selector))
+}
+
+// LookupEvent returns the ranked candidates for an event topic
+func (db *DB) LookupEvent(topic string) []Entry {
+	return db.lookup(db.events, normalizeHash(topic))
+}
+
+// Len returns the number of function and event signatures
+func (db *DB) Len() (int, int) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	functions, events := 0, 0
+	for _, entries := range db.functions {
+		functions += len(entries)
+	}
+	for _, entries := range db.events {
+		events += len(entries)
+	}
+	return functions, events
+}
+
+// Write serialises the database as gzip-compressed TSV, sorted for stable diffs
+func (db *DB) Write(w io.Writer) error {
+	db.mu.RLock()
+	var lines []string
+	for _, index := range []map[string][]*Entry{db.functions, db.events} {
+		for _, entries := range index {
+			for _, entry := range entries {
+				kind := "f"
+				if entry.Kind == KindEvent {
+					kind = "e"
+				}
+				lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%d", kind, entry.Hash, entry.TextSignature, entry.Score))
+			}
+		}
+	}
+	db.mu.RUnlock()
+
+	sort.Strings(lines)
+
+	gz, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(gz, "# kind\thash\ttext_signature\tscore\n"); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(gz, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return gz.Close()
+}
+
+// WriteFile writes the database to a file, replacing it atomically
+func (db *DB) WriteFile(path string) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := db.Write(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (db *DB) put(entry *Entry) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	index := db.functions
+	if entry.Kind == KindEvent {
+		index = db.events
+	}
+
+	for _, existing := range index[entry.Hash] {
+		if existing.TextSignature == entry.TextSignature {
+			if entry.Score > existing.Score {
+				existing.Score = entry.Score
+			}
+			return
+		}
+	}
+	index[entry.Hash] = append(index[entry.Hash], entry)
+}
+
+func (db *DB) lookup(index map[string][]*Entry, hash string) []Entry {
+	db.mu.RLock()
+	entries := index[hash]
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	db.mu.RUnlock()
+
+	SortByPopularity(result)
+	return result
+}
+
+// SortByPopularity orders candidates by score, then demotes names that look like
+// mined selector collisions, then prefers shorter signatures
+func SortByPopularity(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		si, sj := looksLikeCollision(entries[i].TextSignature), looksLikeCollision(entries[j].TextSignature)
+		if si != sj {
+			return !si
+		}
+		return len(entries[i].TextSignature) < len(entries[j].TextSignature)
+	})
+}
+
+// looksLikeCollision flags names typical of brute-forced selector collisions,
+// e.g. "join_tg_invmru_haha_fd06787(address,bool)" or "func_2093253501(bytes)"
+func looksLikeCollision(textSignature string) bool {
+	name := textSignature
+	if idx := strings.Index(name, "("); idx >= 0 {
+		name = name[:idx]
+	}
+
+	// Long hex-ish runs are almost always mined
+	hexRun := 0
+	for _, char := range strings.ToLower(name) {
+		if (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') {
+			hexRun++
+			if hexRun >= 6 {
+				return true
+			}
+		} else {
+			hexRun = 0
+		}
+	}
+	return false
+}
+
+// NormalizeSignature strips whitespace and parameter names, e.g.
+// "transfer(address to, uint256 amount)" -> "transfer(address,uint256)"
+func NormalizeSignature(textSignature string) string {
+	textSignature = strings.TrimSpace(textSignature)
+	open := strings.Index(textSignature, "(")
+	if open <= 0 || !strings.HasSuffix(textSignature, ")") {
+		return textSignature
+	}
+
+	name := strings.TrimSpace(textSignature[:open])
+	name = strings.TrimPrefix(name, "function ")
+	name = strings.TrimPrefix(name, "event ")
+
+	params := SplitParams(textSignature[open+1 : len(textSignature)-1])
+	for i, param := range params {
+		params[i] = normalizeParam(param)
+	}
+
+	return fmt.Sprintf("%s(%s)", strings.TrimSpace(name), strings.Join(params, ","))
+}
+
+// normalizeParam reduces a parameter to its type, dropping its name and the indexed keyword. Tuple
+// components are normalized the same way, keeping the array dimensions that follow the tuple.
+func normalizeParam(param string) string {
+	param = strings.TrimSpace(param)
+	if !strings.HasPrefix(param, "(") {
+		if fields := strings.Fields(param); len(fields) > 0 {
+			return fields[0]
+		}
+		return ""
+	}
+
+	depth, end := 0, -1
+	for i, char := range param {
+		if char == '(' {
+			depth++
+		} else if char == ')' {
+			if depth--; depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return strings.ReplaceAll(param, " ", "") // Unbalanced, left for isWellFormed to reject
+	}
+
+	components := SplitParams(param[1:end])
+	for i, component := range components {
+		components[i] = normalizeParam(component)
+	}
+	dimensions := ""
+	for rest := strings.TrimSpace(param[end+1:]); strings.HasPrefix(rest, "["); {
+		closing := strings.Index(rest, "]")
+		if closing < 0 {
+			break
+		}
+		dimensions += strings.ReplaceAll(rest[:closing+1], " ", "")
+		rest = strings.TrimSpace(rest[closing+1:])
+	}
+	return "(" + strings.Join(components, ",") + ")" + dimensions
+}
+
+// SplitParams splits a parameter list on top-level commas, keeping tuples intact
+func SplitParams(params string) []string {
+	if strings.TrimSpace(params) == "" {
+		return nil
+	}
+
+	var parts []string
+	depth := 0
+	start := 0
+	for i, char := range params {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(params[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(params[start:]))
+}
+
+// isWellFormed checks that a signature is name(types) with balanced parentheses
+func isWellFormed(textSignature string) bool {
+	open := strings.Index(textSignature, "(")
+	if open <= 0 || !strings.HasSuffix(textSignature, ")") {
+		return false
+	}
+	for _, char := range textSignature[:open] {
+		if !(char == '_' || char == '$' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')) {
+			return false
+		}
+	}
+	depth := 0
+	for _, char := range textSignature[open:] {
+		switch char {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		case ' ', '\t', '\n':
+			return false
+		}
+	}
+	return depth == 0
+}
+
+func normalizeHash(hash string) string {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if !strings.HasPrefix(hash, "0x") {
+		hash = "0x" + hash
+	}
+	return hash
+}
+
+func keccak(text string) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(text))
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,189 @@
+package sigdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAndLookup(t *testing.T) {
+	db := New()
+	require.NoError(t, db.Add("transfer(address, uint256)", KindFunction, 10))
+	require.NoError(t, db.Add("Transfer(address,address,uint256)", KindEvent, 10))
+	require.Error(t, db.Add("not a signature", KindFunction, 1))
+
+	functions := db.LookupFunction("0xA9059CBB")
+	require.Len(t, functions, 1)
+	require.Equal(t, "transfer(address,uint256)", functions[0].TextSignature)
+
+	events := db.LookupEvent("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	require.Len(t, events, 1)
+	require.Equal(t, "Transfer(address,address,uint256)", events[0].TextSignature)
+
+	require.Empty(t, db.LookupFunction("0x12345678"))
+}
+
+func TestNormalizeSignature(t *testing.T) {
+	require.Equal(t, "Transfer(address,address,uint256)", NormalizeSignature("event Transfer(address indexed from, address indexed to, uint256 value)"))
+	require.Equal(t, "execute((address,uint256,bytes)[],bytes32)", NormalizeSignature("function execute((address to, uint256 value, bytes data)[] calls, bytes32 salt)"))
+	require.Equal(t, "f(((address,uint256)[2],bool),uint8)", NormalizeSignature("f(((address a, uint256 b)[2] pairs, bool ok) x, uint8 y)"))
+	require.Equal(t, "Filled((address,uint256))", NormalizeSignature("Filled((address maker, uint256 amount) indexed order)"))
+}
+
+func TestEmbeddedDatabase(t *testing.T) {
+	db := New()
+	require.NoError(t, db.LoadEmbedded())
+
+	functions, events := db.Len()
+	require.Greater(t, functions, 100)
+	require.Greater(t, events, 50)
+	require.NotEmpty(t, db.LookupFunction("0x095ea7b3"))
+}
+
+func TestCollisionRanking(t *testing.T) {
+	db := New()
+	// 0xa9059cbb collides with a vanity signature in 4byte.directory
+	require.NoError(t, db.Add("transfer(address,uint256)", KindFunction, 1))
+	require.NoError(t, db.Add("many_msg_babbage(bytes1)", KindFunction, 1))
+	entries := db.LookupFunction("0xa9059cbb")
+	require.Len(t, entries, 2)
+
+	// Calldata with two words only fits transfer
+	calldata := "0xa9059cbb" + strings.Repeat("0", 128)
+	ranked := RankByCalldata(entries, calldata)
+	require.Equal(t, "transfer(address,uint256)", ranked[0].TextSignature)
+
+}
+
+func TestScoreCalldata(t *testing.T) {
+	word := func(hexValue string) string {
+		return strings.Repeat("0", 64-len(hexValue)) + hexValue
+	}
+	recipient := word("d8da6bf26964af9d7eed9e03e53415d37aa96045")
+
+	transfer := "0xa9059cbb" + recipient + word("de0b6b3a7640000")
+	require.Equal(t, 1.0, ScoreCalldata("transfer(address,uint256)", transfer))
+	require.Zero(t, ScoreCalldata("transfer(address,uint256,uint256)", transfer), "too short")
+	require.Less(t, ScoreCalldata("f(address)", transfer), 1.0, "leftover bytes")
+	require.Less(t, ScoreCalldata("f(bool,bool)", transfer), 1.0, "bool out of range")
+
+	// Dirty address padding
+	require.Less(t, ScoreCalldata("f(address)", "0x12345678"+strings.Repeat("f", 64)), 1.0)
+
+	// bytes[] with one 2-byte element: offset, length, element offset, element length, data
+	multicall := "0xac9650d8" + word("20") + word("1") + word("20") + word("2") + "abcd" + strings.Repeat("0", 60)
+	require.Equal(t, 1.0, ScoreCalldata("multicall(bytes[])", multicall))
+	require.Zero(t, ScoreCalldata("multicall(bytes[])", "0xac9650d8"+word("400")), "offset out of bounds")
+
+	// Static tuple arrays are inline
+	require.Equal(t, 1.0, ScoreCalldata("f((address,uint256)[2])", "0x12345678"+recipient+word("1")+recipient+word("2")))
+
+	// Fixed arrays longer than the data, whose head size would overflow, are undecodable rather than a panic
+	require.Zero(t, ScoreCalldata("f(uint256[576460752303423488])", "0x12345678"+word("0")))
+	require.Zero(t, ScoreCalldata("f(uint256[4294967296][4294967296])", "0x12345678"+word("0")))
+	require.Zero(t, ScoreCalldata("f(string[576460752303423488])", "0x12345678"+word("0")))
+	require.NotPanics(t, func() { ScoreCalldata("f(()[3])", "0x12345678") })
+
+	// Every offset of each level points at the same tail, so decoding all of them would take n³ steps
+	const n = 500
+	level := func() string {
+		return word(fmt.Sprintf("%x", n)) + strings.Repeat(word(fmt.Sprintf("%x", n*32)), n)
+	}
+	aliased := "0x12345678" + word("20") + level() + level() + level() + word("0")
+	started := time.Now()
+	require.Zero(t, ScoreCalldata("f(bytes[][][])", aliased))
+	require.Less(t, time.Since(started), 2*time.Second)
+}
+
+func TestRankEventCandidates(t *testing.T) {
+	topic := "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	from := "0x000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045"
+	to := "0x0000000000000000000000001111111111111111111111111111111111111111"
+	amount := "0x0000000000000000000000000000000000000000000000000de0b6b3a7640000"
+
+	candidates := RankEventCandidates([]string{
+		"Transfer(uint8,uint8,bytes32)",
+		"Transfer(address,address,uint256)",
+	}, []string{topic, from, to}, amount)
+
+	require.Equal(t, "Transfer(address,address,uint256)", candidates[0].TextSignature)
+	require.Equal(t, 1.0, candidates[0].Score)
+	require.Greater(t, candidates[0].Confidence, candidates[1].Confidence)
+
+	// More indexed topics than parameters can never match
+	require.Zero(t, ScoreLog("Paused(address)", []string{topic, from, to}, "0x"))
+
+	// Without data, popularity order and an even split
+	unscored := RankFunctionCandidates([]string{"a()", "b()"}, "")
+	require.Equal(t, "a()", unscored[0].TextSignature)
+	require.Equal(t, 0.5, unscored[0].Confidence)
+}
+
+func TestImportFile(t *testing.T) {
+	dir := t.TempDir()
+
+	fourByte := filepath.Join(dir, "4byte.json")
+	require.NoError(t, os.WriteFile(fourByte, []byte(`{"results":[
+		{"text_signature":"approve(address,uint256)","hex_signature":"0x095ea7b3"},
+		{"text_signature":"approve(address,uint256)","hex_signature":"0xdeadbeef"}
+	]}`), 0o644))
+
+	openchain := filepath.Join(dir, "openchain.json")
+	require.NoError(t, os.WriteFile(openchain, []byte(`{"ok":true,"result":{
+		"function":{"0x70a08231":[{"name":"balanceOf(address)","filtered":false}]},
+		"event":{"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925":[{"name":"Approval(address,address,uint256)","filtered":false}]}
+	}}`), 0o644))
+
+	text := filepath.Join(dir, "functions.txt")
+	require.NoError(t, os.WriteFile(text, []byte("# comment\ntotalSupply()\n"), 0o644))
+
+	db := New()
+	_, err := db.ImportFile(fourByte, "", 1)
+	require.NoError(t, err)
+	_, err = db.ImportFile(openchain, "", 1)
+	require.NoError(t, err)
+	_, err = db.ImportFile(text, "", 1)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, db.LookupFunction("0x095ea7b3"))
+	require.Empty(t, db.LookupFunction("0xdeadbeef"), "hash mismatches must be dropped")
+	require.NotEmpty(t, db.LookupFunction("0x70a08231"))
+	require.NotEmpty(t, db.LookupFunction("0x18160ddd"))
+	require.NotEmpty(t, db.LookupEvent("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"))
+
+	// Records of a gzip database are re-hashed too
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("f\t0x095ea7b3\tapprove(address,uint256)\t3\nf\t0xa9059cbb\tdrain(address)\t9\n"))
+	require.NoError(t, gz.Close())
+	database := filepath.Join(dir, "signatures.tsv.gz")
+	require.NoError(t, os.WriteFile(database, compressed.Bytes(), 0o644))
+	db = New()
+	count, err := db.ImportFile(database, "", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Empty(t, db.LookupFunction("0xa9059cbb"), "a record under another signature's selector must be dropped")
+}
+
+func TestWriteLoadRoundTrip(t *testing.T) {
+	db := New()
+	require.NoError(t, db.Add("transfer(address,uint256)", KindFunction, 5))
+	require.NoError(t, db.Add("Transfer(address,address,uint256)", KindEvent, 5))
+
+	var buf bytes.Buffer
+	require.NoError(t, db.Write(&buf))
+
+	loaded := New()
+	require.NoError(t, loaded.Load(&buf))
+	functions, events := loaded.Len()
+	require.Equal(t, 1, functions)
+	require.Equal(t, 1, events)
+	require.Equal(t, 5, loaded.LookupFunction("0xa9059cbb")[0].Score)
+}
@@ -0,0 +1,539 @@
+package sigdb
+
+import (
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RankByCalldata reorders function candidates by how cleanly the calldata decodes with each of
+// them. Popularity order is kept between equally good candidates, and if nothing decodes the
+// original order is returned unchanged.
+func RankByCalldata(entries []Entry, calldata string) []Entry {
+	if len(entries) < 2 {
+		return entries
+	}
+	if _, ok := decodeArgs(calldata); !ok {
+		return entries
+	}
+
+	scores := make([]float64, len(entries))
+	anyDecodes := false
+	for i, entry := range entries {
+		scores[i] = ScoreCalldata(entry.TextSignature, calldata)
+		anyDecodes = anyDecodes || scores[i] > 0
+	}
+	if !anyDecodes {
+		return entries
+	}
+
+	indexes := make([]int, len(entries))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return scores[indexes[a]] > scores[indexes[b]]
+	})
+
+	ranked := make([]Entry, len(entries))
+	for i, index := range indexes {
+		ranked[i] = entries[index]
+	}
+	return ranked
+}
+
+// decodeArgs strips the 0x prefix and 4-byte selector from calldata
+func decodeArgs(calldata string) ([]byte, bool) {
+	calldata = strings.TrimPrefix(strings.ToLower(calldata), "0x")
+	if len(calldata) < 8 {
+		return nil, false
+	}
+	args, err := hex.DecodeString(calldata[8:])
+	if err != nil {
+		return nil, false
+	}
+	return args, true
+}
+
+// maxDecodeDepth bounds recursion through nested arrays and tuples
+const maxDecodeDepth = 8
+
+// maxArrayLength rejects dynamic array lengths no real payload would carry
+const maxArrayLength = 4096
+
+// maxDecodeSteps bounds the values one check decodes. Dynamic offsets may alias, so a shared tail is decoded
+// once per head pointing at it and nested arrays could otherwise cost exponential time.
+const maxDecodeSteps = 1 << 16
+
+// maxHeadSize caps headSize so fixed arrays with absurd lengths (uint256[576460752303423488]) cannot overflow
+// it; anything that large is longer than any calldata and fails to decode
+const maxHeadSize = 1 << 30
+
+// Candidate is a text signature scored against the data it is supposed to describe
+type Candidate struct {
+	TextSignature string
+	Score         float64 // How cleanly the data decodes with this signature (0..1)
+	Confidence    float64 // Score normalised across all candidates for the same hash
+}
+
+// RankFunctionCandidates scores text signatures (in popularity order) against calldata and returns
+// them best first. Without calldata every candidate scores 1 and popularity decides.
+func RankFunctionCandidates(signatures []string, calldata string) []Candidate {
+	candidates := make([]Candidate, len(signatures))
+	for i, signature := range signatures {
+		candidates[i] = Candidate{TextSignature: signature, Score: 1}
+		if calldata != "" {
+			candidates[i].Score = ScoreCalldata(signature, calldata)
+		}
+	}
+	return finishRanking(candidates)
+}
+
+// RankEventCandidates scores event text signatures (in popularity order) against a log's topics
+// and data and returns them best first. Without topics every candidate scores 1.
+func RankEventCandidates(signatures []string, topics []string, data string) []Candidate {
+	candidates := make([]Candidate, len(signatures))
+	for i, signature := range signatures {
+		candidates[i] = Candidate{TextSignature: signature, Score: 1}
+		if len(topics) > 0 {
+			candidates[i].Score = ScoreLog(signature, topics, data)
+		}
+	}
+	return finishRanking(candidates)
+}
+
+// finishRanking sorts candidates by score (stable, so popularity breaks ties) and fills in confidence
+func finishRanking(candidates []Candidate) []Candidate {
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].Score > candidates[b].Score
+	})
+
+	total := 0.0
+	for _, candidate := range candidates {
+		total += candidate.Score
+	}
+	if total > 0 {
+		for i := range candidates {
+			candidates[i].Confidence = candidates[i].Score / total
+		}
+	}
+	return candidates
+}
+
+// ScoreCalldata reports how cleanly calldata (with selector) ABI-decodes as the given function
+// signature: 0 when it cannot be decoded at all, 1 when every length, offset and padding check passes
+func ScoreCalldata(textSignature, calldata string) float64 {
+	types, ok := parseSignatureTypes(textSignature)
+	if !ok {
+		return 0
+	}
+	args, ok := decodeArgs(calldata)
+	if !ok {
+		return 0
+	}
+	return scoreSequence(types, args)
+}
+
+// ScoreLog reports how cleanly a log decodes as the given event signature. topics includes the
+// event hash at index 0; text signatures don't say which parameters are indexed, so every
+// placement matching the topic count is tried and the best one wins.
+func ScoreLog(textSignature string, topics []string, data string) float64 {
+	types, ok := parseSignatureTypes(textSignature)
+	if !ok {
+		return 0
+	}
+	dataBytes, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(data), "0x"))
+	if err != nil {
+		return 0
+	}
+
+	indexedCount := len(topics) - 1
+	if indexedCount < 0 || indexedCount > 3 || indexedCount > len(types) {
+		return 0
+	}
+
+	topicWords := make([][]byte, indexedCount)
+	for i, topic := range topics[1:] {
+		word, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(topic), "0x"))
+		if err != nil || len(word) != 32 {
+			return 0
+		}
+		topicWords[i] = word
+	}
+
+	best := 0.0
+	forEachCombination(len(types), indexedCount, func(indexed []int) {
+		checker := &decodeChecker{}
+		isIndexed := make(map[int]bool, len(indexed))
+		for i, position := range indexed {
+			isIndexed[position] = true
+			// Dynamic and reference types are hashed into the topic, so only value types can be checked
+			if !types[position].isDynamic() && types[position].kind != "tuple" && types[position].kind != "array" {
+				checker.checkWord(types[position], topicWords[i])
+			}
+		}
+
+		var dataTypes []*abiType
+		for position, paramType := range types {
+			if !isIndexed[position] {
+				dataTypes = append(dataTypes, paramType)
+			}
+		}
+
+		score := checker.score(dataTypes, dataBytes)
+		if score > best {
+			best = score
+		}
+	})
+	return best
+}
+
+// scoreSequence decodes data as a tuple of types and turns the collected checks into a score
+func scoreSequence(types []*abiType, data []byte) float64 {
+	return (&decodeChecker{}).score(types, data)
+}
+
+// abiType is a parsed Solidity ABI type
+type abiType struct {
+	kind       string // "uint", "int", "address", "bool", "bytesN", "bytes", "string", "function", "fixed", "array", "tuple"
+	size       int    // Bits for (u)int, bytes for bytesN, element count for fixed arrays (-1 for T[])
+	elem       *abiType
+	components []*abiType
+}
+
+// parseSignatureTypes parses the parameter list of a text signature
+func parseSignatureTypes(textSignature string) ([]*abiType, bool) {
+	open := strings.Index(textSignature, "(")
+	if open <= 0 || !strings.HasSuffix(textSignature, ")") {
+		return nil, false
+	}
+	var types []*abiType
+	for _, param := range SplitParams(textSignature[open+1 : len(textSignature)-1]) {
+		parsed, ok := parseABIType(param)
+		if !ok {
+			return nil, false
+		}
+		types = append(types, parsed)
+	}
+	return types, true
+}
+
+// parseABIType parses a single canonical ABI type such as "uint256", "bytes32[]" or "(address,uint256)[2]"
+func parseABIType(paramType string) (*abiType, bool) {
+	paramType = strings.TrimSpace(paramType)
+
+	if strings.HasSuffix(paramType, "]") {
+		open := strings.LastIndex(paramType, "[")
+		if open < 0 {
+			return nil, false
+		}
+		elem, ok := parseABIType(paramType[:open])
+		if !ok {
+			return nil, false
+		}
+		lengthStr := paramType[open+1 : len(paramType)-1]
+		if lengthStr == "" {
+			return &abiType{kind: "array", size: -1, elem: elem}, true
+		}
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil || length <= 0 {
+			return nil, false
+		}
+		return &abiType{kind: "array", size: length, elem: elem}, true
+	}
+
+	if strings.HasPrefix(paramType, "(") && strings.HasSuffix(paramType, ")") {
+		tuple := &abiType{kind: "tuple"}
+		for _, component := range SplitParams(paramType[1 : len(paramType)-1]) {
+			parsed, ok := parseABIType(component)
+			if !ok {
+				return nil, false
+			}
+			tuple.components = append(tuple.components, parsed)
+		}
+		return tuple, true
+	}
+
+	switch paramType {
+	case "address", "bool", "string", "bytes", "function":
+		return &abiType{kind: paramType}, true
+	}
+
+	for _, prefix := range []string{"uint", "int"} {
+		if strings.HasPrefix(paramType, prefix) {
+			bits := 256
+			if rest := paramType[len(prefix):]; rest != "" {
+				parsed, err := strconv.Atoi(rest)
+				if err != nil || parsed <= 0 || parsed > 256 || parsed%8 != 0 {
+					return nil, false
+				}
+				bits = parsed
+			}
+			return &abiType{kind: prefix, size: bits}, true
+		}
+	}
+
+	if strings.HasPrefix(paramType, "bytes") {
+		size, err := strconv.Atoi(paramType[len("bytes"):])
+		if err != nil || size <= 0 || size > 32 {
+			return nil, false
+		}
+		return &abiType{kind: "bytesN", size: size}, true
+	}
+
+	if strings.HasPrefix(paramType, "fixed") || strings.HasPrefix(paramType, "ufixed") {
+		return &abiType{kind: "fixed"}, true
+	}
+
+	return nil, false
+}
+
+// isDynamic reports whether the type is encoded out-of-line (behind an offset)
+func (t *abiType) isDynamic() bool {
+	switch t.kind {
+	case "bytes", "string":
+		return true
+	case "array":
+		return t.size < 0 || t.elem.isDynamic()
+	case "tuple":
+		for _, component := range t.components {
+			if component.isDynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize is the number of bytes the type occupies in the head of its enclosing tuple, at most maxHeadSize
+func (t *abiType) headSize() int {
+	if t.isDynamic() {
+		return 32
+	}
+	switch t.kind {
+	case "array":
+		elem := t.elem.headSize()
+		if elem == 0 {
+			return 0 // Empty tuples
+		}
+		if t.size > maxHeadSize/elem {
+			return maxHeadSize
+		}
+		return min(t.size*elem, maxHeadSize)
+	case "tuple":
+		total := 0
+		for _, component := range t.components {
+			total = min(total+component.headSize(), maxHeadSize)
+		}
+		return total
+	}
+	return 32
+}
+
+// decodeChecker walks ABI-encoded data, counting soft checks (padding, value ranges) and
+// failing hard on anything that makes the data undecodable (out-of-bounds offsets or lengths)
+type decodeChecker struct {
+	checks     int
+	violations int
+	steps      int // Values decoded so far, bounded by maxDecodeSteps
+}
+
+// score decodes data as a tuple of types: 0 if undecodable, otherwise the share of checks passed,
+// reduced when bytes are left over (e.g. a short signature colliding with a longer one)
+func (c *decodeChecker) score(types []*abiType, data []byte) float64 {
+	end, ok := c.decodeSequence(types, data, 0, 0)
+	if !ok {
+		return 0
+	}
+
+	score := 1.0
+	if c.checks > 0 {
+		score = 1 - float64(c.violations)/float64(c.checks)
+	}
+	if end < len(data) {
+		score *= 0.75
+	}
+	return score
+}
+
+// decodeSequence validates types laid out head/tail starting at base and returns the end of the encoding
+func (c *decodeChecker) decodeSequence(types []*abiType, data []byte, base, depth int) (int, bool) {
+	if depth > maxDecodeDepth {
+		return 0, false
+	}
+
+	headEnd := base
+	for _, paramType := range types {
+		headEnd += paramType.headSize()
+	}
+	if headEnd > len(data) {
+		return 0, false
+	}
+
+	end := headEnd
+	position := base
+	for _, paramType := range types {
+		var valueEnd int
+		var ok bool
+		if paramType.isDynamic() {
+			offset, fits := wordToInt(data[position : position+32])
+			if !fits || offset%32 != 0 || base+offset < headEnd || base+offset+32 > len(data) {
+				return 0, false
+			}
+			valueEnd, ok = c.decodeValue(paramType, data, base+offset, depth+1)
+		} else {
+			valueEnd, ok = c.decodeValue(paramType, data, position, depth+1)
+		}
+		if !ok {
+			return 0, false
+		}
+		if valueEnd > end {
+			end = valueEnd
+		}
+		position += paramType.headSize()
+	}
+
+	return end, true
+}
+
+// decodeValue validates a single value encoded at position and returns where it ends
+func (c *decodeChecker) decodeValue(t *abiType, data []byte, position, depth int) (int, bool) {
+	if c.steps++; c.steps > maxDecodeSteps {
+		return 0, false
+	}
+	switch t.kind {
+	case "bytes", "string":
+		if position+32 > len(data) {
+			return 0, false
+		}
+		length, fits := wordToInt(data[position : position+32])
+		if !fits || position+32+length > len(data) {
+			return 0, false
+		}
+		content := data[position+32 : position+32+length]
+		end := position + 32 + (length+31)/32*32
+		if end > len(data) {
+			return 0, false
+		}
+		c.checks++
+		if !allZero(data[position+32+length : end]) {
+			c.violations++
+		}
+		if t.kind == "string" {
+			c.checks++
+			if !utf8.Valid(content) {
+				c.violations++
+			}
+		}
+		return end, true
+
+	case "array":
+		if t.size >= 0 {
+			// Every element takes at least a word of the head, so longer arrays cannot fit in the data
+			if t.size > (len(data)-position)/32 {
+				return 0, false
+			}
+			elems := make([]*abiType, t.size)
+			for i := range elems {
+				elems[i] = t.elem
+			}
+			return c.decodeSequence(elems, data, position, depth)
+		}
+		if position+32 > len(data) {
+			return 0, false
+		}
+		length, fits := wordToInt(data[position : position+32])
+		if !fits || length > maxArrayLength || position+32+length*t.elem.headSize() > len(data) {
+			return 0, false
+		}
+		elems := make([]*abiType, length)
+		for i := range elems {
+			elems[i] = t.elem
+		}
+		return c.decodeSequence(elems, data, position+32, depth)
+
+	case "tuple":
+		return c.decodeSequence(t.components, data, position, depth)
+	}
+
+	if position+32 > len(data) {
+		return 0, false
+	}
+	c.checkWord(t, data[position:position+32])
+	return position + 32, true
+}
+
+// checkWord validates the padding and range of a 32-byte value-type word
+func (c *decodeChecker) checkWord(t *abiType, word []byte) {
+	valid := true
+	switch t.kind {
+	case "address":
+		valid = allZero(word[:12])
+	case "bool":
+		valid = allZero(word[:31]) && word[31] <= 1
+	case "uint":
+		valid = allZero(word[:32-t.size/8])
+	case "int":
+		pad := word[:32-t.size/8]
+		var fill byte
+		if word[32-t.size/8]&0x80 != 0 {
+			fill = 0xff
+		}
+		for _, b := range pad {
+			if b != fill {
+				valid = false
+				break
+			}
+		}
+	case "bytesN":
+		valid = allZero(word[t.size:])
+	case "function":
+		valid = allZero(word[24:])
+	default:
+		return
+	}
+
+	c.checks++
+	if !valid {
+		c.violations++
+	}
+}
+
+// forEachCombination calls fn with every ascending choice of k indexes out of n
+func forEachCombination(n, k int, fn func([]int)) {
+	chosen := make([]int, 0, k)
+	var walk func(start int)
+	walk = func(start int) {
+		if len(chosen) == k {
+			fn(chosen)
+			return
+		}
+		for i := start; i <= n-(k-len(chosen)); i++ {
+			chosen = append(chosen, i)
+			walk(i + 1)
+			chosen = chosen[:len(chosen)-1]
+		}
+	}
+	walk(0)
+}
+
+// wordToInt reads a 32-byte big-endian word as an offset or length, failing if it can't be one
+func wordToInt(word []byte) (int, bool) {
+	if !allZero(word[:28]) {
+		return 0, false
+	}
+	value := int(word[28])<<24 | int(word[29])<<16 | int(word[30])<<8 | int(word[31])
+	return value, value >= 0
+}
+
+// allZero reports whether every byte is zero
+func allZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -12,15 +12,20 @@ import (
 	"time"
 
 	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
 	"golang.org/x/crypto/sha3"
 )
 
 // ABIResolver fetches contract ABIs and source code from Etherscan API v2 and Sourcify
 type ABIResolver struct {
-	httpClient *http.Client
-	apiKey     string
-	verbose    bool  // Added for debug logging
-	cache      Cache // Cache for ABI data
+	httpClient    *http.Client
+	apiKey        string
+	verbose       bool              // Added for debug logging
+	cache         Cache             // Cache for ABI data
+	localRegistry *LocalABIRegistry // Optional on-disk ABIs consulted before any remote source
+
+	rpcClient       *rpc.Client        // Optional - enables bytecode selector extraction for unverified contracts
+	signatureLookup *SignatureResolver // Resolves selectors extracted from bytecode
 }
 
 // ContractInfo represents resolved contract information
@@ -31,11 +36,14 @@ type ContractInfo struct {
 	ContractName     string      `json:"contract_name"` // Name from verification
 	CompilerVersion  string      `json:"compiler_version"`
 	IsVerified       bool        `json:"is_verified"`
+	Source           string      `json:"source,omitempty"` // local, etherscan, sourcify, bytecode
 	IsProxy          bool        `json:"is_proxy"`
 	Implementation   string      `json:"implementation,omitempty"`    // For proxy contracts
 	IsImplementation bool        `json:"is_implementation,omitempty"` // True if this is an implementation contract
 	ProxyAddress     string      `json:"proxy_address,omitempty"`     // Address of the proxy that uses this implementation
 	ParsedABI        []ABIMethod `json:"parsed_abi"`                  // Parsed ABI for easier access
+
+	UnresolvedSelectors []string `json:"unresolved_selectors,omitempty"` // Bytecode selectors with no known signature
 }
 
 // ABIMethod represents a parsed ABI method or event
@@ -90,12 +98,29 @@ func NewABIResolver(cache Cache, verbose bool) *ABIResolver {
 		httpClient: &http.Client{
 			Timeout: 300 * time.Second, // 5 minutes for slow Etherscan responses
 		},
-		apiKey:  apiKey,
-		verbose: verbose,
-		cache:   cache,
+		apiKey:          apiKey,
+		verbose:         verbose,
+		cache:           cache,
+		signatureLookup: NewSignatureResolver(cache, verbose),
 	}
 }
 
+// NewABIResolverWithLocalRegistry creates an ABI resolver that checks the local ABI registry
+// before the cache, Etherscan and Sourcify
+func NewABIResolverWithLocalRegistry(registry *LocalABIRegistry, cache Cache, verbose bool) *ABIResolver {
+	resolver := NewABIResolver(cache, verbose)
+	resolver.localRegistry = registry
+	return resolver
+}
+
+// NewABIResolverWithRPC creates an ABI resolver that, in addition to the local registry, falls back to
+// extracting function selectors from on-chain bytecode when no verified source exists
+func NewABIResolverWithRPC(rpcClient *rpc.Client, registry *LocalABIRegistry, cache Cache, verbose bool) *ABIResolver {
+	resolver := NewABIResolverWithLocalRegistry(registry, cache, verbose)
+	resolver.rpcClient = rpcClient
+	return resolver
+}
+
 // Name returns the tool name
 func (a *ABIResolver) Name() string {
 	return "abi_resolver"
@@ -321,6 +346,17 @@ func (a *ABIResolver) extractContractAddresses(baggage map[string]interface{}) [
 
 // resolveContract fetches contract information from Etherscan API with Sourcify fallback
 func (a *ABIResolver) resolveContract(ctx context.Context, address string, networkID int64, progressTracker *models.ProgressTracker, hasProgress bool) (*ContractInfo, error) {
+	// Local registry wins over everything else - teams use it for unverified and private contracts
+	if contractInfo := a.resolveFromLocalRegistry(address, networkID); contractInfo != nil {
+		if a.verbose || os.Getenv("DEBUG") == "true" {
+			fmt.Printf("  ✅ Found local ABI for contract %s\n", address)
+		}
+		if hasProgress {
+			progressTracker.UpdateComponent("abi_resolver", models.ComponentGroupDecoding, "Resolving Contract ABIs", models.ComponentStatusRunning, fmt.Sprintf("Using local ABI: %s", address[:10]+"..."))
+		}
+		return contractInfo, nil
+	}
+
 	// Check cache first if available
 	if a.cache != nil {
 		cacheKey := fmt.Sprintf(ABIKeyPattern, networkID, strings.ToLower(address))
@@ -493,6 +529,22 @@ func (a *ABIResolver) resolveContract(ctx context.Context, address string, netwo
 			fmt.Printf("  === END RESOLVING CONTRACT %s ===\n", address)
 		}
 
+		// Last resort: recover a partial ABI from the contract's dispatcher
+		if a.rpcClient != nil {
+			if hasProgress {
+				progressTracker.UpdateComponent("abi_resolver", models.ComponentGroupDecoding, "Resolving Contract ABIs", models.ComponentStatusRunning, fmt.Sprintf("Extracting selectors from bytecode: %s", address[:10]+"..."))
+			}
+
+			if partialInfo, bytecodeErr := a.resolveFromBytecode(ctx, address, networkID); bytecodeErr == nil {
+				if a.verbose || os.Getenv("DEBUG") == "true" {
+					fmt.Printf("  ✅ Recovered %d functions from bytecode (%d selectors unresolved)\n", len(partialInfo.ParsedABI), len(partialInfo.UnresolvedSelectors))
+				}
+				return partialInfo, nil
+			} else if a.verbose || os.Getenv("DEBUG") == "true" {
+				fmt.Printf("  ❌ Bytecode selector extraction failed: %v\n", bytecodeErr)
+			}
+		}
+
 		// Send progress update for final failure
 		if hasProgress {
 			progressTracker.UpdateComponent("abi_resolver", models.ComponentGroupDecoding, "Resolving Contract ABIs", models.ComponentStatusRunning, fmt.Sprintf("All ABI sources failed for %s: %s", address[:10]+"...", err.Error()))
@@ -539,6 +591,33 @@ func (a *ABIResolver) resolveContract(ctx context.Context, address string, netwo
 	return contractInfo, nil
 }
 
+// resolveFromLocalRegistry builds contract info from the local ABI registry, if it has the contract
+func (a *ABIResolver) resolveFromLocalRegistry(address string, networkID int64) *ContractInfo {
+	if a.localRegistry == nil {
+		return nil
+	}
+
+	entry, exists := a.localRegistry.Lookup(networkID, address)
+	if !exists {
+		return nil
+	}
+
+	contractInfo := &ContractInfo{
+		Address:         address,
+		ABI:             entry.ABI,
+		ContractName:    entry.ContractName,
+		CompilerVersion: entry.CompilerVersion,
+		IsVerified:      true,
+		Source:          "local",
+	}
+
+	if parsedABI, err := a.parseABI(entry.ABI); err == nil {
+		contractInfo.ParsedABI = parsedABI
+	}
+
+	return contractInfo
+}
+
 // fetchFromSourceify fetches contract information from Sourcify
 func (a *ABIResolver) fetchFromSourceify(ctx context.Context, address string, networkID int64, contractInfo *ContractInfo, progressTracker *models.ProgressTracker, hasProgress bool) error {
 	// Sourcify uses standard chain IDs - convert our network ID if needed
@@ -677,6 +756,7 @@ func (a *ABIResolver) fetchFromSourceify(ctx context.Context, address string, ne
 			}
 			contractInfo.ABI = string(abiBytes)
 			contractInfo.IsVerified = true
+			contractInfo.Source = "sourcify"
 		}
 	}
 
@@ -826,6 +906,7 @@ func (a *ABIResolver) fetchSourceCode(ctx context.Context, baseURL, address stri
 	contractInfo.ContractName = sourceResult.ContractName
 	contractInfo.CompilerVersion = sourceResult.CompilerVersion
 	contractInfo.IsVerified = true
+	contractInfo.Source = "etherscan"
 
 	// Check if it's a proxy
 	if sourceResult.Proxy == "1" && sourceResult.Implementation != "" {
@@ -904,6 +985,7 @@ func (a *ABIResolver) fetchABI(ctx context.Context, baseURL, address string, con
 
 	contractInfo.ABI = abi
 	contractInfo.IsVerified = true
+	contractInfo.Source = "etherscan"
 
 	return nil
 }
@@ -933,7 +1015,7 @@ func (a *ABIResolver) parseABI(abiJSON string) ([]ABIMethod, error) {
 		}
 
 		// Generate signature and hash based on type
-		if itemType == "function" {
+		if itemType == "function" || itemType == "error" {
 			method.Signature = a.generateFunctionSignature(name, inputs)
 			method.Hash = a.generateFunctionHash(method.Signature)
 		} else if itemType == "event" {
@@ -1109,7 +1191,14 @@ func (a *ABIResolver) GetPromptContext(ctx context.Context, baggage map[string]i
 			}
 
 			// Contract verification status
-			contractInfo = append(contractInfo, "Status: Verified on Etherscan")
+			switch contract.Source {
+			case "local":
+				contractInfo = append(contractInfo, "Status: ABI from local registry")
+			case "sourcify":
+				contractInfo = append(contractInfo, "Status: Verified on Sourcify")
+			default:
+				contractInfo = append(contractInfo, "Status: Verified on Etherscan")
+			}
 
 			if contract.CompilerVersion != "" {
 				contractInfo = append(contractInfo, fmt.Sprintf("Compiler: %s", contract.CompilerVersion))
@@ -1188,8 +1277,11 @@ func (a *ABIResolver) GetPromptContext(ctx context.Context, baggage map[string]i
 		}
 	}
 
+	// Unverified contracts with functions recovered from bytecode
+	partialContext := a.getPartialABIPromptContext(baggage, resolvedContracts)
+
 	if len(contextParts) == 1 {
-		return "" // No verified contracts
+		return partialContext // No verified contracts
 	}
 
 	// Add detailed event parameter information
@@ -1218,6 +1310,10 @@ func (a *ABIResolver) GetPromptContext(ctx context.Context, baggage map[string]i
 		contextParts = append(contextParts, "- When describing transactions, focus on the proxy address that users interact with")
 	}
 
+	if partialContext != "" {
+		contextParts = append(contextParts, "", partialContext)
+	}
+
 	contextParts = append(contextParts, "", "Note: Contract names from Etherscan verification are authoritative. Use verified contract names to distinguish between token contracts (e.g., 'USDC', 'DAI') and protocol contracts (e.g., 'AggregationRouterV6', 'UniswapV2Router02').")
 
 	return strings.Join(contextParts, "\n")
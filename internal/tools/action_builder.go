@@ -0,0 +1,992 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// Events the action builder decodes from raw topics and data, so they work without a verified ABI
+var (
+	erc20ApprovalTopic   = rpc.GenerateEventSignature("Approval(address,address,uint256)")
+	approvalForAllTopic  = rpc.GenerateEventSignature("ApprovalForAll(address,address,bool)")
+	erc1155SingleTopic   = rpc.GenerateEventSignature("TransferSingle(address,address,address,uint256,uint256)")
+	wrappedDepositTopic  = rpc.GenerateEventSignature("Deposit(address,uint256)")
+	wrappedWithdrawTopic = rpc.GenerateEventSignature("Withdrawal(address,uint256)")
+)
+
+// lendingEvent describes where an Aave-style pool event keeps the reserve, the acting account and the amount
+type lendingEvent struct {
+	action       string
+	actorTopic   int // Topic index of the acting account, 0 when it is the first data word
+	amountWord   int // Data word holding the amount
+	tokensToPool bool
+}
+
+// lendingEvents are the Aave v2 and v3 pool events; forks such as Spark emit the same ones
+var lendingEvents = map[string]lendingEvent{
+	rpc.GenerateEventSignature("Deposit(address,address,address,uint256,uint16)"):                {models.ActionTypeSupply, 0, 1, true},
+	rpc.GenerateEventSignature("Supply(address,address,address,uint256,uint16)"):                 {models.ActionTypeSupply, 0, 1, true},
+	rpc.GenerateEventSignature("Borrow(address,address,address,uint256,uint256,uint256,uint16)"): {models.ActionTypeBorrow, 0, 1, false},
+	rpc.GenerateEventSignature("Borrow(address,address,address,uint256,uint8,uint256,uint16)"):   {models.ActionTypeBorrow, 0, 1, false},
+	rpc.GenerateEventSignature("Repay(address,address,address,uint256)"):                         {models.ActionTypeRepay, 3, 0, true},
+	rpc.GenerateEventSignature("Repay(address,address,address,uint256,bool)"):                    {models.ActionTypeRepay, 3, 0, true},
+	rpc.GenerateEventSignature("Withdraw(address,address,address,uint256)"):                      {models.ActionTypeWithdraw, 2, 0, false},
+}
+
+// stakingEvent describes where a staking or bridge event keeps the acting account, the amount and the token
+type stakingEvent struct {
+	action     string
+	actorTopic int  // Topic index of the acting account
+	amountWord int  // Data word holding the amount
+	tokenTopic int  // Topic index of the token moved, 0 when the token is native or only known from the flows
+	native     bool // The amount is in the native currency
+}
+
+// stakingEvents are Lido's stake, the Synthetix StakingRewards events most staking contracts copy, and the
+// OP Stack standard bridge's deposits and withdrawals. Bedrock bridges also emit ETHBridgeInitiated and
+// ERC20BridgeInitiated for the same transfer, so only the legacy events are decoded.
+var stakingEvents = map[string]stakingEvent{
+	rpc.GenerateEventSignature("Submitted(address,uint256,address)"):                                   {models.ActionTypeStake, 1, 0, 0, true},
+	rpc.GenerateEventSignature("Staked(address,uint256)"):                                              {models.ActionTypeStake, 1, 0, 0, false},
+	rpc.GenerateEventSignature("Withdrawn(address,uint256)"):                                           {models.ActionTypeUnstake, 1, 0, 0, false},
+	rpc.GenerateEventSignature("RewardPaid(address,uint256)"):                                          {models.ActionTypeClaim, 1, 0, 0, false},
+	rpc.GenerateEventSignature("ETHDepositInitiated(address,address,uint256,bytes)"):                   {models.ActionTypeBridge, 1, 0, 0, true},
+	rpc.GenerateEventSignature("ERC20DepositInitiated(address,address,address,address,uint256,bytes)"): {models.ActionTypeBridge, 3, 1, 1, false},
+	rpc.GenerateEventSignature("WithdrawalInitiated(address,address,address,address,uint256,bytes)"):   {models.ActionTypeBridge, 3, 1, 2, false},
+}
+
+// Allowances wallets and tokens treat as unlimited: max uint256, and the max uint160 and uint96 that Permit2
+// and compact-allowance tokens cap at
+var unlimitedAllowances = []*big.Int{
+	new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+	new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1)),
+	new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1)),
+}
+
+// assetFlow is one movement of a fungible amount or a single NFT between two addresses
+type assetFlow struct {
+	token   string // "native" or the token contract
+	tokenID string // Set for NFTs
+	from    string
+	to      string
+	amount  *big.Int
+}
+
+// ActionBuilder turns the decoded transaction into a list of structured actions: swaps, transfers,
+// approvals, wraps, lending, staking and bridge operations, and NFT trades. Actions are derived from events,
+// transfers and the call trace whenever possible; when nothing decodes, the LLM fills the list under a
+// strict JSON schema.
+type ActionBuilder struct {
+	llm     llms.Model
+	verbose bool
+}
+
+// NewActionBuilder creates a new action builder
+func NewActionBuilder(llm llms.Model, verbose bool) *ActionBuilder {
+	return &ActionBuilder{
+		llm:     llm,
+		verbose: verbose,
+	}
+}
+
+// Name returns the processor name
+func (a *ActionBuilder) Name() string {
+	return "action_builder"
+}
+
+// Description returns the processor description
+func (a *ActionBuilder) Description() string {
+	return "Builds a structured list of the actions a transaction performed"
+}
+
+// Dependencies returns the tools this processor depends on
+func (a *ActionBuilder) Dependencies() []string {
+	return []string{
+		"log_decoder", "trace_decoder", "token_transfer_extractor", "token_metadata_enricher",
+		"pool_resolver", "nft_sale_detector", "erc20_price_lookup", "protocol_resolver", "user_operation_decoder",
+		"safe_transaction_decoder",
+	}
+}
+
+// Process builds the transaction's actions and stores them in baggage["actions"]
+func (a *ActionBuilder) Process(ctx context.Context, baggage map[string]interface{}) error {
+	if transactionFailed(baggage) {
+		return nil // A reverted transaction did nothing
+	}
+
+	actions := a.buildActions(baggage)
+	if len(actions) == 0 && a.llm != nil && transactionHasActivity(baggage) {
+		inferred, err := a.inferActionsWithAI(ctx, baggage)
+		if err != nil {
+			if a.verbose {
+				fmt.Printf("⚠️  Action inference failed: %v\n", err)
+			}
+		} else {
+			actions = inferred
+		}
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+
+	if a.verbose {
+		for _, action := range actions {
+			fmt.Printf("🧩 Action: %s by %s (%s)\n", action.Type, action.Actor, action.Source)
+		}
+	}
+	baggage["actions"] = actions
+	return nil
+}
+
+// transactionHasActivity reports whether there is anything beyond a bare call to explain
+func transactionHasActivity(baggage map[string]interface{}) bool {
+	events, _ := baggage["events"].([]models.Event)
+	calls, _ := baggage["calls"].([]models.Call)
+	return len(events) > 0 || len(calls) > 0
+}
+
+// buildActions derives actions deterministically. Event-backed actions come first and consume the flows
+// they explain; each actor's remaining flows become a swap, mint, burn or transfers.
+func (a *ActionBuilder) buildActions(baggage map[string]interface{}) []models.Action {
+	b := newActionContext(baggage)
+
+	var actions []models.Action
+	for _, event := range b.events {
+		if len(event.Topics) == 0 {
+			continue
+		}
+		topic := strings.ToLower(event.Topics[0])
+		var action *models.Action
+		switch {
+		case topic == erc20ApprovalTopic || topic == approvalForAllTopic:
+			action = b.approvalAction(event)
+		case topic == wrappedDepositTopic || topic == wrappedWithdrawTopic:
+			action = b.wrapAction(event)
+		default:
+			if spec, ok := lendingEvents[topic]; ok {
+				action = b.lendingAction(event, spec)
+			} else if spec, ok := stakingEvents[topic]; ok {
+				action = b.stakingAction(event, spec)
+			}
+		}
+		if action != nil {
+			actions = append(actions, *action)
+		}
+	}
+
+	actions = append(actions, b.nftSaleActions()...)
+	for _, actor := range b.actors {
+		actions = append(actions, b.flowActions(actor)...)
+	}
+	return actions
+}
+
+// actionContext holds what the builders read from baggage and the flows no action has explained yet
+type actionContext struct {
+	actors    []string          // The transaction sender, or the smart accounts and Safes it relayed for
+	targets   map[string]string // The contract each actor called, when there is a single one
+	events    []models.Event
+	flows     []assetFlow
+	consumed  []bool
+	metadata  map[string]*TokenMetadata
+	prices    map[string]*TokenPrice
+	pools     map[string]*PoolInfo
+	protocols []ProbabilisticProtocol
+	sales     []NFTSale
+}
+
+func newActionContext(baggage map[string]interface{}) *actionContext {
+	b := &actionContext{targets: make(map[string]string)}
+	var entryPoints []string
+	operations, relayed := GetUserOperations(baggage)
+	// The bundler only relays: the smart accounts act
+	for _, operation := range operations {
+		if !containsString(entryPoints, operation.EntryPoint) {
+			entryPoints = append(entryPoints, operation.EntryPoint)
+		}
+		if operation.Success && !containsString(b.actors, operation.Sender) {
+			b.actors = append(b.actors, operation.Sender)
+			if call := operation.Call; call != nil && call.Batch == models.BatchAccount && len(call.Calls) == 1 {
+				b.targets[operation.Sender] = call.Calls[0].Contract
+			}
+		}
+	}
+	// A Safe acts on its owners' approval, whoever submitted the transaction
+	if transactions, ok := GetSafeTransactions(baggage); ok {
+		relayed = true
+		for _, transaction := range transactions {
+			if transaction.Success && !containsString(b.actors, transaction.Safe) {
+				b.actors = append(b.actors, transaction.Safe)
+				if transaction.Call != nil {
+					b.targets[transaction.Safe] = transaction.Call.Contract
+				}
+			}
+		}
+	}
+	if sender := transactionSender(baggage); !relayed && sender != "" {
+		b.actors = []string{sender}
+		if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+			if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+				to, _ := receipt["to"].(string)
+				b.targets[sender] = strings.ToLower(to)
+			}
+		}
+	}
+	b.events, _ = baggage["events"].([]models.Event)
+	b.metadata, _ = baggage["token_metadata"].(map[string]*TokenMetadata)
+	b.prices, _ = baggage["token_prices"].(map[string]*TokenPrice)
+	b.pools, _ = baggage["pools"].(map[string]*PoolInfo)
+	b.protocols, _ = baggage["protocols"].([]ProbabilisticProtocol)
+	b.sales, _ = GetNFTSales(baggage)
+
+	b.flows = assetFlowsFromEvents(b.events)
+	for _, flow := range nativeTransfersFromTrace(baggage) {
+		b.flows = append(b.flows, assetFlow{token: flow.token, from: flow.from, to: flow.to, amount: flow.amount})
+	}
+	b.consumed = make([]bool, len(b.flows))
+	// Gas prefunds, refunds and the bundler's compensation are accounting, not actions
+	b.consume(func(flow assetFlow) bool {
+		return flow.token == nativePaymentToken && (containsString(entryPoints, flow.from) || containsString(entryPoints, flow.to))
+	})
+	return b
+}
+
+func (b *actionContext) isActor(address string) bool {
+	return containsString(b.actors, address)
+}
+
+// assetFlowsFromEvents collects ERC20 and ERC721 Transfer logs and ERC1155 TransferSingle logs
+func assetFlowsFromEvents(events []models.Event) []assetFlow {
+	var flows []assetFlow
+	for _, event := range events {
+		if len(event.Topics) == 0 {
+			continue
+		}
+		token := strings.ToLower(event.Contract)
+		data := newABIData(event.Data)
+		switch topic := strings.ToLower(event.Topics[0]); {
+		case topic == erc20TransferTopic && len(event.Topics) == 3:
+			if amount := data.uintAt(0); amount != nil && amount.Sign() > 0 {
+				flows = append(flows, assetFlow{token: token, from: topicAddress(event.Topics[1]), to: topicAddress(event.Topics[2]), amount: amount})
+			}
+		case topic == erc20TransferTopic && len(event.Topics) == 4:
+			tokenID, _ := new(big.Int).SetString(strings.TrimPrefix(event.Topics[3], "0x"), 16)
+			if tokenID != nil {
+				flows = append(flows, assetFlow{token: token, tokenID: tokenID.String(), from: topicAddress(event.Topics[1]), to: topicAddress(event.Topics[2]), amount: big.NewInt(1)})
+			}
+		case topic == erc1155SingleTopic && len(event.Topics) == 4:
+			tokenID, amount := data.uintAt(0), data.uintAt(32)
+			if tokenID != nil && amount != nil && amount.Sign() > 0 {
+				flows = append(flows, assetFlow{token: token, tokenID: tokenID.String(), from: topicAddress(event.Topics[2]), to: topicAddress(event.Topics[3]), amount: amount})
+			}
+		}
+	}
+	return flows
+}
+
+// consume marks the unexplained flows matching the predicate as explained and returns them
+func (b *actionContext) consume(match func(assetFlow) bool) []assetFlow {
+	var matched []assetFlow
+	for i, flow := range b.flows {
+		if !b.consumed[i] && match(flow) {
+			b.consumed[i] = true
+			matched = append(matched, flow)
+		}
+	}
+	return matched
+}
+
+// approvalAction turns an Approval or ApprovalForAll granted by an actor into an approve action. A finite
+// Approval next to a transfer of the actor's tokens is skipped: tokens emit those from transferFrom to
+// report the reduced allowance.
+func (b *actionContext) approvalAction(event models.Event) *models.Action {
+	if len(event.Topics) < 3 {
+		return nil
+	}
+	owner, spender := topicAddress(event.Topics[1]), topicAddress(event.Topics[2])
+	token := strings.ToLower(event.Contract)
+	if !b.isActor(owner) {
+		return nil
+	}
+
+	allowance := b.amount(token, "", nil)
+	switch {
+	case strings.ToLower(event.Topics[0]) == approvalForAllTopic:
+		if approved := newABIData(event.Data).uintAt(0); approved != nil && approved.Sign() > 0 {
+			allowance.Amount = "all"
+		} else {
+			allowance.Amount = "0"
+		}
+	case len(event.Topics) == 4: // ERC721 approval of a single token
+		tokenID, _ := new(big.Int).SetString(strings.TrimPrefix(event.Topics[3], "0x"), 16)
+		if tokenID != nil {
+			allowance.TokenID = tokenID.String()
+		}
+	default:
+		value := newABIData(event.Data).uintAt(0)
+		if value == nil {
+			return nil
+		}
+		allowance = b.amount(token, "", value)
+		switch {
+		case isUnlimitedAllowance(value):
+			allowance.Amount = "unlimited"
+			allowance.AmountUSD = ""
+		case token != b.targets[owner] && b.hasFlow(func(flow assetFlow) bool { return flow.token == token && flow.from == owner }):
+			return nil
+		}
+	}
+
+	return &models.Action{
+		Type:         models.ActionTypeApprove,
+		Actor:        owner,
+		Counterparty: spender,
+		Contract:     token,
+		TokensOut:    []models.ActionAmount{allowance},
+		Source:       models.ActionSourceDecoded,
+	}
+}
+
+// isUnlimitedAllowance reports whether an allowance is one of the conventional "infinite" values
+func isUnlimitedAllowance(value *big.Int) bool {
+	for _, unlimited := range unlimitedAllowances {
+		if value.Cmp(unlimited) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *actionContext) hasFlow(match func(assetFlow) bool) bool {
+	for _, flow := range b.flows {
+		if match(flow) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapAction turns a wrapped native token Deposit or Withdrawal into a wrap or unwrap, but only when the
+// trace shows the matching native transfer: plenty of other contracts emit a Deposit(address,uint256)
+func (b *actionContext) wrapAction(event models.Event) *models.Action {
+	if len(event.Topics) != 2 {
+		return nil
+	}
+	account, token := topicAddress(event.Topics[1]), strings.ToLower(event.Contract)
+	amount := newABIData(event.Data).uintAt(0)
+	if amount == nil || !b.isActor(account) {
+		return nil
+	}
+
+	deposit := strings.ToLower(event.Topics[0]) == wrappedDepositTopic
+	native := b.consume(func(flow assetFlow) bool {
+		if flow.token != nativePaymentToken || flow.amount.Cmp(amount) != 0 {
+			return false
+		}
+		if deposit {
+			return flow.from == account && flow.to == token
+		}
+		return flow.from == token && flow.to == account
+	})
+	if len(native) == 0 {
+		return nil
+	}
+
+	action := &models.Action{
+		Type:     models.ActionTypeWrap,
+		Actor:    account,
+		Contract: token,
+		Source:   models.ActionSourceDecoded,
+	}
+	if deposit {
+		action.TokensOut = []models.ActionAmount{b.amount(nativePaymentToken, "", amount)}
+		action.TokensIn = []models.ActionAmount{b.amount(token, "", amount)}
+	} else {
+		action.Type = models.ActionTypeUnwrap
+		action.TokensOut = []models.ActionAmount{b.amount(token, "", amount)}
+		action.TokensIn = []models.ActionAmount{b.amount(nativePaymentToken, "", amount)}
+	}
+	return action
+}
+
+// lendingAction turns an Aave-style pool event into a supply, borrow, repay or withdraw. The reserve
+// moves between the account and the pool, and interest-bearing or debt tokens are minted or burned.
+func (b *actionContext) lendingAction(event models.Event, spec lendingEvent) *models.Action {
+	if len(event.Topics) < 3 {
+		return nil
+	}
+	data := newABIData(event.Data)
+	reserve := topicAddress(event.Topics[1])
+	amount := data.uintAt(spec.amountWord * 32)
+	actor := data.addressAt(0)
+	if spec.actorTopic > 0 && len(event.Topics) > spec.actorTopic {
+		actor = topicAddress(event.Topics[spec.actorTopic])
+	}
+	if amount == nil || actor == "" {
+		return nil
+	}
+	beneficiary := topicAddress(event.Topics[2])
+
+	involved := func(address string) bool { return address == actor || address == beneficiary }
+	b.consume(func(flow assetFlow) bool {
+		switch {
+		case flow.token == reserve:
+			return involved(flow.from) || involved(flow.to)
+		case flow.from == zeroAddress:
+			return involved(flow.to)
+		case flow.to == zeroAddress:
+			return involved(flow.from)
+		}
+		return false
+	})
+
+	action := &models.Action{
+		Type:         spec.action,
+		Protocol:     b.protocolFor(strings.ToLower(event.Contract)),
+		Actor:        actor,
+		Counterparty: strings.ToLower(event.Contract),
+		Contract:     strings.ToLower(event.Contract),
+		Source:       models.ActionSourceDecoded,
+	}
+	if spec.tokensToPool {
+		action.TokensOut = []models.ActionAmount{b.amount(reserve, "", amount)}
+	} else {
+		action.TokensIn = []models.ActionAmount{b.amount(reserve, "", amount)}
+	}
+	return action
+}
+
+// stakingAction turns a staking or bridge event into a stake, unstake, claim or bridge. The flows between
+// the account and the emitting contract, and any receipt token minted or burned for the account, belong
+// to it; the event's own amount is used when the trace shows no flows.
+func (b *actionContext) stakingAction(event models.Event, spec stakingEvent) *models.Action {
+	if len(event.Topics) <= spec.actorTopic || len(event.Topics) <= spec.tokenTopic {
+		return nil
+	}
+	contract := strings.ToLower(event.Contract)
+	actor := topicAddress(event.Topics[spec.actorTopic])
+	amount := newABIData(event.Data).uintAt(spec.amountWord * 32)
+	if amount == nil || actor == "" {
+		return nil
+	}
+
+	flows := b.consume(func(flow assetFlow) bool {
+		switch {
+		case flow.from == actor:
+			return flow.to == contract || flow.to == zeroAddress
+		case flow.to == actor:
+			return flow.from == contract || flow.from == zeroAddress
+		}
+		return false
+	})
+
+	action := &models.Action{
+		Type:         spec.action,
+		Protocol:     b.protocolFor(contract),
+		Actor:        actor,
+		Counterparty: contract,
+		Contract:     contract,
+		Source:       models.ActionSourceDecoded,
+	}
+	action.TokensIn, action.TokensOut = b.netAmounts(flows, actor)
+	if len(action.TokensIn) > 0 || len(action.TokensOut) > 0 {
+		return action
+	}
+
+	token := ""
+	switch {
+	case spec.native:
+		token = nativePaymentToken
+	case spec.tokenTopic > 0:
+		token = topicAddress(event.Topics[spec.tokenTopic])
+	}
+	if token != "" {
+		moved := []models.ActionAmount{b.amount(token, "", amount)}
+		if spec.action == models.ActionTypeUnstake || spec.action == models.ActionTypeClaim {
+			action.TokensIn = moved
+		} else {
+			action.TokensOut = moved
+		}
+	}
+	return action
+}
+
+// nftSaleActions turns detected NFT sales into buys or sells from the actor's side
+func (b *actionContext) nftSaleActions() []models.Action {
+	var actions []models.Action
+	for _, sale := range b.sales {
+		collection := strings.ToLower(sale.Collection)
+		buyer, seller := strings.ToLower(sale.Buyer), strings.ToLower(sale.Seller)
+		paymentToken := strings.ToLower(sale.PaymentToken)
+
+		b.consume(func(flow assetFlow) bool {
+			if flow.token == collection {
+				return containsString(sale.TokenIDs, flow.tokenID)
+			}
+			return flow.token == paymentToken && (flow.from == buyer || flow.to == seller)
+		})
+
+		var nfts []models.ActionAmount
+		for _, tokenID := range sale.TokenIDs {
+			nft := b.amount(collection, tokenID, nil)
+			if nft.Symbol == "" {
+				nft.Symbol = sale.CollectionName
+			}
+			nfts = append(nfts, nft)
+		}
+		price, _ := new(big.Int).SetString(sale.Price, 10)
+		payment := b.amount(paymentToken, "", price)
+		if payment.Symbol == "" {
+			payment.Symbol = sale.PaymentSymbol
+		}
+
+		action := models.Action{
+			Type:         models.ActionTypeNFTBuy,
+			Protocol:     sale.Marketplace,
+			Actor:        buyer,
+			Counterparty: seller,
+			Contract:     collection,
+			TokensIn:     nfts,
+			TokensOut:    []models.ActionAmount{payment},
+			Source:       models.ActionSourceDecoded,
+		}
+		if b.isActor(seller) && !b.isActor(buyer) {
+			if proceeds, ok := new(big.Int).SetString(sale.SellerProceeds, 10); ok {
+				payment = b.amount(paymentToken, "", proceeds)
+			}
+			action.Type = models.ActionTypeNFTSell
+			action.Actor, action.Counterparty = seller, buyer
+			action.TokensIn, action.TokensOut = []models.ActionAmount{payment}, nfts
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// flowActions explains an actor's remaining flows. Receiving a freshly minted token makes it a mint
+// (a deposit into a vault or liquidity pool), burning one makes it a burn, giving one asset for another
+// a swap, and anything one-directional plain transfers.
+func (b *actionContext) flowActions(actor string) []models.Action {
+	var flows []assetFlow
+	for i, flow := range b.flows {
+		if !b.consumed[i] && (flow.from == actor || flow.to == actor) && flow.from != flow.to {
+			b.consumed[i] = true
+			flows = append(flows, flow)
+		}
+	}
+	if len(flows) == 0 {
+		return nil
+	}
+
+	var minted, burned string
+	for _, flow := range flows {
+		if flow.from == zeroAddress && minted == "" {
+			minted = flow.token
+		}
+		if flow.to == zeroAddress && burned == "" {
+			burned = flow.token
+		}
+	}
+
+	tokensIn, tokensOut := b.netAmounts(flows, actor)
+	action := models.Action{
+		Actor:     actor,
+		TokensIn:  tokensIn,
+		TokensOut: tokensOut,
+		Source:    models.ActionSourceDecoded,
+	}
+	switch {
+	case minted != "" && minted != nativePaymentToken:
+		action.Type = models.ActionTypeMint
+		action.Contract = minted
+		action.Protocol = b.protocolFor(minted)
+		action.Counterparty = b.counterparty(flows, actor)
+	case burned != "" && burned != nativePaymentToken:
+		action.Type = models.ActionTypeBurn
+		action.Contract = burned
+		action.Protocol = b.protocolFor(burned)
+		action.Counterparty = b.counterparty(flows, actor)
+	case len(tokensIn) > 0 && len(tokensOut) > 0:
+		action.Type = models.ActionTypeSwap
+		action.Contract = b.targets[actor]
+		action.Protocol = b.swapProtocol()
+		action.Counterparty = b.counterparty(flows, actor)
+	default:
+		return b.transferActions(flows)
+	}
+	return []models.Action{action}
+}
+
+// netAmounts nets an actor's flows per asset into what it received and what it gave
+func (b *actionContext) netAmounts(flows []assetFlow, actor string) (tokensIn, tokensOut []models.ActionAmount) {
+	type asset struct{ token, tokenID string }
+	var order []asset
+	net := make(map[asset]*big.Int)
+	for _, flow := range flows {
+		key := asset{flow.token, flow.tokenID}
+		if net[key] == nil {
+			net[key] = new(big.Int)
+			order = append(order, key)
+		}
+		if flow.to == actor {
+			net[key].Add(net[key], flow.amount)
+		} else {
+			net[key].Sub(net[key], flow.amount)
+		}
+	}
+
+	for _, key := range order {
+		amount := net[key]
+		switch amount.Sign() {
+		case 1:
+			tokensIn = append(tokensIn, b.amount(key.token, key.tokenID, amount))
+		case -1:
+			tokensOut = append(tokensOut, b.amount(key.token, key.tokenID, new(big.Int).Neg(amount)))
+		}
+	}
+	return tokensIn, tokensOut
+}
+
+// transferActions makes one transfer per flow, seen from the side that sent it
+func (b *actionContext) transferActions(flows []assetFlow) []models.Action {
+	var actions []models.Action
+	for _, flow := range flows {
+		contract := flow.token
+		if contract == nativePaymentToken {
+			contract = ""
+		}
+		actions = append(actions, models.Action{
+			Type:         models.ActionTypeTransfer,
+			Actor:        flow.from,
+			Counterparty: flow.to,
+			Contract:     contract,
+			TokensOut:    []models.ActionAmount{b.amount(flow.token, flow.tokenID, flow.amount)},
+			Source:       models.ActionSourceDecoded,
+		})
+	}
+	return actions
+}
+
+// counterparty is the single pool the actor traded with, or else the contract it called
+func (b *actionContext) counterparty(flows []assetFlow, actor string) string {
+	var pools []string
+	for _, flow := range flows {
+		for _, address := range []string{flow.from, flow.to} {
+			if _, ok := b.pools[address]; ok && !containsString(pools, address) {
+				pools = append(pools, address)
+			}
+		}
+	}
+	if len(pools) == 1 {
+		return pools[0]
+	}
+	return b.targets[actor]
+}
+
+// swapProtocol names the protocols of the verified pools the swap went through, falling back to the most
+// confident DEX or aggregator attribution
+func (b *actionContext) swapProtocol() string {
+	var names []string
+	addresses := make([]string, 0, len(b.pools))
+	for address := range b.pools {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		if !b.hasFlow(func(flow assetFlow) bool { return flow.from == address || flow.to == address }) {
+			continue
+		}
+		pool := b.pools[address]
+		if name := strings.TrimSpace(pool.Protocol + " " + pool.Version); name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		return strings.Join(names, ", ")
+	}
+
+	var best *ProbabilisticProtocol
+	for i, protocol := range b.protocols {
+		kind := strings.ToLower(protocol.Type)
+		if !strings.Contains(kind, "dex") && !strings.Contains(kind, "aggregator") {
+			continue
+		}
+		if best == nil || protocol.Confidence > best.Confidence {
+			best = &b.protocols[i]
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return strings.TrimSpace(best.Name + " " + best.Version)
+}
+
+// protocolFor names the protocol attributed to a contract, if any
+func (b *actionContext) protocolFor(address string) string {
+	for _, protocol := range b.protocols {
+		for _, contract := range protocol.Contracts {
+			if strings.EqualFold(contract, address) {
+				return strings.TrimSpace(protocol.Name + " " + protocol.Version)
+			}
+		}
+	}
+	return ""
+}
+
+// amount describes an amount of a token with its symbol, formatted value and USD value when known
+func (b *actionContext) amount(token, tokenID string, raw *big.Int) models.ActionAmount {
+	result := models.ActionAmount{Token: token, TokenID: tokenID}
+
+	decimals := 0
+	if token == nativePaymentToken {
+		decimals = 18
+	}
+	if metadata, ok := b.metadata[token]; ok {
+		result.Symbol = metadata.Symbol
+		if metadata.Decimals > 0 {
+			decimals = metadata.Decimals
+		}
+	}
+	price, hasPrice := b.prices[token]
+	if hasPrice && result.Symbol == "" {
+		result.Symbol = price.Symbol
+	}
+	if raw == nil {
+		return result
+	}
+
+	result.RawAmount = raw.String()
+	if tokenID != "" {
+		if raw.Cmp(big.NewInt(1)) != 0 {
+			result.Amount = raw.String()
+		}
+		return result
+	}
+	formatted := new(big.Float).SetInt(raw)
+	if decimals > 0 {
+		formatted.Quo(formatted, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	}
+	result.Amount = strings.TrimRight(strings.TrimRight(formatted.Text('f', 6), "0"), ".")
+	if hasPrice && price.Price > 0 {
+		value, _ := formatted.Float64()
+		result.AmountUSD = fmt.Sprintf("%.2f", value*price.Price)
+	}
+	return result
+}
+
+// actionSchema is the JSON schema the LLM must follow when decoders could not produce the actions
+func actionSchema() map[string]interface{} {
+	amount := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"token":    map[string]interface{}{"type": "string", "description": "Token contract address, or \"native\""},
+			"symbol":   map[string]interface{}{"type": "string"},
+			"amount":   map[string]interface{}{"type": "string", "description": "Human-readable amount, empty if unknown"},
+			"token_id": map[string]interface{}{"type": "string", "description": "NFT token ID, empty for fungible tokens"},
+		},
+		"required":             []string{"token", "symbol", "amount", "token_id"},
+		"additionalProperties": false,
+	}
+	address := map[string]interface{}{"type": "string", "description": "0x address, empty if unknown"}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"actions": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"type":         map[string]interface{}{"type": "string", "enum": models.ActionTypes},
+						"protocol":     map[string]interface{}{"type": "string"},
+						"actor":        address,
+						"counterparty": address,
+						"contract":     address,
+						"tokens_in":    map[string]interface{}{"type": "array", "items": amount},
+						"tokens_out":   map[string]interface{}{"type": "array", "items": amount},
+					},
+					"required":             []string{"type", "protocol", "actor", "counterparty", "contract", "tokens_in", "tokens_out"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"actions"},
+		"additionalProperties": false,
+	}
+}
+
+// inferActionsWithAI asks the LLM for the actions through a strict function call, so the reply has to match
+// actionSchema
+func (a *ActionBuilder) inferActionsWithAI(ctx context.Context, baggage map[string]interface{}) ([]models.Action, error) {
+	var contextParts []string
+	if providers, ok := baggage["context_providers"].([]interface{}); ok {
+		for _, provider := range providers {
+			if provider == a {
+				continue
+			}
+			if contextProvider, ok := provider.(interface {
+				GetPromptContext(context.Context, map[string]interface{}) string
+			}); ok {
+				if part := contextProvider.GetPromptContext(ctx, baggage); part != "" {
+					contextParts = append(contextParts, part)
+				}
+			}
+		}
+	}
+
+	prompt := fmt.Sprintf(`List the actions this blockchain transaction performed, in execution order, by calling record_actions.
+
+Each action is one user-level step: a swap, transfer, approval, mint, burn, wrap, unwrap, supply, withdraw, stake,
+unstake, borrow, repay, claim, bridge, NFT buy or NFT sell ("other" only if none fits). Describe each from the
+actor's side: tokens_out left the actor, tokens_in reached it. For approvals put the approved token and allowance
+in tokens_out and the spender in counterparty. Use addresses exactly as they appear in the context, leave fields
+you cannot determine empty, and do not invent amounts. Intermediate hops inside a swap are not separate actions.
+
+TRANSACTION CONTEXT:
+%s`, strings.Join(contextParts, "\n\n"))
+
+	response, err := CallLLMWithRetry(ctx, a.llm, []llms.MessageContent{
+		{
+			Role: llms.ChatMessageTypeHuman,
+			Parts: []llms.ContentPart{
+				llms.TextPart(prompt),
+			},
+		},
+	}, a.verbose,
+		llms.WithTools([]llms.Tool{{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        "record_actions",
+				Description: "Records the structured actions of the transaction",
+				Parameters:  actionSchema(),
+				Strict:      true,
+			},
+		}}),
+		llms.WithToolChoice(llms.ToolChoice{Type: "function", Function: &llms.FunctionReference{Name: "record_actions"}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("LLM call failed: %w", err)
+	}
+	if response == nil || len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty LLM response")
+	}
+
+	choice := response.Choices[0]
+	arguments := choice.Content
+	for _, call := range choice.ToolCalls {
+		if call.FunctionCall != nil && call.FunctionCall.Name == "record_actions" {
+			arguments = call.FunctionCall.Arguments
+			break
+		}
+	}
+	return parseInferredActions(arguments)
+}
+
+// parseInferredActions validates the LLM's actions: unknown types are dropped and addresses normalised
+func parseInferredActions(arguments string) ([]models.Action, error) {
+	var reply struct {
+		Actions []models.Action `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse actions: %w", err)
+	}
+
+	var actions []models.Action
+	for _, action := range reply.Actions {
+		if !containsString(models.ActionTypes, action.Type) {
+			continue
+		}
+		action.Actor = normalizeActionAddress(action.Actor)
+		action.Counterparty = normalizeActionAddress(action.Counterparty)
+		action.Contract = normalizeActionAddress(action.Contract)
+		for _, amounts := range [][]models.ActionAmount{action.TokensIn, action.TokensOut} {
+			for i := range amounts {
+				if amounts[i].Token != nativePaymentToken {
+					amounts[i].Token = normalizeActionAddress(amounts[i].Token)
+				}
+			}
+		}
+		action.Source = models.ActionSourceLLM
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// normalizeActionAddress lower-cases an address, dropping anything that is not one
+func normalizeActionAddress(address string) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return ""
+	}
+	return address
+}
+
+// GetActions returns the structured actions from baggage
+func GetActions(baggage map[string]interface{}) ([]models.Action, bool) {
+	actions, ok := baggage["actions"].([]models.Action)
+	return actions, ok
+}
+
+// GetPromptContext lists the actions so the explanation follows them
+func (a *ActionBuilder) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	actions, ok := GetActions(baggage)
+	if !ok || len(actions) == 0 {
+		return ""
+	}
+
+	lines := []string{"### ACTIONS (structured steps the transaction performed):"}
+	for i, action := range actions {
+		line := fmt.Sprintf("%d. %s by %s", i+1, action.Type, action.Actor)
+		if action.Protocol != "" {
+			line += " on " + action.Protocol
+		}
+		if len(action.TokensOut) > 0 {
+			line += ", gave " + describeActionAmounts(action.TokensOut)
+		}
+		if len(action.TokensIn) > 0 {
+			line += ", received " + describeActionAmounts(action.TokensIn)
+		}
+		if action.Counterparty != "" {
+			line += ", counterparty " + action.Counterparty
+		}
+		if action.Source == models.ActionSourceLLM {
+			line += " (inferred)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeActionAmounts renders amounts as "1.5 WETH, USDC #12"
+func describeActionAmounts(amounts []models.ActionAmount) string {
+	parts := make([]string, 0, len(amounts))
+	for _, amount := range amounts {
+		name := amount.Symbol
+		if name == "" {
+			name = amount.Token
+		}
+		switch {
+		case amount.TokenID != "" && amount.Amount != "":
+			parts = append(parts, fmt.Sprintf("%s x %s #%s", amount.Amount, name, amount.TokenID))
+		case amount.TokenID != "":
+			parts = append(parts, fmt.Sprintf("%s #%s", name, amount.TokenID))
+		case amount.Amount != "":
+			parts = append(parts, amount.Amount+" "+name)
+		default:
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// GetRagContext provides RAG context for actions (none - actions are transaction specific)
+func (a *ActionBuilder) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
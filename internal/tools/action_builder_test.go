@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+const (
+	testTrader    = "0x4444444444444444444444444444444444444444"
+	testRouter    = "0x5555555555555555555555555555555555555555"
+	testAavePool  = "0x87870bca3f3fd6335c3f4ce8392d69350b4fa4e2"
+	testAaveToken = "0x98c23e9d8f34fefb1b7bd6a91b7ff122f4e16f5c"
+)
+
+// testLog builds a log with address topics and uint256 data words
+func testLog(contract, signature string, indexed []string, data ...string) models.Event {
+	event := models.Event{Contract: contract, Topics: []string{eventTopic(signature)}, Data: "0x"}
+	for _, topic := range indexed {
+		event.Topics = append(event.Topics, "0x"+abiWord(topic))
+	}
+	for _, word := range data {
+		event.Data += abiWord(word)
+	}
+	return event
+}
+
+func testActionBaggage(events []models.Event, trace map[string]interface{}) map[string]interface{} {
+	rawData := map[string]interface{}{
+		"receipt": map[string]interface{}{"from": testTrader, "to": testRouter, "status": "0x1"},
+	}
+	if trace != nil {
+		rawData["trace"] = trace
+	}
+	return map[string]interface{}{
+		"raw_data": rawData,
+		"events":   events,
+		"token_metadata": map[string]*TokenMetadata{
+			testUSDC: {Symbol: "USDC", Decimals: 6},
+			testWETH: {Symbol: "WETH", Decimals: 18},
+		},
+		"token_prices": map[string]*TokenPrice{testUSDC: {Symbol: "USDC", Price: 1}},
+	}
+}
+
+func TestActionBuilderDecodesSwapThroughVerifiedPool(t *testing.T) {
+	transfer := "Transfer(address,address,uint256)"
+	baggage := testActionBaggage([]models.Event{
+		// Approval emitted by transferFrom only reports the reduced allowance
+		testLog(testUSDC, "Approval(address,address,uint256)", []string{testTrader, testRouter}, "0"),
+		testLog(testUSDC, transfer, []string{testTrader, testV3Pool}, fmt.Sprintf("%x", 2500_000000)),
+		testLog(testWETH, transfer, []string{testV3Pool, testTrader}, fmt.Sprintf("%x", uint64(1e18))),
+	}, nil)
+	baggage["pools"] = map[string]*PoolInfo{testV3Pool: {Address: testV3Pool, Protocol: "Uniswap", Version: "v3"}}
+
+	builder := NewActionBuilder(nil, false)
+	require.NoError(t, builder.Process(context.Background(), baggage))
+
+	actions, ok := GetActions(baggage)
+	require.True(t, ok)
+	require.Len(t, actions, 1)
+	swap := actions[0]
+	require.Equal(t, models.ActionTypeSwap, swap.Type)
+	require.Equal(t, "Uniswap v3", swap.Protocol)
+	require.Equal(t, testTrader, swap.Actor)
+	require.Equal(t, testV3Pool, swap.Counterparty)
+	require.Equal(t, models.ActionSourceDecoded, swap.Source)
+	require.Equal(t, []models.ActionAmount{{Token: testUSDC, Symbol: "USDC", Amount: "2500", RawAmount: "2500000000", AmountUSD: "2500.00"}}, swap.TokensOut)
+	require.Equal(t, []models.ActionAmount{{Token: testWETH, Symbol: "WETH", Amount: "1", RawAmount: "1000000000000000000"}}, swap.TokensIn)
+	require.Contains(t, builder.GetPromptContext(context.Background(), baggage), "1. swap by "+testTrader+" on Uniswap v3, gave 2500 USDC, received 1 WETH")
+}
+
+func TestActionBuilderDecodesApprovalsWrapsAndLending(t *testing.T) {
+	maxUint := "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	baggage := testActionBaggage([]models.Event{
+		testLog(testUSDC, "Approval(address,address,uint256)", []string{testTrader, testAavePool}, maxUint),
+		testLog(testWETH, "Deposit(address,uint256)", []string{testTrader}, fmt.Sprintf("%x", uint64(2e18))),
+		testLog(testAavePool, "Supply(address,address,address,uint256,uint16)", []string{testUSDC, testTrader, "0"}, testTrader, fmt.Sprintf("%x", 100_000000)),
+		testLog(testUSDC, "Transfer(address,address,uint256)", []string{testTrader, testAaveToken}, fmt.Sprintf("%x", 100_000000)),
+		testLog(testAaveToken, "Transfer(address,address,uint256)", []string{zeroAddress, testTrader}, fmt.Sprintf("%x", 100_000000)),
+	}, map[string]interface{}{
+		"type": "CALL", "from": testTrader, "to": testWETH, "value": fmt.Sprintf("0x%x", uint64(2e18)),
+	})
+	baggage["protocols"] = []ProbabilisticProtocol{{Name: "Aave", Version: "v3", Type: "Lending", Contracts: []string{testAavePool}}}
+
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), baggage))
+	actions, _ := GetActions(baggage)
+	require.Len(t, actions, 3, "the aToken mint belongs to the supply, not a separate action")
+
+	require.Equal(t, models.ActionTypeApprove, actions[0].Type)
+	require.Equal(t, testAavePool, actions[0].Counterparty)
+	require.Equal(t, "unlimited", actions[0].TokensOut[0].Amount)
+
+	require.Equal(t, models.ActionTypeWrap, actions[1].Type)
+	require.Equal(t, nativePaymentToken, actions[1].TokensOut[0].Token)
+	require.Equal(t, "2", actions[1].TokensIn[0].Amount)
+
+	require.Equal(t, models.ActionTypeSupply, actions[2].Type)
+	require.Equal(t, "Aave v3", actions[2].Protocol)
+	require.Equal(t, testTrader, actions[2].Actor)
+	require.Equal(t, "100", actions[2].TokensOut[0].Amount)
+
+	// A Deposit(address,uint256) without the matching native transfer is not a wrap
+	delete(baggage["raw_data"].(map[string]interface{}), "trace")
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), baggage))
+	actions, _ = GetActions(baggage)
+	require.Len(t, actions, 2)
+}
+
+func TestActionBuilderDecodesStakingAndBridges(t *testing.T) {
+	lido := "0xae7ab96520de3a18e5e111b5eaa5ae6d8c2b2e2e"
+	rewards := "0x6666666666666666666666666666666666666666"
+	bridge := "0x99c9fc46f92e8a1c0dec1b1747d010903e884be1"
+	stake := fmt.Sprintf("%x", uint64(1e18))
+	baggage := testActionBaggage([]models.Event{
+		testLog(lido, "Submitted(address,uint256,address)", []string{testTrader}, stake, "0"),
+		testLog(lido, "Transfer(address,address,uint256)", []string{zeroAddress, testTrader}, stake),
+		testLog(rewards, "RewardPaid(address,uint256)", []string{testTrader}, fmt.Sprintf("%x", 5_000000)),
+		testLog(testUSDC, "Transfer(address,address,uint256)", []string{rewards, testTrader}, fmt.Sprintf("%x", 5_000000)),
+		// No trace: the bridged amount comes from the event
+		testLog(bridge, "ERC20DepositInitiated(address,address,address,address,uint256,bytes)",
+			[]string{testUSDC, "0x0b2c639c533813f4aa9d7837caf62653d097ff85", testTrader}, testTrader, fmt.Sprintf("%x", 20_000000), "60", "0"),
+	}, map[string]interface{}{
+		"type": "CALL", "from": testTrader, "to": lido, "value": "0x" + stake,
+	})
+	baggage["token_metadata"].(map[string]*TokenMetadata)[lido] = &TokenMetadata{Symbol: "stETH", Decimals: 18}
+
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), baggage))
+	actions, _ := GetActions(baggage)
+	require.Len(t, actions, 3, "the stETH mint and the reward transfer belong to their events")
+
+	require.Equal(t, models.ActionTypeStake, actions[0].Type)
+	require.Equal(t, lido, actions[0].Contract)
+	require.Equal(t, nativePaymentToken, actions[0].TokensOut[0].Token)
+	require.Equal(t, "stETH", actions[0].TokensIn[0].Symbol)
+
+	require.Equal(t, models.ActionTypeClaim, actions[1].Type)
+	require.Equal(t, []models.ActionAmount{{Token: testUSDC, Symbol: "USDC", Amount: "5", RawAmount: "5000000", AmountUSD: "5.00"}}, actions[1].TokensIn)
+
+	require.Equal(t, models.ActionTypeBridge, actions[2].Type)
+	require.Equal(t, testTrader, actions[2].Actor)
+	require.Equal(t, "20", actions[2].TokensOut[0].Amount)
+	require.Equal(t, testUSDC, actions[2].TokensOut[0].Token)
+}
+
+func TestActionBuilderDecodesNFTPurchase(t *testing.T) {
+	baggage := testActionBaggage([]models.Event{
+		testLog(testCollection, "Transfer(address,address,uint256)", []string{testSeller, testTrader, "2a"}),
+	}, map[string]interface{}{
+		"type": "CALL", "from": testTrader, "to": testRouter, "value": "0xde0b6b3a7640000",
+	})
+	baggage["nft_sales"] = []NFTSale{{
+		Marketplace: "Blur", Collection: testCollection, CollectionName: "BAYC", TokenIDs: []string{"42"},
+		Buyer: testTrader, Seller: testSeller, PaymentToken: nativePaymentToken, Price: "1000000000000000000",
+	}}
+
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), baggage))
+	actions, _ := GetActions(baggage)
+	require.Len(t, actions, 1, "the NFT and payment flows are explained by the sale")
+	require.Equal(t, models.ActionTypeNFTBuy, actions[0].Type)
+	require.Equal(t, testSeller, actions[0].Counterparty)
+	require.Equal(t, []models.ActionAmount{{Token: testCollection, Symbol: "BAYC", TokenID: "42"}}, actions[0].TokensIn)
+	require.Equal(t, "1", actions[0].TokensOut[0].Amount)
+}
+
+func TestActionBuilderSkipsFailedTransactions(t *testing.T) {
+	baggage := testActionBaggage([]models.Event{
+		testLog(testUSDC, "Transfer(address,address,uint256)", []string{testTrader, testRouter}, "1"),
+	}, nil)
+	baggage["raw_data"].(map[string]interface{})["receipt"].(map[string]interface{})["status"] = "0x0"
+
+	// No LLM configured: calling it would panic
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), baggage))
+	require.NotContains(t, baggage, "actions")
+}
+
+func TestParseInferredActionsValidatesSchema(t *testing.T) {
+	actions, err := parseInferredActions(`{"actions":[
+		{"type":"bridge","protocol":"Across","actor":"0x4444444444444444444444444444444444444444","counterparty":"","contract":"0xABC",
+		 "tokens_in":[],"tokens_out":[{"token":"0xA0B86991C6218B36C1D19D4A2E9EB0CE3606EB48","symbol":"USDC","amount":"10","token_id":""}]},
+		{"type":"teleport","protocol":"","actor":"","counterparty":"","contract":"","tokens_in":[],"tokens_out":[]}
+	]}`)
+	require.NoError(t, err)
+	require.Len(t, actions, 1, "unknown action types are dropped")
+	require.Equal(t, models.ActionTypeBridge, actions[0].Type)
+	require.Equal(t, models.ActionSourceLLM, actions[0].Source)
+	require.Empty(t, actions[0].Contract, "invalid addresses are cleared")
+	require.Equal(t, testUSDC, actions[0].TokensOut[0].Token)
+
+	_, err = parseInferredActions("not json")
+	require.Error(t, err)
+}
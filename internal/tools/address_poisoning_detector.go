@@ -0,0 +1,452 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// Address poisoning kinds
+const (
+	PoisoningKindZeroValue = "zero_value" // A zero-value transfer the victim never sent, usually a third-party transferFrom
+	PoisoningKindDust      = "dust"       // A tiny transfer to the victim from a lookalike of an address they deal with
+	PoisoningKindFakeToken = "fake_token" // A token reusing the symbol of a listed token from another contract
+)
+
+const (
+	// lookalikeMinDigits is how many leading and trailing hex digits a lookalike shares at least with the address
+	// it imitates, and lookalikeMinTotal how many in all: wallets shorten addresses to 0x1234…abcd
+	lookalikeMinDigits = 3
+	lookalikeMinTotal  = 7
+
+	// poisoningHistoryBlocks is how far back the victim's transfers are searched for their real counterparties
+	poisoningHistoryBlocks = 10000
+
+	// poisoningMaxVictims caps the history lookups of batch poisoning transactions that target many wallets
+	poisoningMaxVictims = 10
+
+	// poisoningDustUSD is the value below which a priced transfer is dust. Unpriced transfers are dust below
+	// 0.001 tokens.
+	poisoningDustUSD = 0.1
+)
+
+// PoisonedTransfer is a Transfer event that looks like address poisoning or a fake token
+type PoisonedTransfer struct {
+	Kind          string              `json:"kind"` // PoisoningKind*
+	From          string              `json:"from"`
+	To            string              `json:"to"`
+	Amount        models.ActionAmount `json:"amount"`
+	Victim        string              `json:"victim"`                   // The wallet the scam targets
+	Lookalike     string              `json:"lookalike,omitempty"`      // The address posing as one the victim deals with
+	Imitates      string              `json:"imitates,omitempty"`       // The real counterparty the lookalike imitates
+	ImitatedToken string              `json:"imitated_token,omitempty"` // The listed token a fake token copies
+}
+
+// AddressPoisoningDetector flags scam transfers that plant addresses in a victim's history: zero-value
+// transfers out of the victim's wallet and dust transfers into it from an address whose first and last hex
+// digits match someone the victim really transacted with, and transfers of fake tokens that reuse a listed
+// token's symbol. It reads every Transfer event - including the zero-value and intermediate ones
+// TokenTransferExtractor leaves out - flags them in the risks, marks the lookalikes and fake tokens among the
+// participants and tells the explainer to call them scams.
+type AddressPoisoningDetector struct {
+	rpcClient      *rpc.Client
+	staticProvider *StaticContextProvider
+	verbose        bool
+}
+
+// NewAddressPoisoningDetector creates a new address poisoning detector. Without an RPC client the victim's
+// past counterparties are unknown, so only fake tokens are flagged; without a static context provider there
+// are no listed tokens to compare symbols with.
+func NewAddressPoisoningDetector(rpcClient *rpc.Client, staticProvider *StaticContextProvider, verbose bool) *AddressPoisoningDetector {
+	return &AddressPoisoningDetector{
+		rpcClient:      rpcClient,
+		staticProvider: staticProvider,
+		verbose:        verbose,
+	}
+}
+
+// Name returns the processor name
+func (d *AddressPoisoningDetector) Name() string {
+	return "address_poisoning_detector"
+}
+
+// Description returns the processor description
+func (d *AddressPoisoningDetector) Description() string {
+	return "Detects address poisoning with lookalike addresses and fake tokens copying listed symbols"
+}
+
+// Dependencies returns the tools this processor depends on
+func (d *AddressPoisoningDetector) Dependencies() []string {
+	return []string{"log_decoder", "token_metadata_enricher", "erc20_price_lookup", "address_role_resolver"}
+}
+
+// Process stores the scam transfers in baggage["poisoned_transfers"] and marks their lookalike addresses and
+// fake tokens in baggage["address_participants"]
+func (d *AddressPoisoningDetector) Process(ctx context.Context, baggage map[string]interface{}) error {
+	if transactionFailed(baggage) {
+		return nil // Nothing was transferred
+	}
+	events, _ := baggage["events"].([]models.Event)
+	amounts := &actionContext{}
+	amounts.metadata, _ = baggage["token_metadata"].(map[string]*TokenMetadata)
+	amounts.prices, _ = baggage["token_prices"].(map[string]*TokenPrice)
+
+	history := d.newPoisoningHistory(baggage)
+	listed := d.listedSymbols(history.chainID)
+	sender := transactionSender(baggage)
+
+	var poisoned []PoisonedTransfer
+	for _, event := range events {
+		if len(event.Topics) != 3 || strings.ToLower(event.Topics[0]) != erc20TransferTopic {
+			continue
+		}
+		value := newABIData(event.Data).uintAt(0)
+		if value == nil {
+			continue
+		}
+		token := strings.ToLower(event.Contract)
+		transfer := PoisonedTransfer{
+			From:   topicAddress(event.Topics[1]),
+			To:     topicAddress(event.Topics[2]),
+			Amount: amounts.amount(token, "", value),
+		}
+		if metadata, ok := amounts.metadata[token]; ok && !metadata.Listed && listed[strings.ToUpper(metadata.Symbol)] != token {
+			transfer.ImitatedToken = listed[strings.ToUpper(metadata.Symbol)]
+		}
+		dust := isDust(transfer.Amount, value, amounts.metadata[token])
+		if value.Sign() != 0 && !dust && transfer.ImitatedToken == "" {
+			continue // Only worthless transfers are worth checking for lookalikes
+		}
+
+		// The scam plants the lookalike next to the victim: try the recipient as the victim first. Dust can
+		// only be sent to the victim.
+		orientations := [][2]string{{transfer.To, transfer.From}, {transfer.From, transfer.To}}
+		if dust && transfer.ImitatedToken == "" {
+			orientations = orientations[:1]
+		}
+		for _, parties := range orientations {
+			victim, lookalike := parties[0], parties[1]
+			if victim == sender || isEmptyAddress(victim) || isEmptyAddress(lookalike) {
+				continue
+			}
+			if imitates := history.imitated(ctx, victim, lookalike); imitates != "" {
+				transfer.Victim, transfer.Lookalike, transfer.Imitates = victim, lookalike, imitates
+				break
+			}
+		}
+
+		switch {
+		case transfer.ImitatedToken != "":
+			transfer.Kind = PoisoningKindFakeToken
+			if transfer.Victim == "" {
+				transfer.Victim = transfer.To
+			}
+		case value.Sign() == 0 && transfer.Lookalike != "":
+			transfer.Kind = PoisoningKindZeroValue
+		case value.Sign() == 0 && transfer.From != sender && !isEmptyAddress(transfer.From) && history.isEOA(ctx, transfer.From):
+			// Only the owner can move an account's tokens without an allowance, and no one needs one for zero
+			transfer.Kind = PoisoningKindZeroValue
+			transfer.Victim, transfer.Lookalike = transfer.From, transfer.To
+		case dust && transfer.Lookalike != "" && transfer.Victim == transfer.To:
+			transfer.Kind = PoisoningKindDust
+		default:
+			continue
+		}
+		if d.verbose {
+			fmt.Printf("☠️  %s transfer of %s from %s to %s (imitates %s)\n", transfer.Kind, token, transfer.From, transfer.To, transfer.Imitates)
+		}
+		poisoned = append(poisoned, transfer)
+	}
+	if len(poisoned) == 0 {
+		return nil
+	}
+
+	baggage["poisoned_transfers"] = poisoned
+	markPoisonedParticipants(baggage, poisoned)
+	return nil
+}
+
+// listedSymbols maps the upper-case symbols of the chain's listed tokens to their contract
+func (d *AddressPoisoningDetector) listedSymbols(chainID int64) map[string]string {
+	symbols := make(map[string]string)
+	if d.staticProvider == nil || chainID == 0 {
+		return symbols
+	}
+	for _, entry := range d.staticProvider.ListTokens(chainID) {
+		symbol := strings.ToUpper(entry.Symbol)
+		if _, exists := symbols[symbol]; !exists && symbol != "" {
+			symbols[symbol] = strings.ToLower(entry.Address)
+		}
+	}
+	return symbols
+}
+
+// isDust reports whether a transfer is worth less than poisoningDustUSD, or without a price less than 0.001
+// tokens
+func isDust(amount models.ActionAmount, value *big.Int, metadata *TokenMetadata) bool {
+	if value.Sign() == 0 {
+		return false
+	}
+	if amount.AmountUSD != "" {
+		usd, err := strconv.ParseFloat(amount.AmountUSD, 64)
+		return err == nil && usd < poisoningDustUSD
+	}
+	if metadata == nil || metadata.Decimals < 3 {
+		return false
+	}
+	threshold := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(metadata.Decimals-3)), nil)
+	return value.Cmp(threshold) < 0
+}
+
+// isLookalike reports whether two different addresses share enough leading and trailing hex digits to pass
+// for each other when shortened
+func isLookalike(a, b string) bool {
+	a, b = strings.ToLower(strings.TrimPrefix(a, "0x")), strings.ToLower(strings.TrimPrefix(b, "0x"))
+	if a == b || len(a) != 40 || len(b) != 40 {
+		return false
+	}
+	prefix, suffix := 0, 0
+	for prefix < 40 && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < 40 && a[39-suffix] == b[39-suffix] {
+		suffix++
+	}
+	return prefix >= lookalikeMinDigits && suffix >= lookalikeMinDigits && prefix+suffix >= lookalikeMinTotal
+}
+
+// poisoningHistory looks up and caches the addresses victims transferred tokens with before the transaction
+type poisoningHistory struct {
+	detector       *AddressPoisoningDetector
+	chainID        int64
+	block          uint64
+	txHash         string
+	counterparties map[string][]string // victim -> addresses it sent tokens to, then addresses it received from
+}
+
+func (d *AddressPoisoningDetector) newPoisoningHistory(baggage map[string]interface{}) *poisoningHistory {
+	history := &poisoningHistory{detector: d, counterparties: make(map[string][]string)}
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	if networkID, ok := rawData["network_id"].(float64); ok {
+		history.chainID = int64(networkID)
+	}
+	history.txHash, _ = rawData["tx_hash"].(string)
+	block, _ := rawData["block"].(map[string]interface{})
+	if number, err := parseHexUint(block["number"]); err == nil {
+		history.block = number
+	} else if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+		history.block, _ = parseHexUint(receipt["blockNumber"])
+	}
+	return history
+}
+
+// imitated returns the victim's counterparty the lookalike imitates, if any
+func (h *poisoningHistory) imitated(ctx context.Context, victim, lookalike string) string {
+	for _, counterparty := range h.lookup(ctx, victim) {
+		if isLookalike(lookalike, counterparty) {
+			return counterparty
+		}
+	}
+	return ""
+}
+
+// lookup returns the addresses the victim exchanged a non-zero amount of tokens with in the
+// poisoningHistoryBlocks blocks before the transaction
+func (h *poisoningHistory) lookup(ctx context.Context, victim string) []string {
+	if counterparties, ok := h.counterparties[victim]; ok {
+		return counterparties
+	}
+	client := h.detector.rpcClient
+	if client == nil || h.block <= 1 || len(h.counterparties) >= poisoningMaxVictims {
+		return nil
+	}
+
+	var counterparties []string
+	from := uint64(1)
+	if h.block > poisoningHistoryBlocks {
+		from = h.block - poisoningHistoryBlocks
+	}
+	topic := "0x" + abiWordAddress(victim)
+	// Sent first: poisoners imitate the addresses victims pay, hoping they copy the lookalike next time
+	for _, topics := range [][]interface{}{{erc20TransferTopic, topic}, {erc20TransferTopic, nil, topic}} {
+		logs, err := client.GetLogs(ctx, map[string]interface{}{
+			"fromBlock": fmt.Sprintf("0x%x", from),
+			"toBlock":   fmt.Sprintf("0x%x", h.block-1),
+			"topics":    topics,
+		})
+		if err != nil {
+			if h.detector.verbose {
+				fmt.Printf("⚠️  Could not read the transfer history of %s: %v\n", victim, err)
+			}
+			continue
+		}
+		for _, log := range logs {
+			counterparty := transferCounterparty(log, victim, h.txHash)
+			if counterparty != "" && !containsString(counterparties, counterparty) {
+				counterparties = append(counterparties, counterparty)
+			}
+		}
+	}
+	h.counterparties[victim] = counterparties
+	return counterparties
+}
+
+// isEOA reports whether an address had no code at the transaction's block. EIP-7702 delegated accounts count
+// as EOAs.
+func (h *poisoningHistory) isEOA(ctx context.Context, address string) bool {
+	if h.detector.rpcClient == nil {
+		return false
+	}
+	block := "latest"
+	if h.block > 0 {
+		block = fmt.Sprintf("0x%x", h.block)
+	}
+	code, err := h.detector.rpcClient.GetCodeAt(ctx, address, block)
+	if err != nil {
+		return false
+	}
+	return code == "" || code == "0x" || strings.HasPrefix(code, "0xef0100")
+}
+
+// transferCounterparty returns the other party of a non-zero ERC20 Transfer log involving the victim, skipping
+// logs of the transaction being explained
+func transferCounterparty(log map[string]interface{}, victim, txHash string) string {
+	topics, _ := log["topics"].([]interface{})
+	if len(topics) != 3 {
+		return ""
+	}
+	if hash, _ := log["transactionHash"].(string); txHash != "" && strings.EqualFold(hash, txHash) {
+		return ""
+	}
+	data, _ := log["data"].(string)
+	if value := newABIData(data).uintAt(0); value == nil || value.Sign() == 0 {
+		return ""
+	}
+	from, _ := topics[1].(string)
+	to, _ := topics[2].(string)
+	counterparty := topicAddress(to)
+	if topicAddress(to) == victim {
+		counterparty = topicAddress(from)
+	}
+	if isEmptyAddress(counterparty) || counterparty == victim {
+		return ""
+	}
+	return counterparty
+}
+
+// abiWordAddress left-pads an address to a 32-byte topic, without 0x
+func abiWordAddress(address string) string {
+	return strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(address, "0x"))
+}
+
+// markPoisonedParticipants marks the lookalike addresses and fake tokens in baggage["address_participants"]
+// with metadata "scam" (address_poisoning or fake_token) and "imitates", adding those the role resolver missed
+func markPoisonedParticipants(baggage map[string]interface{}, poisoned []PoisonedTransfer) {
+	participants, _ := baggage["address_participants"].([]models.AddressParticipant)
+	mark := func(address, scam, imitates, role, description string) {
+		for i := range participants {
+			if strings.EqualFold(participants[i].Address, address) {
+				if participants[i].Metadata == nil {
+					participants[i].Metadata = make(map[string]interface{})
+				}
+				participants[i].Metadata["scam"] = scam
+				if imitates != "" {
+					participants[i].Metadata["imitates"] = imitates
+				}
+				participants[i].Description = description
+				return
+			}
+		}
+		participant := models.AddressParticipant{
+			Address:     address,
+			Role:        role,
+			Category:    "scam",
+			Description: description,
+			Metadata:    map[string]interface{}{"scam": scam},
+		}
+		if imitates != "" {
+			participant.Metadata["imitates"] = imitates
+		}
+		participants = append(participants, participant)
+	}
+
+	for _, transfer := range poisoned {
+		if transfer.Lookalike != "" {
+			description := "Address poisoning: fakes a transfer with " + transfer.Victim
+			if transfer.Imitates != "" {
+				description = fmt.Sprintf("Address poisoning: lookalike of %s, an address %s dealt with", transfer.Imitates, transfer.Victim)
+			}
+			mark(transfer.Lookalike, "address_poisoning", transfer.Imitates, "Address Poisoner", description)
+		}
+		if transfer.Kind == PoisoningKindFakeToken {
+			mark(transfer.Amount.Token, "fake_token", transfer.ImitatedToken, fmt.Sprintf("Fake Token (%s)", transfer.Amount.Symbol),
+				fmt.Sprintf("Fake token: copies the symbol of the listed %s at %s", transfer.Amount.Symbol, transfer.ImitatedToken))
+		}
+	}
+	baggage["address_participants"] = participants
+}
+
+// poisoningRisk describes a scam transfer as a warning
+func poisoningRisk(transfer PoisonedTransfer) string {
+	symbol := transfer.Amount.Symbol
+	if symbol == "" {
+		symbol = transfer.Amount.Token
+	}
+	var risk string
+	switch transfer.Kind {
+	case PoisoningKindFakeToken:
+		risk = fmt.Sprintf("Fake token: %s at %s copies the symbol of the listed token at %s, its transfer from %s to %s is worthless",
+			symbol, transfer.Amount.Token, transfer.ImitatedToken, transfer.From, transfer.To)
+	case PoisoningKindZeroValue:
+		risk = fmt.Sprintf("Address poisoning: zero-value %s transfer from %s to %s that %s did not send", symbol, transfer.From, transfer.To, transfer.Victim)
+		if transfer.Victim != transfer.From {
+			risk = fmt.Sprintf("Address poisoning: zero-value %s transfer from %s to %s", symbol, transfer.From, transfer.To)
+		}
+	case PoisoningKindDust:
+		risk = fmt.Sprintf("Address poisoning: dust transfer of %s %s from %s to %s", transfer.Amount.Amount, symbol, transfer.From, transfer.To)
+	}
+	if transfer.Imitates != "" {
+		risk += fmt.Sprintf("; %s is a lookalike of %s, do not copy it from the transaction history", transfer.Lookalike, transfer.Imitates)
+	}
+	return risk
+}
+
+// GetPoisonedTransfers returns the scam transfers from baggage
+func GetPoisonedTransfers(baggage map[string]interface{}) ([]PoisonedTransfer, bool) {
+	poisoned, ok := baggage["poisoned_transfers"].([]PoisonedTransfer)
+	return poisoned, ok && len(poisoned) > 0
+}
+
+// PoisoningRisks returns the warnings for the scam transfers, without duplicates
+func PoisoningRisks(poisoned []PoisonedTransfer) []string {
+	var risks []string
+	for _, transfer := range poisoned {
+		if risk := poisoningRisk(transfer); !containsString(risks, risk) {
+			risks = append(risks, risk)
+		}
+	}
+	return risks
+}
+
+// GetPromptContext lists the scam transfers for the explainer
+func (d *AddressPoisoningDetector) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	poisoned, ok := GetPoisonedTransfers(baggage)
+	if !ok {
+		return ""
+	}
+	lines := []string{"### ADDRESS POISONING AND FAKE TOKENS (these transfers are scams - call them out and warn the victim not to reuse the lookalike address):"}
+	for _, risk := range PoisoningRisks(poisoned) {
+		lines = append(lines, "- "+risk)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for address poisoning (none - it is transaction specific)
+func (d *AddressPoisoningDetector) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+func TestAddressPoisoningDetectorFlagsLookalikes(t *testing.T) {
+	const (
+		networkID = 99003
+		block     = 0x100000
+		victim    = "0x7777777777777777777777777777777777777777"
+		newWallet = "0x8888888888888888888888888888888888888888"
+		real      = "0xabcd000000000000000000000000000000001234"
+		lookalike = "0xabcd999999999999999999999999999999991234"
+	)
+
+	// A node on which the victim paid real 1000 USDC shortly before, and newWallet has no history
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int           `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		var result interface{} = []interface{}{}
+		switch request.Method {
+		case "eth_getLogs":
+			filter := request.Params[0].(map[string]interface{})
+			require.Equal(t, fmt.Sprintf("0x%x", block-poisoningHistoryBlocks), filter["fromBlock"])
+			require.Equal(t, fmt.Sprintf("0x%x", block-1), filter["toBlock"])
+			topics := filter["topics"].([]interface{})
+			if len(topics) == 2 && topics[1] == "0x"+abiWord(victim) {
+				result = []interface{}{map[string]interface{}{
+					"address": testUSDC, "transactionHash": "0x01", "data": "0x" + abiWord(fmt.Sprintf("%x", 1000_000000)),
+					"topics": []string{erc20TransferTopic, "0x" + abiWord(victim), "0x" + abiWord(real)},
+				}}
+			}
+		case "eth_getCode":
+			result = "0x"
+		default:
+			t.Fatalf("unexpected method %s", request.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	defer server.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: server.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+
+	transfer := "Transfer(address,address,uint256)"
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(networkID),
+			"tx_hash":    "0x02",
+			"receipt":    map[string]interface{}{"from": testRouter, "to": testUSDC, "status": "0x1"},
+			"block":      map[string]interface{}{"number": fmt.Sprintf("0x%x", block)},
+		},
+		"events": []models.Event{
+			// A zero-value transferFrom out of the victim's wallet to the lookalike
+			testLog(testUSDC, transfer, []string{victim, lookalike}, "0"),
+			// A tenth of a cent from the lookalike
+			testLog(testUSDC, transfer, []string{lookalike, victim}, fmt.Sprintf("%x", 1000)),
+			// A zero-value transfer out of a wallet with no history still moves an EOA's tokens without its consent
+			testLog(testUSDC, transfer, []string{newWallet, testTrader}, "0"),
+			// The attacker's own transfer is not checked
+			testLog(testUSDC, transfer, []string{testRouter, testTrader}, fmt.Sprintf("%x", 5_000000)),
+		},
+		"token_metadata":       map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6, Listed: true}},
+		"token_prices":         map[string]*TokenPrice{testUSDC: {Symbol: "USDC", Price: 1}},
+		"address_participants": []models.AddressParticipant{{Address: lookalike, Role: "Token Recipient", Category: "user"}},
+	}
+	detector := NewAddressPoisoningDetector(client, nil, false)
+	require.NoError(t, detector.Process(context.Background(), baggage))
+
+	poisoned, ok := GetPoisonedTransfers(baggage)
+	require.True(t, ok)
+	require.Len(t, poisoned, 3)
+	require.Equal(t, PoisoningKindZeroValue, poisoned[0].Kind)
+	require.Equal(t, victim, poisoned[0].Victim)
+	require.Equal(t, lookalike, poisoned[0].Lookalike)
+	require.Equal(t, real, poisoned[0].Imitates)
+	require.Equal(t, PoisoningKindDust, poisoned[1].Kind)
+	require.Equal(t, "0.001", poisoned[1].Amount.Amount)
+	require.Equal(t, real, poisoned[1].Imitates)
+	require.Equal(t, PoisoningKindZeroValue, poisoned[2].Kind)
+	require.Equal(t, newWallet, poisoned[2].Victim)
+	require.Empty(t, poisoned[2].Imitates)
+
+	require.Equal(t, []string{
+		"Address poisoning: zero-value USDC transfer from " + victim + " to " + lookalike + " that " + victim + " did not send; " +
+			lookalike + " is a lookalike of " + real + ", do not copy it from the transaction history",
+		"Address poisoning: dust transfer of 0.001 USDC from " + lookalike + " to " + victim + "; " +
+			lookalike + " is a lookalike of " + real + ", do not copy it from the transaction history",
+		"Address poisoning: zero-value USDC transfer from " + newWallet + " to " + testTrader + " that " + newWallet + " did not send",
+	}, PoisoningRisks(poisoned))
+
+	participants := baggage["address_participants"].([]models.AddressParticipant)
+	require.Len(t, participants, 2)
+	require.Equal(t, "Token Recipient", participants[0].Role, "the resolved role is kept")
+	require.Equal(t, map[string]interface{}{"scam": "address_poisoning", "imitates": real}, participants[0].Metadata)
+	require.Equal(t, testTrader, participants[1].Address)
+	require.Equal(t, "scam", participants[1].Category)
+
+	require.Contains(t, detector.GetPromptContext(context.Background(), baggage), "### ADDRESS POISONING AND FAKE TOKENS")
+}
+
+func TestAddressPoisoningDetectorFlagsFakeTokens(t *testing.T) {
+	const fakeUSDC = "0xfa4e000000000000000000000000000000000001"
+	victim := "0x7777777777777777777777777777777777777777"
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "tokens", "1.csv"), "chain_id,address,symbol,name,decimals\n"+
+		"1,"+testUSDC+",USDC,USD Coin,6\n")
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(1),
+			"receipt":    map[string]interface{}{"from": testRouter, "to": fakeUSDC, "status": "0x1"},
+		},
+		"events": []models.Event{
+			testLog(fakeUSDC, "Transfer(address,address,uint256)", []string{testRouter, victim}, fmt.Sprintf("%x", 25_000_000000)),
+			testLog(testUSDC, "Transfer(address,address,uint256)", []string{testRouter, victim}, fmt.Sprintf("%x", 25_000_000000)),
+		},
+		"token_metadata": map[string]*TokenMetadata{
+			fakeUSDC: {Symbol: "USDC", Decimals: 6},
+			testUSDC: {Symbol: "USDC", Decimals: 6, Listed: true},
+		},
+	}
+	require.NoError(t, NewAddressPoisoningDetector(nil, NewStaticContextProvider(dir, false), false).Process(context.Background(), baggage))
+
+	poisoned, ok := GetPoisonedTransfers(baggage)
+	require.True(t, ok)
+	require.Len(t, poisoned, 1, "the listed USDC is real")
+	require.Equal(t, PoisoningKindFakeToken, poisoned[0].Kind)
+	require.Equal(t, victim, poisoned[0].Victim)
+	require.Equal(t, testUSDC, poisoned[0].ImitatedToken)
+	require.Equal(t, []string{"Fake token: USDC at " + fakeUSDC + " copies the symbol of the listed token at " + testUSDC +
+		", its transfer from " + testRouter + " to " + victim + " is worthless"}, PoisoningRisks(poisoned))
+
+	participants := baggage["address_participants"].([]models.AddressParticipant)
+	require.Len(t, participants, 1)
+	require.Equal(t, fakeUSDC, participants[0].Address)
+	require.Equal(t, "Fake Token (USDC)", participants[0].Role)
+	require.Equal(t, map[string]interface{}{"scam": "fake_token", "imitates": testUSDC}, participants[0].Metadata)
+
+	// Wallets shorten addresses to their first and last digits
+	require.True(t, isLookalike("0xabc0000000000000000000000000000000001234", "0xabc9999999999999999999999999999999991234"))
+	require.False(t, isLookalike("0xab00000000000000000000000000000000001234", "0xab99999999999999999999999999999999991234"))
+	require.False(t, isLookalike(testUSDC, testUSDC))
+}
@@ -41,7 +41,7 @@ func (a *AddressRoleResolver) Description() string {
 // Dependencies returns the tools this processor depends on
 func (a *AddressRoleResolver) Dependencies() []string {
 	return []string{
-		"abi_resolver", "log_decoder", "trace_decoder", "ens_resolver", "token_metadata_enricher",
+		"abi_resolver", "log_decoder", "trace_decoder", "ens_resolver", "token_metadata_enricher", "pool_resolver",
 	}
 }
 
@@ -395,6 +395,14 @@ ADDRESSES TO ANALYZE:`
 		}
 	}
 
+	// Add verified pools so they are not given invented names
+	if pools, ok := baggage["pools"].(map[string]*PoolInfo); ok && len(pools) > 0 {
+		prompt += "\n\nLIQUIDITY POOLS (verified with their factory, use these names):"
+		for addr, pool := range pools {
+			prompt += fmt.Sprintf("\n- %s: %s", addr, pool.Label)
+		}
+	}
+
 	// Add protocol context
 	if protocols, ok := baggage["protocols"].([]ProbabilisticProtocol); ok && len(protocols) > 0 {
 		prompt += "\n\nDETECTED PROTOCOLS:"
@@ -667,6 +675,7 @@ func (a *AddressRoleResolver) buildParticipants(addresses []string, addressTypes
 	if names, ok := baggage["ens_names"].(map[string]string); ok {
 		ensNames = names
 	}
+	ensRecords, _ := baggage["ens_records"].(map[string]*ENSRecord)
 
 	// Get token metadata for names and icons
 	tokenMetadata := make(map[string]*TokenMetadata)
@@ -674,6 +683,8 @@ func (a *AddressRoleResolver) buildParticipants(addresses []string, addressTypes
 		tokenMetadata = metadata
 	}
 
+	pools, _ := baggage["pools"].(map[string]*PoolInfo)
+
 	for _, address := range addresses {
 		lowerAddr := strings.ToLower(address)
 
@@ -697,12 +708,54 @@ func (a *AddressRoleResolver) buildParticipants(addresses []string, addressTypes
 			participant.ENSName = ensName
 		}
 
+		// Add name service text records for tooltips
+		if record, exists := ensRecords[lowerAddr]; exists && record != nil {
+			participant.Metadata = map[string]interface{}{"name_service": record.Service}
+			if record.CurrentName != "" {
+				participant.Metadata["ens_current_name"] = record.CurrentName // Name changed since the transaction
+			}
+			if record.Avatar != "" {
+				participant.Metadata["ens_avatar"] = record.Avatar
+				if participant.Icon == "" {
+					participant.Icon = record.Avatar
+				}
+			}
+			if record.URL != "" {
+				participant.Metadata["ens_url"] = record.URL
+			}
+			if record.Twitter != "" {
+				participant.Metadata["ens_twitter"] = record.Twitter
+			}
+		}
+
 		// Add token metadata if this is a token contract
 		if metadata, exists := tokenMetadata[lowerAddr]; exists {
 			participant.Name = fmt.Sprintf("%s (%s)", metadata.Name, metadata.Symbol)
 			participant.Description = fmt.Sprintf("%s token contract", metadata.Type)
 		}
 
+		// Pools are named after their verified pair rather than their LP token
+		if pool, exists := pools[lowerAddr]; exists {
+			participant.Name = pool.Label
+			participant.Description = fmt.Sprintf("%s liquidity pool created by the %s", pool.Protocol, pool.FactoryName)
+			if participant.Metadata == nil {
+				participant.Metadata = make(map[string]interface{})
+			}
+			participant.Metadata["pool_protocol"] = pool.Protocol
+			if pool.Version != "" {
+				participant.Metadata["pool_version"] = pool.Version
+			}
+			participant.Metadata["pool_factory"] = pool.Factory
+			participant.Metadata["pool_tokens"] = pool.Tokens
+			participant.Metadata["pool_symbols"] = pool.Symbols
+			participant.Metadata["token0"] = pool.Tokens[0]
+			participant.Metadata["token1"] = pool.Tokens[1]
+			if pool.FeeTier != 0 {
+				participant.Metadata["fee_tier"] = pool.FeeTier
+				participant.Metadata["fee"] = pool.Fee()
+			}
+		}
+
 		// Generate explorer link
 		if network.Explorer != "" {
 			participant.Link = fmt.Sprintf("%s/address/%s", network.Explorer, address)
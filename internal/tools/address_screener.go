@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+)
+
+// AddressScreener checks every address a transaction touches - its participants, sender and recipient,
+// emitting contracts, indexed event addresses and traced call frames - against the locally loaded sanctions
+// (OFAC SDN), scam and denylist screening lists. Lookups never leave the process, so no address is sent to a
+// third party. Matches are attributed to their list and source in the participants' metadata and the risks.
+type AddressScreener struct {
+	staticProvider *StaticContextProvider
+	verbose        bool
+}
+
+// NewAddressScreener creates a new address screener over the screening lists of a static context provider
+func NewAddressScreener(staticProvider *StaticContextProvider, verbose bool) *AddressScreener {
+	return &AddressScreener{
+		staticProvider: staticProvider,
+		verbose:        verbose,
+	}
+}
+
+// Name returns the processor name
+func (s *AddressScreener) Name() string {
+	return "address_screener"
+}
+
+// Description returns the processor description
+func (s *AddressScreener) Description() string {
+	return "Screens transaction addresses against local sanctions, scam and denylist lists"
+}
+
+// Dependencies returns the tools this processor depends on. It runs after the poisoning detector so the two
+// do not mark participants at the same time.
+func (s *AddressScreener) Dependencies() []string {
+	return []string{"log_decoder", "trace_decoder", "address_role_resolver", "address_poisoning_detector"}
+}
+
+// Process stores the screening list matches in baggage["screening_matches"] and attaches them to the
+// participants in baggage["address_participants"]
+func (s *AddressScreener) Process(ctx context.Context, baggage map[string]interface{}) error {
+	if s.staticProvider == nil {
+		return nil
+	}
+	var chainID int64
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if networkID, ok := rawData["network_id"].(float64); ok {
+			chainID = int64(networkID)
+		}
+	}
+
+	var matches []ScreeningEntry
+	for _, address := range screeningCandidates(baggage) {
+		matches = append(matches, s.staticProvider.ScreenAddress(chainID, address)...)
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	if s.verbose {
+		for _, match := range matches {
+			fmt.Printf("🚫 %s is on the %s list (%s)\n", match.Address, match.List, match.Source)
+		}
+	}
+
+	baggage["screening_matches"] = matches
+	markScreenedParticipants(baggage, matches)
+	return nil
+}
+
+// screeningCandidates collects every address the transaction touches, sorted
+func screeningCandidates(baggage map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	add := func(address string) {
+		address = strings.ToLower(address)
+		if isScreeningAddress(address) && !isEmptyAddress(address) {
+			seen[address] = true
+		}
+	}
+
+	participants, _ := baggage["address_participants"].([]models.AddressParticipant)
+	for _, participant := range participants {
+		add(participant.Address)
+	}
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+		for _, field := range []string{"from", "to", "contractAddress"} {
+			address, _ := receipt[field].(string)
+			add(address)
+		}
+	}
+	events, _ := baggage["events"].([]models.Event)
+	for _, event := range events {
+		add(event.Contract)
+		for i, topic := range event.Topics {
+			// Indexed addresses are left-padded to 32 bytes; topic 0 is the event signature
+			if hex := strings.TrimPrefix(strings.ToLower(topic), "0x"); i > 0 && len(hex) == 64 && strings.Trim(hex[:24], "0") == "" {
+				add("0x" + hex[24:])
+			}
+		}
+	}
+	var walk func(frame map[string]interface{})
+	walk = func(frame map[string]interface{}) {
+		from, _ := frame["from"].(string)
+		to, _ := frame["to"].(string)
+		add(from)
+		add(to)
+		calls, _ := frame["calls"].([]interface{})
+		for _, call := range calls {
+			if child, ok := call.(map[string]interface{}); ok {
+				walk(child)
+			}
+		}
+	}
+	if trace, ok := rawData["trace"].(map[string]interface{}); ok {
+		walk(trace)
+	}
+
+	addresses := make([]string, 0, len(seen))
+	for address := range seen {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// screeningRoles names participants the role resolver did not list after the list they are on
+var screeningRoles = map[string]string{
+	ScreeningListSanctions: "Sanctioned Address",
+	ScreeningListScam:      "Known Scam Address",
+	ScreeningListDenylist:  "Denylisted Address",
+}
+
+// markScreenedParticipants attaches each address's matches to its participant as metadata "screening",
+// adding the addresses the role resolver did not list
+func markScreenedParticipants(baggage map[string]interface{}, matches []ScreeningEntry) {
+	participants, _ := baggage["address_participants"].([]models.AddressParticipant)
+	byAddress := make(map[string][]ScreeningEntry)
+	var order []string
+	for _, match := range matches {
+		if _, ok := byAddress[match.Address]; !ok {
+			order = append(order, match.Address)
+		}
+		byAddress[match.Address] = append(byAddress[match.Address], match)
+	}
+
+	for _, address := range order {
+		found := false
+		for i := range participants {
+			if strings.EqualFold(participants[i].Address, address) {
+				if participants[i].Metadata == nil {
+					participants[i].Metadata = make(map[string]interface{})
+				}
+				participants[i].Metadata["screening"] = byAddress[address]
+				found = true
+			}
+		}
+		if !found {
+			first := byAddress[address][0]
+			participants = append(participants, models.AddressParticipant{
+				Address:     address,
+				Role:        screeningRoles[first.List],
+				Category:    "flagged",
+				Name:        first.Label,
+				Description: screeningRisk(first),
+				Metadata:    map[string]interface{}{"screening": byAddress[address]},
+			})
+		}
+	}
+	baggage["address_participants"] = participants
+}
+
+// screeningRisk describes a screening list match as a warning, e.g. "Sanctioned address: 0x... (Tornado Cash)
+// is on the sanctions list (source: sdn.csv, SDN 12345)"
+func screeningRisk(match ScreeningEntry) string {
+	title := map[string]string{
+		ScreeningListSanctions: "Sanctioned address",
+		ScreeningListScam:      "Scam address",
+		ScreeningListDenylist:  "Denylisted address",
+	}[match.List]
+	address := match.Address
+	if match.Label != "" {
+		address += " (" + match.Label + ")"
+	}
+	source := match.Source
+	if match.Reference != "" {
+		source += ", " + match.Reference
+	}
+	return fmt.Sprintf("%s: %s is on the %s list (source: %s)", title, address, match.List, source)
+}
+
+// GetScreeningMatches returns the screening list matches from baggage
+func GetScreeningMatches(baggage map[string]interface{}) ([]ScreeningEntry, bool) {
+	matches, ok := baggage["screening_matches"].([]ScreeningEntry)
+	return matches, ok && len(matches) > 0
+}
+
+// ScreeningRisks returns the warnings for screening list matches, without duplicates
+func ScreeningRisks(matches []ScreeningEntry) []string {
+	var risks []string
+	for _, match := range matches {
+		if risk := screeningRisk(match); !containsString(risks, risk) {
+			risks = append(risks, risk)
+		}
+	}
+	return risks
+}
+
+// GetPromptContext lists the screening list matches for the explainer
+func (s *AddressScreener) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	matches, ok := GetScreeningMatches(baggage)
+	if !ok {
+		return ""
+	}
+	lines := []string{"### SCREENING MATCHES (addresses on sanctions, scam or denylist lists - state them in the explanation):"}
+	for _, risk := range ScreeningRisks(matches) {
+		lines = append(lines, "- "+risk)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for screening (none - matches are transaction specific)
+func (s *AddressScreener) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
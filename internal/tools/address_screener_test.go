@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+const (
+	testTornado = "0x8589427373d6d84e98730d7795d8f6f8731fda16"
+	testLazarus = "0x098b716b8aaf21512996dc57eb0615e2383e2f96"
+	testPhisher = "0x9999999999999999999999999999999999999999"
+)
+
+func TestImportScreeningListsRefreshesSources(t *testing.T) {
+	dataDir := t.TempDir()
+	dir := filepath.Join(dataDir, ScreeningDir)
+	sources := t.TempDir()
+	sdn := filepath.Join(sources, "sdn.csv")
+	writeTestFile(t, sdn, `36135,"TORNADO CASH","-0-","CYBER2] [DPRK3","-0-","-0-","-0-","-0-","-0-","-0-","-0-","Digital Currency Address - ETH `+
+		testTornado[:2]+strings.ToUpper(testTornado[2:])+`; Website https://tornado.cash."`+"\n"+
+		`30722,"LAZARUS GROUP","-0-","DPRK3","-0-","-0-","-0-","-0-","-0-","-0-","-0-","Digital Currency Address - ETH `+testLazarus+`; Digital Currency Address - XBT 1abc."`+"\n")
+
+	added, removed, err := ImportScreeningLists(ScreeningListSanctions, []string{sdn}, dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+	require.Zero(t, removed)
+
+	file, err := os.Open(filepath.Join(dir, "sanctions.csv"))
+	require.NoError(t, err)
+	entries, err := ReadScreeningCSV(file, ScreeningListSanctions, "")
+	file.Close()
+	require.NoError(t, err)
+	require.Equal(t, []ScreeningEntry{
+		{List: ScreeningListSanctions, Address: testLazarus, Label: "LAZARUS GROUP (DPRK3)", Source: "sdn.csv", Reference: "SDN 30722"},
+		{List: ScreeningListSanctions, Address: testTornado, Label: "TORNADO CASH (CYBER2, DPRK3)", Source: "sdn.csv", Reference: "SDN 36135"},
+	}, entries)
+
+	// A hand-written row survives, and a delisted address drops out on refresh
+	content, err := os.ReadFile(filepath.Join(dir, "sanctions.csv"))
+	require.NoError(t, err)
+	writeTestFile(t, filepath.Join(dir, "sanctions.csv"), string(content)+testPhisher+",1,Manual,compliance,\n")
+	writeTestFile(t, sdn, `30722,"LAZARUS GROUP","-0-","DPRK3","-0-","-0-","-0-","-0-","-0-","-0-","-0-","Digital Currency Address - ETH `+testLazarus+`;"`+"\n")
+	added, removed, err = ImportScreeningLists(ScreeningListSanctions, []string{sdn}, dir)
+	require.NoError(t, err)
+	require.Zero(t, added)
+	require.Equal(t, 1, removed)
+
+	// JSON arrays and one address per line
+	scam := filepath.Join(sources, "scam.json")
+	writeTestFile(t, scam, `["`+testPhisher+`", {"address": "`+testTornado+`", "name": "Drainer", "chainId": 1}, "not an address"]`)
+	added, _, err = ImportScreeningLists(ScreeningListScam, []string{scam}, dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+	denylist := filepath.Join(sources, "team.txt")
+	writeTestFile(t, denylist, "# blocked by the team\n"+testRouter+"\n")
+	added, _, err = ImportScreeningLists(ScreeningListDenylist, []string{denylist}, dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, added)
+
+	_, _, err = ImportScreeningLists(ScreeningListScam, []string{sdn}, dir)
+	require.Error(t, err, "the SDN list only goes into the sanctions list")
+	_, _, err = ImportScreeningLists("watchlist", []string{scam}, dir)
+	require.Error(t, err)
+
+	provider := NewStaticContextProvider(dataDir, false)
+	require.Equal(t, []ScreeningEntry{{List: ScreeningListScam, Address: testTornado, ChainID: 1, Label: "Drainer", Source: "scam.json"}},
+		provider.ScreenAddress(1, testTornado), "delisted from the sanctions list")
+	require.Len(t, provider.ScreenAddress(10, testPhisher), 1, "the manual sanctions row is for mainnet only")
+	require.Len(t, provider.ScreenAddress(1, strings.ToUpper(testPhisher[:2])+testPhisher[2:]), 2)
+}
+
+func TestAddressScreenerFlagsParticipants(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ScreeningDir, "sanctions.csv"), "address,chain_id,label,source,reference\n"+
+		testTornado+",,TORNADO CASH,sdn.csv,SDN 36135\n")
+	writeTestFile(t, filepath.Join(dir, ScreeningDir, "scam.csv"), "address,chain_id,label\n"+
+		testPhisher+",1,Inferno Drainer\n"+
+		testRouter+",137,Polygon only\n")
+	writeTestFile(t, filepath.Join(dir, ScreeningDir, "denylist.csv"), testTornado+"\n")
+	provider := NewStaticContextProvider(dir, false)
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(1),
+			"receipt":    map[string]interface{}{"from": testTrader, "to": testRouter, "status": "0x1"},
+			"trace": map[string]interface{}{
+				"from": testTrader, "to": testRouter,
+				"calls": []interface{}{map[string]interface{}{"from": testRouter, "to": testTornado}},
+			},
+		},
+		"events": []models.Event{
+			testLog(testUSDC, "Transfer(address,address,uint256)", []string{testTrader, testPhisher}, "01"),
+		},
+		"address_participants": []models.AddressParticipant{
+			{Address: testTrader, Role: "Sender", Category: "user"},
+			{Address: testTornado, Role: "Mixer", Category: "protocol"},
+		},
+	}
+	screener := NewAddressScreener(provider, false)
+	require.NoError(t, screener.Process(context.Background(), baggage))
+
+	matches, ok := GetScreeningMatches(baggage)
+	require.True(t, ok)
+	require.Len(t, matches, 3, "the Polygon scam entry does not apply to mainnet")
+	require.Equal(t, []string{
+		"Sanctioned address: " + testTornado + " (TORNADO CASH) is on the sanctions list (source: sdn.csv, SDN 36135)",
+		"Denylisted address: " + testTornado + " is on the denylist list (source: denylist.csv)",
+		"Scam address: " + testPhisher + " (Inferno Drainer) is on the scam list (source: scam.csv)",
+	}, ScreeningRisks(matches))
+
+	participants := baggage["address_participants"].([]models.AddressParticipant)
+	require.Len(t, participants, 3)
+	require.Nil(t, participants[0].Metadata)
+	require.Len(t, participants[1].Metadata["screening"], 2)
+	require.Equal(t, "Mixer", participants[1].Role)
+	require.Equal(t, testPhisher, participants[2].Address)
+	require.Equal(t, "Known Scam Address", participants[2].Role)
+	require.Equal(t, "Inferno Drainer", participants[2].Name)
+
+	require.Contains(t, screener.GetPromptContext(context.Background(), baggage), "### SCREENING MATCHES")
+}
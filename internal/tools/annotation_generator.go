@@ -63,6 +63,37 @@ func (ag *AnnotationGenerator) Process(ctx context.Context, baggage map[string]i
 		fmt.Printf("📄 Explanation text length: %d characters\n", len(explanation.Summary))
 	}
 
+	// Generate annotations using AI with the text context
+	annotations, err := ag.Annotate(ctx, explanation.Summary, baggage, explanation.NetworkID)
+	if err != nil {
+		// Update progress tracker to show the error before returning
+		if progressTracker, ok := baggage["progress_tracker"].(*models.ProgressTracker); ok {
+			progressTracker.UpdateComponent("annotation_generator", models.ComponentGroupFinishing, "Creating Annotations", models.ComponentStatusRunning, fmt.Sprintf("AI annotation generation failed: %v", err))
+		}
+		return fmt.Errorf("failed to generate annotations: %w", err)
+	}
+
+	// Add annotations to the explanation
+	explanation.Annotations = annotations
+
+	// Update baggage
+	baggage["explanation"] = explanation
+
+	if ag.verbose {
+		fmt.Printf("📊 Generated %d annotations for text: '%s'\n", len(annotations), explanation.Summary)
+		for i, annotation := range annotations {
+			fmt.Printf("  Annotation[%d]: Text='%s', HasLink=%t, HasTooltip=%t, HasIcon=%t\n",
+				i, annotation.Text, annotation.Link != "", annotation.Tooltip != "", annotation.Icon != "")
+		}
+		fmt.Println(strings.Repeat("📝", 60) + "\n")
+	}
+
+	return nil
+}
+
+// Annotate generates annotations for text from the prompt context of baggage["context_providers"] - the
+// transaction explanation, or any other text explained from the same tools
+func (ag *AnnotationGenerator) Annotate(ctx context.Context, text string, baggage map[string]interface{}, networkID int64) ([]models.Annotation, error) {
 	// Collect context from all context providers using GetPromptContext
 	var contextParts []string
 	if contextProviders, ok := baggage["context_providers"].([]interface{}); ok {
@@ -100,7 +131,6 @@ func (ag *AnnotationGenerator) Process(ctx context.Context, baggage map[string]i
 
 	// Add network-specific context to the text
 	networkContext := ""
-	networkID := explanation.NetworkID
 	if networkID > 0 {
 		if network, exists := models.GetNetwork(networkID); exists {
 			networkContext = fmt.Sprintf("\n### NETWORK CONTEXT:\n- Network: %s (ID: %d, Explorer: %s)",
@@ -116,31 +146,7 @@ func (ag *AnnotationGenerator) Process(ctx context.Context, baggage map[string]i
 	}
 
 	// Generate annotations using AI with the text context
-	annotations, err := ag.generateAnnotationsFromText(ctx, explanation.Summary, fullContextText)
-	if err != nil {
-		// Update progress tracker to show the error before returning
-		if progressTracker, ok := baggage["progress_tracker"].(*models.ProgressTracker); ok {
-			progressTracker.UpdateComponent("annotation_generator", models.ComponentGroupFinishing, "Creating Annotations", models.ComponentStatusRunning, fmt.Sprintf("AI annotation generation failed: %v", err))
-		}
-		return fmt.Errorf("failed to generate annotations: %w", err)
-	}
-
-	// Add annotations to the explanation
-	explanation.Annotations = annotations
-
-	// Update baggage
-	baggage["explanation"] = explanation
-
-	if ag.verbose {
-		fmt.Printf("📊 Generated %d annotations for text: '%s'\n", len(annotations), explanation.Summary)
-		for i, annotation := range annotations {
-			fmt.Printf("  Annotation[%d]: Text='%s', HasLink=%t, HasTooltip=%t, HasIcon=%t\n",
-				i, annotation.Text, annotation.Link != "", annotation.Tooltip != "", annotation.Icon != "")
-		}
-		fmt.Println(strings.Repeat("📝", 60) + "\n")
-	}
-
-	return nil
+	return ag.generateAnnotationsFromText(ctx, text, fullContextText)
 }
 
 // Name returns the tool name
@@ -229,8 +235,10 @@ INSTRUCTIONS:
    - ANY token amounts (100 USDT, 57,071 GrowAI, 0.5 ETH) - TABLE with: Amount, USD value (if available), Token name, Price per token, Contract address  
    - ANY gas fees ($0.82 gas, + $1.23 gas, $1.024) - Use GAS FEE CONTEXT above to provide detailed breakdown with: USD value, Native token value, Gas used, Gas price (Gwei), Total cost
    - ANY addresses (0x39e5...09c5, 0x1234...5678) - TABLE with: Address (shortened), ENS name (if available), Type (EOA/Contract), Link to explorer
+   - ANY ENS or Basenames names (vitalik.eth, jesse.base.eth) - use the name's avatar as the icon when available, TABLE with: Name, Address, Name service, Current name (only if it changed since the transaction), Website (url record), Twitter
    - ANY protocol names (1inch v6 aggregator, Uniswap, etc.) - TABLE with: Protocol name, Type (DEX/Aggregator/Lending), Function, Website link
    - ANY USD values ($100.00, $0.82) - TABLE with calculation breakdown and source data
+   - ANY NFT names or token IDs (Bored Ape #1234, CryptoPunk 42) - use the NFT's "Image" URL from NFT context as the icon (skip images stored on-chain), TABLE with: Token name, Collection, Token ID, key traits, Contract address
 5. CRITICAL TOKEN SYMBOL LINKING RULE:
    - EVERY token symbol (USDT, PEPE, GrowAI, etc.) MUST ALWAYS include a "link" field pointing to the contract address
    - FOR ERC20/ERC721 TOKENS: Contract link format: [NETWORK_EXPLORER]/token/[contract_address] or [NETWORK_EXPLORER]/address/[contract_address]
@@ -384,7 +392,8 @@ func (ag *AnnotationGenerator) parseAnnotationResponse(response string) ([]model
 			annotation.Link = "" // Clear invalid URLs
 		}
 
-		if annotation.Icon != "" && !ag.isValidURL(annotation.Icon) {
+		// Proxied icons are same-origin paths unless PUBLIC_BASE_URL is set
+		if annotation.Icon != "" && !ag.isValidURL(annotation.Icon) && !strings.HasPrefix(annotation.Icon, "/api/v1/icons/") {
 			annotation.Icon = "" // Clear invalid icon URLs
 		}
 
@@ -0,0 +1,620 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// permit2Address is the canonical Permit2 deployment, the same on every chain
+const permit2Address = "0x000000000022d473030f116ddee9f6b43ac78ba3"
+
+// freshContractAge is how recently a spender must have been deployed to be flagged
+const freshContractAge = 7 * 24 * time.Hour
+
+// freshnessSampleBlocks is how far back the block time is sampled to turn freshContractAge into blocks
+const freshnessSampleBlocks = 10000
+
+// Permit2 allowance events
+var (
+	permit2ApprovalTopic = eventTopic("Approval(address,address,address,uint160,uint48)")
+	permit2PermitTopic   = eventTopic("Permit(address,address,address,uint160,uint48,uint48)")
+)
+
+// Approval and permit functions recognized in calldata
+var (
+	approveSelector                    = functionSelector("approve(address,uint256)")
+	increaseAllowanceSelector          = functionSelector("increaseAllowance(address,uint256)")
+	setApprovalForAllSelector          = functionSelector("setApprovalForAll(address,bool)")
+	erc2612PermitSelector              = functionSelector("permit(address,address,uint256,uint256,uint8,bytes32,bytes32)")
+	daiPermitSelector                  = functionSelector("permit(address,address,uint256,uint256,bool,uint8,bytes32,bytes32)")
+	permit2PermitSingleSelector        = functionSelector("permit(address,((address,uint160,uint48,uint48),address,uint256),bytes)")
+	permit2PermitBatchSelector         = functionSelector("permit(address,((address,uint160,uint48,uint48)[],address,uint256),bytes)")
+	permit2TransferFromSelector        = functionSelector("permitTransferFrom(((address,uint256),uint256,uint256),(address,uint256),address,bytes)")
+	permit2WitnessTransferFromSelector = functionSelector("permitWitnessTransferFrom(((address,uint256),uint256,uint256),(address,uint256),address,bytes32,string,bytes)")
+)
+
+// ApprovalAnalyzer lists the approvals a transaction granted or revoked - Approval and ApprovalForAll
+// events, approve/increaseAllowance/setApprovalForAll calls, EIP-2612 permits and Permit2 permits and
+// signature transfers - and flags the risky ones: approvals to an externally owned account, to a contract
+// deployed in the last week, or to an unverified contract nobody has identified.
+type ApprovalAnalyzer struct {
+	rpcClient *rpc.Client
+	verbose   bool
+}
+
+// NewApprovalAnalyzer creates a new approval analyzer. Without an RPC client spenders are not classified as
+// EOA or contract and deployment age is not checked.
+func NewApprovalAnalyzer(rpcClient *rpc.Client, verbose bool) *ApprovalAnalyzer {
+	return &ApprovalAnalyzer{
+		rpcClient: rpcClient,
+		verbose:   verbose,
+	}
+}
+
+// Name returns the processor name
+func (a *ApprovalAnalyzer) Name() string {
+	return "approval_analyzer"
+}
+
+// Description returns the processor description
+func (a *ApprovalAnalyzer) Description() string {
+	return "Analyzes token approvals and permits and flags risky spenders"
+}
+
+// Dependencies returns the tools this processor depends on
+func (a *ApprovalAnalyzer) Dependencies() []string {
+	return []string{
+		"abi_resolver", "log_decoder", "trace_decoder", "token_metadata_enricher",
+		"erc20_price_lookup", "protocol_resolver", "ens_resolver",
+	}
+}
+
+// Process collects the transaction's approvals with their risks and stores them in baggage["approvals"]
+func (a *ApprovalAnalyzer) Process(ctx context.Context, baggage map[string]interface{}) error {
+	if transactionFailed(baggage) {
+		return nil // Nothing was approved
+	}
+	events, _ := baggage["events"].([]models.Event)
+	amounts := &actionContext{}
+	amounts.metadata, _ = baggage["token_metadata"].(map[string]*TokenMetadata)
+	amounts.prices, _ = baggage["token_prices"].(map[string]*TokenPrice)
+
+	calls := approvalsFromCalldata(baggage, amounts)
+	approvals := mergeApprovals(approvalsFromEvents(events, amounts), calls, assetFlowsFromEvents(events))
+	if len(approvals) == 0 {
+		return nil
+	}
+
+	spenders := a.identifySpenders(ctx, baggage, approvals)
+	for i := range approvals {
+		approvals[i].Risks = approvalRisks(approvals[i], spenders[approvals[i].Spender])
+		if a.verbose {
+			fmt.Printf("🔑 %s approval of %s to %s (%d risks)\n", approvals[i].Kind, approvals[i].Allowance.Token, approvals[i].Spender, len(approvals[i].Risks))
+		}
+	}
+	baggage["approvals"] = approvals
+	return nil
+}
+
+// approvalsFromEvents reads Approval (ERC20 and ERC721), ApprovalForAll and Permit2 allowance events
+func approvalsFromEvents(events []models.Event, amounts *actionContext) []models.Approval {
+	var approvals []models.Approval
+	for _, event := range events {
+		if len(event.Topics) < 3 {
+			continue
+		}
+		topic := strings.ToLower(event.Topics[0])
+		token := strings.ToLower(event.Contract)
+		data := newABIData(event.Data)
+		approval := models.Approval{
+			Owner:   topicAddress(event.Topics[1]),
+			Spender: topicAddress(event.Topics[2]),
+			Source:  "event",
+		}
+
+		switch {
+		case topic == erc20ApprovalTopic && len(event.Topics) == 4:
+			tokenID, ok := new(big.Int).SetString(strings.TrimPrefix(event.Topics[3], "0x"), 16)
+			if !ok || approval.Spender == zeroAddress {
+				continue // Transfers clear the single-token approval
+			}
+			approval.Kind = models.ApprovalKindToken
+			approval.Allowance = amounts.amount(token, tokenID.String(), nil)
+		case topic == erc20ApprovalTopic:
+			value := data.uintAt(0)
+			if value == nil {
+				continue
+			}
+			approval.Kind = models.ApprovalKindAllowance
+			setAllowance(&approval, amounts, token, value)
+		case topic == approvalForAllTopic:
+			approved := data.uintAt(0)
+			if approved == nil {
+				continue
+			}
+			approval.Kind = models.ApprovalKindOperator
+			approval.Allowance = amounts.amount(token, "", nil)
+			approval.Allowance.Amount = "all"
+			approval.Unlimited = approved.Sign() > 0
+			approval.Revoked = approved.Sign() == 0
+		case (topic == permit2ApprovalTopic || topic == permit2PermitTopic) && len(event.Topics) == 4:
+			value := data.uintAt(0)
+			if value == nil {
+				continue
+			}
+			approval.Kind = models.ApprovalKindPermit2
+			approval.Spender = topicAddress(event.Topics[3])
+			setAllowance(&approval, amounts, topicAddress(event.Topics[2]), value)
+			approval.Expiration = unixTime(data.uintAt(32))
+		default:
+			continue
+		}
+		approvals = append(approvals, approval)
+	}
+	return approvals
+}
+
+// setAllowance sets a fungible allowance, marking conventional "infinite" values unlimited and zero revoked
+func setAllowance(approval *models.Approval, amounts *actionContext, token string, value *big.Int) {
+	approval.Allowance = amounts.amount(token, "", value)
+	switch {
+	case isUnlimitedAllowance(value):
+		approval.Unlimited = true
+		approval.Allowance.Amount = "unlimited"
+		approval.Allowance.AmountUSD = ""
+	case value.Sign() == 0:
+		approval.Revoked = true
+	}
+}
+
+// unixTime converts a unix timestamp, treating zero as unset
+func unixTime(value *big.Int) *time.Time {
+	if value == nil || value.Sign() == 0 || !value.IsInt64() {
+		return nil
+	}
+	t := time.Unix(value.Int64(), 0).UTC()
+	return &t
+}
+
+// approvalCall is a call that may grant an approval: its caller, target and calldata
+type approvalCall struct {
+	from, to, input string
+}
+
+// approvalsFromCalldata decodes approval and permit calls from the trace, which shows every call that ran
+// (including permits a router submits for the user), or else from the transaction's own call. Reverted
+// frames and everything under them are skipped: their approvals were rolled back.
+func approvalsFromCalldata(baggage map[string]interface{}, amounts *actionContext) []models.Approval {
+	var calls []approvalCall
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	if trace, ok := rawData["trace"].(map[string]interface{}); ok {
+		var walk func(frame map[string]interface{})
+		walk = func(frame map[string]interface{}) {
+			if failure, _ := frame["error"].(string); failure != "" {
+				return
+			}
+			callType, _ := frame["type"].(string)
+			from, _ := frame["from"].(string)
+			to, _ := frame["to"].(string)
+			input, _ := frame["input"].(string)
+			// A proxy's delegatecall repeats the input against the implementation
+			if !strings.EqualFold(callType, "DELEGATECALL") {
+				calls = append(calls, approvalCall{from: strings.ToLower(from), to: strings.ToLower(to), input: strings.ToLower(input)})
+			}
+			if children, ok := frame["calls"].([]interface{}); ok {
+				for _, child := range children {
+					if childFrame, ok := child.(map[string]interface{}); ok {
+						walk(childFrame)
+					}
+				}
+			}
+		}
+		walk(trace)
+	} else if to, input, _ := topLevelCall(baggage); to != "" {
+		calls = append(calls, approvalCall{from: transactionSender(baggage), to: to, input: input})
+	}
+
+	var approvals []models.Approval
+	for _, call := range calls {
+		approvals = append(approvals, decodeApprovalCall(call, amounts)...)
+	}
+	return approvals
+}
+
+// decodeApprovalCall decodes one approval or permit call
+func decodeApprovalCall(call approvalCall, amounts *actionContext) []models.Approval {
+	if len(call.input) < 10 {
+		return nil
+	}
+	args, err := hex.DecodeString(call.input[10:])
+	if err != nil {
+		return nil
+	}
+	data := abiData(args)
+	approval := models.Approval{Owner: call.from, Source: "calldata"}
+
+	switch call.input[:10] {
+	case approveSelector:
+		value := data.uintAt(32)
+		if value == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindAllowance
+		approval.Spender = data.addressAt(0)
+		setAllowance(&approval, amounts, call.to, value)
+	case increaseAllowanceSelector:
+		// The amount is added to the current allowance, so even zero revokes nothing
+		value := data.uintAt(32)
+		if value == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindAllowance
+		approval.Spender = data.addressAt(0)
+		approval.Increase = true
+		approval.Allowance = amounts.amount(call.to, "", value)
+	case setApprovalForAllSelector:
+		approved := data.uintAt(32)
+		if approved == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindOperator
+		approval.Spender = data.addressAt(0)
+		approval.Allowance = amounts.amount(call.to, "", nil)
+		approval.Allowance.Amount = "all"
+		approval.Unlimited = approved.Sign() > 0
+		approval.Revoked = approved.Sign() == 0
+	case erc2612PermitSelector:
+		value := data.uintAt(64)
+		if value == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindPermit
+		approval.Owner, approval.Spender = data.addressAt(0), data.addressAt(32)
+		setAllowance(&approval, amounts, call.to, value)
+		approval.Deadline = unixTime(data.uintAt(96))
+	case daiPermitSelector:
+		// permit(holder, spender, nonce, expiry, allowed, ...) grants all or nothing
+		allowed := data.uintAt(128)
+		if allowed == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindPermit
+		approval.Owner, approval.Spender = data.addressAt(0), data.addressAt(32)
+		approval.Allowance = amounts.amount(call.to, "", nil)
+		approval.Allowance.Amount = "unlimited"
+		approval.Unlimited = allowed.Sign() > 0
+		approval.Revoked = allowed.Sign() == 0
+		approval.Deadline = unixTime(data.uintAt(96))
+	case permit2PermitSingleSelector:
+		// owner, details (token, amount, expiration, nonce), spender, sigDeadline
+		value := data.uintAt(64)
+		if value == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindPermit2
+		approval.Owner, approval.Spender = data.addressAt(0), data.addressAt(160)
+		setAllowance(&approval, amounts, data.addressAt(32), value)
+		approval.Expiration = unixTime(data.uintAt(96))
+		approval.Deadline = unixTime(data.uintAt(192))
+	case permit2PermitBatchSelector:
+		return decodePermit2Batch(data, amounts)
+	case permit2TransferFromSelector, permit2WitnessTransferFromSelector:
+		// permitted (token, amount), nonce, deadline, transferDetails (to, requestedAmount), owner; the caller
+		// is the spender
+		value := data.uintAt(32)
+		if value == nil {
+			return nil
+		}
+		approval.Kind = models.ApprovalKindPermit2Transfer
+		approval.Owner, approval.Spender = data.addressAt(192), call.from
+		setAllowance(&approval, amounts, data.addressAt(0), value)
+		approval.Deadline = unixTime(data.uintAt(96))
+	default:
+		return nil
+	}
+	if approval.Spender == "" || approval.Allowance.Token == "" {
+		return nil
+	}
+	return []models.Approval{approval}
+}
+
+// decodePermit2Batch decodes Permit2 permit(owner, (details[], spender, sigDeadline), signature)
+func decodePermit2Batch(data abiData, amounts *actionContext) []models.Approval {
+	owner := data.addressAt(0)
+	tuple, ok := data.intAt(32)
+	if !ok {
+		return nil
+	}
+	detailsOffset, ok := data.intAt(tuple)
+	if !ok {
+		return nil
+	}
+	spender := data.addressAt(tuple + 32)
+	deadline := unixTime(data.uintAt(tuple + 64))
+	details := tuple + detailsOffset
+	count, ok := data.intAt(details)
+	if !ok || spender == "" {
+		return nil
+	}
+
+	var approvals []models.Approval
+	for i := 0; i < count; i++ {
+		base := details + 32 + i*4*32
+		value := data.uintAt(base + 32)
+		if value == nil {
+			return nil
+		}
+		approval := models.Approval{Kind: models.ApprovalKindPermit2, Owner: owner, Spender: spender, Source: "calldata", Deadline: deadline}
+		setAllowance(&approval, amounts, data.addressAt(base), value)
+		approval.Expiration = unixTime(data.uintAt(base + 64))
+		approvals = append(approvals, approval)
+	}
+	return approvals
+}
+
+// mergeApprovals combines event and calldata approvals. Events are authoritative for what was approved; a
+// matching permit call adds its kind and deadline. Calldata approvals with no event are kept, while finite
+// Approval events with no call that set them, next to a transfer of the owner's tokens, are dropped: tokens
+// emit those from transferFrom to report the reduced allowance.
+func mergeApprovals(events, calls []models.Approval, flows []assetFlow) []models.Approval {
+	key := func(approval models.Approval) string {
+		return approval.Allowance.Token + ":" + approval.Allowance.TokenID + ":" + approval.Owner + ":" + approval.Spender
+	}
+	byKey := make(map[string]int)
+	for i, call := range calls {
+		if _, exists := byKey[key(call)]; !exists {
+			byKey[key(call)] = i
+		}
+	}
+
+	var approvals []models.Approval
+	matched := make(map[int]bool)
+	for _, event := range events {
+		index, hasCall := byKey[key(event)]
+		if !hasCall {
+			// ERC721 approve(spender, tokenId) decodes like an ERC20 approve
+			for i, call := range calls {
+				if event.Kind == models.ApprovalKindToken && call.Allowance.Token == event.Allowance.Token && call.Spender == event.Spender {
+					index, hasCall = i, true
+					break
+				}
+			}
+		}
+		if hasCall {
+			matched[index] = true
+			if call := calls[index]; call.Kind == models.ApprovalKindPermit || call.Kind == models.ApprovalKindPermit2 {
+				event.Kind = call.Kind
+				event.Deadline = call.Deadline
+				if event.Expiration == nil {
+					event.Expiration = call.Expiration
+				}
+			}
+		} else if event.Kind == models.ApprovalKindAllowance && !event.Unlimited && !event.Revoked {
+			transferred := false
+			for _, flow := range flows {
+				if flow.token == event.Allowance.Token && flow.from == event.Owner {
+					transferred = true
+				}
+			}
+			if transferred {
+				continue
+			}
+		}
+		approvals = append(approvals, event)
+	}
+	for i, call := range calls {
+		if !matched[i] {
+			approvals = append(approvals, call)
+		}
+	}
+	return approvals
+}
+
+// spenderInfo is what is known about a spender
+type spenderInfo struct {
+	name    string
+	kind    string // models.SpenderTypeEOA or models.SpenderTypeContract; empty when unknown
+	fresh   bool   // Contract deployed within freshContractAge
+	unknown bool   // Unverified contract with no name
+}
+
+// identifySpenders names each spender and, with an RPC client, classifies it as an EOA or a contract and checks
+// whether the contract was deployed recently
+func (a *ApprovalAnalyzer) identifySpenders(ctx context.Context, baggage map[string]interface{}, approvals []models.Approval) map[string]spenderInfo {
+	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
+	protocols, _ := baggage["protocols"].([]ProbabilisticProtocol)
+	ensNames, _ := baggage["ens_names"].(map[string]string)
+	names := &actionContext{protocols: protocols}
+
+	block, freshnessBlock := "", ""
+	if a.rpcClient != nil {
+		block, freshnessBlock = a.blocks(ctx, baggage)
+	}
+
+	spenders := make(map[string]spenderInfo)
+	for i := range approvals {
+		spender := approvals[i].Spender
+		info, seen := spenders[spender]
+		if !seen {
+			contract, resolved := resolvedContracts[spender]
+			verified := resolved && contract != nil && contract.IsVerified
+			if verified && contract.ContractName != "" {
+				info.name = contract.ContractName
+			}
+			if protocol := names.protocolFor(spender); protocol != "" {
+				info.name = protocol
+			}
+			if info.name == "" && spender == permit2Address {
+				info.name = "Permit2"
+			}
+			if info.name == "" {
+				info.name = ensNames[spender]
+			}
+			if a.rpcClient != nil {
+				info.kind, info.fresh = a.classifySpender(ctx, spender, block, freshnessBlock)
+			}
+			info.unknown = info.kind == models.SpenderTypeContract && info.name == "" && !verified
+			spenders[spender] = info
+		}
+		approvals[i].SpenderName = info.name
+		approvals[i].SpenderType = info.kind
+	}
+	return spenders
+}
+
+// classifySpender tells an EOA from a contract by its code at the transaction's block, and reports a
+// contract as fresh when it had no code at freshnessBlock. An EIP-7702 delegation designator is still an EOA.
+func (a *ApprovalAnalyzer) classifySpender(ctx context.Context, spender, block, freshnessBlock string) (string, bool) {
+	code, err := a.rpcClient.GetCodeAt(ctx, spender, block)
+	if err != nil {
+		return "", false
+	}
+	if code == "" || code == "0x" || strings.HasPrefix(code, "0xef0100") {
+		return models.SpenderTypeEOA, false
+	}
+	if freshnessBlock == "" {
+		return models.SpenderTypeContract, false
+	}
+	earlier, err := a.rpcClient.GetCodeAt(ctx, spender, freshnessBlock)
+	return models.SpenderTypeContract, err == nil && (earlier == "" || earlier == "0x")
+}
+
+// blocks returns the transaction's block and the block freshContractAge before it, estimated from the
+// average block time over the last freshnessSampleBlocks blocks
+func (a *ApprovalAnalyzer) blocks(ctx context.Context, baggage map[string]interface{}) (string, string) {
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	block, _ := rawData["block"].(map[string]interface{})
+	number, errNumber := parseHexUint(block["number"])
+	timestamp, errTimestamp := parseHexUint(block["timestamp"])
+	if errNumber != nil || errTimestamp != nil {
+		return "latest", ""
+	}
+	current := fmt.Sprintf("0x%x", number)
+	if number <= freshnessSampleBlocks {
+		return current, ""
+	}
+
+	sample, err := a.rpcClient.GetBlockByNumber(ctx, fmt.Sprintf("0x%x", number-freshnessSampleBlocks))
+	if err != nil {
+		return current, ""
+	}
+	sampleTimestamp, err := parseHexUint(sample["timestamp"])
+	if err != nil || sampleTimestamp >= timestamp {
+		return current, ""
+	}
+	secondsPerBlock := float64(timestamp-sampleTimestamp) / freshnessSampleBlocks
+	window := uint64(freshContractAge.Seconds() / secondsPerBlock)
+	if window >= number {
+		return current, ""
+	}
+	return current, fmt.Sprintf("0x%x", number-window)
+}
+
+func parseHexUint(value interface{}) (uint64, error) {
+	text, _ := value.(string)
+	return strconv.ParseUint(strings.TrimPrefix(text, "0x"), 16, 64)
+}
+
+// approvalRisks warns about approvals to EOAs, fresh contracts and unknown contracts. Revocations carry no
+// risk.
+func approvalRisks(approval models.Approval, spender spenderInfo) []string {
+	if approval.Revoked {
+		return nil
+	}
+
+	what := describeApproval(approval)
+	var risks []string
+	if spender.kind == models.SpenderTypeEOA {
+		risks = append(risks, fmt.Sprintf("%s granted to %s, an externally owned account rather than a contract: whoever holds its key can move the tokens", what, approval.Spender))
+	}
+	if spender.fresh {
+		risks = append(risks, fmt.Sprintf("%s granted to %s, a contract deployed within the last %d days", what, approval.Spender, int(freshContractAge.Hours()/24)))
+	}
+	if spender.unknown {
+		risks = append(risks, fmt.Sprintf("%s granted to %s, an unverified contract with no known identity", what, approval.Spender))
+	}
+	return risks
+}
+
+// describeApproval summarizes what an approval lets the spender take, e.g. "Unlimited USDC approval"
+func describeApproval(approval models.Approval) string {
+	symbol := approval.Allowance.Symbol
+	if symbol == "" {
+		symbol = "token " + approval.Allowance.Token
+	}
+	switch {
+	case approval.Revoked:
+		return fmt.Sprintf("Revocation of %s approval", symbol)
+	case approval.Kind == models.ApprovalKindOperator:
+		return fmt.Sprintf("Approval for all %s", symbol)
+	case approval.Kind == models.ApprovalKindToken:
+		return fmt.Sprintf("Approval of %s #%s", symbol, approval.Allowance.TokenID)
+	case approval.Kind == models.ApprovalKindPermit2Transfer:
+		return fmt.Sprintf("Permit2 signature transfer of %s %s", approval.Allowance.Amount, symbol)
+	case approval.Unlimited:
+		return fmt.Sprintf("Unlimited %s approval", symbol)
+	case approval.Increase:
+		return fmt.Sprintf("Increase of %s approval by %s", symbol, approval.Allowance.Amount)
+	}
+	return fmt.Sprintf("Approval of %s %s", approval.Allowance.Amount, symbol)
+}
+
+// GetApprovals returns the analyzed approvals from baggage
+func GetApprovals(baggage map[string]interface{}) ([]models.Approval, bool) {
+	approvals, ok := baggage["approvals"].([]models.Approval)
+	return approvals, ok && len(approvals) > 0
+}
+
+// ApprovalRisks returns the risk warnings of all approvals, without duplicates
+func ApprovalRisks(approvals []models.Approval) []string {
+	var risks []string
+	for _, approval := range approvals {
+		for _, risk := range approval.Risks {
+			if !containsString(risks, risk) {
+				risks = append(risks, risk)
+			}
+		}
+	}
+	return risks
+}
+
+// GetPromptContext lists the approvals and their risks
+func (a *ApprovalAnalyzer) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	approvals, ok := GetApprovals(baggage)
+	if !ok {
+		return ""
+	}
+
+	lines := []string{"### TOKEN APPROVALS AND PERMITS (mention risky ones explicitly):"}
+	for _, approval := range approvals {
+		spender := approval.Spender
+		if approval.SpenderName != "" {
+			spender = fmt.Sprintf("%s (%s)", approval.SpenderName, approval.Spender)
+		}
+		line := fmt.Sprintf("- %s by %s to %s [%s]", describeApproval(approval), approval.Owner, spender, strings.ReplaceAll(approval.Kind, "_", " "))
+		if approval.SpenderType != "" {
+			line += ", spender is " + map[string]string{models.SpenderTypeEOA: "an EOA", models.SpenderTypeContract: "a contract"}[approval.SpenderType]
+		}
+		if approval.Expiration != nil {
+			line += ", expires " + approval.Expiration.Format(time.RFC3339)
+		}
+		lines = append(lines, line)
+		for _, risk := range approval.Risks {
+			lines = append(lines, "  RISK: "+risk)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for approvals (none - they are transaction specific)
+func (a *ApprovalAnalyzer) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+var testUnlimited = strings.Repeat("f", 64)
+
+func TestApprovalAnalyzerMergesEventsAndPermits(t *testing.T) {
+	deadline := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	permit := encodeCall("permit(address,address,uint256,uint256,uint8,bytes32,bytes32)",
+		testTrader, testRouter, testUnlimited, fmt.Sprintf("%x", deadline.Unix()), "1b", "01", "02")
+	transferAmount := fmt.Sprintf("%x", 5_000000000000000000)
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt": map[string]interface{}{"from": testTrader, "to": testRouter, "status": "0x1"},
+			"trace": map[string]interface{}{
+				"type": "CALL", "from": testTrader, "to": testRouter, "input": "0x12345678",
+				"calls": []interface{}{
+					// The router submits the user's signed permit
+					map[string]interface{}{"type": "CALL", "from": testRouter, "to": testUSDC, "input": permit},
+				},
+			},
+		},
+		"events": []models.Event{
+			testLog(testUSDC, "Approval(address,address,uint256)", []string{testTrader, testRouter}, testUnlimited),
+			// transferFrom reports the allowance it used up
+			testLog(testWETH, "Approval(address,address,uint256)", []string{testTrader, testRouter}, fmt.Sprintf("%x", 1_000000000000000000)),
+			testLog(testWETH, "Transfer(address,address,uint256)", []string{testTrader, testRouter}, transferAmount),
+		},
+		"token_metadata": map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}},
+	}
+
+	analyzer := NewApprovalAnalyzer(nil, false)
+	require.NoError(t, analyzer.Process(context.Background(), baggage))
+
+	approvals, ok := GetApprovals(baggage)
+	require.True(t, ok)
+	require.Len(t, approvals, 1, "the allowance reduction from transferFrom is not an approval")
+	approval := approvals[0]
+	require.Equal(t, models.ApprovalKindPermit, approval.Kind)
+	require.Equal(t, testTrader, approval.Owner)
+	require.Equal(t, testRouter, approval.Spender)
+	require.Equal(t, "event", approval.Source)
+	require.True(t, approval.Unlimited)
+	require.Equal(t, "unlimited", approval.Allowance.Amount)
+	require.NotNil(t, approval.Deadline)
+	require.True(t, deadline.Equal(*approval.Deadline))
+	require.Empty(t, approval.Risks, "spenders are not classified without an RPC client")
+
+	prompt := analyzer.GetPromptContext(context.Background(), baggage)
+	require.Contains(t, prompt, "- Unlimited USDC approval by "+testTrader+" to "+testRouter+" [permit]")
+}
+
+func TestApprovalAnalyzerDecodesPermit2SignatureTransfer(t *testing.T) {
+	// permitTransferFrom(((token, amount), nonce, deadline), (to, requestedAmount), owner, signature)
+	amount := fmt.Sprintf("%x", 250_000000)
+	transfer := functionSelector("permitTransferFrom(((address,uint256),uint256,uint256),(address,uint256),address,bytes)") +
+		abiWord(testUSDC) + abiWord(amount) + abiWord("7") + abiWord("77359400") +
+		abiWord(testRouter) + abiWord(amount) + abiWord(testTrader) + abiWord(fmt.Sprintf("%x", 8*32)) + encodeTail(strings.Repeat("ab", 65))
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt": map[string]interface{}{"from": testTrader, "to": testRouter, "status": "0x1"},
+			"trace": map[string]interface{}{
+				"type": "CALL", "from": testTrader, "to": testRouter, "input": "0x12345678",
+				"calls": []interface{}{
+					map[string]interface{}{"type": "CALL", "from": testRouter, "to": permit2Address, "input": transfer},
+				},
+			},
+		},
+		"token_metadata": map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}},
+	}
+	require.NoError(t, NewApprovalAnalyzer(nil, false).Process(context.Background(), baggage))
+
+	approvals, ok := GetApprovals(baggage)
+	require.True(t, ok)
+	require.Len(t, approvals, 1)
+	require.Equal(t, models.ApprovalKindPermit2Transfer, approvals[0].Kind)
+	require.Equal(t, testTrader, approvals[0].Owner)
+	require.Equal(t, testRouter, approvals[0].Spender, "the caller spends the signature")
+	require.Equal(t, testUSDC, approvals[0].Allowance.Token)
+	require.Equal(t, "calldata", approvals[0].Source)
+	require.False(t, approvals[0].Unlimited)
+	require.Equal(t, time.Unix(0x77359400, 0).UTC(), *approvals[0].Deadline)
+}
+
+func TestApprovalAnalyzerDecodesIncreasesAndSkipsRevertedCalls(t *testing.T) {
+	increase := encodeCall("increaseAllowance(address,uint256)", testRouter, fmt.Sprintf("%x", 3_000000))
+	approve := encodeCall("approve(address,uint256)", testSeller, testUnlimited)
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt": map[string]interface{}{"from": testTrader, "to": testSafe, "status": "0x1"},
+			"trace": map[string]interface{}{
+				"type": "CALL", "from": testTrader, "to": testSafe, "input": "0x12345678",
+				"calls": []interface{}{
+					map[string]interface{}{"type": "CALL", "from": testSafe, "to": testUSDC, "input": increase},
+					map[string]interface{}{"type": "CALL", "from": testSafe, "to": testWETH, "input": approve, "error": "execution reverted"},
+					// The failed call's subtree was rolled back with it
+					map[string]interface{}{"type": "CALL", "from": testSafe, "to": testRouter, "input": "0x12345678", "error": "out of gas",
+						"calls": []interface{}{
+							map[string]interface{}{"type": "CALL", "from": testRouter, "to": testWETH, "input": approve},
+						},
+					},
+				},
+			},
+		},
+		"token_metadata": map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}},
+	}
+	analyzer := NewApprovalAnalyzer(nil, false)
+	require.NoError(t, analyzer.Process(context.Background(), baggage))
+
+	approvals, ok := GetApprovals(baggage)
+	require.True(t, ok)
+	require.Len(t, approvals, 1, "approvals in reverted frames did not happen")
+	require.Equal(t, testSafe, approvals[0].Owner)
+	require.True(t, approvals[0].Increase)
+	require.False(t, approvals[0].Revoked)
+	require.Equal(t, "3", approvals[0].Allowance.Amount)
+	require.Contains(t, analyzer.GetPromptContext(context.Background(), baggage), "Increase of USDC approval by 3")
+
+	// Increasing by zero changes nothing; it is not a revocation
+	trace := baggage["raw_data"].(map[string]interface{})["trace"].(map[string]interface{})
+	trace["calls"] = []interface{}{map[string]interface{}{
+		"type": "CALL", "from": testSafe, "to": testUSDC, "input": encodeCall("increaseAllowance(address,uint256)", testRouter, "0"),
+	}}
+	require.NoError(t, analyzer.Process(context.Background(), baggage))
+	approvals, _ = GetApprovals(baggage)
+	require.Len(t, approvals, 1)
+	require.True(t, approvals[0].Increase)
+	require.False(t, approvals[0].Revoked)
+}
+
+func TestApprovalAnalyzerFlagsRiskySpenders(t *testing.T) {
+	const (
+		networkID = 990046
+		block     = 20_000_000
+		timestamp = 1_700_000_000
+	)
+	eoa := "0x2222222222222222222222222222222222222222"
+
+	// A node on which testRouter was deployed a day before the transaction, at 12 seconds per block
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int           `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		var result interface{}
+		switch request.Method {
+		case "eth_getCode":
+			result = "0x"
+			if request.Params[0] == testRouter && request.Params[1] == fmt.Sprintf("0x%x", block) {
+				result = "0x6080604052"
+			}
+		case "eth_getBlockByNumber":
+			number, err := parseHexUint(request.Params[0])
+			require.NoError(t, err)
+			result = map[string]interface{}{"number": request.Params[0], "timestamp": fmt.Sprintf("0x%x", timestamp-(block-number)*12)}
+		default:
+			t.Fatalf("unexpected method %s", request.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	defer server.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: server.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt": map[string]interface{}{"from": testTrader, "to": testUSDC, "status": "0x1"},
+			"block":   map[string]interface{}{"number": fmt.Sprintf("0x%x", block), "timestamp": fmt.Sprintf("0x%x", timestamp)},
+		},
+		"events": []models.Event{
+			testLog(testUSDC, "Approval(address,address,uint256)", []string{testTrader, eoa}, testUnlimited),
+			testLog(testWETH, "Approval(address,address,uint256)", []string{testTrader, testRouter}, testUnlimited),
+			// Revoking an approval is never risky
+			testLog(testUSDC, "Approval(address,address,uint256)", []string{testTrader, testV3Pool}, "0"),
+		},
+		"token_metadata": map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}, testWETH: {Symbol: "WETH", Decimals: 18}},
+	}
+	require.NoError(t, NewApprovalAnalyzer(client, false).Process(context.Background(), baggage))
+
+	approvals, ok := GetApprovals(baggage)
+	require.True(t, ok)
+	require.Len(t, approvals, 3)
+	require.Equal(t, models.SpenderTypeEOA, approvals[0].SpenderType)
+	require.Equal(t, []string{
+		"Unlimited USDC approval granted to " + eoa + ", an externally owned account rather than a contract: whoever holds its key can move the tokens",
+	}, approvals[0].Risks)
+	require.Equal(t, models.SpenderTypeContract, approvals[1].SpenderType)
+	require.Equal(t, []string{
+		"Unlimited WETH approval granted to " + testRouter + ", a contract deployed within the last 7 days",
+		"Unlimited WETH approval granted to " + testRouter + ", an unverified contract with no known identity",
+	}, approvals[1].Risks)
+	require.True(t, approvals[2].Revoked)
+	require.Empty(t, approvals[2].Risks)
+
+	require.Len(t, ApprovalRisks(approvals), 3)
+}
@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/txplain/txplain/internal/sigdb"
+)
+
+// maxBytecodeSelectors caps how many dispatcher selectors are looked up per contract, and
+// bytecodeLookupWorkers how many of those lookups run at once
+const (
+	maxBytecodeSelectors  = 100
+	bytecodeLookupWorkers = 8
+)
+
+// EVM opcodes used to recognise the function dispatcher
+const (
+	opEQ     = 0x14
+	opJUMPI  = 0x57
+	opPUSH1  = 0x60
+	opPUSH3  = 0x62
+	opPUSH4  = 0x63
+	opPUSH32 = 0x7f
+	opDUP1   = 0x80
+	opDUP16  = 0x8f
+)
+
+// ExtractFunctionSelectors walks contract bytecode and returns the 4-byte selectors compared in
+// the function dispatcher. Solidity and Vyper emit `PUSH4 <selector> EQ` (optionally with a DUPn
+// before EQ), so only PUSH4 values feeding an EQ are kept - this skips constants and metadata.
+// Selectors with leading zero bytes are pushed with PUSH1..PUSH3; those are only kept when the EQ
+// feeds a `PUSHn <dest> JUMPI` branch, since short constants are compared far more often.
+func ExtractFunctionSelectors(bytecode string) []string {
+	code, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(bytecode), "0x"))
+	if err != nil || len(code) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var selectors []string
+
+	for i := 0; i < len(code); {
+		op := code[i]
+
+		if op >= opPUSH1 && op <= opPUSH4 {
+			size := int(op-opPUSH1) + 1
+			next := i + 1 + size
+			if next > len(code) {
+				break
+			}
+			// Index of the EQ consuming the pushed value, if any
+			eq := -1
+			if next < len(code) && code[next] == opEQ {
+				eq = next
+			} else if next+1 < len(code) && code[next] >= opDUP1 && code[next] <= opDUP16 && code[next+1] == opEQ {
+				eq = next + 1
+			}
+
+			isSelector := eq >= 0
+			if isSelector && op < opPUSH4 {
+				isSelector = feedsJumpI(code, eq+1)
+			}
+			word := make([]byte, 4)
+			copy(word[4-size:], code[i+1:next])
+			selector := "0x" + hex.EncodeToString(word)
+			if isSelector && selector != "0x00000000" && selector != "0xffffffff" && !seen[selector] {
+				seen[selector] = true
+				selectors = append(selectors, selector)
+			}
+			i = next
+			continue
+		}
+
+		// Skip immediate data of PUSH5..PUSH32 so it's never parsed as opcodes
+		if op > opPUSH4 && op <= opPUSH32 {
+			i += int(op-opPUSH1) + 2
+			continue
+		}
+
+		i++
+	}
+
+	return selectors
+}
+
+// feedsJumpI reports whether the code at i is `PUSH1..PUSH4 <dest> JUMPI`, the dispatcher's branch
+func feedsJumpI(code []byte, i int) bool {
+	if i >= len(code) || code[i] < opPUSH1 || code[i] > opPUSH4 {
+		return false
+	}
+	jump := i + int(code[i]-opPUSH1) + 2
+	return jump < len(code) && code[jump] == opJUMPI
+}
+
+// resolveFromBytecode builds a partial ABI for an unverified contract by extracting dispatcher
+// selectors from its bytecode and resolving them through the signature database
+func (a *ABIResolver) resolveFromBytecode(ctx context.Context, address string, networkID int64) (*ContractInfo, error) {
+	if a.rpcClient == nil {
+		return nil, fmt.Errorf("no RPC client for bytecode lookup")
+	}
+
+	selectors, err := a.getBytecodeSelectors(ctx, address, networkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("no function selectors found in bytecode")
+	}
+	if len(selectors) > maxBytecodeSelectors {
+		selectors = selectors[:maxBytecodeSelectors]
+	}
+
+	if a.verbose || os.Getenv("DEBUG") == "true" {
+		fmt.Printf("  Found %d dispatcher selectors in bytecode of %s\n", len(selectors), address)
+	}
+
+	// Look the selectors up on a bounded pool of workers, keeping the dispatcher order
+	signatures := make([]*ResolvedSignature, len(selectors))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(bytecodeLookupWorkers, len(selectors)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if sig, err := a.signatureLookup.lookupFunctionSignature(ctx, selectors[i], ""); err == nil {
+					signatures[i] = sig
+				}
+			}
+		}()
+	}
+	for i := range selectors {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var resolved []*ResolvedSignature
+	var unresolved []string
+	for i, sig := range signatures {
+		if sig != nil {
+			resolved = append(resolved, sig)
+		} else {
+			unresolved = append(unresolved, selectors[i])
+		}
+	}
+
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("none of %d bytecode selectors could be resolved", len(selectors))
+	}
+
+	abiJSON, err := buildSyntheticABI(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	contractInfo := &ContractInfo{
+		Address:             address,
+		ABI:                 abiJSON,
+		IsVerified:          false,
+		Source:              "bytecode",
+		UnresolvedSelectors: unresolved,
+	}
+	if parsedABI, err := a.parseABI(abiJSON); err == nil {
+		contractInfo.ParsedABI = parsedABI
+	}
+
+	return contractInfo, nil
+}
+
+// getBytecodeSelectors returns the dispatcher selectors of a contract, using the cache when possible
+func (a *ABIResolver) getBytecodeSelectors(ctx context.Context, address string, networkID int64) ([]string, error) {
+	cacheKey := fmt.Sprintf(BytecodeSelectorsKeyPattern, networkID, strings.ToLower(address))
+	if a.cache != nil {
+		var cached []string
+		if err := a.cache.GetJSON(ctx, cacheKey, &cached); err == nil {
+			return cached, nil
+		}
+	}
+
+	code, err := a.rpcClient.GetCode(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bytecode: %w", err)
+	}
+	if code == "" || code == "0x" {
+		return nil, fmt.Errorf("address has no bytecode")
+	}
+
+	selectors := ExtractFunctionSelectors(code)
+
+	if a.cache != nil {
+		if err := a.cache.SetJSON(ctx, cacheKey, selectors, &BytecodeSelectorsTTLDuration); err != nil && (a.verbose || os.Getenv("DEBUG") == "true") {
+			fmt.Printf("  ⚠️  Failed to cache bytecode selectors for %s: %v\n", address, err)
+		}
+	}
+
+	return selectors, nil
+}
+
+// buildSyntheticABI turns text signatures like "transfer(address,uint256)" into ABI JSON.
+// Parameter names are unknown, so inputs are named arg0, arg1, ...
+func buildSyntheticABI(signatures []*ResolvedSignature) (string, error) {
+	var items []map[string]interface{}
+
+	for _, sig := range signatures {
+		open := strings.Index(sig.TextSignature, "(")
+		if open <= 0 || !strings.HasSuffix(sig.TextSignature, ")") {
+			continue
+		}
+
+		name := sig.TextSignature[:open]
+		inputs := []map[string]interface{}{}
+		for i, paramType := range sigdb.SplitParams(sig.TextSignature[open+1 : len(sig.TextSignature)-1]) {
+			inputs = append(inputs, map[string]interface{}{
+				"name": fmt.Sprintf("arg%d", i),
+				"type": paramType,
+			})
+		}
+
+		items = append(items, map[string]interface{}{
+			"type":   "function",
+			"name":   name,
+			"inputs": inputs,
+		})
+	}
+
+	if len(items) == 0 {
+		return "", fmt.Errorf("no usable signatures for synthetic ABI")
+	}
+
+	abiBytes, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode synthetic ABI: %w", err)
+	}
+	return string(abiBytes), nil
+}
+
+// getPartialABIPromptContext describes unverified contracts whose functions were recovered from bytecode,
+// including which of those functions were actually called in this transaction
+func (a *ABIResolver) getPartialABIPromptContext(baggage map[string]interface{}, resolvedContracts map[string]*ContractInfo) string {
+	calledSelectors := collectCalledSelectors(baggage)
+
+	var addresses []string
+	for address, contract := range resolvedContracts {
+		if contract.Source == "bytecode" && len(contract.ParsedABI) > 0 {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		return ""
+	}
+	sort.Strings(addresses)
+
+	contextParts := []string{"### Unverified Contracts (functions recovered from bytecode):"}
+	for _, address := range addresses {
+		contract := resolvedContracts[address]
+
+		var functions, called []string
+		for _, method := range contract.ParsedABI {
+			if method.Type != "function" {
+				continue
+			}
+			functions = append(functions, method.Name)
+			if calledSelectors[address][method.Hash] {
+				called = append(called, method.Signature)
+			}
+		}
+
+		info := []string{fmt.Sprintf("Contract: %s (unverified, partial ABI)", address)}
+		if len(called) > 0 {
+			info = append(info, fmt.Sprintf("Called in this transaction: %s", strings.Join(called, ", ")))
+		}
+		if len(functions) > 8 {
+			functions = append(functions[:8], fmt.Sprintf("...and %d more", len(functions)-8))
+		}
+		info = append(info, fmt.Sprintf("Functions: %s", strings.Join(functions, ", ")))
+
+		contextParts = append(contextParts, "- "+strings.Join(info, "\n  "))
+	}
+
+	contextParts = append(contextParts, "", "Note: These names come from public signature databases, not verified source code. Treat them as likely, not certain.")
+
+	return strings.Join(contextParts, "\n")
+}
+
+// collectCalledSelectors walks the call trace and returns the selectors invoked on each contract
+func collectCalledSelectors(baggage map[string]interface{}) map[string]map[string]bool {
+	called := make(map[string]map[string]bool)
+
+	var walk func(frame map[string]interface{})
+	walk = func(frame map[string]interface{}) {
+		to, _ := frame["to"].(string)
+		input, _ := frame["input"].(string)
+		if to != "" && len(input) >= 10 {
+			to = strings.ToLower(to)
+			if called[to] == nil {
+				called[to] = make(map[string]bool)
+			}
+			called[to][strings.ToLower(input[:10])] = true
+		}
+		if calls, ok := frame["calls"].([]interface{}); ok {
+			for _, call := range calls {
+				if callMap, ok := call.(map[string]interface{}); ok {
+					walk(callMap)
+				}
+			}
+		}
+	}
+
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if trace, ok := rawData["trace"].(map[string]interface{}); ok {
+			walk(trace)
+		}
+	}
+
+	return called
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+func TestExtractFunctionSelectors(t *testing.T) {
+	// Typical solc dispatcher:
+	//   PUSH1 0xe0 SHR DUP1 PUSH4 a9059cbb EQ PUSH2 0041 JUMPI
+	//   DUP1 PUSH4 70a08231 DUP2 EQ ...      (DUPn before EQ)
+	//   PUSH4 ffffffff AND                   (mask, not a selector)
+	//   PUSH32 <data containing 63 12345678 14> (push data must be skipped)
+	bytecode := "0x60e01c80" +
+		"63a9059cbb14610041" + "57" +
+		"80" + "6370a082318114" +
+		"63ffffffff16" +
+		"7f" + "631234567814" + "000000000000000000000000000000000000000000000000000000"
+
+	selectors := ExtractFunctionSelectors(bytecode)
+	require.Equal(t, []string{"0xa9059cbb", "0x70a08231"}, selectors)
+
+	// Selectors with leading zero bytes are pushed with fewer bytes, but only count when the EQ
+	// branches like a dispatcher: PUSH3 0abcde EQ PUSH2 JUMPI, PUSH2 1234 DUP2 EQ PUSH1 JUMPI
+	bytecode = "0x80" + "620abcde14610041" + "57" +
+		"80" + "6112348114605057" +
+		"600114" + "15" + // PUSH1 01 EQ ISZERO compares a constant
+		"6163a9059cbb1457" // PUSH4 hidden inside PUSH2 data
+	require.Equal(t, []string{"0x000abcde", "0x00001234"}, ExtractFunctionSelectors(bytecode))
+
+	require.Empty(t, ExtractFunctionSelectors("0x"))
+	require.Empty(t, ExtractFunctionSelectors("not-hex"))
+}
+
+func TestBuildSyntheticABI(t *testing.T) {
+	abiJSON, err := buildSyntheticABI([]*ResolvedSignature{
+		{Signature: "0xa9059cbb", TextSignature: "transfer(address,uint256)", Type: "function"},
+		{Signature: "0x12345678", TextSignature: "execute((address,uint256,bytes)[],bytes32)", Type: "function"},
+	})
+	require.NoError(t, err)
+
+	methods, err := (&ABIResolver{}).parseABI(abiJSON)
+	require.NoError(t, err)
+	require.Len(t, methods, 2)
+	require.Equal(t, "transfer(address,uint256)", methods[0].Signature)
+	require.Equal(t, "0xa9059cbb", methods[0].Hash)
+	require.Equal(t, []string{"(address,uint256,bytes)[]", "bytes32"}, []string{methods[1].Inputs[0].Type, methods[1].Inputs[1].Type})
+}
+
+// slowBackend resolves every selector after a delay, recording how many lookups overlap
+type slowBackend struct {
+	mu           sync.Mutex
+	active, peak int
+}
+
+func (b *slowBackend) Name() string { return "slow" }
+
+func (b *slowBackend) LookupSignatures(ctx context.Context, hexSignature, sigType string) ([]string, error) {
+	b.mu.Lock()
+	b.active++
+	b.peak = max(b.peak, b.active)
+	b.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	b.mu.Lock()
+	b.active--
+	b.mu.Unlock()
+	return []string{"fn_" + hexSignature[2:] + "()"}, nil
+}
+
+func TestResolveFromBytecodeLooksUpSelectorsConcurrently(t *testing.T) {
+	const networkID = 99007
+	bytecode := "0x"
+	for i := 1; i <= 2*bytecodeLookupWorkers; i++ {
+		bytecode += fmt.Sprintf("8063%08x1461004157", i<<8)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "eth_getCode", request.Method)
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": bytecode})
+	}))
+	defer server.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: server.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+
+	backend := &slowBackend{}
+	resolver := &ABIResolver{rpcClient: client, signatureLookup: &SignatureResolver{backends: []SignatureBackend{backend}}}
+	contract, err := resolver.resolveFromBytecode(context.Background(), testRouter, networkID)
+	require.NoError(t, err)
+	require.Empty(t, contract.UnresolvedSelectors)
+	require.Len(t, contract.ParsedABI, 2*bytecodeLookupWorkers)
+	require.Equal(t, "fn_00000100()", contract.ParsedABI[0].Signature, "dispatcher order is kept")
+	require.Greater(t, backend.peak, 1, "lookups run concurrently")
+	require.LessOrEqual(t, backend.peak, bytecodeLookupWorkers, "on a bounded pool")
+}
@@ -122,12 +122,21 @@ var (
 	// ENS names change rarely but can change
 	ENSTTLDuration = time.Hour * 24 * 30 // 30 days
 
+	// ENS names at a past block never change - permanent
+	ENSHistoricalTTLDuration = time.Hour * 24 * 365 // 1 year (effectively permanent)
+
 	// Icon URLs are permanent once set
 	IconTTLDuration = time.Hour * 24 * 365 // 1 year (effectively permanent)
 
+	// Tokens without an icon may get one later (e.g. a TrustWallet PR) - retry weekly
+	IconMissingTTLDuration = time.Hour * 24 * 7 // 7 days
+
 	// Network data is permanent
 	NetworkTTLDuration = time.Hour * 24 * 365 // 1 year (effectively permanent)
 
+	// Dispatcher selectors only change if the contract is redeployed
+	BytecodeSelectorsTTLDuration = time.Hour * 24 * 30 // 30 days
+
 	// Signature data is permanent
 	SignatureTTLDuration = time.Hour * 24 * 365 // 1 year (effectively permanent)
 
@@ -148,6 +157,9 @@ var (
 
 	// Static context data is permanent
 	StaticContextTTLDuration = time.Hour * 24 * 365 // 1 year (effectively permanent)
+
+	// A pool's factory and tokens never change
+	PoolTTLDuration = time.Hour * 24 * 365 // 1 year (effectively permanent)
 )
 
 // Cache key patterns for consistent naming - includes network ID for uniqueness
@@ -157,6 +169,9 @@ const (
 	ABIFunctionKeyPattern = "abi-func-sig:%s"    // abi-func-sig:0x12345678 (universal)
 	ABIEventKeyPattern    = "abi-event-sig:%s"   // abi-event-sig:0xddf252ad... (universal)
 
+	// Bytecode selector caching - format: bytecode-selectors:networkId:address (network-specific)
+	BytecodeSelectorsKeyPattern = "bytecode-selectors:%d:%s" // bytecode-selectors:1:0x123...
+
 	// 4byte signature caching - format: 4byte-sig:type:hash (universal)
 	FunctionSigKeyPattern = "4byte-func-sig:%s"  // 4byte-func-sig:0x12345678
 	EventSigKeyPattern    = "4byte-event-sig:%s" // 4byte-event-sig:0xddf252ad...
@@ -164,9 +179,11 @@ const (
 	// Token price caching - format: erc20-price:networkId:address (network-specific)
 	TokenPriceKeyPattern = "erc20-price:%d:%s" // erc20-price:1:0x123...
 
-	// ENS caching - format: ens-name:address or ens-addr:name (universal - ENS is on mainnet)
-	ENSNameKeyPattern    = "ens-name:%s" // ens-name:0x123...
-	ENSAddressKeyPattern = "ens-addr:%s" // ens-addr:vitalik.eth
+	// ENS caching - format: ens-record:service:address or ens-addr:name (universal - each service lives on one chain)
+	ENSRecordKeyPattern  = "ens-record:%s:%s" // ens-record:ens:0x123...
+	ENSAddressKeyPattern = "ens-addr:%s"      // ens-addr:vitalik.eth
+	// Names at a past block - format: ens-record:service:address:block
+	ENSHistoricalRecordKeyPattern = "ens-record:%s:%s:%s" // ens-record:ens:0x123...:0x12d687
 
 	// Token metadata caching - format: token-meta:networkId:address
 	TokenMetadataKeyPattern = "token-meta:%d:%s" // token-meta:1:0x123...
@@ -174,6 +191,9 @@ const (
 	// Icon caching - format: token-icon:networkId:address
 	TokenIconKeyPattern = "token-icon:%d:%s" // token-icon:1:0x123...
 
+	// Proxied icon images (resized PNG) - format: icon-image:networkId:address
+	IconImageKeyPattern = "icon-image:%d:%s" // icon-image:1:0x123...
+
 	// Network caching - format: network-info:chainId
 	NetworkKeyPattern = "network-info:%d" // network-info:1
 
@@ -196,4 +216,7 @@ const (
 	StaticTokenKeyPattern    = "static-token:%s"    // static-token:0x123...
 	StaticProtocolKeyPattern = "static-protocol:%s" // static-protocol:uniswap
 	StaticAddressKeyPattern  = "static-address:%s"  // static-address:0x123...
+
+	// Verified liquidity pools - format: pool-info:networkId:address
+	PoolKeyPattern = "pool-info:%d:%s" // pool-info:1:0x123...
 )
@@ -0,0 +1,696 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/sigdb"
+)
+
+// maxUnwrapDepth bounds how deep batches nested in batches are unwrapped
+const maxUnwrapDepth = 8
+
+// batchFunction describes a function that wraps other calls and where it keeps them
+type batchFunction struct {
+	signature string
+	kind      string
+	names     []string // Parameter names, used when the contract has no verified ABI
+	head      int      // Argument index of the wrapped calls
+	fields    []string // Tuple fields of each wrapped call ("target", "allowFailure", "value", "callData"); empty for bytes[] self-calls
+	parallel  bool     // fields are separate array arguments, starting at head, rather than tuple fields
+	operation int      // Argument index of a call/delegatecall operation; 0 when the wrapped call is always a call
+	payload   []int    // Further argument indexes represented by the inner calls
+}
+
+// Safe execTransaction, whose data argument is the wrapped call
+var safeExecTransaction = batchFunction{
+	signature: "execTransaction(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,bytes)",
+	kind:      models.BatchSafe,
+	names:     []string{"to", "value", "data", "operation", "safeTxGas", "baseGas", "gasPrice", "gasToken", "refundReceiver", "signatures"},
+	head:      2,
+	operation: 3,
+}
+
+// ERC-7579 modular account execute(mode, executionCalldata). The first byte of mode is the call type.
+var erc7579Execute = batchFunction{
+	signature: "execute(bytes32,bytes)",
+	kind:      models.BatchAccount,
+	names:     []string{"mode", "executionCalldata"},
+	head:      1,
+}
+
+// ERC-7579 call types
+const (
+	erc7579CallSingle       = 0x00
+	erc7579CallBatch        = 0x01
+	erc7579CallDelegatecall = 0xff
+)
+
+// batchFunctions are the batch functions by selector. Multicall self-calls run against the same contract,
+// the Multicall3 aggregate family carries a target per call.
+var batchFunctions = indexBatchFunctions([]batchFunction{
+	{signature: "multicall(bytes[])", kind: models.BatchMulticall, names: []string{"data"}},
+	{signature: "multicall(uint256,bytes[])", kind: models.BatchMulticall, names: []string{"deadline", "data"}, head: 1},
+	{signature: "multicall(bytes32,bytes[])", kind: models.BatchMulticall, names: []string{"previousBlockhash", "data"}, head: 1},
+	{signature: "aggregate((address,bytes)[])", kind: models.BatchMulticall, names: []string{"calls"}, fields: []string{"target", "callData"}},
+	{signature: "tryAggregate(bool,(address,bytes)[])", kind: models.BatchMulticall, names: []string{"requireSuccess", "calls"}, head: 1, fields: []string{"target", "callData"}},
+	{signature: "tryBlockAndAggregate(bool,(address,bytes)[])", kind: models.BatchMulticall, names: []string{"requireSuccess", "calls"}, head: 1, fields: []string{"target", "callData"}},
+	{signature: "aggregate3((address,bool,bytes)[])", kind: models.BatchMulticall, names: []string{"calls"}, fields: []string{"target", "allowFailure", "callData"}},
+	{signature: "aggregate3Value((address,bool,uint256,bytes)[])", kind: models.BatchMulticall, names: []string{"calls"}, fields: []string{"target", "allowFailure", "value", "callData"}},
+	safeExecTransaction,
+	{signature: "multiSend(bytes)", kind: models.BatchMultiSend, names: []string{"transactions"}},
+	{signature: "execute(bytes,bytes[])", kind: models.BatchUniversalRouter, names: []string{"commands", "inputs"}, payload: []int{1}},
+	{signature: "execute(bytes,bytes[],uint256)", kind: models.BatchUniversalRouter, names: []string{"commands", "inputs", "deadline"}, payload: []int{1}},
+	// Smart accounts: SimpleAccount, Biconomy, Kernel and the Safe 4337 module, then ERC-7579 accounts
+	{signature: "execute(address,uint256,bytes)", kind: models.BatchAccount, names: []string{"dest", "value", "func"}, head: 2},
+	{signature: "execute(address,uint256,bytes,uint8)", kind: models.BatchAccount, names: []string{"to", "value", "data", "operation"}, head: 2, operation: 3},
+	{signature: "executeUserOp(address,uint256,bytes,uint8)", kind: models.BatchAccount, names: []string{"to", "value", "data", "operation"}, head: 2, operation: 3},
+	{signature: "executeUserOpWithErrorString(address,uint256,bytes,uint8)", kind: models.BatchAccount, names: []string{"to", "value", "data", "operation"}, head: 2, operation: 3},
+	{signature: "executeBatch(address[],bytes[])", kind: models.BatchAccount, names: []string{"dest", "func"}, fields: []string{"target", "callData"}, parallel: true, payload: []int{0, 1}},
+	{signature: "executeBatch(address[],uint256[],bytes[])", kind: models.BatchAccount, names: []string{"dest", "value", "func"}, fields: []string{"target", "value", "callData"}, parallel: true, payload: []int{0, 1, 2}},
+	{signature: "executeBatch((address,uint256,bytes)[])", kind: models.BatchAccount, names: []string{"calls"}, fields: []string{"target", "value", "callData"}},
+	erc7579Execute,
+})
+
+func indexBatchFunctions(functions []batchFunction) map[string]batchFunction {
+	index := make(map[string]batchFunction, len(functions))
+	for _, function := range functions {
+		index[functionSelector(function.signature)] = function
+	}
+	return index
+}
+
+// universalRouterCommand is a Universal Router command with its input encoded as a function's arguments
+type universalRouterCommand struct {
+	name      string
+	signature string // Flattened argument types; static tuples are inlined as the ABI encodes them
+	names     []string
+}
+
+// universalRouterCommands are the Universal Router command types every version shares (the low 6 bits of
+// each command byte)
+var universalRouterCommands = map[byte]universalRouterCommand{
+	0x00: {"V3_SWAP_EXACT_IN", "(address,uint256,uint256,bytes,bool)", []string{"recipient", "amountIn", "amountOutMin", "path", "payerIsUser"}},
+	0x01: {"V3_SWAP_EXACT_OUT", "(address,uint256,uint256,bytes,bool)", []string{"recipient", "amountOut", "amountInMax", "path", "payerIsUser"}},
+	0x02: {"PERMIT2_TRANSFER_FROM", "(address,address,uint160)", []string{"token", "recipient", "amount"}},
+	0x03: {"PERMIT2_PERMIT_BATCH", "", nil},
+	0x04: {"SWEEP", "(address,address,uint256)", []string{"token", "recipient", "amountMin"}},
+	0x05: {"TRANSFER", "(address,address,uint256)", []string{"token", "recipient", "value"}},
+	0x06: {"PAY_PORTION", "(address,address,uint256)", []string{"token", "recipient", "bips"}},
+	0x08: {"V2_SWAP_EXACT_IN", "(address,uint256,uint256,address[],bool)", []string{"recipient", "amountIn", "amountOutMin", "path", "payerIsUser"}},
+	0x09: {"V2_SWAP_EXACT_OUT", "(address,uint256,uint256,address[],bool)", []string{"recipient", "amountOut", "amountInMax", "path", "payerIsUser"}},
+	0x0a: {"PERMIT2_PERMIT", "(address,uint160,uint48,uint48,address,uint256,bytes)", []string{"token", "amount", "expiration", "nonce", "spender", "sigDeadline", "signature"}},
+	0x0b: {"WRAP_ETH", "(address,uint256)", []string{"recipient", "amountMin"}},
+	0x0c: {"UNWRAP_WETH", "(address,uint256)", []string{"recipient", "amountMin"}},
+	0x0d: {"PERMIT2_TRANSFER_FROM_BATCH", "", nil},
+	0x0e: {"BALANCE_CHECK_ERC20", "(address,address,uint256)", []string{"owner", "token", "minBalance"}},
+	0x21: {"EXECUTE_SUB_PLAN", "(bytes,bytes[])", []string{"commands", "inputs"}},
+}
+
+// universalRouterV1Commands are the NFT marketplace commands of Universal Router v1.2
+var universalRouterV1Commands = map[byte]universalRouterCommand{
+	0x10: {"SEAPORT_V1_5", "", nil},
+	0x11: {"LOOKS_RARE_V2", "", nil},
+	0x12: {"NFTX", "", nil},
+	0x13: {"CRYPTOPUNKS", "", nil},
+	// 0x14 was LOOKS_RARE_1155, removed in v1.2
+	0x15: {"OWNER_CHECK_721", "(address,address,uint256)", []string{"owner", "token", "id"}},
+	0x16: {"OWNER_CHECK_1155", "(address,address,uint256,uint256)", []string{"owner", "token", "id", "minBalance"}},
+	0x17: {"SWEEP_ERC721", "(address,address,uint256)", []string{"token", "recipient", "id"}},
+	0x18: {"X2Y2_721", "", nil},
+	0x19: {"SUDOSWAP", "", nil},
+	0x1a: {"NFT20", "", nil},
+	0x1b: {"X2Y2_1155", "", nil},
+	0x1c: {"FOUNDATION", "", nil},
+	0x1d: {"SWEEP_ERC1155", "(address,address,uint256,uint256)", []string{"token", "recipient", "id", "amount"}},
+	0x1e: {"ELEMENT_MARKET", "", nil},
+	0x20: {"SEAPORT_V1_4", "", nil},
+	0x22: {"APPROVE_ERC20", "(address,uint256)", []string{"token", "spender"}},
+}
+
+// universalRouterV2Commands are the Uniswap v4 and v3 position commands of Universal Router v2
+var universalRouterV2Commands = map[byte]universalRouterCommand{
+	0x10: {"V4_SWAP", "", nil},
+	0x11: {"V3_POSITION_MANAGER_PERMIT", "", nil},
+	0x12: {"V3_POSITION_MANAGER_CALL", "", nil},
+	0x13: {"V4_INITIALIZE_POOL", "", nil},
+	0x14: {"V4_POSITION_MANAGER_CALL", "", nil},
+}
+
+// universalRouterVersions maps deployed routers to the commands of their version. The versions reuse
+// 0x10-0x22 for different commands, so those stay unnamed on routers not listed here.
+var universalRouterVersions = map[string]map[byte]universalRouterCommand{
+	"0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad": universalRouterV1Commands, // v1.2, same address on every chain
+	"0x66a9893cc07d91d95644aedd05d03f95e1dba8af": universalRouterV2Commands, // v2 on Ethereum
+}
+
+// lookupUniversalRouterCommand returns the command a type stands for on a router
+func lookupUniversalRouterCommand(router string, commandType byte) (universalRouterCommand, bool) {
+	if known, exists := universalRouterCommands[commandType]; exists {
+		return known, true
+	}
+	known, exists := universalRouterVersions[strings.ToLower(router)][commandType]
+	return known, exists
+}
+
+const (
+	universalRouterAllowRevert = 0x80
+	universalRouterCommandMask = 0x3f
+	universalRouterSubPlan     = 0x21
+)
+
+// CallUnwrapper decodes the transaction's calldata into a tree of calls, unwrapping multicall(bytes[]) and
+// Multicall3 aggregates, Safe execTransaction, Safe MultiSend packed transactions, Universal Router
+// commands and smart account execute/executeBatch recursively. Working from calldata means wrapped calls are found even when the trace does not
+// show them as separate frames.
+type CallUnwrapper struct {
+	db      *sigdb.DB
+	verbose bool
+}
+
+// NewCallUnwrapper creates a new call unwrapper
+func NewCallUnwrapper(verbose bool) *CallUnwrapper {
+	return &CallUnwrapper{
+		db:      sigdb.Default(),
+		verbose: verbose,
+	}
+}
+
+// Name returns the processor name
+func (u *CallUnwrapper) Name() string {
+	return "call_unwrapper"
+}
+
+// Description returns the processor description
+func (u *CallUnwrapper) Description() string {
+	return "Unwraps multicall, Safe MultiSend and Universal Router calldata into a tree of inner calls"
+}
+
+// Dependencies returns the tools this processor depends on
+func (u *CallUnwrapper) Dependencies() []string {
+	return []string{"abi_resolver", "trace_decoder"}
+}
+
+// Process decodes the top-level call and stores the tree in baggage["call_tree"] when it wraps other calls
+func (u *CallUnwrapper) Process(ctx context.Context, baggage map[string]interface{}) error {
+	to, input, value := topLevelCall(baggage)
+	if to == "" || len(input) < 10 {
+		return nil
+	}
+	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
+
+	tree := u.decodeCall(to, input, value, "call", resolvedContracts, 0)
+	if len(tree.Calls) == 0 {
+		return nil
+	}
+
+	if u.verbose {
+		fmt.Printf("📦 Unwrapped %s into %d inner calls\n", tree.Method, countDecodedCalls(tree.Calls))
+	}
+	baggage["call_tree"] = &tree
+	return nil
+}
+
+// topLevelCall returns the target, calldata and value (decimal wei) of the transaction, from the
+// transaction itself or else the root trace frame
+func topLevelCall(baggage map[string]interface{}) (string, string, string) {
+	rawData, ok := baggage["raw_data"].(map[string]interface{})
+	if !ok {
+		return "", "", ""
+	}
+	frame, ok := rawData["transaction"].(map[string]interface{})
+	if !ok || frame == nil {
+		if frame, ok = rawData["trace"].(map[string]interface{}); !ok {
+			return "", "", ""
+		}
+	}
+	to, _ := frame["to"].(string)
+	input, _ := frame["input"].(string)
+	value, _ := frame["value"].(string)
+	return strings.ToLower(to), strings.ToLower(input), hexToDecimal(value)
+}
+
+// hexToDecimal converts a hex quantity to decimal, returning "" for zero or invalid values
+func hexToDecimal(value string) string {
+	amount, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16)
+	if !ok || amount.Sign() == 0 {
+		return ""
+	}
+	return amount.String()
+}
+
+func countDecodedCalls(calls []models.DecodedCall) int {
+	count := len(calls)
+	for _, call := range calls {
+		count += countDecodedCalls(call.Calls)
+	}
+	return count
+}
+
+// decodeCall decodes one call and, for batch functions, the calls it wraps
+func (u *CallUnwrapper) decodeCall(contract, data, value, operation string, resolvedContracts map[string]*ContractInfo, depth int) models.DecodedCall {
+	call := models.DecodedCall{Contract: strings.ToLower(contract), Value: value, Operation: operation}
+	data = strings.ToLower(data)
+	if len(data) < 10 {
+		return call
+	}
+
+	selector := data[:10]
+	signature, names := u.lookupFunction(call.Contract, selector, data, resolvedContracts)
+	batch, isBatch := batchFunctions[selector]
+	if isBatch && (signature == "" || (signature == batch.signature && len(names) == 0)) {
+		signature, names = batch.signature, batch.names
+	}
+	if signature == "" {
+		call.Method = selector
+		return call
+	}
+	call.Signature = signature
+	call.Method = signature[:strings.Index(signature, "(")]
+	call.Arguments = decodeArguments(signature, names, data)
+
+	if !isBatch || signature != batch.signature || depth >= maxUnwrapDepth {
+		return call
+	}
+	args, err := hex.DecodeString(data[10:])
+	if err != nil {
+		return call
+	}
+
+	call.Batch = batch.kind
+	switch batch.kind {
+	case models.BatchMulticall:
+		call.Calls = u.unwrapMulticall(call.Contract, args, batch, resolvedContracts, depth)
+	case models.BatchSafe:
+		call.Calls = u.unwrapSingleCall(args, batch, resolvedContracts, depth)
+	case models.BatchAccount:
+		call.Calls = u.unwrapAccountExecution(call.Contract, args, batch, resolvedContracts, depth)
+	case models.BatchMultiSend:
+		call.Calls = u.unwrapMultiSend(args, resolvedContracts, depth)
+	case models.BatchUniversalRouter:
+		call.Calls = u.unwrapUniversalRouter(call.Contract, args, resolvedContracts, depth)
+	}
+	if len(call.Calls) == 0 {
+		call.Batch = ""
+		return call
+	}
+	// The wrapped payload is represented by the inner calls
+	delete(call.Arguments, argumentName(names, batch.head))
+	for _, index := range batch.payload {
+		delete(call.Arguments, argumentName(names, index))
+	}
+	return call
+}
+
+// argumentName returns the name decodeArguments gives a parameter
+func argumentName(names []string, index int) string {
+	if index < len(names) && names[index] != "" {
+		return names[index]
+	}
+	return fmt.Sprintf("arg%d", index)
+}
+
+// lookupFunction finds a function by selector in the contract's verified ABI (with parameter names), then in
+// the signature database ranked by how well the calldata decodes
+func (u *CallUnwrapper) lookupFunction(contract, selector, data string, resolvedContracts map[string]*ContractInfo) (string, []string) {
+	if info, ok := resolvedContracts[contract]; ok && info != nil {
+		for _, method := range info.ParsedABI {
+			if method.Type != "function" || method.Hash != selector {
+				continue
+			}
+			names := make([]string, 0, len(method.Inputs))
+			for _, input := range method.Inputs {
+				names = append(names, input.Name)
+			}
+			return method.Signature, names
+		}
+	}
+
+	if u.db == nil {
+		return "", nil
+	}
+	var signatures []string
+	for _, entry := range u.db.LookupFunction(selector) {
+		signatures = append(signatures, entry.TextSignature)
+	}
+	if ranked := sigdb.RankFunctionCandidates(signatures, data); len(ranked) > 0 && ranked[0].Score > 0 {
+		return ranked[0].TextSignature, nil
+	}
+	return "", nil
+}
+
+// unwrapMulticall decodes a bytes[] of self-calls or an array of (target, [allowFailure], [value], callData)
+func (u *CallUnwrapper) unwrapMulticall(contract string, args []byte, layout batchFunction, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	elements, ok := abiElements(args, layout.head*32)
+	if !ok {
+		return nil
+	}
+
+	var calls []models.DecodedCall
+	for _, element := range elements {
+		if len(layout.fields) == 0 {
+			data, ok := abiBytesAt(args, element)
+			if !ok {
+				return nil
+			}
+			calls = append(calls, u.decodeCall(contract, "0x"+hex.EncodeToString(data), "", "delegatecall", resolvedContracts, depth+1))
+			continue
+		}
+
+		tuple := args[element:]
+		target, allowFailure, value, data := "", false, "", []byte(nil)
+		for i, field := range layout.fields {
+			if len(tuple) < (i+1)*32 {
+				return nil
+			}
+			word := tuple[i*32 : (i+1)*32]
+			switch field {
+			case "target":
+				target = "0x" + hex.EncodeToString(word[12:])
+			case "allowFailure":
+				allowFailure = word[31] == 1
+			case "value":
+				if amount := new(big.Int).SetBytes(word); amount.Sign() > 0 {
+					value = amount.String()
+				}
+			case "callData":
+				if data, ok = abiBytes(tuple, i*32); !ok {
+					return nil
+				}
+			}
+		}
+		call := u.decodeCall(target, "0x"+hex.EncodeToString(data), value, "call", resolvedContracts, depth+1)
+		call.AllowFailure = allowFailure
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// unwrapSingleCall decodes a (to, value, data[, operation]) call such as Safe execTransaction or a smart
+// account's execute; operation 1 is a delegatecall
+func (u *CallUnwrapper) unwrapSingleCall(args []byte, layout batchFunction, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	if len(args) < 3*32 || len(args) < (layout.operation+1)*32 {
+		return nil
+	}
+	data, ok := abiBytes(args, layout.head*32)
+	if !ok {
+		return nil
+	}
+	to := "0x" + hex.EncodeToString(args[12:32])
+	value := ""
+	if amount := new(big.Int).SetBytes(args[32:64]); amount.Sign() > 0 {
+		value = amount.String()
+	}
+	operation := "call"
+	if layout.operation > 0 && args[(layout.operation+1)*32-1] == 1 {
+		operation = "delegatecall"
+	}
+	return []models.DecodedCall{u.decodeCall(to, "0x"+hex.EncodeToString(data), value, operation, resolvedContracts, depth+1)}
+}
+
+// unwrapAccountExecution decodes a smart account's execute or executeBatch: a single call, parallel arrays of
+// targets, values and calldata, an array of (target, value, callData) tuples or an ERC-7579 execution
+func (u *CallUnwrapper) unwrapAccountExecution(account string, args []byte, layout batchFunction, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	switch {
+	case layout.signature == erc7579Execute.signature:
+		return u.unwrapERC7579Execution(account, args, resolvedContracts, depth)
+	case layout.parallel:
+		return u.unwrapParallelCalls(args, layout, resolvedContracts, depth)
+	case len(layout.fields) > 0:
+		return u.unwrapMulticall(account, args, layout, resolvedContracts, depth)
+	}
+	return u.unwrapSingleCall(args, layout, resolvedContracts, depth)
+}
+
+// unwrapParallelCalls decodes executeBatch(targets, [values], calldata): one array argument per field
+func (u *CallUnwrapper) unwrapParallelCalls(args []byte, layout batchFunction, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	var targets, values [][]byte
+	var data []int
+	for i, field := range layout.fields {
+		var ok bool
+		switch field {
+		case "target":
+			targets, ok = abiWords(args, (layout.head+i)*32)
+		case "value":
+			values, ok = abiWords(args, (layout.head+i)*32)
+		case "callData":
+			data, ok = abiElements(args, (layout.head+i)*32)
+		}
+		if !ok {
+			return nil
+		}
+	}
+	// Some accounts accept an empty value array to mean no value on every call
+	if len(targets) != len(data) || (len(values) > 0 && len(values) != len(targets)) {
+		return nil
+	}
+
+	calls := make([]models.DecodedCall, 0, len(targets))
+	for i, target := range targets {
+		callData, ok := abiBytesAt(args, data[i])
+		if !ok {
+			return nil
+		}
+		value := ""
+		if len(values) > 0 {
+			if amount := new(big.Int).SetBytes(values[i]); amount.Sign() > 0 {
+				value = amount.String()
+			}
+		}
+		calls = append(calls, u.decodeCall("0x"+hex.EncodeToString(target[12:]), "0x"+hex.EncodeToString(callData), value, "call", resolvedContracts, depth+1))
+	}
+	return calls
+}
+
+// unwrapERC7579Execution decodes an ERC-7579 execution: packed target (20 bytes), value (32) and calldata
+// for a single call, ABI-encoded (target, value, callData)[] for a batch, or packed target and calldata for
+// a delegatecall
+func (u *CallUnwrapper) unwrapERC7579Execution(account string, args []byte, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	if len(args) < 32 {
+		return nil
+	}
+	execution, ok := abiBytes(args, 32)
+	if !ok {
+		return nil
+	}
+
+	switch args[0] {
+	case erc7579CallSingle:
+		if len(execution) < 52 {
+			return nil
+		}
+		value := ""
+		if amount := new(big.Int).SetBytes(execution[20:52]); amount.Sign() > 0 {
+			value = amount.String()
+		}
+		to := "0x" + hex.EncodeToString(execution[:20])
+		return []models.DecodedCall{u.decodeCall(to, "0x"+hex.EncodeToString(execution[52:]), value, "call", resolvedContracts, depth+1)}
+	case erc7579CallBatch:
+		batch := batchFunction{fields: []string{"target", "value", "callData"}}
+		return u.unwrapMulticall(account, execution, batch, resolvedContracts, depth)
+	case erc7579CallDelegatecall:
+		if len(execution) < 20 {
+			return nil
+		}
+		to := "0x" + hex.EncodeToString(execution[:20])
+		return []models.DecodedCall{u.decodeCall(to, "0x"+hex.EncodeToString(execution[20:]), "", "delegatecall", resolvedContracts, depth+1)}
+	}
+	return nil
+}
+
+// unwrapMultiSend decodes MultiSend's packed transactions: operation (1 byte), to (20), value (32),
+// data length (32) and data, back to back
+func (u *CallUnwrapper) unwrapMultiSend(args []byte, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	packed, ok := abiBytes(args, 0)
+	if !ok {
+		return nil
+	}
+
+	var calls []models.DecodedCall
+	for offset := 0; offset < len(packed); {
+		if len(packed)-offset < 85 {
+			return nil
+		}
+		operation := "call"
+		if packed[offset] == 1 {
+			operation = "delegatecall"
+		}
+		to := "0x" + hex.EncodeToString(packed[offset+1:offset+21])
+		value := ""
+		if amount := new(big.Int).SetBytes(packed[offset+21 : offset+53]); amount.Sign() > 0 {
+			value = amount.String()
+		}
+		length := new(big.Int).SetBytes(packed[offset+53 : offset+85])
+		if !length.IsInt64() || length.Int64() > int64(len(packed)-offset-85) {
+			return nil
+		}
+		data := packed[offset+85 : offset+85+int(length.Int64())]
+		calls = append(calls, u.decodeCall(to, "0x"+hex.EncodeToString(data), value, operation, resolvedContracts, depth+1))
+		offset += 85 + int(length.Int64())
+	}
+	return calls
+}
+
+// unwrapUniversalRouter decodes execute(commands, inputs): one command byte per input
+func (u *CallUnwrapper) unwrapUniversalRouter(router string, args []byte, resolvedContracts map[string]*ContractInfo, depth int) []models.DecodedCall {
+	commands, ok := abiBytes(args, 0)
+	if !ok {
+		return nil
+	}
+	elements, ok := abiElements(args, 32)
+	if !ok || len(elements) != len(commands) {
+		return nil
+	}
+
+	calls := make([]models.DecodedCall, 0, len(commands))
+	for i, command := range commands {
+		input, ok := abiBytesAt(args, elements[i])
+		if !ok {
+			return nil
+		}
+		commandType := command & universalRouterCommandMask
+		call := models.DecodedCall{
+			Contract:     router,
+			Method:       fmt.Sprintf("COMMAND_0x%02x", commandType),
+			Operation:    "command",
+			AllowFailure: command&universalRouterAllowRevert != 0,
+		}
+		if known, exists := lookupUniversalRouterCommand(router, commandType); exists {
+			call.Method = known.name
+			if known.signature != "" {
+				call.Signature = known.name + known.signature
+				call.Arguments = decodeArgumentBytes(call.Signature, known.names, input)
+			}
+		}
+		if path, ok := call.Arguments["path"].(string); ok && (commandType == 0x00 || commandType == 0x01) {
+			if tokens, fees, ok := decodeV3Path(path); ok {
+				call.Arguments["tokens"] = tokens
+				call.Arguments["fees"] = fees
+			}
+		}
+		if commandType == universalRouterSubPlan && depth+1 < maxUnwrapDepth {
+			if inner := u.unwrapUniversalRouter(router, input, resolvedContracts, depth+1); len(inner) > 0 {
+				call.Batch = models.BatchUniversalRouter
+				call.Calls = inner
+				delete(call.Arguments, "commands")
+				delete(call.Arguments, "inputs")
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// decodeV3Path splits a Uniswap v3 path (token, 3-byte fee, token, ...) into its tokens and fee tiers
+func decodeV3Path(path string) ([]interface{}, []interface{}, bool) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(path, "0x"))
+	if err != nil || len(raw) < 43 || (len(raw)-20)%23 != 0 {
+		return nil, nil, false
+	}
+	tokens := []interface{}{"0x" + hex.EncodeToString(raw[:20])}
+	var fees []interface{}
+	for offset := 20; offset < len(raw); offset += 23 {
+		fees = append(fees, new(big.Int).SetBytes(raw[offset:offset+3]).String())
+		tokens = append(tokens, "0x"+hex.EncodeToString(raw[offset+3:offset+23]))
+	}
+	return tokens, fees, true
+}
+
+// abiElements follows the offset stored at head to an array of dynamic elements and returns the absolute
+// position of each element
+func abiElements(args []byte, head int) ([]int, bool) {
+	start, length, ok := abiDynamic(args, head, 32)
+	if !ok {
+		return nil, false
+	}
+	elements := make([]int, 0, length)
+	for i := 0; i < length; i++ {
+		offset := new(big.Int).SetBytes(args[start+i*32 : start+(i+1)*32])
+		if !offset.IsInt64() || offset.Int64() > int64(len(args)-start-32) {
+			return nil, false
+		}
+		elements = append(elements, start+int(offset.Int64()))
+	}
+	return elements, true
+}
+
+// abiBytesAt decodes length-prefixed bytes stored at an absolute position
+func abiBytesAt(args []byte, position int) ([]byte, bool) {
+	if position+32 > len(args) {
+		return nil, false
+	}
+	length := new(big.Int).SetBytes(args[position : position+32])
+	if !length.IsInt64() || length.Int64() > int64(len(args)-position-32) {
+		return nil, false
+	}
+	return args[position+32 : position+32+int(length.Int64())], true
+}
+
+// GetCallTree returns the unwrapped call tree from baggage
+func GetCallTree(baggage map[string]interface{}) (*models.DecodedCall, bool) {
+	tree, ok := baggage["call_tree"].(*models.DecodedCall)
+	return tree, ok && tree != nil
+}
+
+// GetPromptContext renders the call tree, one indented line per call
+func (u *CallUnwrapper) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	tree, ok := GetCallTree(baggage)
+	if !ok {
+		return ""
+	}
+
+	lines := []string{"### BATCHED CALLS (unwrapped from calldata, in execution order):"}
+	lines = append(lines, renderDecodedCall(*tree, 0)...)
+	return strings.Join(lines, "\n")
+}
+
+// renderDecodedCall renders a call and the calls it wraps, one indented line per call
+func renderDecodedCall(call models.DecodedCall, indent int) []string {
+	line := fmt.Sprintf("%s- %s %s on %s", strings.Repeat("  ", indent), call.Operation, describeDecodedCall(call), call.Contract)
+	if call.Value != "" {
+		line += fmt.Sprintf(" (value %s wei)", call.Value)
+	}
+	if call.AllowFailure {
+		line += " (may revert)"
+	}
+	if call.Batch != "" {
+		line += fmt.Sprintf(" [%s batch of %d]", call.Batch, len(call.Calls))
+	}
+	lines := []string{line}
+	for _, inner := range call.Calls {
+		lines = append(lines, renderDecodedCall(inner, indent+1)...)
+	}
+	return lines
+}
+
+// describeDecodedCall renders a call as method(name: value, ...) with arguments in a stable order
+func describeDecodedCall(call models.DecodedCall) string {
+	if call.Method == "" {
+		return "transfer"
+	}
+	keys := make([]string, 0, len(call.Arguments))
+	for key := range call.Arguments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := fmt.Sprintf("%v", call.Arguments[key])
+		if len(value) > 80 {
+			value = value[:77] + "..."
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", key, value))
+	}
+	return fmt.Sprintf("%s(%s)", call.Method, strings.Join(parts, ", "))
+}
+
+// GetRagContext provides RAG context for batched calls (none - the tree is transaction specific)
+func (u *CallUnwrapper) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+const (
+	testSafe      = "0x6666666666666666666666666666666666666666"
+	testMultiSend = "0x40a2accbd92bca938b02010e17a5b8929b49130d"
+)
+
+// encodeTail ABI-encodes dynamic bytes: length word followed by the padded content
+func encodeTail(data string) string {
+	data = strings.TrimPrefix(data, "0x")
+	padded := data + strings.Repeat("0", (64-len(data)%64)%64)
+	return abiWord(fmt.Sprintf("%x", len(data)/2)) + padded
+}
+
+// encodeBytesArray ABI-encodes a bytes[] tail: length, element offsets, element tails
+func encodeBytesArray(elements ...string) string {
+	head := abiWord(fmt.Sprintf("%x", len(elements)))
+	var tails string
+	offset := 32 * len(elements)
+	for _, element := range elements {
+		head += abiWord(fmt.Sprintf("%x", offset))
+		tail := encodeTail(element)
+		tails += tail
+		offset += len(tail) / 2
+	}
+	return head + tails
+}
+
+func encodeCall(signature string, words ...string) string {
+	data := functionSelector(signature)
+	for _, word := range words {
+		data += abiWord(word)
+	}
+	return data
+}
+
+func TestCallUnwrapperUnwrapsSafeMultiSendAndMulticall(t *testing.T) {
+	transfer := encodeCall("transfer(address,uint256)", testTrader, "64")
+	approve := encodeCall("approve(address,uint256)", testRouter, "ff")
+	multicall := functionSelector("multicall(bytes[])") + abiWord("20") + encodeBytesArray(approve)
+
+	var packed string
+	for _, tx := range []struct{ to, data string }{{testUSDC, transfer}, {testRouter, multicall}} {
+		data := strings.TrimPrefix(tx.data, "0x")
+		packed += "00" + strings.TrimPrefix(tx.to, "0x") + abiWord("0") + abiWord(fmt.Sprintf("%x", len(data)/2)) + data
+	}
+	multiSend := functionSelector("multiSend(bytes)") + abiWord("20") + encodeTail(packed)
+
+	// execTransaction(to, value, data, operation, safeTxGas, baseGas, gasPrice, gasToken, refundReceiver, signatures)
+	execTransaction := functionSelector(safeExecTransaction.signature) +
+		abiWord(testMultiSend) + abiWord("0") + abiWord(fmt.Sprintf("%x", 10*32)) + abiWord("1") +
+		abiWord("0") + abiWord("0") + abiWord("0") + abiWord("0") + abiWord("0")
+	dataTail := encodeTail(multiSend)
+	execTransaction += abiWord(fmt.Sprintf("%x", 10*32+len(dataTail)/2)) + dataTail + encodeTail("aabb")
+
+	unwrapper := NewCallUnwrapper(false)
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			// Without a transaction the root trace frame supplies the calldata
+			"trace": map[string]interface{}{"to": testSafe, "input": execTransaction, "value": "0x0"},
+		},
+	}
+	require.NoError(t, unwrapper.Process(context.Background(), baggage))
+
+	tree, ok := GetCallTree(baggage)
+	require.True(t, ok)
+	require.Equal(t, "execTransaction", tree.Method)
+	require.Equal(t, models.BatchSafe, tree.Batch)
+	require.Equal(t, "0xaabb", tree.Arguments["signatures"])
+	require.NotContains(t, tree.Arguments, "data", "the wrapped payload is replaced by the inner calls")
+
+	require.Len(t, tree.Calls, 1)
+	send := tree.Calls[0]
+	require.Equal(t, testMultiSend, send.Contract)
+	require.Equal(t, "delegatecall", send.Operation)
+	require.Equal(t, models.BatchMultiSend, send.Batch)
+
+	require.Len(t, send.Calls, 2)
+	require.Equal(t, testUSDC, send.Calls[0].Contract)
+	require.Equal(t, "transfer(address,uint256)", send.Calls[0].Signature)
+	require.Equal(t, "100", send.Calls[0].Arguments["arg1"])
+
+	nested := send.Calls[1]
+	require.Equal(t, models.BatchMulticall, nested.Batch)
+	require.Len(t, nested.Calls, 1)
+	require.Equal(t, testRouter, nested.Calls[0].Contract, "multicall runs against the contract itself")
+	require.Equal(t, "approve", nested.Calls[0].Method)
+
+	prompt := unwrapper.GetPromptContext(context.Background(), baggage)
+	require.Contains(t, prompt, "    - call multicall() on "+testRouter+" [multicall batch of 1]")
+}
+
+func TestCallUnwrapperDecodesUniversalRouterCommands(t *testing.T) {
+	path := strings.TrimPrefix(testUSDC, "0x") + "0001f4" + strings.TrimPrefix(testWETH, "0x")
+	wrap := abiWord(testRouter) + abiWord("de0b6b3a7640000")
+	swap := abiWord(testTrader) + abiWord("de0b6b3a7640000") + abiWord("1") + abiWord(fmt.Sprintf("%x", 5*32)) + abiWord("1") + encodeTail(path)
+
+	// execute(commands, inputs, deadline) with WRAP_ETH then V3_SWAP_EXACT_IN, the swap allowed to revert
+	commands := encodeTail("0b80")
+	inputs := encodeBytesArray(wrap, swap)
+	execute := functionSelector("execute(bytes,bytes[],uint256)") +
+		abiWord(fmt.Sprintf("%x", 3*32)) + abiWord(fmt.Sprintf("%x", 3*32+len(commands)/2)) + abiWord("ffff") + commands + inputs
+
+	unwrapper := NewCallUnwrapper(false)
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"transaction": map[string]interface{}{"to": testRouter, "input": execute, "value": "0xde0b6b3a7640000"},
+		},
+	}
+	require.NoError(t, unwrapper.Process(context.Background(), baggage))
+
+	tree, ok := GetCallTree(baggage)
+	require.True(t, ok)
+	require.Equal(t, models.BatchUniversalRouter, tree.Batch)
+	require.Equal(t, "1000000000000000000", tree.Value)
+	require.Equal(t, map[string]interface{}{"deadline": "65535"}, tree.Arguments)
+
+	require.Len(t, tree.Calls, 2)
+	require.Equal(t, "WRAP_ETH", tree.Calls[0].Method)
+	require.Equal(t, "command", tree.Calls[0].Operation)
+	require.Equal(t, testRouter, tree.Calls[0].Arguments["recipient"])
+
+	swapCall := tree.Calls[1]
+	require.Equal(t, "V3_SWAP_EXACT_IN", swapCall.Method)
+	require.True(t, swapCall.AllowFailure)
+	require.Equal(t, []interface{}{testUSDC, testWETH}, swapCall.Arguments["tokens"])
+	require.Equal(t, []interface{}{"500"}, swapCall.Arguments["fees"])
+
+	// Plain calls are not stored as a tree
+	delete(baggage, "call_tree")
+	baggage["raw_data"] = map[string]interface{}{
+		"transaction": map[string]interface{}{"to": testUSDC, "input": encodeCall("transfer(address,uint256)", testTrader, "1")},
+	}
+	require.NoError(t, unwrapper.Process(context.Background(), baggage))
+	require.NotContains(t, baggage, "call_tree")
+}
+
+func TestDecodeArgumentsReadsStaticArrays(t *testing.T) {
+	args, err := hex.DecodeString(abiWord("40") + abiWord("1") + abiWord("2") + abiWord(testUSDC) + abiWord(testWETH))
+	require.NoError(t, err)
+	arguments := decodeArgumentBytes("swap(address[],bool)", []string{"path", "exact"}, args)
+	require.Equal(t, []interface{}{testUSDC, testWETH}, arguments["path"])
+	require.Equal(t, true, arguments["exact"])
+}
+
+func TestCallUnwrapperUnwrapsSmartAccountBatches(t *testing.T) {
+	transfer := encodeCall("transfer(address,uint256)", testTrader, "64")
+	approve := encodeCall("approve(address,uint256)", testRouter, "ff")
+
+	// executeBatch(dest[], value[], func[]) with parallel arrays
+	targets := abiWord("2") + abiWord(testUSDC) + abiWord(testWETH)
+	values := abiWord("2") + abiWord("0") + abiWord("5")
+	calls := encodeBytesArray(transfer, approve)
+	executeBatch := functionSelector("executeBatch(address[],uint256[],bytes[])") +
+		abiWord(fmt.Sprintf("%x", 3*32)) + abiWord(fmt.Sprintf("%x", 3*32+len(targets)/2)) +
+		abiWord(fmt.Sprintf("%x", 3*32+len(targets)/2+len(values)/2)) + targets + values + calls
+
+	unwrapper := NewCallUnwrapper(false)
+	tree := unwrapper.decodeCall(testSafe, executeBatch, "", "call", nil, 0)
+	require.Equal(t, models.BatchAccount, tree.Batch)
+	require.Empty(t, tree.Arguments, "all three arrays are represented by the inner calls")
+	require.Len(t, tree.Calls, 2)
+	require.Equal(t, testUSDC, tree.Calls[0].Contract)
+	require.Equal(t, "transfer", tree.Calls[0].Method)
+	require.Equal(t, testWETH, tree.Calls[1].Contract)
+	require.Equal(t, "5", tree.Calls[1].Value)
+
+	// ERC-7579 execute(mode, executionCalldata): a single call packs target, value and calldata
+	single := strings.TrimPrefix(testUSDC, "0x") + abiWord("0") + strings.TrimPrefix(transfer, "0x")
+	execute := functionSelector("execute(bytes32,bytes)") + abiWord("0") + abiWord("40") + encodeTail(single)
+	tree = unwrapper.decodeCall(testSafe, execute, "", "call", nil, 0)
+	require.Len(t, tree.Calls, 1)
+	require.Equal(t, testUSDC, tree.Calls[0].Contract)
+	require.Equal(t, "call", tree.Calls[0].Operation)
+
+	// ... and a batch ABI-encodes (target, value, callData)[]
+	tuple := abiWord(testWETH) + abiWord("0") + abiWord("60") + encodeTail(approve)
+	batch := abiWord("20") + abiWord("1") + abiWord("20") + tuple
+	execute = functionSelector("execute(bytes32,bytes)") + "01" + strings.Repeat("0", 62) + abiWord("40") + encodeTail(batch)
+	tree = unwrapper.decodeCall(testSafe, execute, "", "call", nil, 0)
+	require.Len(t, tree.Calls, 1)
+	require.Equal(t, testWETH, tree.Calls[0].Contract)
+	require.Equal(t, "approve", tree.Calls[0].Method)
+	require.Contains(t, tree.Arguments, "mode")
+}
+
+func TestUniversalRouterCommandsDependOnTheRouterVersion(t *testing.T) {
+	// Command 0x10 with an empty input, on the v1.2 router, the v2 router and an unknown one
+	commands := encodeTail("10")
+	args, err := hex.DecodeString(abiWord("40") + abiWord(fmt.Sprintf("%x", 0x40+len(commands)/2)) + commands + encodeBytesArray(""))
+	require.NoError(t, err)
+
+	unwrapper := NewCallUnwrapper(false)
+	method := func(router string) string {
+		calls := unwrapper.unwrapUniversalRouter(router, args, nil, 0)
+		require.Len(t, calls, 1)
+		return calls[0].Method
+	}
+	require.Equal(t, "SEAPORT_V1_5", method("0x3fC91A3afd70395Cd496C647d5a6CC9D4B2b7FAD"))
+	require.Equal(t, "V4_SWAP", method("0x66a9893cc07d91d95644aedd05d03f95e1dba8af"))
+	require.Equal(t, "COMMAND_0x10", method(testRouter))
+
+	// The v1.2 NFT helpers sit after the removed 0x14 and decode with their own layouts
+	const v12 = "0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad"
+	commands = encodeTail("14151617")
+	inputs := encodeBytesArray("", abiWord(testTrader)+abiWord(testCollection)+abiWord("2a"),
+		abiWord(testTrader)+abiWord(testCollection)+abiWord("7")+abiWord("1"), abiWord(testCollection)+abiWord(testTrader)+abiWord("2a"))
+	args, err = hex.DecodeString(abiWord("40") + abiWord(fmt.Sprintf("%x", 0x40+len(commands)/2)) + commands + inputs)
+	require.NoError(t, err)
+	calls := unwrapper.unwrapUniversalRouter(v12, args, nil, 0)
+	require.Len(t, calls, 4)
+	require.Equal(t, "COMMAND_0x14", calls[0].Method)
+	require.Equal(t, "OWNER_CHECK_721", calls[1].Method)
+	require.Equal(t, map[string]interface{}{"owner": testTrader, "token": testCollection, "id": "42"}, calls[1].Arguments)
+	require.Equal(t, "OWNER_CHECK_1155", calls[2].Method)
+	require.Equal(t, "1", calls[2].Arguments["minBalance"])
+	require.Equal(t, "SWEEP_ERC721", calls[3].Method)
+	require.Equal(t, map[string]interface{}{"token": testCollection, "recipient": testTrader, "id": "42"}, calls[3].Arguments)
+}
@@ -3,28 +3,43 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/txplain/txplain/internal/models"
 	"github.com/txplain/txplain/internal/rpc"
 )
 
-// ENSResolver resolves ENS names for addresses found in transaction data
+// ENSResolver resolves ENS (and other name service) names for addresses found in transaction data
 type ENSResolver struct {
-	rpcClient *rpc.Client
-	verbose   bool
-	cache     Cache // Cache for ENS lookups
+	rpcClient       *rpc.Client
+	verbose         bool
+	cache           Cache // Cache for ENS lookups
+	nameServices    []NameService
+	metadataFetcher *NFTMetadataFetcher // Resolves IPFS/Arweave avatars
 }
 
-// NewENSResolver creates a new ENS resolver
+// NewENSResolver creates a new ENS resolver using the name services that apply to the client's network
 func NewENSResolver(cache Cache, verbose bool, rpcClient *rpc.Client) *ENSResolver {
+	var nameServices []NameService
+	if rpcClient != nil {
+		nameServices = NameServicesForNetwork(rpcClient.GetNetwork().ID, rpcClient)
+	}
+
 	return &ENSResolver{
-		rpcClient: rpcClient,
-		verbose:   verbose,
-		cache:     cache,
+		rpcClient:       rpcClient,
+		verbose:         verbose,
+		cache:           cache,
+		nameServices:    nameServices,
+		metadataFetcher: NewNFTMetadataFetcher(verbose),
 	}
 }
 
+// SetNameServices replaces the name services consulted, in priority order
+func (e *ENSResolver) SetNameServices(services ...NameService) {
+	e.nameServices = services
+}
+
 // Name returns the tool name
 func (e *ENSResolver) Name() string {
 	return "ens_resolver"
@@ -32,7 +47,7 @@ func (e *ENSResolver) Name() string {
 
 // Description returns the tool description
 func (e *ENSResolver) Description() string {
-	return "Resolves ENS names for all addresses found in transaction data"
+	return "Resolves verified ENS and L2 names (e.g. Basenames) with their text records for all addresses found in transaction data"
 }
 
 // Dependencies returns the tools this processor depends on
@@ -48,7 +63,7 @@ func (e *ENSResolver) Process(ctx context.Context, baggage map[string]interface{
 		fmt.Println(strings.Repeat("🏷️", 60))
 	}
 
-	if e.rpcClient == nil {
+	if e.rpcClient == nil && len(e.nameServices) == 0 {
 		// Update progress tracker to show the error before returning
 		if progressTracker, ok := baggage["progress_tracker"].(*models.ProgressTracker); ok {
 			progressTracker.UpdateComponent("ens_resolver", models.ComponentGroupEnrichment, "Resolving ENS Names", models.ComponentStatusRunning, "Configuration error: RPC client not set")
@@ -90,17 +105,36 @@ func (e *ENSResolver) Process(ctx context.Context, baggage map[string]interface{
 			fmt.Println(strings.Repeat("🏷️", 60) + "\n")
 		}
 		baggage["ens_names"] = make(map[string]string)
+		baggage["ens_records"] = make(map[string]*ENSRecord)
+		return nil
+	}
+
+	if len(e.nameServices) == 0 {
+		if e.verbose {
+			fmt.Println("⚠️  No name service covers this network, skipping ENS resolution")
+			fmt.Println(strings.Repeat("🏷️", 60) + "\n")
+		}
+		baggage["ens_names"] = make(map[string]string)
+		baggage["ens_records"] = make(map[string]*ENSRecord)
 		return nil
 	}
 
 	// Get progress tracker from baggage if available
 	progressTracker, hasProgress := baggage["progress_tracker"].(*models.ProgressTracker)
 
+	// Names are resolved as of the transaction's block so addresses keep the label they had at the time
+	networkID, block := e.transactionBlock(baggage)
+
 	if e.verbose {
-		fmt.Println("🔄 Resolving ENS names...")
+		if block != "" {
+			fmt.Printf("🔄 Resolving ENS names at block %s...\n", block)
+		} else {
+			fmt.Println("🔄 Resolving ENS names...")
+		}
 	}
 
 	ensNames := make(map[string]string)
+	ensRecords := make(map[string]*ENSRecord)
 	successCount := 0
 
 	// Resolve ENS names for each address with granular progress updates
@@ -115,49 +149,33 @@ func (e *ENSResolver) Process(ctx context.Context, baggage map[string]interface{
 			fmt.Printf("   [%d/%d] Resolving %s...", i+1, len(addressList), address)
 		}
 
-		var ensName string
-		var err error
-
-		// Check cache first if available
-		if e.cache != nil {
-			cacheKey := fmt.Sprintf(ENSNameKeyPattern, strings.ToLower(address))
-			if err := e.cache.GetJSON(ctx, cacheKey, &ensName); err == nil {
-				if e.verbose {
-					fmt.Printf(" ✅ (cached) %s\n", ensName)
-				}
-			} else {
-				// Cache miss, make RPC call
-				ensName, err = e.rpcClient.ResolveENSName(ctx, address)
-				if err != nil {
-					if e.verbose {
-						fmt.Printf(" ❌ Failed: %v\n", err)
-					}
-					continue // Skip this address if resolution fails
-				}
-
-				// Cache successful result (including empty results to avoid repeated lookups)
-				if cacheErr := e.cache.SetJSON(ctx, cacheKey, ensName, &ENSTTLDuration); cacheErr != nil {
-					if e.verbose {
-						fmt.Printf(" ⚠️ Cache store failed: %v\n", cacheErr)
-					}
-				}
-			}
-		} else {
-			// No cache, make RPC call directly
-			ensName, err = e.rpcClient.ResolveENSName(ctx, address)
-			if err != nil {
-				if e.verbose {
-					fmt.Printf(" ❌ Failed: %v\n", err)
-				}
-				continue // Skip this address if resolution fails
+		record, err := e.lookupAddress(ctx, address, networkID, block)
+		if err != nil {
+			if e.verbose {
+				fmt.Printf(" ❌ Failed: %v\n", err)
 			}
+			continue // Skip this address if resolution fails
+		}
+
+		ensName := ""
+		if record != nil {
+			ensName = record.Name
+			ensRecords[address] = record
+		}
+		if ensName == "" && record != nil && e.verbose {
+			fmt.Printf(" ⚪ No ENS name at the time (now %s)\n", record.CurrentName)
+			continue
 		}
 
 		if ensName != "" {
 			ensNames[address] = ensName
 			successCount++
 			if e.verbose {
-				fmt.Printf(" ✅ %s\n", ensName)
+				if record.CurrentName != "" {
+					fmt.Printf(" ✅ %s (now %s)\n", ensName, record.CurrentName)
+				} else {
+					fmt.Printf(" ✅ %s\n", ensName)
+				}
 			}
 			// Send progress update when we find an ENS name
 			if hasProgress {
@@ -197,9 +215,121 @@ func (e *ENSResolver) Process(ctx context.Context, baggage map[string]interface{
 	}
 
 	baggage["ens_names"] = ensNames
+	baggage["ens_records"] = ensRecords
 	return nil
 }
 
+// lookupAddress asks each name service in turn for a verified name, fetching text records for the first hit.
+// Services on the transaction's network are queried at the transaction's block and, when today's name differs,
+// the record carries it as CurrentName. Services on other chains (mainnet ENS for Polygon) only know current names.
+func (e *ENSResolver) lookupAddress(ctx context.Context, address string, networkID int64, block string) (*ENSRecord, error) {
+	var lastErr error
+	for _, service := range e.nameServices {
+		current, currentErr := e.lookupRecord(ctx, service, address, "")
+
+		if block == "" || service.NetworkID() != networkID {
+			if currentErr != nil {
+				lastErr = currentErr
+				continue
+			}
+			if current.Name != "" {
+				return current, nil
+			}
+			continue
+		}
+
+		historical, err := e.lookupRecord(ctx, service, address, block)
+		if err != nil {
+			// Most likely a non-archive node; today's name is better than none
+			if e.verbose {
+				fmt.Printf(" ⚠️ Lookup at block %s failed (%v), using current name", block, err)
+			}
+			if currentErr != nil {
+				lastErr = currentErr
+				continue
+			}
+			if current.Name != "" {
+				return current, nil
+			}
+			continue
+		}
+
+		if currentErr == nil && current.Name != historical.Name {
+			historical.CurrentName = current.Name
+		}
+		if historical.Name != "" || historical.CurrentName != "" {
+			return historical, nil
+		}
+	}
+
+	// Only report an error if no service could answer
+	return nil, lastErr
+}
+
+// lookupRecord resolves one service's record for an address at a block ("" for the current name).
+// Misses are cached too so repeated addresses do not cost RPC calls; names at a past block never change.
+func (e *ENSResolver) lookupRecord(ctx context.Context, service NameService, address, block string) (*ENSRecord, error) {
+	cacheKey := fmt.Sprintf(ENSRecordKeyPattern, strings.ToLower(service.Name()), strings.ToLower(address))
+	ttl := ENSTTLDuration
+	if block != "" {
+		cacheKey = fmt.Sprintf(ENSHistoricalRecordKeyPattern, strings.ToLower(service.Name()), strings.ToLower(address), block)
+		ttl = ENSHistoricalTTLDuration
+	}
+
+	if e.cache != nil {
+		var cached ENSRecord
+		if err := e.cache.GetJSON(ctx, cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	name, err := service.LookupAddress(ctx, address, block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", service.Name(), err)
+	}
+
+	record := &ENSRecord{Service: service.Name()}
+	if name != "" {
+		texts, err := service.TextRecords(ctx, name, block)
+		if err != nil && e.verbose {
+			fmt.Printf(" ⚠️ Text records for %s failed: %v", name, err)
+		}
+		record = newENSRecord(service.Name(), name, texts, e.metadataFetcher)
+	}
+	record.Block = block
+
+	if e.cache != nil {
+		if cacheErr := e.cache.SetJSON(ctx, cacheKey, record, &ttl); cacheErr != nil && e.verbose {
+			fmt.Printf(" ⚠️ Cache store failed: %v", cacheErr)
+		}
+	}
+	return record, nil
+}
+
+// transactionBlock returns the transaction's network and block number (0x-prefixed hex, "" if unknown)
+func (e *ENSResolver) transactionBlock(baggage map[string]interface{}) (int64, string) {
+	rawData, ok := baggage["raw_data"].(map[string]interface{})
+	if !ok {
+		return 0, ""
+	}
+
+	var networkID int64
+	if id, ok := rawData["network_id"].(float64); ok {
+		networkID = int64(id)
+	}
+
+	receipt, ok := rawData["receipt"].(map[string]interface{})
+	if !ok {
+		return networkID, ""
+	}
+	blockNumber, _ := receipt["blockNumber"].(string)
+	number, err := strconv.ParseUint(strings.TrimPrefix(blockNumber, "0x"), 16, 64)
+	if err != nil || number == 0 {
+		return networkID, ""
+	}
+	return networkID, fmt.Sprintf("0x%x", number)
+}
+
 // extractAllAddresses extracts all unique addresses from transaction data
 func (e *ENSResolver) extractAllAddresses(baggage map[string]interface{}) map[string]bool {
 	addresses := make(map[string]bool)
@@ -337,12 +467,19 @@ func (e *ENSResolver) GetPromptContext(ctx context.Context, baggage map[string]i
 
 	var contextParts []string
 
-	// Add ENS names section if any were resolved
-	if len(ensNames) > 0 {
-		contextParts = append(contextParts, "### ENS Names Resolved:")
+	// Add ENS names section if any were resolved (records also cover addresses that only have a name today)
+	ensRecords, _ := baggage["ens_records"].(map[string]*ENSRecord)
+	if len(ensNames) > 0 || len(ensRecords) > 0 {
+		contextParts = append(contextParts, "### ENS Names Resolved (forward-verified, as of the transaction's block):")
+		for address, record := range ensRecords {
+			if record != nil {
+				contextParts = append(contextParts, fmt.Sprintf("- %s: %s", e.shortenAddress(address), formatENSRecord(record)))
+			}
+		}
 		for address, ensName := range ensNames {
-			shortAddr := e.shortenAddress(address)
-			contextParts = append(contextParts, fmt.Sprintf("- %s: %s", shortAddr, ensName))
+			if _, ok := ensRecords[address]; !ok {
+				contextParts = append(contextParts, fmt.Sprintf("- %s: %s", e.shortenAddress(address), ensName))
+			}
 		}
 		contextParts = append(contextParts, "")
 	}
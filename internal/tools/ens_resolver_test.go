@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNameService answers from a table of block -> address -> name ("" is the current name)
+type fakeNameService struct {
+	networkID int64
+	names     map[string]map[string]string
+}
+
+func (s *fakeNameService) Name() string     { return "ENS" }
+func (s *fakeNameService) NetworkID() int64 { return s.networkID }
+
+func (s *fakeNameService) LookupAddress(ctx context.Context, address, block string) (string, error) {
+	return s.names[block][address], nil
+}
+
+func (s *fakeNameService) TextRecords(ctx context.Context, name, block string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func TestENSResolverUsesNamesAtTransactionBlock(t *testing.T) {
+	renamed := "0x1111111111111111111111111111111111111111"
+	unchanged := "0x2222222222222222222222222222222222222222"
+	newcomer := "0x3333333333333333333333333333333333333333"
+
+	service := &fakeNameService{networkID: 1, names: map[string]map[string]string{
+		"0x12d687": {renamed: "old.eth", unchanged: "same.eth"},
+		"":         {renamed: "new.eth", unchanged: "same.eth", newcomer: "later.eth"},
+	}}
+	resolver := NewENSResolver(nil, false, nil)
+	resolver.SetNameServices(service)
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(1),
+			"receipt":    map[string]interface{}{"from": renamed, "to": unchanged, "blockNumber": "0x12D687"},
+		},
+		"token_metadata": map[string]*TokenMetadata{newcomer: {}},
+	}
+	require.NoError(t, resolver.Process(context.Background(), baggage))
+
+	ensNames := baggage["ens_names"].(map[string]string)
+	require.Equal(t, map[string]string{renamed: "old.eth", unchanged: "same.eth"}, ensNames)
+
+	records := baggage["ens_records"].(map[string]*ENSRecord)
+	require.Equal(t, "new.eth", records[renamed].CurrentName)
+	require.Equal(t, "0x12d687", records[renamed].Block)
+	require.Empty(t, records[unchanged].CurrentName)
+	require.Equal(t, "later.eth", records[newcomer].CurrentName, "name registered after the transaction")
+
+	prompt := resolver.GetPromptContext(context.Background(), baggage)
+	require.Contains(t, prompt, "old.eth (ENS, at tx time; now new.eth)")
+
+	// Services on another chain cannot be pinned to this network's block
+	service.networkID = 137
+	require.NoError(t, resolver.Process(context.Background(), baggage))
+	require.Equal(t, "new.eth", baggage["ens_names"].(map[string]string)[renamed])
+}
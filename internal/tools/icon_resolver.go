@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/txplain/txplain/internal/models"
+	"golang.org/x/crypto/sha3"
 )
 
 // IconResolver discovers token icons from multiple sources (TrustWallet)
@@ -17,8 +19,9 @@ type IconResolver struct {
 	staticContextProvider *StaticContextProvider
 	discoveredIcons       map[string]string // address -> icon URL
 	verbose               bool
-	cache                 Cache // Cache for icon URLs
-	currentNetworkID      int64 // To store network ID for dynamic chain slug
+	cache                 Cache      // Cache for icon URLs
+	iconStore             *IconStore // Downloads icons once so they are served from our API (optional)
+	currentNetworkID      int64      // To store network ID for dynamic chain slug
 }
 
 // NewIconResolver creates a new icon resolver
@@ -35,6 +38,11 @@ func NewIconResolver(staticContextProvider *StaticContextProvider, cache Cache,
 	}
 }
 
+// SetIconStore makes discovered icons get downloaded into the store and referenced by their proxy URL
+func (ir *IconResolver) SetIconStore(store *IconStore) {
+	ir.iconStore = store
+}
+
 // Name returns the processor name
 func (ir *IconResolver) Name() string {
 	return "icon_resolver"
@@ -158,13 +166,22 @@ func (ir *IconResolver) getTrustWalletIcon(ctx context.Context, address string)
 		}
 	}
 
-	// Try both original address and checksummed version
-	iconURLs := []string{
-		ir.buildTrustWalletIconURL(address, networkID),
-		ir.buildTrustWalletIconURL(ir.toChecksumAddress(address), networkID),
+	// With a store the icon is downloaded once (the GET doubles as the existence check) and served by our API
+	if ir.iconStore != nil {
+		icon, err := ir.iconStore.Ensure(ctx, networkID, address, TrustWalletIconURLs(networkID, address))
+		if err != nil {
+			if ir.verbose {
+				fmt.Printf("    ⚠️ Failed to fetch TrustWallet icon for %s: %v\n", address, err)
+			}
+			return ""
+		}
+		if icon.Missing {
+			return ""
+		}
+		return IconProxyURL(networkID, address)
 	}
 
-	for _, iconURL := range iconURLs {
+	for _, iconURL := range TrustWalletIconURLs(networkID, address) {
 		if ir.checkIconExists(ctx, iconURL) {
 			// Cache successful result
 			if ir.cache != nil {
@@ -188,45 +205,37 @@ func (ir *IconResolver) getTrustWalletIcon(ctx context.Context, address string)
 	return ""
 }
 
-// hasIconInCSV checks if an address already has an icon in the static CSV data
+// hasIconInCSV checks if an address already has an icon in the static token data for the current network
 func (ir *IconResolver) hasIconInCSV(address string) bool {
 	if ir.staticContextProvider == nil {
 		return false
 	}
 
-	// Get static context and check if this address has an icon
-	ragContext := ir.staticContextProvider.GetRagContext(context.Background(), nil)
-	for _, item := range ragContext.Items {
-		if item.Type == "token" {
-			if addrVal, ok := item.Metadata["address"].(string); ok {
-				if strings.EqualFold(addrVal, address) {
-					if iconVal, ok := item.Metadata["icon"].(string); ok && iconVal != "" {
-						return true
-					}
-				}
-			}
-		}
-	}
-
-	return false
+	item, exists := ir.staticContextProvider.GetTokenInfo(ir.currentNetworkID, address)
+	return exists && item.Icon != ""
 }
 
-// buildTrustWalletIconURL constructs the TrustWallet icon URL for an address and network
-func (ir *IconResolver) buildTrustWalletIconURL(address string, networkID int64) string {
-	chainSlug, err := ir.getTrustWalletChainSlug(networkID)
+// TrustWalletIconURLs returns the TrustWallet logo URLs to try for a token. The assets repository uses
+// EIP-55 checksummed addresses in its paths; the lowercase form is kept as a fallback.
+func TrustWalletIconURLs(networkID int64, address string) []string {
+	chainSlug, err := trustWalletChainSlug(networkID)
 	if err != nil {
-		if ir.verbose {
-			fmt.Printf("    ⚠️ No TrustWallet chain slug configured for network %d: %v\n", networkID, err)
-		}
 		// Fall back to ethereum for backward compatibility
 		chainSlug = "ethereum"
 	}
 
-	return fmt.Sprintf("https://raw.githubusercontent.com/trustwallet/assets/master/blockchains/%s/assets/%s/logo.png", chainSlug, address)
+	var urls []string
+	for _, variant := range []string{toChecksumAddress(address), strings.ToLower(address)} {
+		url := fmt.Sprintf("https://raw.githubusercontent.com/trustwallet/assets/master/blockchains/%s/assets/%s/logo.png", chainSlug, variant)
+		if len(urls) == 0 || urls[0] != url {
+			urls = append(urls, url)
+		}
+	}
+	return urls
 }
 
-// getTrustWalletChainSlug gets the TrustWallet chain slug from environment variables
-func (ir *IconResolver) getTrustWalletChainSlug(networkID int64) (string, error) {
+// trustWalletChainSlug gets the TrustWallet chain slug from environment variables
+func trustWalletChainSlug(networkID int64) (string, error) {
 	// Look for network-specific environment variable
 	// Pattern: TRUSTWALLET_ASSETS_SLUG_CHAIN_<CHAIN_ID>=<SLUG>
 	envKey := fmt.Sprintf("TRUSTWALLET_ASSETS_SLUG_CHAIN_%d", networkID)
@@ -263,14 +272,21 @@ func (ir *IconResolver) getNetworkIDFromBaggage() int64 {
 	return ir.currentNetworkID
 }
 
-// toChecksumAddress converts an address to EIP-55 checksum format (simplified)
-func (ir *IconResolver) toChecksumAddress(address string) string {
-	// This is a simplified checksum implementation
-	// For production, you might want to use a proper library
-	if !strings.HasPrefix(address, "0x") {
-		address = "0x" + address
+// toChecksumAddress converts an address to EIP-55 checksum format
+func toChecksumAddress(address string) string {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	checksummed := []byte(lower)
+	for i, c := range checksummed {
+		// Letters are uppercased when the matching hash nibble is 8 or higher
+		if c >= 'a' && c <= 'f' && i < len(digest) && digest[i] >= '8' {
+			checksummed[i] = c - 'a' + 'A'
+		}
 	}
-	return address
+	return "0x" + string(checksummed)
 }
 
 // checkIconExists checks if an icon URL is accessible
@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"  // Register GIF decoder
+	_ "image/jpeg" // Register JPEG decoder
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// IconSize is the width and height of every icon served by the proxy
+	IconSize = 64
+
+	// IconProxyPathPattern is the API path icons are served from: /api/v1/icons/{chain}/{address}
+	IconProxyPathPattern = "/api/v1/icons/%d/%s"
+
+	maxIconDownloadSize = 2 << 20 // Token logos are a few KB; anything larger is not an icon
+	maxIconDimension    = 4096    // Rejects decompression bombs before decoding
+	maxMemoryIcons      = 2048    // In-process store size when no cache is configured
+)
+
+// StoredIcon is a validated, resized PNG icon (or a remembered miss)
+type StoredIcon struct {
+	Data      []byte    `json:"data,omitempty"` // PNG, IconSize x IconSize
+	ETag      string    `json:"etag,omitempty"`
+	Source    string    `json:"source,omitempty"` // Upstream URL the icon was downloaded from
+	Missing   bool      `json:"missing,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// IconStore downloads token icons once, normalizes them to PNG and keeps them in the cache so they can be
+// served from our own API instead of hotlinking third parties on every view
+type IconStore struct {
+	httpClient *http.Client
+	cache      Cache
+	verbose    bool
+
+	mu     sync.Mutex
+	memory map[string]*StoredIcon // Used when no cache is configured
+}
+
+// NewIconStore creates an icon store backed by the cache (in memory if cache is nil)
+func NewIconStore(cache Cache, verbose bool) *IconStore {
+	return &IconStore{
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		cache:   cache,
+		verbose: verbose,
+		memory:  make(map[string]*StoredIcon),
+	}
+}
+
+// IconProxyURL returns the URL an icon is served at. It is a path on this server unless PUBLIC_BASE_URL is set.
+func IconProxyURL(networkID int64, address string) string {
+	baseURL := strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/")
+	return baseURL + fmt.Sprintf(IconProxyPathPattern, networkID, strings.ToLower(address))
+}
+
+// Get returns the stored icon for a token, if it has been fetched before
+func (s *IconStore) Get(ctx context.Context, networkID int64, address string) (*StoredIcon, bool) {
+	key := fmt.Sprintf(IconImageKeyPattern, networkID, strings.ToLower(address))
+
+	if s.cache == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		icon, ok := s.memory[key]
+		return icon, ok
+	}
+
+	var icon StoredIcon
+	if err := s.cache.GetJSON(ctx, key, &icon); err != nil {
+		return nil, false
+	}
+	return &icon, true
+}
+
+// Ensure returns the stored icon for a token, downloading it from the first working source URL if it has
+// never been fetched. Misses are remembered too (for a shorter time) so unknown tokens are not retried on
+// every request. An error means no source could be reached; nothing is stored in that case.
+func (s *IconStore) Ensure(ctx context.Context, networkID int64, address string, sourceURLs []string) (*StoredIcon, error) {
+	if icon, ok := s.Get(ctx, networkID, address); ok {
+		return icon, nil
+	}
+
+	var lastErr error
+	reachable := false
+	for _, sourceURL := range sourceURLs {
+		raw, status, err := s.download(ctx, sourceURL)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reachable = true
+		if status != http.StatusOK {
+			continue
+		}
+
+		data, err := NormalizeIcon(raw)
+		if err != nil {
+			if s.verbose {
+				fmt.Printf("    ⚠️ Rejected icon %s: %v\n", sourceURL, err)
+			}
+			continue
+		}
+
+		icon := &StoredIcon{
+			Data:      data,
+			ETag:      IconETag(data),
+			Source:    sourceURL,
+			FetchedAt: time.Now().UTC(),
+		}
+		s.put(ctx, networkID, address, icon, IconTTLDuration)
+		return icon, nil
+	}
+
+	if !reachable && lastErr != nil {
+		return nil, lastErr
+	}
+
+	icon := &StoredIcon{Missing: true, FetchedAt: time.Now().UTC()}
+	s.put(ctx, networkID, address, icon, IconMissingTTLDuration)
+	return icon, nil
+}
+
+// put stores an icon in the cache or the in-process store
+func (s *IconStore) put(ctx context.Context, networkID int64, address string, icon *StoredIcon, ttl time.Duration) {
+	key := fmt.Sprintf(IconImageKeyPattern, networkID, strings.ToLower(address))
+
+	if s.cache == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.memory) >= maxMemoryIcons {
+			s.memory = make(map[string]*StoredIcon) // Crude but bounded; icons are cheap to refetch
+		}
+		s.memory[key] = icon
+		return
+	}
+
+	if err := s.cache.SetJSON(ctx, key, icon, &ttl); err != nil && s.verbose {
+		fmt.Printf("    ⚠️ Failed to store icon for %s: %v\n", address, err)
+	}
+}
+
+// download fetches a source URL, returning the body only for 200 responses
+func (s *IconStore) download(ctx context.Context, sourceURL string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sourceURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download icon: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode >= 500 {
+			return nil, resp.StatusCode, fmt.Errorf("icon source returned status %d", resp.StatusCode)
+		}
+		return nil, resp.StatusCode, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIconDownloadSize+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read icon: %w", err)
+	}
+	if len(body) > maxIconDownloadSize {
+		return nil, resp.StatusCode, nil // Treated like a missing icon
+	}
+	return body, resp.StatusCode, nil
+}
+
+// NormalizeIcon validates a PNG, JPEG or GIF image and converts it to an IconSize x IconSize PNG.
+// Non-square images are scaled to fit and centered on a transparent background. SVGs are rejected
+// because they can carry scripts.
+func NormalizeIcon(raw []byte) ([]byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("not a supported image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxIconDimension || config.Height > maxIconDimension {
+		return nil, fmt.Errorf("invalid %s dimensions %dx%d", format, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", format, err)
+	}
+
+	// Fit the longer side to IconSize
+	width, height := IconSize, IconSize
+	if config.Width > config.Height {
+		height = max(1, config.Height*IconSize/config.Width)
+	} else if config.Height > config.Width {
+		width = max(1, config.Width*IconSize/config.Height)
+	}
+	scaled := resizeImage(src, width, height)
+
+	dst := image.NewRGBA(image.Rect(0, 0, IconSize, IconSize))
+	offset := image.Pt((IconSize-width)/2, (IconSize-height)/2)
+	draw.Draw(dst, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeImage scales an image with an area-averaging (box) filter, which is good enough for downscaling logos
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	// Work on premultiplied RGBA so transparent pixels don't bleed color into edges
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	srcW, srcH := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, max((y+1)*srcH/height, y*srcH/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, max((x+1)*srcW/width, x*srcW/width+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint32(pixel[0])
+					g += uint32(pixel[1])
+					b += uint32(pixel[2])
+					a += uint32(pixel[3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// Identicon renders a deterministic 5x5 mirrored block icon for an address, used when no real icon exists
+func Identicon(address string) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(strings.ToLower(address)))
+	sum := hash.Sum(nil)
+
+	// Keep colors away from white so the blocks stay visible on light backgrounds
+	foreground := color.RGBA{R: 40 + sum[0]%160, G: 40 + sum[1]%160, B: 40 + sum[2]%160, A: 255}
+	background := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+	const cells, cellSize = 5, 12
+	margin := (IconSize - cells*cellSize) / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, IconSize, IconSize))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+	for row := 0; row < cells; row++ {
+		for col := 0; col < (cells+1)/2; col++ {
+			if sum[3+row*3+col]%2 == 0 {
+				continue
+			}
+			for _, c := range []int{col, cells - 1 - col} {
+				cell := image.Rect(margin+c*cellSize, margin+row*cellSize, margin+(c+1)*cellSize, margin+(row+1)*cellSize)
+				draw.Draw(img, cell, &image.Uniform{C: foreground}, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// IconETag hashes icon bytes into a strong HTTP entity tag
+func IconETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
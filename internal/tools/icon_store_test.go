@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestNormalizeIcon(t *testing.T) {
+	data, err := NormalizeIcon(testPNG(t, 200, 100))
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, IconSize, IconSize), img.Bounds())
+
+	// Wide image is letterboxed: opaque in the middle, transparent above
+	_, _, _, alpha := img.At(IconSize/2, IconSize/2).RGBA()
+	require.Equal(t, uint32(0xffff), alpha)
+	_, _, _, alpha = img.At(IconSize/2, 2).RGBA()
+	require.Zero(t, alpha)
+
+	_, err = NormalizeIcon([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+	require.Error(t, err)
+}
+
+func TestIconStoreDownloadsOnce(t *testing.T) {
+	logo := testPNG(t, 256, 256)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(logo)
+	}))
+	defer server.Close()
+
+	store := NewIconStore(nil, false)
+	ctx := context.Background()
+
+	icon, err := store.Ensure(ctx, 1, testWETH, []string{server.URL + "/missing.png", server.URL + "/logo.png"})
+	require.NoError(t, err)
+	require.False(t, icon.Missing)
+	require.Equal(t, server.URL+"/logo.png", icon.Source)
+	require.Equal(t, IconETag(icon.Data), icon.ETag)
+
+	again, err := store.Ensure(ctx, 1, testWETH, []string{server.URL + "/logo.png"})
+	require.NoError(t, err)
+	require.Equal(t, icon.ETag, again.ETag)
+	require.Equal(t, 2, requests, "stored icons are not downloaded again")
+
+	missing, err := store.Ensure(ctx, 1, testSeller, []string{server.URL + "/missing.png"})
+	require.NoError(t, err)
+	require.True(t, missing.Missing)
+	_, err = store.Ensure(ctx, 1, testSeller, []string{server.URL + "/missing.png"})
+	require.NoError(t, err)
+	require.Equal(t, 3, requests, "misses are remembered")
+}
+
+func TestIdenticonIsDeterministic(t *testing.T) {
+	first := Identicon(testWETH)
+	require.Equal(t, first, Identicon(testWETH))
+	require.NotEqual(t, first, Identicon(testSeller))
+
+	img, err := png.Decode(bytes.NewReader(first))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, IconSize, IconSize), img.Bounds())
+}
+
+func TestTrustWalletIconURLsUseChecksumAddress(t *testing.T) {
+	urls := TrustWalletIconURLs(1, "0xdac17f958d2ee523a2206206994597c13d831ec7")
+	require.Equal(t, "https://raw.githubusercontent.com/trustwallet/assets/master/blockchains/ethereum/assets/0xdAC17F958D2ee523a2206206994597C13D831ec7/logo.png", urls[0])
+}
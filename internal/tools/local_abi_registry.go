@@ -0,0 +1,433 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLocalABIDir is used when LOCAL_ABI_DIR is not set
+const DefaultLocalABIDir = "data/abis"
+
+// LocalABIEntry is a single ABI file known to the local registry
+type LocalABIEntry struct {
+	ChainID         int64     `json:"chain_id"`
+	Address         string    `json:"address"`
+	ContractName    string    `json:"contract_name,omitempty"`
+	CompilerVersion string    `json:"compiler_version,omitempty"`
+	Format          string    `json:"format"` // abi, hardhat, foundry, etherscan
+	ABI             string    `json:"abi"`
+	Path            string    `json:"path"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// LocalABIRegistry serves contract ABIs from a directory laid out as <dir>/<chainId>/<address>.json.
+// Files may contain a plain ABI array or a Hardhat/Foundry build artifact.
+type LocalABIRegistry struct {
+	dir     string
+	verbose bool
+
+	mu       sync.RWMutex
+	entries  map[string]*LocalABIEntry // key: chainID:lowercase address
+	modTimes map[string]time.Time      // file path -> last seen modification time
+}
+
+// NewLocalABIRegistry creates a registry for the given directory and loads it.
+// An empty dir falls back to LOCAL_ABI_DIR and then DefaultLocalABIDir.
+func NewLocalABIRegistry(dir string, verbose bool) *LocalABIRegistry {
+	if dir == "" {
+		dir = os.Getenv("LOCAL_ABI_DIR")
+	}
+	if dir == "" {
+		dir = DefaultLocalABIDir
+	}
+
+	r := &LocalABIRegistry{
+		dir:      dir,
+		verbose:  verbose,
+		entries:  make(map[string]*LocalABIEntry),
+		modTimes: make(map[string]time.Time),
+	}
+
+	if err := r.Reload(); err != nil {
+		fmt.Printf("Warning: failed to load local ABI registry from %s: %v\n", dir, err)
+	}
+
+	return r
+}
+
+// Dir returns the directory backing the registry
+func (r *LocalABIRegistry) Dir() string {
+	return r.dir
+}
+
+// Reload rescans the registry directory and replaces the in-memory index
+func (r *LocalABIRegistry) Reload() error {
+	entries := make(map[string]*LocalABIEntry)
+	modTimes := r.scanModTimes()
+
+	chainDirs, err := os.ReadDir(r.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No directory simply means no local ABIs
+			r.mu.Lock()
+			r.entries = entries
+			r.modTimes = modTimes
+			r.mu.Unlock()
+			return nil
+		}
+		return fmt.Errorf("failed to read ABI directory: %w", err)
+	}
+
+	for _, chainDir := range chainDirs {
+		if !chainDir.IsDir() {
+			continue
+		}
+		chainID, err := strconv.ParseInt(chainDir.Name(), 10, 64)
+		if err != nil {
+			continue // Not a chain directory
+		}
+
+		chainPath := filepath.Join(r.dir, chainDir.Name())
+		files, err := os.ReadDir(chainPath)
+		if err != nil {
+			return fmt.Errorf("failed to read chain directory %s: %w", chainPath, err)
+		}
+
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(strings.ToLower(file.Name()), ".json") {
+				continue
+			}
+
+			address := strings.ToLower(strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())))
+			if !r.isValidAddress(address) {
+				if r.verbose {
+					fmt.Printf("⚠️  Skipping local ABI file with non-address name: %s\n", file.Name())
+				}
+				continue
+			}
+
+			path := filepath.Join(chainPath, file.Name())
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				fmt.Printf("Warning: failed to read local ABI %s: %v\n", path, err)
+				continue
+			}
+
+			entry, err := parseLocalABIContent(content)
+			if err != nil {
+				fmt.Printf("Warning: invalid local ABI %s: %v\n", path, err)
+				continue
+			}
+			entry.ChainID = chainID
+			entry.Address = address
+			entry.Path = path
+			entry.UpdatedAt = info.ModTime()
+
+			entries[r.key(chainID, address)] = entry
+		}
+	}
+
+	r.mu.Lock()
+	r.entries = entries
+	r.modTimes = modTimes
+	r.mu.Unlock()
+
+	if r.verbose {
+		fmt.Printf("📂 Loaded %d local ABIs from %s\n", len(entries), r.dir)
+	}
+
+	return nil
+}
+
+// Watch polls the registry directory and reloads it whenever files are added, changed or removed.
+// It blocks until the context is cancelled.
+func (r *LocalABIRegistry) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r.hasChanged() {
+				if err := r.Reload(); err != nil {
+					fmt.Printf("Warning: failed to reload local ABI registry: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// Lookup returns the local ABI for a contract on the given chain
+func (r *LocalABIRegistry) Lookup(chainID int64, address string) (*LocalABIEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, exists := r.entries[r.key(chainID, address)]
+	return entry, exists
+}
+
+// List returns all local ABIs ordered by chain and address
+func (r *LocalABIRegistry) List() []*LocalABIEntry {
+	r.mu.RLock()
+	entries := make([]*LocalABIEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChainID != entries[j].ChainID {
+			return entries[i].ChainID < entries[j].ChainID
+		}
+		return entries[i].Address < entries[j].Address
+	})
+
+	return entries
+}
+
+// Save validates an ABI or build artifact and writes it to the registry directory
+func (r *LocalABIRegistry) Save(chainID int64, address string, content []byte) (*LocalABIEntry, error) {
+	address = strings.ToLower(address)
+	if !r.isValidAddress(address) {
+		return nil, fmt.Errorf("invalid contract address: %s", address)
+	}
+
+	entry, err := parseLocalABIContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	chainPath := filepath.Join(r.dir, strconv.FormatInt(chainID, 10))
+	if err := os.MkdirAll(chainPath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create chain directory: %w", err)
+	}
+
+	// Write to a temp file first so the watcher never sees a partial file
+	path := filepath.Join(chainPath, address+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write ABI file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write ABI file: %w", err)
+	}
+
+	entry.ChainID = chainID
+	entry.Address = address
+	entry.Path = path
+	entry.UpdatedAt = time.Now()
+	if info, err := os.Stat(path); err == nil {
+		entry.UpdatedAt = info.ModTime()
+	}
+
+	r.mu.Lock()
+	r.entries[r.key(chainID, address)] = entry
+	r.modTimes[path] = entry.UpdatedAt
+	r.mu.Unlock()
+
+	return entry, nil
+}
+
+// Delete removes a local ABI from disk and from the index
+func (r *LocalABIRegistry) Delete(chainID int64, address string) error {
+	entry, exists := r.Lookup(chainID, address)
+	if !exists {
+		return fmt.Errorf("no local ABI for %s on chain %d", address, chainID)
+	}
+
+	if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete ABI file: %w", err)
+	}
+
+	r.mu.Lock()
+	delete(r.entries, r.key(chainID, address))
+	delete(r.modTimes, entry.Path)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// hasChanged reports whether any ABI file was added, modified or removed since the last load
+func (r *LocalABIRegistry) hasChanged() bool {
+	current := r.scanModTimes()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(current) != len(r.modTimes) {
+		return true
+	}
+	for path, modTime := range current {
+		if known, exists := r.modTimes[path]; !exists || !known.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanModTimes collects the modification times of every JSON file under the registry directory
+func (r *LocalABIRegistry) scanModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	filepath.WalkDir(r.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".json") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+		return nil
+	})
+	return modTimes
+}
+
+func (r *LocalABIRegistry) key(chainID int64, address string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(address))
+}
+
+// isValidAddress checks if a string looks like a valid Ethereum address
+func (r *LocalABIRegistry) isValidAddress(addr string) bool {
+	if len(addr) != 42 || !strings.HasPrefix(addr, "0x") {
+		return false
+	}
+	for _, char := range addr[2:] {
+		if !((char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLocalABIContent extracts the ABI from a plain ABI array, a Hardhat artifact,
+// a Foundry artifact or an Etherscan-style {ABI, ContractName} object
+func parseLocalABIContent(content []byte) (*LocalABIEntry, error) {
+	trimmed := strings.TrimSpace(string(content))
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty ABI file")
+	}
+
+	// Plain ABI array
+	if strings.HasPrefix(trimmed, "[") {
+		if err := validateABIArray(trimmed); err != nil {
+			return nil, err
+		}
+		return &LocalABIEntry{Format: "abi", ABI: trimmed}, nil
+	}
+
+	var artifact map[string]interface{}
+	if err := json.Unmarshal([]byte(trimmed), &artifact); err != nil {
+		return nil, fmt.Errorf("failed to parse ABI file: %w", err)
+	}
+
+	entry := &LocalABIEntry{}
+
+	// The ABI may be embedded as an array or as a JSON string (Etherscan style)
+	var abiRaw interface{}
+	for _, key := range []string{"abi", "ABI"} {
+		if value, ok := artifact[key]; ok {
+			abiRaw = value
+			break
+		}
+	}
+	switch v := abiRaw.(type) {
+	case []interface{}:
+		abiBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode ABI: %w", err)
+		}
+		entry.ABI = string(abiBytes)
+	case string:
+		entry.ABI = strings.TrimSpace(v)
+	default:
+		return nil, fmt.Errorf("no abi field found in artifact")
+	}
+
+	if err := validateABIArray(entry.ABI); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case artifact["_format"] != nil || artifact["contractName"] != nil:
+		// Hardhat artifact: {_format, contractName, sourceName, abi, bytecode, ...}
+		entry.Format = "hardhat"
+		entry.ContractName, _ = artifact["contractName"].(string)
+	case artifact["metadata"] != nil || artifact["deployedBytecode"] != nil:
+		// Foundry artifact: {abi, bytecode, deployedBytecode, metadata, ...}
+		entry.Format = "foundry"
+		entry.ContractName, entry.CompilerVersion = parseFoundryMetadata(artifact["metadata"])
+	case artifact["ContractName"] != nil:
+		entry.Format = "etherscan"
+		entry.ContractName, _ = artifact["ContractName"].(string)
+		entry.CompilerVersion, _ = artifact["CompilerVersion"].(string)
+	default:
+		entry.Format = "abi"
+	}
+
+	return entry, nil
+}
+
+// parseFoundryMetadata reads the contract name and compiler version from Foundry's
+// metadata field, which is either an object or a JSON-encoded string
+func parseFoundryMetadata(raw interface{}) (string, string) {
+	var metadata map[string]interface{}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		metadata = v
+	case string:
+		if err := json.Unmarshal([]byte(v), &metadata); err != nil {
+			return "", ""
+		}
+	default:
+		return "", ""
+	}
+
+	var contractName, compilerVersion string
+	if compiler, ok := metadata["compiler"].(map[string]interface{}); ok {
+		compilerVersion, _ = compiler["version"].(string)
+	}
+	if settings, ok := metadata["settings"].(map[string]interface{}); ok {
+		if targets, ok := settings["compilationTarget"].(map[string]interface{}); ok {
+			for _, name := range targets {
+				if s, ok := name.(string); ok {
+					contractName = s
+					break
+				}
+			}
+		}
+	}
+
+	return contractName, compilerVersion
+}
+
+// validateABIArray ensures the ABI is a JSON array of typed entries
+func validateABIArray(abiJSON string) error {
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(abiJSON), &items); err != nil {
+		return fmt.Errorf("ABI is not a valid JSON array: %w", err)
+	}
+	for i, item := range items {
+		if _, ok := item["type"].(string); !ok {
+			return fmt.Errorf("ABI entry %d has no type", i)
+		}
+	}
+	return nil
+}
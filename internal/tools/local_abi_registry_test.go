@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testTransferABI = `[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}]`
+
+func TestParseLocalABIContent(t *testing.T) {
+	t.Run("plain abi array", func(t *testing.T) {
+		entry, err := parseLocalABIContent([]byte(testTransferABI))
+		require.NoError(t, err)
+		require.Equal(t, "abi", entry.Format)
+	})
+
+	t.Run("hardhat artifact", func(t *testing.T) {
+		artifact := `{"_format":"hh-sol-artifact-1","contractName":"Vault","sourceName":"contracts/Vault.sol","abi":` + testTransferABI + `}`
+		entry, err := parseLocalABIContent([]byte(artifact))
+		require.NoError(t, err)
+		require.Equal(t, "hardhat", entry.Format)
+		require.Equal(t, "Vault", entry.ContractName)
+	})
+
+	t.Run("foundry artifact", func(t *testing.T) {
+		artifact := `{"abi":` + testTransferABI + `,"deployedBytecode":{"object":"0x"},"metadata":{"compiler":{"version":"0.8.24+commit.e11b9ed9"},"settings":{"compilationTarget":{"src/Vault.sol":"Vault"}}}}`
+		entry, err := parseLocalABIContent([]byte(artifact))
+		require.NoError(t, err)
+		require.Equal(t, "foundry", entry.Format)
+		require.Equal(t, "Vault", entry.ContractName)
+		require.Equal(t, "0.8.24+commit.e11b9ed9", entry.CompilerVersion)
+	})
+
+	t.Run("invalid abi", func(t *testing.T) {
+		_, err := parseLocalABIContent([]byte(`{"abi":[{"name":"x"}]}`))
+		require.Error(t, err)
+	})
+}
+
+func TestLocalABIRegistry_SaveLookupDelete(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewLocalABIRegistry(dir, false)
+	address := "0x1111111111111111111111111111111111111111"
+
+	_, err := registry.Save(1337, address, []byte(testTransferABI))
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, "1337", address+".json"))
+
+	entry, ok := registry.Lookup(1337, "0x1111111111111111111111111111111111111111")
+	require.True(t, ok)
+	require.Equal(t, int64(1337), entry.ChainID)
+
+	_, ok = registry.Lookup(1, address)
+	require.False(t, ok, "ABIs are keyed by chain")
+
+	// A fresh registry picks up the file from disk
+	require.Len(t, NewLocalABIRegistry(dir, false).List(), 1)
+
+	require.NoError(t, registry.Delete(1337, address))
+	_, err = os.Stat(filepath.Join(dir, "1337", address+".json"))
+	require.True(t, os.IsNotExist(err))
+}
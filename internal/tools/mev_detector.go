@@ -0,0 +1,811 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// mevReceiptWindow is how many transactions on each side of the explained one have their receipts fetched one by
+// one when the node does not support eth_getBlockReceipts. Sandwich legs and JIT positions sit right next to
+// their victims.
+const mevReceiptWindow = 5
+
+var (
+	uniswapV2SwapTopic    = eventTopic("Swap(address,uint256,uint256,uint256,uint256,address)")
+	uniswapV3SwapTopic    = eventTopic("Swap(address,address,int256,int256,uint160,uint128,int24)")
+	uniswapV3MintTopic    = eventTopic("Mint(address,address,int24,int24,uint128,uint256,uint256)")
+	uniswapV3BurnTopic    = eventTopic("Burn(address,int24,int24,uint128,uint256,uint256)")
+	uniswapV3CollectTopic = eventTopic("Collect(address,address,int24,int24,uint128,uint128)")
+
+	aaveLiquidationCallTopic     = eventTopic("LiquidationCall(address,address,address,uint256,uint256,address,bool)")
+	compoundLiquidateBorrowTopic = eventTopic("LiquidateBorrow(address,address,uint256,address,uint256)")
+	selectorComptroller          = functionSelector("comptroller()")
+	selectorLiquidationIncentive = functionSelector("liquidationIncentiveMantissa()")
+)
+
+// poolSwap is one swap against a pool, read from the token transfers into and out of it
+type poolSwap struct {
+	pool      string
+	tokenIn   string
+	tokenOut  string
+	amountIn  *big.Int
+	amountOut *big.Int
+	payer     string // Sender of the first transfer into the pool
+	recipient string // Recipient of the first transfer out of the pool
+}
+
+// liquidityChange is a Uniswap V3 position minted or burned in a pool
+type liquidityChange struct {
+	pool      string
+	position  string // Pool, owner and tick range
+	mint      bool
+	recipient string      // Recipient of the collected tokens, for burns collected in the same transaction
+	earned    []assetFlow // Fees collected on top of the burned liquidity
+}
+
+// mevTransaction is a transaction of the block reduced to its swaps and liquidity changes
+type mevTransaction struct {
+	hash      string
+	from      string
+	to        string
+	swaps     []poolSwap
+	liquidity []liquidityChange
+}
+
+// MEVDetector finds the MEV patterns a transaction took part in: sandwiches around its swaps or with it as a
+// leg, atomic arbitrage (swaps in a cycle that end in profit), just-in-time liquidity added right before a
+// swap and removed right after, and Aave and Compound V2 liquidations. Arbitrage and liquidations are read from
+// the transaction alone; sandwiches and JIT liquidity compare its swaps with those of the other transactions of
+// its block, which are only fetched when it swaps or changes liquidity in a Uniswap V2 or V3 style pool.
+// Extracted values are before gas and builder tips.
+type MEVDetector struct {
+	rpcClient *rpc.Client
+	verbose   bool
+}
+
+// NewMEVDetector creates a new MEV detector. Without an RPC client only arbitrage and liquidations are detected,
+// and Compound liquidation bonuses are unknown.
+func NewMEVDetector(rpcClient *rpc.Client, verbose bool) *MEVDetector {
+	return &MEVDetector{
+		rpcClient: rpcClient,
+		verbose:   verbose,
+	}
+}
+
+// Name returns the processor name
+func (d *MEVDetector) Name() string {
+	return "mev_detector"
+}
+
+// Description returns the processor description
+func (d *MEVDetector) Description() string {
+	return "Detects sandwiches, atomic arbitrage, JIT liquidity and liquidations using the other transactions of the block"
+}
+
+// Dependencies returns the tools this processor depends on
+func (d *MEVDetector) Dependencies() []string {
+	return []string{"log_decoder", "token_metadata_enricher", "erc20_price_lookup", "pool_resolver"}
+}
+
+// Process stores the MEV patterns in baggage["mev"]
+func (d *MEVDetector) Process(ctx context.Context, baggage map[string]interface{}) error {
+	if transactionFailed(baggage) {
+		return nil // A reverted transaction swapped nothing
+	}
+	events, _ := baggage["events"].([]models.Event)
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	receipt, _ := rawData["receipt"].(map[string]interface{})
+	target := newMEVTransaction(rawData["tx_hash"], receipt, events)
+
+	amounts := &actionContext{}
+	amounts.metadata, _ = baggage["token_metadata"].(map[string]*TokenMetadata)
+	amounts.prices, _ = baggage["token_prices"].(map[string]*TokenPrice)
+	amounts.pools, _ = baggage["pools"].(map[string]*PoolInfo)
+
+	found := d.liquidations(ctx, events, amounts)
+	if arbitrage := atomicArbitrage(target, amounts); arbitrage != nil {
+		found = append(found, *arbitrage)
+	}
+	if len(target.swaps) > 0 || len(target.liquidity) > 0 {
+		if block := d.blockTransactions(ctx, rawData, target); block != nil {
+			found = append(found, sandwiches(block, target, amounts)...)
+			found = append(found, jitLiquidity(block, target, amounts)...)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	if d.verbose {
+		for _, mev := range found {
+			fmt.Printf("🥪 %s (%s) by %s\n", mev.Kind, mev.Role, mev.Searcher)
+		}
+	}
+
+	baggage["mev"] = found
+	return nil
+}
+
+// newMEVTransaction reduces a transaction to its swaps and liquidity changes
+func newMEVTransaction(hash interface{}, receipt map[string]interface{}, events []models.Event) *mevTransaction {
+	tx := &mevTransaction{swaps: poolSwaps(events), liquidity: liquidityChanges(events)}
+	if text, ok := hash.(string); ok {
+		tx.hash = strings.ToLower(text)
+	}
+	if from, ok := receipt["from"].(string); ok {
+		tx.from = strings.ToLower(from)
+	}
+	if to, ok := receipt["to"].(string); ok {
+		tx.to = strings.ToLower(to)
+	}
+	return tx
+}
+
+// blockTransactions returns the transactions of the target's block in order, with the target itself taken from
+// baggage, or nil if the block or the target's position in it is unavailable
+func (d *MEVDetector) blockTransactions(ctx context.Context, rawData map[string]interface{}, target *mevTransaction) []*mevTransaction {
+	if d.rpcClient == nil || target.hash == "" {
+		return nil
+	}
+	blockData, _ := rawData["block"].(map[string]interface{})
+	number, err := parseHexUint(blockData["number"])
+	if err != nil {
+		receipt, _ := rawData["receipt"].(map[string]interface{})
+		if number, err = parseHexUint(receipt["blockNumber"]); err != nil {
+			return nil
+		}
+	}
+	blockNumber := fmt.Sprintf("0x%x", number)
+	block, err := d.rpcClient.GetBlockWithTransactions(ctx, blockNumber)
+	if err != nil {
+		if d.verbose {
+			fmt.Printf("⚠️  MEV detection skipped, block %s unavailable: %v\n", blockNumber, err)
+		}
+		return nil
+	}
+	transactions, _ := block["transactions"].([]interface{})
+	position := -1
+	for i, item := range transactions {
+		if tx, ok := item.(map[string]interface{}); ok {
+			if hash, _ := tx["hash"].(string); strings.EqualFold(hash, target.hash) {
+				position = i
+			}
+		}
+	}
+	if position < 0 {
+		return nil
+	}
+
+	receipts := make(map[string]map[string]interface{})
+	if all, err := d.rpcClient.GetBlockReceipts(ctx, blockNumber); err == nil {
+		for _, receipt := range all {
+			if hash, ok := receipt["transactionHash"].(string); ok {
+				receipts[strings.ToLower(hash)] = receipt
+			}
+		}
+	}
+	result := make([]*mevTransaction, len(transactions))
+	for i, item := range transactions {
+		if i == position {
+			result[i] = target
+			continue
+		}
+		tx, _ := item.(map[string]interface{})
+		hash, _ := tx["hash"].(string)
+		hash = strings.ToLower(hash)
+		receipt, ok := receipts[hash]
+		if !ok && i >= position-mevReceiptWindow && i <= position+mevReceiptWindow {
+			receipt, _ = d.rpcClient.GetReceipt(ctx, hash)
+		}
+		if status, _ := receipt["status"].(string); status == "0x0" {
+			receipt = nil // Reverted, nothing was swapped
+		}
+		result[i] = newMEVTransaction(hash, tx, receiptEvents(receipt))
+	}
+	return result
+}
+
+// receiptEvents reads the raw logs of a receipt as undecoded events
+func receiptEvents(receipt map[string]interface{}) []models.Event {
+	logs, _ := receipt["logs"].([]interface{})
+	events := make([]models.Event, 0, len(logs))
+	for _, item := range logs {
+		log, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var event models.Event
+		event.Contract, _ = log["address"].(string)
+		event.Data, _ = log["data"].(string)
+		topics, _ := log["topics"].([]interface{})
+		for _, topic := range topics {
+			if text, ok := topic.(string); ok {
+				event.Topics = append(event.Topics, text)
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// poolSwaps finds the pools emitting Uniswap V2 or V3 style Swap events and reads each swap from the token
+// transfers into and out of the pool. Pools that received or sent more than one token are skipped.
+func poolSwaps(events []models.Event) []poolSwap {
+	var pools []string
+	for _, event := range events {
+		if len(event.Topics) == 0 {
+			continue
+		}
+		topic := strings.ToLower(event.Topics[0])
+		if pool := strings.ToLower(event.Contract); (topic == uniswapV2SwapTopic || topic == uniswapV3SwapTopic) && !containsString(pools, pool) {
+			pools = append(pools, pool)
+		}
+	}
+	if len(pools) == 0 {
+		return nil
+	}
+
+	flows := assetFlowsFromEvents(events)
+	var swaps []poolSwap
+	for _, pool := range pools {
+		swap := poolSwap{pool: pool}
+		in, out := make(map[string]*big.Int), make(map[string]*big.Int)
+		for _, flow := range flows {
+			switch {
+			case flow.tokenID != "" || flow.from == flow.to:
+			case flow.to == pool:
+				if in[flow.token] == nil {
+					in[flow.token] = new(big.Int)
+				}
+				in[flow.token].Add(in[flow.token], flow.amount)
+				if swap.payer == "" {
+					swap.payer = flow.from
+				}
+			case flow.from == pool:
+				if out[flow.token] == nil {
+					out[flow.token] = new(big.Int)
+				}
+				out[flow.token].Add(out[flow.token], flow.amount)
+				if swap.recipient == "" {
+					swap.recipient = flow.to
+				}
+			}
+		}
+		if len(in) != 1 || len(out) != 1 {
+			continue
+		}
+		for token, amount := range in {
+			swap.tokenIn, swap.amountIn = token, amount
+		}
+		for token, amount := range out {
+			swap.tokenOut, swap.amountOut = token, amount
+		}
+		if swap.tokenIn != swap.tokenOut {
+			swaps = append(swaps, swap)
+		}
+	}
+	return swaps
+}
+
+// liquidityChanges reads the Uniswap V3 positions minted and burned in a transaction. The fees earned by a burned
+// position are what its Collect paid out beyond the burned liquidity; the collected tokens are matched to
+// token0 and token1 by the amounts the pool transferred.
+func liquidityChanges(events []models.Event) []liquidityChange {
+	var changes []liquidityChange
+	type burned struct{ amount0, amount1 *big.Int }
+	burns := make(map[string]burned)
+	for _, event := range events {
+		if len(event.Topics) != 4 {
+			continue
+		}
+		pool := strings.ToLower(event.Contract)
+		position := pool + ":" + strings.ToLower(strings.Join(event.Topics[1:], ":"))
+		data := newABIData(event.Data)
+		switch strings.ToLower(event.Topics[0]) {
+		case uniswapV3MintTopic:
+			changes = append(changes, liquidityChange{pool: pool, position: position, mint: true})
+		case uniswapV3BurnTopic:
+			if amount0, amount1 := data.uintAt(32), data.uintAt(64); amount0 != nil && amount1 != nil {
+				burns[position] = burned{amount0, amount1}
+			}
+			changes = append(changes, liquidityChange{pool: pool, position: position})
+		}
+	}
+
+	flows := assetFlowsFromEvents(events)
+	for _, event := range events {
+		if len(event.Topics) != 4 || strings.ToLower(event.Topics[0]) != uniswapV3CollectTopic {
+			continue
+		}
+		pool := strings.ToLower(event.Contract)
+		position := pool + ":" + strings.ToLower(strings.Join(event.Topics[1:], ":"))
+		burn, ok := burns[position]
+		data := newABIData(event.Data)
+		collected0, collected1 := data.uintAt(32), data.uintAt(64)
+		if !ok || collected0 == nil || collected1 == nil {
+			continue
+		}
+		for i := range changes {
+			if changes[i].mint || changes[i].position != position {
+				continue
+			}
+			changes[i].recipient = data.addressAt(0)
+			for _, pair := range [][2]*big.Int{{collected0, burn.amount0}, {collected1, burn.amount1}} {
+				fee := new(big.Int).Sub(pair[0], pair[1])
+				if fee.Sign() <= 0 {
+					continue
+				}
+				for _, flow := range flows {
+					if flow.from == pool && flow.tokenID == "" && flow.amount.Cmp(pair[0]) == 0 {
+						changes[i].earned = append(changes[i].earned, assetFlow{token: flow.token, amount: fee})
+						break
+					}
+				}
+			}
+		}
+	}
+	return changes
+}
+
+// atomicArbitrage reports a transaction whose swaps form a cycle through at least two pools and leave it with
+// at least as much of every token as it paid in, and more of at least one
+func atomicArbitrage(tx *mevTransaction, amounts *actionContext) *models.MEV {
+	if len(tx.swaps) < 2 || !isSwapCycle(tx.swaps) {
+		return nil
+	}
+	net := make(map[string]*big.Int)
+	var tokens []string
+	for _, swap := range tx.swaps {
+		for _, token := range []string{swap.tokenIn, swap.tokenOut} {
+			if net[token] == nil {
+				net[token] = new(big.Int)
+				tokens = append(tokens, token)
+			}
+		}
+		net[swap.tokenIn].Sub(net[swap.tokenIn], swap.amountIn)
+		net[swap.tokenOut].Add(net[swap.tokenOut], swap.amountOut)
+	}
+	mev := &models.MEV{Kind: models.MEVKindArbitrage, Role: models.MEVRoleSearcher, Searcher: tx.to}
+	if mev.Searcher == "" {
+		mev.Searcher = tx.from
+	}
+	for _, token := range tokens {
+		switch net[token].Sign() {
+		case -1:
+			return nil
+		case 1:
+			mev.Extracted = append(mev.Extracted, amounts.amount(token, "", net[token]))
+		}
+	}
+	if len(mev.Extracted) == 0 {
+		return nil
+	}
+	for _, swap := range tx.swaps {
+		mev.Pools = append(mev.Pools, swap.pool)
+	}
+	mev.Description = fmt.Sprintf("Atomic arbitrage: %d swaps through %s in a cycle, leaving %s with a profit of %s before gas",
+		len(tx.swaps), poolNames(mev.Pools, amounts), mev.Searcher, describeExtracted(mev.Extracted))
+	return mev
+}
+
+// isSwapCycle reports whether the swaps chain into a closed loop: every token is swapped in as often as out,
+// and all the swaps are connected through their tokens
+func isSwapCycle(swaps []poolSwap) bool {
+	degree := make(map[string]int)
+	group := make(map[string]string)
+	var find func(token string) string
+	find = func(token string) string {
+		if parent, ok := group[token]; ok && parent != token {
+			return find(parent)
+		}
+		return token
+	}
+	for _, swap := range swaps {
+		degree[swap.tokenIn]++
+		degree[swap.tokenOut]--
+		group[find(swap.tokenIn)] = find(swap.tokenOut)
+		if _, ok := group[swap.tokenOut]; !ok {
+			group[swap.tokenOut] = swap.tokenOut
+		}
+	}
+	root := find(swaps[0].tokenIn)
+	for token, balance := range degree {
+		if balance != 0 || find(token) != root {
+			return false
+		}
+	}
+	return true
+}
+
+// poolTrade is a swap of one of the block's transactions
+type poolTrade struct {
+	position int
+	tx       *mevTransaction
+	swap     poolSwap
+}
+
+// sandwiches finds the sandwiches the target is a victim or a leg of in the pools it swapped: a swap, then
+// swaps in the same direction by other senders, then a swap back that sells between half and all of what the
+// first one bought, by the same sender or out of the contract that received the first swap's tokens
+func sandwiches(block []*mevTransaction, target *mevTransaction, amounts *actionContext) []models.MEV {
+	var found []models.MEV
+	var pools []string
+	for _, swap := range target.swaps {
+		if !containsString(pools, swap.pool) {
+			pools = append(pools, swap.pool)
+		}
+	}
+	for _, pool := range pools {
+		var trades []poolTrade
+		for position, tx := range block {
+			for _, swap := range tx.swaps {
+				if swap.pool == pool {
+					trades = append(trades, poolTrade{position, tx, swap})
+				}
+			}
+		}
+
+		for i, front := range trades {
+			for _, back := range trades[i+1:] {
+				if back.position == front.position || !isSandwichBackRun(front, back) {
+					continue
+				}
+				var victims []string
+				role := ""
+				for _, trade := range trades[i+1:] {
+					if trade.position > front.position && trade.position < back.position && trade.tx.from != front.tx.from &&
+						trade.swap.tokenIn == front.swap.tokenIn && trade.swap.tokenOut == front.swap.tokenOut && !containsString(victims, trade.tx.hash) {
+						victims = append(victims, trade.tx.hash)
+						if trade.tx == target {
+							role = models.MEVRoleVictim
+						}
+					}
+				}
+				switch {
+				case len(victims) == 0:
+				case front.tx == target:
+					role = models.MEVRoleFrontRun
+				case back.tx == target:
+					role = models.MEVRoleBackRun
+				}
+				if role != "" && len(victims) > 0 {
+					found = append(found, newSandwich(front, back, victims, role, amounts))
+				}
+				break // The first swap back closes the position
+			}
+		}
+	}
+	return found
+}
+
+// isSandwichBackRun reports whether back sells what front bought: the reverse direction, the same sender or
+// the tokens paid out of the contract that received them, and between half and all of the amount (1% slack for
+// transfer fees)
+func isSandwichBackRun(front, back poolTrade) bool {
+	if back.swap.tokenIn != front.swap.tokenOut || back.swap.tokenOut != front.swap.tokenIn {
+		return false
+	}
+	if front.tx.from != back.tx.from && (front.swap.recipient == "" || front.swap.recipient != back.swap.payer || front.tx.to != back.tx.to) {
+		return false
+	}
+	sold, bought := new(big.Int).Mul(back.swap.amountIn, big.NewInt(100)), new(big.Int).Mul(front.swap.amountOut, big.NewInt(101))
+	return sold.Cmp(bought) <= 0 && new(big.Int).Mul(back.swap.amountIn, big.NewInt(2)).Cmp(front.swap.amountOut) >= 0
+}
+
+// newSandwich describes a sandwich from the target's point of view. The extracted value is what the back-run
+// returned beyond what the front-run paid, plus the bought tokens the back-run did not sell.
+func newSandwich(front, back poolTrade, victims []string, role string, amounts *actionContext) models.MEV {
+	mev := models.MEV{
+		Kind:     models.MEVKindSandwich,
+		Role:     role,
+		Searcher: front.swap.recipient,
+		Pools:    []string{front.swap.pool},
+		FrontRun: front.tx.hash,
+		BackRun:  back.tx.hash,
+		Victims:  victims,
+	}
+	if mev.Searcher == "" {
+		mev.Searcher = front.tx.from
+	}
+	if profit := new(big.Int).Sub(back.swap.amountOut, front.swap.amountIn); profit.Sign() > 0 {
+		mev.Extracted = append(mev.Extracted, amounts.amount(front.swap.tokenIn, "", profit))
+	}
+	if kept := new(big.Int).Sub(front.swap.amountOut, back.swap.amountIn); kept.Sign() > 0 {
+		mev.Extracted = append(mev.Extracted, amounts.amount(front.swap.tokenOut, "", kept))
+	}
+
+	pool, extracted := poolNames(mev.Pools, amounts), describeExtracted(mev.Extracted)
+	switch role {
+	case models.MEVRoleVictim:
+		mev.Description = fmt.Sprintf("Sandwich attack: %s swapped %s in the same direction right before this swap (%s) and back right after (%s), "+
+			"pushing the price against it; the attacker extracted %s before gas", mev.Searcher, pool, mev.FrontRun, mev.BackRun, extracted)
+	case models.MEVRoleFrontRun:
+		mev.Description = fmt.Sprintf("Sandwich front-run: this swap in %s buys ahead of %d victim swap(s) and is sold back in %s; "+
+			"%s extracted %s before gas", pool, len(victims), mev.BackRun, mev.Searcher, extracted)
+	case models.MEVRoleBackRun:
+		mev.Description = fmt.Sprintf("Sandwich back-run: this swap in %s sells what %s bought ahead of %d victim swap(s); "+
+			"%s extracted %s before gas", pool, mev.FrontRun, len(victims), mev.Searcher, extracted)
+	}
+	return mev
+}
+
+// jitLiquidity finds the just-in-time positions around the target: a position minted, swaps in its pool by
+// other senders, and the same sender burning the position. The target is one of those swaps, or the mint or
+// the burn.
+func jitLiquidity(block []*mevTransaction, target *mevTransaction, amounts *actionContext) []models.MEV {
+	var found []models.MEV
+	for i, mintTx := range block {
+		for _, mint := range mintTx.liquidity {
+			if !mint.mint {
+				continue
+			}
+			for j := i + 1; j < len(block); j++ {
+				burnTx := block[j]
+				if burnTx.from != mintTx.from {
+					continue
+				}
+				burn := findLiquidityChange(burnTx.liquidity, mint.position, false)
+				if burn == nil {
+					continue
+				}
+				role := ""
+				var victims []string
+				for _, tx := range block[i+1 : j] {
+					for _, swap := range tx.swaps {
+						if swap.pool == mint.pool && tx.from != mintTx.from && !containsString(victims, tx.hash) {
+							victims = append(victims, tx.hash)
+							if tx == target {
+								role = models.MEVRoleVictim
+							}
+						}
+					}
+				}
+				switch {
+				case len(victims) == 0:
+				case mintTx == target:
+					role = models.MEVRoleJITMint
+				case burnTx == target:
+					role = models.MEVRoleJITBurn
+				}
+				if role != "" && len(victims) > 0 {
+					found = append(found, newJITLiquidity(mintTx, burnTx, *burn, victims, role, amounts))
+				}
+				break
+			}
+		}
+	}
+	return found
+}
+
+// findLiquidityChange returns the mint or burn of a position, or nil
+func findLiquidityChange(changes []liquidityChange, position string, mint bool) *liquidityChange {
+	for i := range changes {
+		if changes[i].position == position && changes[i].mint == mint {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+// newJITLiquidity describes a JIT position from the target's point of view; the extracted value is the fees it
+// collected
+func newJITLiquidity(mintTx, burnTx *mevTransaction, burn liquidityChange, victims []string, role string, amounts *actionContext) models.MEV {
+	mev := models.MEV{
+		Kind:     models.MEVKindJIT,
+		Role:     role,
+		Searcher: burn.recipient,
+		Pools:    []string{burn.pool},
+		FrontRun: mintTx.hash,
+		BackRun:  burnTx.hash,
+		Victims:  victims,
+	}
+	if mev.Searcher == "" {
+		mev.Searcher = mintTx.from
+	}
+	for _, fee := range burn.earned {
+		mev.Extracted = append(mev.Extracted, amounts.amount(fee.token, "", fee.amount))
+	}
+
+	pool, extracted := poolNames(mev.Pools, amounts), describeExtracted(mev.Extracted)
+	switch role {
+	case models.MEVRoleVictim:
+		mev.Description = fmt.Sprintf("JIT liquidity: %s added liquidity to %s right before this swap (%s) and removed it right after (%s), "+
+			"earning %s in fees the pool's other liquidity providers would have earned; the swap's price was not worsened by it",
+			mev.Searcher, pool, mev.FrontRun, mev.BackRun, extracted)
+	case models.MEVRoleJITMint:
+		mev.Description = fmt.Sprintf("JIT liquidity: this transaction adds liquidity to %s right before %d swap(s) and %s removes it, "+
+			"earning %s in fees", pool, len(victims), mev.BackRun, extracted)
+	case models.MEVRoleJITBurn:
+		mev.Description = fmt.Sprintf("JIT liquidity: this transaction removes the liquidity %s added to %s right before %d swap(s), "+
+			"earning %s in fees", mev.FrontRun, pool, len(victims), extracted)
+	}
+	return mev
+}
+
+// liquidations reads the Aave LiquidationCall and Compound V2 LiquidateBorrow events of a transaction. The
+// extracted value is the liquidator's bonus: for Aave the seized collateral worth more than the repaid debt
+// (needs both prices), for Compound the market's liquidation incentive on the repaid amount (needs an RPC
+// client).
+func (d *MEVDetector) liquidations(ctx context.Context, events []models.Event, amounts *actionContext) []models.MEV {
+	var found []models.MEV
+	flows := assetFlowsFromEvents(events)
+	for _, event := range events {
+		if len(event.Topics) == 0 {
+			continue
+		}
+		market := strings.ToLower(event.Contract)
+		data := newABIData(event.Data)
+		switch topic := strings.ToLower(event.Topics[0]); {
+		case topic == aaveLiquidationCallTopic && len(event.Topics) == 4:
+			debtToCover, seized, liquidator := data.uintAt(0), data.uintAt(32), data.addressAt(64)
+			if debtToCover == nil || seized == nil {
+				continue
+			}
+			collateral, debt := topicAddress(event.Topics[1]), topicAddress(event.Topics[2])
+			repaid, received := amounts.amount(debt, "", debtToCover), amounts.amount(collateral, "", seized)
+			mev := models.MEV{
+				Kind:     models.MEVKindLiquidation,
+				Role:     models.MEVRoleLiquidator,
+				Searcher: liquidator,
+				Pools:    []string{market},
+				Borrower: topicAddress(event.Topics[3]),
+			}
+			if bonus := collateralBonus(seized, received.AmountUSD, repaid.AmountUSD); bonus != nil {
+				mev.Extracted = append(mev.Extracted, amounts.amount(collateral, "", bonus))
+			}
+			mev.Description = fmt.Sprintf("Liquidation: %s repaid %s of %s's debt to the Aave pool %s and seized %s of collateral, a bonus of %s before gas",
+				mev.Searcher, describeExtracted([]models.ActionAmount{repaid}), mev.Borrower, market,
+				describeExtracted([]models.ActionAmount{received}), describeExtracted(mev.Extracted))
+			found = append(found, mev)
+
+		case topic == compoundLiquidateBorrowTopic && len(event.Topics) == 1:
+			repayAmount, seizeTokens := data.uintAt(64), data.uintAt(128)
+			if repayAmount == nil || seizeTokens == nil {
+				continue
+			}
+			// The liquidator pays the borrowed market in its underlying token, or in ETH for cETH
+			debt := nativePaymentToken
+			for _, flow := range flows {
+				if flow.to == market && flow.tokenID == "" && flow.amount.Cmp(repayAmount) == 0 {
+					debt = flow.token
+					break
+				}
+			}
+			collateral := data.addressAt(96)
+			repaid := amounts.amount(debt, "", repayAmount)
+			mev := models.MEV{
+				Kind:     models.MEVKindLiquidation,
+				Role:     models.MEVRoleLiquidator,
+				Searcher: data.addressAt(0),
+				Pools:    []string{market},
+				Borrower: data.addressAt(32),
+			}
+			if incentive := d.liquidationIncentive(ctx, market); incentive != nil {
+				// The collateral seized is worth incentive (e.g. 1.08e18) times the repaid amount
+				bonus := new(big.Int).Mul(repayAmount, new(big.Int).Sub(incentive, mantissaScale))
+				if bonus.Div(bonus, mantissaScale).Sign() > 0 {
+					mev.Extracted = append(mev.Extracted, amounts.amount(debt, "", bonus))
+				}
+			}
+			mev.Description = fmt.Sprintf("Liquidation: %s repaid %s of %s's debt to the Compound market %s and seized %s, a bonus worth %s before gas",
+				mev.Searcher, describeExtracted([]models.ActionAmount{repaid}), mev.Borrower, market,
+				describeExtracted([]models.ActionAmount{amounts.amount(collateral, "", seizeTokens)}), describeExtracted(mev.Extracted))
+			found = append(found, mev)
+		}
+	}
+	return found
+}
+
+// mantissaScale is 1e18, the scale of Compound mantissas
+var mantissaScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// collateralBonus returns the part of the seized collateral worth more than the repaid debt, from their USD
+// values, or nil when either is unpriced
+func collateralBonus(seized *big.Int, collateralUSD, debtUSD string) *big.Int {
+	collateralValue, err := strconv.ParseFloat(collateralUSD, 64)
+	if err != nil || collateralValue <= 0 {
+		return nil
+	}
+	debtValue, err := strconv.ParseFloat(debtUSD, 64)
+	if err != nil || debtValue <= 0 || debtValue >= collateralValue {
+		return nil
+	}
+	bonus, _ := new(big.Float).Mul(new(big.Float).SetInt(seized), big.NewFloat((collateralValue-debtValue)/collateralValue)).Int(nil)
+	return bonus
+}
+
+// liquidationIncentive reads the liquidation incentive mantissa of a Compound V2 market's comptroller, or nil
+func (d *MEVDetector) liquidationIncentive(ctx context.Context, market string) *big.Int {
+	if d.rpcClient == nil {
+		return nil
+	}
+	result, err := d.rpcClient.CallContractAt(ctx, market, selectorComptroller, "latest")
+	if err != nil || len(result) < 66 {
+		return nil
+	}
+	result, err = d.rpcClient.CallContractAt(ctx, "0x"+result[26:66], selectorLiquidationIncentive, "latest")
+	if err != nil || len(result) < 66 {
+		return nil
+	}
+	incentive, ok := new(big.Int).SetString(result[2:66], 16)
+	if !ok || incentive.Cmp(mantissaScale) <= 0 {
+		return nil
+	}
+	return incentive
+}
+
+// poolNames lists pools by their label, or address when unverified
+func poolNames(pools []string, amounts *actionContext) string {
+	names := make([]string, 0, len(pools))
+	for _, pool := range pools {
+		name := pool
+		if info, ok := amounts.pools[pool]; ok && info.Label != "" {
+			name = fmt.Sprintf("the %s (%s)", info.Label, pool)
+		}
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// describeExtracted formats extracted amounts, e.g. "0.5 WETH ($1250.00) and 10 USDC"
+func describeExtracted(extracted []models.ActionAmount) string {
+	if len(extracted) == 0 {
+		return "an unknown amount"
+	}
+	parts := make([]string, 0, len(extracted))
+	for _, amount := range extracted {
+		symbol := amount.Symbol
+		if symbol == "" {
+			symbol = amount.Token
+		}
+		part := amount.Amount + " " + symbol
+		if amount.AmountUSD != "" {
+			part += " ($" + amount.AmountUSD + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// GetMEV returns the MEV patterns from baggage
+func GetMEV(baggage map[string]interface{}) ([]models.MEV, bool) {
+	mev, ok := baggage["mev"].([]models.MEV)
+	return mev, ok && len(mev) > 0
+}
+
+// MEVRisks returns the warnings for sandwiches the transaction fell victim to, without duplicates. Arbitrage,
+// JIT liquidity and sandwich legs are reported with the MEV patterns but cost the transaction nothing.
+func MEVRisks(found []models.MEV) []string {
+	var risks []string
+	for _, mev := range found {
+		if mev.Kind == models.MEVKindSandwich && mev.Role == models.MEVRoleVictim && !containsString(risks, mev.Description) {
+			risks = append(risks, mev.Description)
+		}
+	}
+	return risks
+}
+
+// GetPromptContext lists the MEV patterns for the explainer
+func (d *MEVDetector) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	found, ok := GetMEV(baggage)
+	if !ok {
+		return ""
+	}
+	lines := []string{"### MEV (patterns found among the block's transactions - a sandwich explains a worse price than expected):"}
+	for _, mev := range found {
+		lines = append(lines, "- "+mev.Description)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for MEV (none - patterns are transaction specific)
+func (d *MEVDetector) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
@@ -0,0 +1,254 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+const (
+	testSearcherEOA = "0xb0b0000000000000000000000000000000000001"
+	testSearcherBot = "0xb0b0000000000000000000000000000000000002"
+	testV2Swap      = "Swap(address,uint256,uint256,uint256,uint256,address)"
+)
+
+// testPoolSwap is a swap of amountIn of tokenIn for amountOut of tokenOut through pool, paid by and sent to account
+func testPoolSwap(pool, account, tokenIn, tokenOut string, amountIn, amountOut uint64) []models.Event {
+	transfer := "Transfer(address,address,uint256)"
+	return []models.Event{
+		testLog(tokenIn, transfer, []string{account, pool}, fmt.Sprintf("%x", amountIn)),
+		testLog(tokenOut, transfer, []string{pool, account}, fmt.Sprintf("%x", amountOut)),
+		testLog(pool, testV2Swap, []string{testRouter, account}, "0", "0", "0", "0"),
+	}
+}
+
+func TestMEVDetectorFindsSandwichAndJITLiquidity(t *testing.T) {
+	const networkID = 99004
+	transfer := "Transfer(address,address,uint256)"
+	position := []string{testSearcherBot, "fffe", "2"} // Owner and tick range
+	type blockTx struct {
+		hash, from, to string
+		events         []models.Event
+	}
+	block := []blockTx{
+		// The bot buys WETH ahead of the trader and sells it right after
+		{"0x01", testSearcherEOA, testSearcherBot, testPoolSwap(testV2Pair, testSearcherBot, testUSDC, testWETH, 10_000_000000, 4e18)},
+		{"0x02", testTrader, testRouter, testPoolSwap(testV2Pair, testTrader, testUSDC, testWETH, 2500_000000, 0.9e18)},
+		{"0x03", testSearcherEOA, testSearcherBot, testPoolSwap(testV2Pair, testSearcherBot, testWETH, testUSDC, 4e18, 10_150_000000)},
+		// A JIT position in the V3 pool around another swap, collecting 3 USDC in fees
+		{"0x04", testSearcherEOA, testSearcherBot, []models.Event{
+			testLog(testV3Pool, "Mint(address,address,int24,int24,uint128,uint256,uint256)", position, testSearcherBot, "1", fmt.Sprintf("%x", 1000_000000), "0"),
+		}},
+		{"0x05", testTrader, testRouter, testPoolSwap(testV3Pool, testTrader, testUSDC, testWETH, 2000_000000, 0.8e18)},
+		{"0x06", testSearcherEOA, testSearcherBot, []models.Event{
+			testLog(testV3Pool, "Burn(address,int24,int24,uint128,uint256,uint256)", position, "1", fmt.Sprintf("%x", 3000_000000), "0"),
+			testLog(testUSDC, transfer, []string{testV3Pool, testSearcherBot}, fmt.Sprintf("%x", 3003_000000)),
+			testLog(testV3Pool, "Collect(address,address,int24,int24,uint128,uint128)", position, testSearcherBot, fmt.Sprintf("%x", 3003_000000), "0"),
+		}},
+	}
+
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int           `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		methods = append(methods, request.Method)
+		var transactions, receipts []interface{}
+		for _, tx := range block {
+			transactions = append(transactions, map[string]interface{}{"hash": tx.hash, "from": tx.from, "to": tx.to})
+			var logs []interface{}
+			for _, event := range tx.events {
+				logs = append(logs, map[string]interface{}{"address": event.Contract, "topics": event.Topics, "data": event.Data})
+			}
+			receipts = append(receipts, map[string]interface{}{"transactionHash": tx.hash, "status": "0x1", "logs": logs})
+		}
+		var result interface{}
+		switch request.Method {
+		case "eth_getBlockByNumber":
+			require.Equal(t, []interface{}{"0x10", true}, request.Params, "full transactions")
+			result = map[string]interface{}{"number": "0x10", "transactions": transactions}
+		case "eth_getBlockReceipts":
+			result = receipts
+		default:
+			t.Fatalf("unexpected method %s", request.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	defer server.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: server.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+	detector := NewMEVDetector(client, false)
+
+	explain := func(i int) []models.MEV {
+		baggage := testActionBaggage(block[i].events, nil)
+		rawData := baggage["raw_data"].(map[string]interface{})
+		rawData["tx_hash"] = block[i].hash
+		rawData["block"] = map[string]interface{}{"number": "0x10"}
+		rawData["receipt"] = map[string]interface{}{"from": block[i].from, "to": block[i].to, "status": "0x1"}
+		baggage["pools"] = map[string]*PoolInfo{testV2Pair: {Address: testV2Pair, Label: "Uniswap V2 USDC/WETH pool"}}
+		require.NoError(t, detector.Process(context.Background(), baggage))
+		found, _ := GetMEV(baggage)
+		return found
+	}
+
+	found := explain(1)
+	require.Equal(t, []string{"eth_getBlockByNumber", "eth_getBlockReceipts"}, methods)
+	require.Len(t, found, 1)
+	require.Equal(t, models.MEVKindSandwich, found[0].Kind)
+	require.Equal(t, models.MEVRoleVictim, found[0].Role)
+	require.Equal(t, testSearcherBot, found[0].Searcher)
+	require.Equal(t, "0x01", found[0].FrontRun)
+	require.Equal(t, "0x03", found[0].BackRun)
+	require.Equal(t, []string{"0x02"}, found[0].Victims)
+	require.Equal(t, []models.ActionAmount{{Token: testUSDC, Symbol: "USDC", Amount: "150", RawAmount: "150000000", AmountUSD: "150.00"}}, found[0].Extracted)
+	require.Equal(t, []string{"Sandwich attack: " + testSearcherBot + " swapped the Uniswap V2 USDC/WETH pool (" + testV2Pair + ") in the same direction " +
+		"right before this swap (0x01) and back right after (0x03), pushing the price against it; the attacker extracted 150 USDC ($150.00) before gas"},
+		MEVRisks(found))
+
+	found = explain(2)
+	require.Len(t, found, 1)
+	require.Equal(t, models.MEVRoleBackRun, found[0].Role)
+	require.Empty(t, MEVRisks(found), "the searcher lost nothing")
+
+	found = explain(4)
+	require.Len(t, found, 1)
+	require.Equal(t, models.MEVKindJIT, found[0].Kind)
+	require.Equal(t, models.MEVRoleVictim, found[0].Role)
+	require.Equal(t, "0x04", found[0].FrontRun)
+	require.Equal(t, "0x06", found[0].BackRun)
+	require.Equal(t, "3", found[0].Extracted[0].Amount)
+
+	found = explain(5)
+	require.Len(t, found, 1)
+	require.Equal(t, models.MEVRoleJITBurn, found[0].Role)
+	require.Contains(t, detector.GetPromptContext(context.Background(), map[string]interface{}{"mev": found}), "### MEV")
+}
+
+func TestMEVDetectorFindsAtomicArbitrage(t *testing.T) {
+	const (
+		usdt     = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+		usdtPool = "0x3041cbd36888becc7bbcbc0045e3b1f144466f5f"
+	)
+	var events []models.Event
+	events = append(events, testPoolSwap(testV2Pair, testSearcherBot, testWETH, testUSDC, 1e18, 2600_000000)...)
+	events = append(events, testPoolSwap(testV3Pool, testSearcherBot, testUSDC, usdt, 2600_000000, 2601_000000)...)
+	events = append(events, testPoolSwap(usdtPool, testSearcherBot, usdt, testWETH, 2601_000000, 1.01e18)...)
+	baggage := testActionBaggage(events, nil)
+	baggage["raw_data"].(map[string]interface{})["receipt"] = map[string]interface{}{"from": testSearcherEOA, "to": testSearcherBot, "status": "0x1"}
+	require.NoError(t, NewMEVDetector(nil, false).Process(context.Background(), baggage))
+
+	found, ok := GetMEV(baggage)
+	require.True(t, ok)
+	require.Len(t, found, 1)
+	require.Equal(t, models.MEVKindArbitrage, found[0].Kind)
+	require.Equal(t, testSearcherBot, found[0].Searcher)
+	require.Equal(t, []string{testV2Pair, testV3Pool, usdtPool}, found[0].Pools)
+	require.Equal(t, []models.ActionAmount{{Token: testWETH, Symbol: "WETH", Amount: "0.01", RawAmount: "10000000000000000"}}, found[0].Extracted)
+	require.Empty(t, MEVRisks(found))
+
+	// A round trip at a loss is not arbitrage
+	baggage = testActionBaggage(append(testPoolSwap(testV2Pair, testTrader, testWETH, testUSDC, 1e18, 2600_000000),
+		testPoolSwap(testV3Pool, testTrader, testUSDC, testWETH, 2600_000000, 0.99e18)...), nil)
+	require.NoError(t, NewMEVDetector(nil, false).Process(context.Background(), baggage))
+	_, ok = GetMEV(baggage)
+	require.False(t, ok)
+}
+
+func TestMEVDetectorFindsLiquidations(t *testing.T) {
+	const (
+		networkID   = 99005
+		aavePool    = "0x87870bca3f3fd6335c3f4ce8392d69350b4fa4e2"
+		cUSDC       = "0x39aa39c021dfbae8fac545936693ac917d5e7563"
+		cETH        = "0x4ddc2d193948926d02f9b1fe9e1daa0718270ed5"
+		comptroller = "0x3d9819210a31b4961b30ef54be2aed79b9c9cd3b"
+		testBorrow  = "0xb0b0000000000000000000000000000000000003"
+	)
+	transfer := "Transfer(address,address,uint256)"
+
+	// The bot repays 1500 USDC of debt and seizes 0.8 WETH worth $1600
+	baggage := testActionBaggage([]models.Event{
+		testLog(testUSDC, transfer, []string{testSearcherBot, aavePool}, fmt.Sprintf("%x", 1500_000000)),
+		testLog(testWETH, transfer, []string{aavePool, testSearcherBot}, fmt.Sprintf("%x", uint64(0.8e18))),
+		testLog(aavePool, "LiquidationCall(address,address,address,uint256,uint256,address,bool)", []string{testWETH, testUSDC, testBorrow},
+			fmt.Sprintf("%x", 1500_000000), fmt.Sprintf("%x", uint64(0.8e18)), testSearcherBot, "0"),
+	}, nil)
+	baggage["token_prices"].(map[string]*TokenPrice)[testWETH] = &TokenPrice{Symbol: "WETH", Price: 2000}
+	require.NoError(t, NewMEVDetector(nil, false).Process(context.Background(), baggage))
+
+	found, ok := GetMEV(baggage)
+	require.True(t, ok)
+	require.Len(t, found, 1)
+	require.Equal(t, models.MEVKindLiquidation, found[0].Kind)
+	require.Equal(t, models.MEVRoleLiquidator, found[0].Role)
+	require.Equal(t, testSearcherBot, found[0].Searcher)
+	require.Equal(t, testBorrow, found[0].Borrower)
+	require.Equal(t, []string{aavePool}, found[0].Pools)
+	require.Equal(t, []models.ActionAmount{{Token: testWETH, Symbol: "WETH", Amount: "0.05", RawAmount: "50000000000000000", AmountUSD: "100.00"}}, found[0].Extracted)
+	require.Empty(t, MEVRisks(found), "a liquidation harms no other transaction")
+
+	// Compound's bonus is the comptroller's liquidation incentive on the repaid amount
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int           `json:"id"`
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.Equal(t, "eth_call", request.Method)
+		call := request.Params[0].(map[string]interface{})
+		calls = append(calls, call["to"].(string))
+		var result string
+		switch call["data"] {
+		case selectorComptroller:
+			result = "0x" + abiWord(comptroller)
+		case selectorLiquidationIncentive:
+			result = "0x" + abiWord(fmt.Sprintf("%x", uint64(1.08e18)))
+		default:
+			t.Fatalf("unexpected call %v", call["data"])
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	defer server.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: server.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+
+	baggage = testActionBaggage([]models.Event{
+		testLog(testUSDC, transfer, []string{testSearcherBot, cUSDC}, fmt.Sprintf("%x", 1000_000000)),
+		testLog(cUSDC, "LiquidateBorrow(address,address,uint256,address,uint256)", nil,
+			testSearcherBot, testBorrow, fmt.Sprintf("%x", 1000_000000), cETH, fmt.Sprintf("%x", 2_700_000000)),
+	}, nil)
+	require.NoError(t, NewMEVDetector(client, false).Process(context.Background(), baggage))
+
+	found, ok = GetMEV(baggage)
+	require.True(t, ok)
+	require.Len(t, found, 1)
+	require.Equal(t, []string{cUSDC, comptroller}, calls)
+	require.Equal(t, models.MEVKindLiquidation, found[0].Kind)
+	require.Equal(t, testSearcherBot, found[0].Searcher)
+	require.Equal(t, testBorrow, found[0].Borrower)
+	require.Equal(t, []models.ActionAmount{{Token: testUSDC, Symbol: "USDC", Amount: "80", RawAmount: "80000000", AmountUSD: "80.00"}}, found[0].Extracted)
+	require.Contains(t, found[0].Description, "repaid 1000 USDC ($1000.00) of "+testBorrow+"'s debt to the Compound market "+cUSDC)
+}
@@ -54,7 +54,7 @@ func (m *MonetaryValueEnricher) Description() string {
 
 // Dependencies returns the tools this processor depends on
 func (m *MonetaryValueEnricher) Dependencies() []string {
-	return []string{"amounts_finder", "erc20_price_lookup"}
+	return []string{"amounts_finder", "erc20_price_lookup", "nft_sale_detector"}
 }
 
 // Process enriches detected amounts with USD equivalents
@@ -87,6 +87,9 @@ func (m *MonetaryValueEnricher) Process(ctx context.Context, baggage map[string]
 		fmt.Println("🔍 Sub-step 1: Checking detected amounts for USD conversion...")
 	}
 
+	// NFT sale prices come from the sale detector rather than amounts_finder
+	m.enrichNFTSales(ctx, baggage)
+
 	// Get detected amounts from amounts_finder
 	detectedAmounts, ok := baggage["detected_amounts"].([]DetectedAmount)
 	if !ok || len(detectedAmounts) == 0 {
@@ -248,6 +251,55 @@ func (m *MonetaryValueEnricher) Process(ctx context.Context, baggage map[string]
 	return nil
 }
 
+// enrichNFTSales formats sale prices and adds their USD value
+func (m *MonetaryValueEnricher) enrichNFTSales(ctx context.Context, baggage map[string]interface{}) {
+	sales, ok := GetNFTSales(baggage)
+	if !ok || len(sales) == 0 {
+		return
+	}
+
+	tokenPrices, _ := baggage["token_prices"].(map[string]*TokenPrice)
+	var networkID int64
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if id, ok := rawData["network_id"].(float64); ok {
+			networkID = int64(id)
+		}
+	}
+
+	var nativePrice float64
+	nativePriceFetched := false
+	for i := range sales {
+		sale := &sales[i]
+		amount := m.convertAmountToTokens(sale.Price, sale.PaymentDecimals)
+		if amount == 0 {
+			continue
+		}
+
+		var pricePerToken float64
+		if sale.PaymentToken == nativePaymentToken {
+			if sale.PaymentSymbol == "" && networkID > 0 {
+				sale.PaymentSymbol = m.getNativeTokenSymbol(networkID)
+			}
+			if !nativePriceFetched {
+				nativePrice = m.getNativeTokenPrice(ctx, baggage)
+				nativePriceFetched = true
+			}
+			pricePerToken = nativePrice
+		} else if price, exists := tokenPrices[strings.ToLower(sale.PaymentToken)]; exists {
+			pricePerToken = price.Price
+		}
+
+		sale.PriceFormatted = m.formatAmount(amount)
+		if pricePerToken > 0 {
+			sale.PriceUSD = amount * pricePerToken
+		}
+
+		if m.verbose {
+			fmt.Printf("🛒 NFT sale %s #%s: %s %s ($%.2f)\n", sale.Collection, strings.Join(sale.TokenIDs, ","), sale.PriceFormatted, sale.PaymentSymbol, sale.PriceUSD)
+		}
+	}
+}
+
 // enrichDetectedAmount converts a detected amount to an enriched amount with USD values
 func (m *MonetaryValueEnricher) enrichDetectedAmount(detected DetectedAmount, tokenPrices map[string]*TokenPrice, tokenMetadata map[string]*TokenMetadata, hasPrices bool, baggage map[string]interface{}) *EnrichedAmount {
 	var formattedAmount float64
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// DefaultENSMainnetNetworks are the networks whose addresses are also looked up in mainnet ENS.
+// An EOA is controlled by the same key on every EVM chain, so its mainnet primary name applies there too.
+// Override with ENS_MAINNET_NETWORKS (comma-separated chain IDs).
+const DefaultENSMainnetNetworks = "137,42161"
+
+// ensAvatarServiceURL serves any ENS avatar record (including NFT avatars) as an image
+const ensAvatarServiceURL = "https://metadata.ens.domains/mainnet/avatar/"
+
+// NameService resolves addresses to human-readable names on one naming system.
+// block is a block number on the service's own chain (hex number or tag; "latest" if empty).
+type NameService interface {
+	// Name returns the naming system, e.g. "ENS" or "Basenames"
+	Name() string
+	// NetworkID returns the chain the naming system's registry lives on
+	NetworkID() int64
+	// LookupAddress returns the verified primary name of an address at a block, or "" if it has none
+	LookupAddress(ctx context.Context, address, block string) (string, error)
+	// TextRecords returns display records (avatar, url, com.twitter) for a name at a block
+	TextRecords(ctx context.Context, name, block string) (map[string]string, error)
+}
+
+// ENSRecord is a verified name with the text records used for display.
+// Name is the name the address had at the transaction's block (or now, if Block is empty).
+type ENSRecord struct {
+	Name        string `json:"name"`
+	Service     string `json:"service"` // Naming system that resolved the name
+	Avatar      string `json:"avatar,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Twitter     string `json:"twitter,omitempty"`
+	Block       string `json:"block,omitempty"`        // Block the name was resolved at; empty for current names
+	CurrentName string `json:"current_name,omitempty"` // Today's name, set only when it differs from Name
+}
+
+// RegistryNameService is a NameService backed by an ENS-compatible registry (ENS, Basenames)
+type RegistryNameService struct {
+	client   *rpc.Client
+	registry rpc.NameRegistry
+}
+
+// NewRegistryNameService creates a name service for a registry; the client must be on the registry's chain
+func NewRegistryNameService(client *rpc.Client, registry rpc.NameRegistry) *RegistryNameService {
+	return &RegistryNameService{
+		client:   client,
+		registry: registry,
+	}
+}
+
+// Name returns the registry name
+func (s *RegistryNameService) Name() string {
+	return s.registry.Name
+}
+
+// NetworkID returns the chain of the registry
+func (s *RegistryNameService) NetworkID() int64 {
+	return s.registry.NetworkID
+}
+
+// LookupAddress performs a forward-verified reverse lookup
+func (s *RegistryNameService) LookupAddress(ctx context.Context, address, block string) (string, error) {
+	return s.client.LookupName(ctx, s.registry, address, block)
+}
+
+// TextRecords fetches the display text records for a name
+func (s *RegistryNameService) TextRecords(ctx context.Context, name, block string) (map[string]string, error) {
+	return s.client.GetTextRecords(ctx, s.registry, name, rpc.ENSTextRecordKeys, block)
+}
+
+// NameServicesForNetwork returns the name services that apply to addresses seen on a network, in priority
+// order: the chain's own naming system (Basenames on Base), then mainnet ENS on mainnet and on the networks
+// listed in ENS_MAINNET_NETWORKS. client is the transaction network's client.
+func NameServicesForNetwork(networkID int64, client *rpc.Client) []NameService {
+	var services []NameService
+
+	if client != nil && networkID == rpc.BasenamesRegistry.NetworkID {
+		services = append(services, NewRegistryNameService(client, rpc.BasenamesRegistry))
+	}
+
+	if networkID == rpc.ENSRegistry.NetworkID {
+		if client != nil {
+			services = append(services, NewRegistryNameService(client, rpc.ENSRegistry))
+		}
+	} else if ensMainnetNetworks()[networkID] {
+		// Needs an RPC endpoint for mainnet to be configured
+		if mainnet, err := rpc.NewClient(rpc.ENSRegistry.NetworkID); err == nil {
+			services = append(services, NewRegistryNameService(mainnet, rpc.ENSRegistry))
+		}
+	}
+
+	return services
+}
+
+// ensMainnetNetworks parses ENS_MAINNET_NETWORKS
+func ensMainnetNetworks() map[int64]bool {
+	value := os.Getenv("ENS_MAINNET_NETWORKS")
+	if value == "" {
+		value = DefaultENSMainnetNetworks
+	}
+
+	networks := make(map[int64]bool)
+	for _, part := range strings.Split(value, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			networks[id] = true
+		}
+	}
+	return networks
+}
+
+// newENSRecord builds a record from a name and its text records, turning the avatar into a displayable URL
+func newENSRecord(service, name string, texts map[string]string, fetcher *NFTMetadataFetcher) *ENSRecord {
+	record := &ENSRecord{
+		Name:    name,
+		Service: service,
+		URL:     texts["url"],
+		Twitter: strings.TrimPrefix(texts["com.twitter"], "@"),
+	}
+
+	avatar := strings.TrimSpace(texts["avatar"])
+	switch {
+	case avatar == "":
+	case strings.HasPrefix(avatar, "https://") || strings.HasPrefix(avatar, "data:image/"):
+		record.Avatar = avatar
+	case strings.HasPrefix(avatar, "ipfs://") || strings.HasPrefix(avatar, "ar://"):
+		record.Avatar = fetcher.ResolveURI(avatar)
+	case service == rpc.ENSRegistry.Name:
+		// eip155:1/erc721:... NFT avatars are rendered by the ENS metadata service
+		record.Avatar = ensAvatarServiceURL + name
+	}
+
+	return record
+}
+
+// formatENSRecord renders a record for prompts, e.g. "vitalik.eth (ENS) | twitter: @VitalikButerin".
+// Names that changed since the transaction are shown as "old.eth (ENS, at tx time; now new.eth)".
+func formatENSRecord(record *ENSRecord) string {
+	var formatted string
+	switch {
+	case record.Name == "":
+		formatted = fmt.Sprintf("no name at tx time (%s; now %s)", record.Service, record.CurrentName)
+	case record.CurrentName != "":
+		formatted = fmt.Sprintf("%s (%s, at tx time; now %s)", record.Name, record.Service, record.CurrentName)
+	default:
+		formatted = fmt.Sprintf("%s (%s)", record.Name, record.Service)
+	}
+	if record.URL != "" {
+		formatted += " | url: " + record.URL
+	}
+	if record.Twitter != "" {
+		formatted += " | twitter: @" + record.Twitter
+	}
+	if record.Avatar != "" {
+		formatted += " | avatar: " + record.Avatar
+	}
+	return formatted
+}
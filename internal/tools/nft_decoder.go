@@ -3,18 +3,31 @@ package tools
 import (
 	"context"
 	"fmt" // Added for os.Getenv
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/txplain/txplain/internal/models"
 	"github.com/txplain/txplain/internal/rpc"
 )
 
+// maxNFTMetadataLookups caps how many distinct tokens get their metadata fetched per transaction,
+// nftMetadataWorkers how many are fetched at once and nftMetadataBudget how long all of them may take.
+// Token URIs are chosen by the contract, so a slow host must not stall the pipeline.
+const (
+	maxNFTMetadataLookups = 20
+	nftMetadataWorkers    = 5
+	nftMetadataBudget     = 30 * time.Second
+)
+
 // NFTDecoder extracts and enriches NFT transfers from events
 type NFTDecoder struct {
-	rpcClient *rpc.Client
-	verbose   bool
-	cache     Cache // Cache for NFT metadata
+	rpcClient       *rpc.Client
+	verbose         bool
+	cache           Cache // Cache for NFT metadata
+	metadataFetcher *NFTMetadataFetcher
 }
 
 // NFTTransfer represents an NFT transfer with metadata
@@ -29,14 +42,20 @@ type NFTTransfer struct {
 	Symbol         string `json:"symbol"`          // Contract symbol
 	TokenURI       string `json:"token_uri"`       // Token metadata URI (if available)
 	CollectionName string `json:"collection_name"` // Human-friendly collection name
+
+	// Token metadata resolved from TokenURI
+	TokenName string         `json:"token_name,omitempty"` // Name of this specific token (e.g. "Bored Ape #1234")
+	Image     string         `json:"image,omitempty"`      // Image URL (gateway-resolved) or data: URI
+	Traits    []NFTAttribute `json:"traits,omitempty"`     // Token attributes
 }
 
 // NewNFTDecoder creates a new NFT decoder
 func NewNFTDecoder(cache Cache, verbose bool, rpcClient *rpc.Client) *NFTDecoder {
 	return &NFTDecoder{
-		rpcClient: rpcClient,
-		verbose:   verbose,
-		cache:     cache,
+		rpcClient:       rpcClient,
+		verbose:         verbose,
+		cache:           cache,
+		metadataFetcher: NewNFTMetadataFetcher(verbose),
 	}
 }
 
@@ -160,6 +179,11 @@ func (n *NFTDecoder) Process(ctx context.Context, baggage map[string]interface{}
 		if n.verbose {
 			fmt.Printf("✅ Successfully enriched %d/%d NFT transfers\n", successCount, len(nftTransfers))
 		}
+
+		if hasProgress {
+			progressTracker.UpdateComponent("nft_decoder", models.ComponentGroupEnrichment, "Enriching NFT Metadata", models.ComponentStatusRunning, "Fetching token metadata")
+		}
+		baggage["nft_metadata"] = n.enrichTokenMetadata(ctx, baggage, nftTransfers)
 	} else if n.verbose {
 		fmt.Println("⚠️  No RPC client available, skipping metadata enrichment")
 	}
@@ -189,6 +213,126 @@ func (n *NFTDecoder) Process(ctx context.Context, baggage map[string]interface{}
 	return nil
 }
 
+// enrichTokenMetadata resolves tokenURI/uri at the transaction's block for each distinct token and
+// copies the metadata (name, image, traits) onto the transfers. Returns metadata keyed by NFTMetadataKey.
+func (n *NFTDecoder) enrichTokenMetadata(ctx context.Context, baggage map[string]interface{}, transfers []NFTTransfer) map[string]*NFTMetadata {
+	networkID := int64(0)
+	block := ""
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if nid, ok := rawData["network_id"].(float64); ok {
+			networkID = int64(nid)
+		}
+		if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+			block, _ = receipt["blockNumber"].(string)
+		}
+	}
+
+	// The first transfer of each distinct token, up to the cap
+	var pending []NFTTransfer
+	queued := make(map[string]bool)
+	for _, transfer := range transfers {
+		if transfer.TokenID == "" || transfer.TokenID == "batch" {
+			continue
+		}
+		if key := NFTMetadataKey(transfer.Contract, transfer.TokenID); !queued[key] && len(pending) < maxNFTMetadataLookups {
+			queued[key] = true
+			pending = append(pending, transfer)
+		}
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, nftMetadataBudget)
+	defer cancel()
+	results := make([]*NFTMetadata, len(pending))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(nftMetadataWorkers, len(pending)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				metadata, err := n.getTokenMetadata(fetchCtx, networkID, pending[i], block)
+				if err != nil && (n.verbose || os.Getenv("DEBUG") == "true") {
+					fmt.Printf("   ⚪ No metadata for %s #%s: %v\n", pending[i].Contract, pending[i].TokenID, err)
+				}
+				results[i] = metadata
+			}
+		}()
+	}
+	for i := range pending {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	metadataByToken := make(map[string]*NFTMetadata)
+	for i, transfer := range pending {
+		metadataByToken[NFTMetadataKey(transfer.Contract, transfer.TokenID)] = results[i]
+	}
+	for i, transfer := range transfers {
+		metadata := metadataByToken[NFTMetadataKey(transfer.Contract, transfer.TokenID)]
+		if metadata == nil {
+			continue
+		}
+
+		transfers[i].TokenURI = metadata.TokenURI
+		transfers[i].TokenName = metadata.Name
+		transfers[i].Image = metadata.Image
+		transfers[i].Traits = metadata.Traits
+	}
+
+	for key, metadata := range metadataByToken {
+		if metadata == nil {
+			delete(metadataByToken, key)
+		}
+	}
+
+	if n.verbose {
+		fmt.Printf("🖼️  Resolved metadata for %d tokens\n", len(metadataByToken))
+	}
+
+	return metadataByToken
+}
+
+// getTokenMetadata fetches a token's URI at the given block and the metadata it points to, using the cache
+func (n *NFTDecoder) getTokenMetadata(ctx context.Context, networkID int64, transfer NFTTransfer, block string) (*NFTMetadata, error) {
+	cacheKey := fmt.Sprintf(NFTMetadataKeyPattern, networkID, strings.ToLower(transfer.Contract), transfer.TokenID)
+	if n.cache != nil {
+		var cached NFTMetadata
+		if err := n.cache.GetJSON(ctx, cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	tokenURI, err := n.rpcClient.GetTokenURI(ctx, transfer.Contract, transfer.TokenID, transfer.Type, block)
+	if err != nil && block != "" {
+		// Burned tokens have no URI after the transaction; try the state just before it
+		if blockNumber, parseErr := strconv.ParseUint(strings.TrimPrefix(block, "0x"), 16, 64); parseErr == nil && blockNumber > 0 {
+			tokenURI, err = n.rpcClient.GetTokenURI(ctx, transfer.Contract, transfer.TokenID, transfer.Type, fmt.Sprintf("0x%x", blockNumber-1))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token URI: %w", err)
+	}
+
+	metadata, err := n.metadataFetcher.FetchMetadata(ctx, tokenURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.cache != nil {
+		if err := n.cache.SetJSON(ctx, cacheKey, metadata, &NFTMetadataTTLDuration); err != nil && (n.verbose || os.Getenv("DEBUG") == "true") {
+			fmt.Printf("   ⚠️  Failed to cache NFT metadata for %s #%s: %v\n", transfer.Contract, transfer.TokenID, err)
+		}
+	}
+
+	return metadata, nil
+}
+
+// NFTMetadataKey is the key for a token in the "nft_metadata" baggage map
+func NFTMetadataKey(contract, tokenID string) string {
+	return strings.ToLower(contract) + ":" + tokenID
+}
+
 // extractNFTTransfers extracts NFT transfers from events
 func (n *NFTDecoder) extractNFTTransfers(ctx context.Context, events []models.Event) []NFTTransfer {
 	var transfers []NFTTransfer
@@ -487,6 +631,7 @@ func (n *NFTDecoder) GetPromptContext(ctx context.Context, baggage map[string]in
 			transferInfo := fmt.Sprintf("\nNFT Transfer #%d:", i+1)
 			transferInfo += fmt.Sprintf("\n- Collection: %s", n.getDisplayName(transfer))
 			transferInfo += fmt.Sprintf("\n- Token ID: %s", transfer.TokenID)
+			transferInfo += n.formatTokenMetadata(transfer)
 			transferInfo += fmt.Sprintf("\n- From: %s", transfer.From)
 			transferInfo += fmt.Sprintf("\n- To: %s (NFT RECIPIENT)", transfer.To)
 			transferInfo += fmt.Sprintf("\n- Contract: %s", transfer.Contract)
@@ -501,6 +646,7 @@ func (n *NFTDecoder) GetPromptContext(ctx context.Context, baggage map[string]in
 			transferInfo := fmt.Sprintf("\nERC-1155 Transfer #%d:", i+1)
 			transferInfo += fmt.Sprintf("\n- Collection: %s", n.getDisplayName(transfer))
 			transferInfo += fmt.Sprintf("\n- Token ID: %s", transfer.TokenID)
+			transferInfo += n.formatTokenMetadata(transfer)
 
 			// Enhanced amount formatting
 			amount := transfer.Amount
@@ -574,6 +720,36 @@ func (n *NFTDecoder) GetPromptContext(ctx context.Context, baggage map[string]in
 	return strings.Join(contextParts, "")
 }
 
+// formatTokenMetadata renders the token name, image and traits lines for the prompt context
+func (n *NFTDecoder) formatTokenMetadata(transfer NFTTransfer) string {
+	var info string
+	if transfer.TokenName != "" {
+		info += fmt.Sprintf("\n- Token Name: %s", transfer.TokenName)
+	}
+	if transfer.Image != "" {
+		if strings.HasPrefix(transfer.Image, "data:") {
+			info += "\n- Image: stored on-chain"
+		} else {
+			info += fmt.Sprintf("\n- Image: %s (use as icon)", transfer.Image)
+		}
+	}
+	if len(transfer.Traits) > 0 {
+		var traits []string
+		for _, trait := range transfer.Traits {
+			if trait.TraitType != "" {
+				traits = append(traits, fmt.Sprintf("%s: %s", trait.TraitType, trait.Value))
+			} else {
+				traits = append(traits, trait.Value)
+			}
+		}
+		if len(traits) > 12 {
+			traits = append(traits[:12], fmt.Sprintf("...and %d more", len(traits)-12))
+		}
+		info += fmt.Sprintf("\n- Traits: %s", strings.Join(traits, ", "))
+	}
+	return info
+}
+
 // buildRecipientSummary creates a detailed summary of recipients and amounts for the LLM
 func (n *NFTDecoder) buildRecipientSummary(transfers []NFTTransfer) []string {
 	var summaries []string
@@ -0,0 +1,300 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// Default gateways for decentralised storage URIs; override with IPFS_GATEWAY_URL / ARWEAVE_GATEWAY_URL
+const (
+	DefaultIPFSGateway    = "https://ipfs.io/ipfs/"
+	DefaultArweaveGateway = "https://arweave.net/"
+)
+
+// maxNFTMetadataSize caps how much of a metadata document is read
+const maxNFTMetadataSize = 1 << 20 // 1MB
+
+// NFTMetadata is the parsed ERC721/ERC1155 metadata JSON of a token
+type NFTMetadata struct {
+	TokenURI     string         `json:"token_uri"`
+	Name         string         `json:"name,omitempty"`
+	Description  string         `json:"description,omitempty"`
+	Image        string         `json:"image,omitempty"` // HTTP(S) URL via the configured gateways, or a data: URI for on-chain art
+	AnimationURL string         `json:"animation_url,omitempty"`
+	ExternalURL  string         `json:"external_url,omitempty"`
+	Traits       []NFTAttribute `json:"traits,omitempty"`
+}
+
+// NFTAttribute is a single trait from the metadata "attributes" list
+type NFTAttribute struct {
+	TraitType   string `json:"trait_type"`
+	Value       string `json:"value"`
+	DisplayType string `json:"display_type,omitempty"`
+}
+
+// NFTMetadataFetcher fetches NFT metadata from data:, HTTP(S), IPFS and Arweave URIs. Token URIs are chosen by
+// the contract, so URLs outside the configured gateways are fetched with a client that refuses to connect to
+// loopback, private, link-local and unspecified addresses, redirects included.
+type NFTMetadataFetcher struct {
+	httpClient     *http.Client // For the configured gateways, which may be a local node
+	publicClient   *http.Client // For any other URL
+	ipfsGateway    string
+	arweaveGateway string
+	verbose        bool
+}
+
+// NewNFTMetadataFetcher creates a metadata fetcher using the gateways from the environment
+func NewNFTMetadataFetcher(verbose bool) *NFTMetadataFetcher {
+	ipfsGateway := os.Getenv("IPFS_GATEWAY_URL")
+	if ipfsGateway == "" {
+		ipfsGateway = DefaultIPFSGateway
+	}
+	arweaveGateway := os.Getenv("ARWEAVE_GATEWAY_URL")
+	if arweaveGateway == "" {
+		arweaveGateway = DefaultArweaveGateway
+	}
+
+	return &NFTMetadataFetcher{
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		publicClient:   rpc.NewPublicHTTPClient(15 * time.Second),
+		ipfsGateway:    strings.TrimRight(ipfsGateway, "/") + "/",
+		arweaveGateway: strings.TrimRight(arweaveGateway, "/") + "/",
+		verbose:        verbose,
+	}
+}
+
+// ResolveURI turns ipfs:// and ar:// URIs into gateway URLs; other URIs are returned unchanged
+func (f *NFTMetadataFetcher) ResolveURI(uri string) string {
+	uri = strings.TrimSpace(uri)
+
+	switch {
+	case strings.HasPrefix(uri, "ipfs://"):
+		path := strings.TrimPrefix(uri, "ipfs://")
+		path = strings.TrimPrefix(path, "ipfs/")
+		return f.ipfsGateway + path
+	case strings.HasPrefix(uri, "ar://"):
+		return f.arweaveGateway + strings.TrimPrefix(uri, "ar://")
+	case strings.HasPrefix(uri, "Qm") && len(uri) >= 46 && !strings.Contains(uri, ":"):
+		// Bare CIDv0, seen on some older collections
+		return f.ipfsGateway + uri
+	}
+
+	// Public gateway URLs are rewritten to the configured gateway so a local node can serve them
+	if parsed, err := url.Parse(uri); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		if idx := strings.Index(parsed.Path, "/ipfs/"); idx >= 0 {
+			rest := parsed.Path[idx+len("/ipfs/"):]
+			if parsed.RawQuery != "" {
+				rest += "?" + parsed.RawQuery
+			}
+			return f.ipfsGateway + rest
+		}
+	}
+
+	return uri
+}
+
+// FetchMetadata loads and parses the metadata document a token URI points to
+func (f *NFTMetadataFetcher) FetchMetadata(ctx context.Context, tokenURI string) (*NFTMetadata, error) {
+	var content []byte
+	var err error
+
+	if strings.HasPrefix(tokenURI, "data:") {
+		content, err = decodeDataURI(tokenURI)
+	} else {
+		resolved := f.ResolveURI(tokenURI)
+		client := f.publicClient
+		if strings.HasPrefix(resolved, f.ipfsGateway) || strings.HasPrefix(resolved, f.arweaveGateway) {
+			client = f.httpClient
+		}
+		content, err = f.fetchHTTP(ctx, client, resolved)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := parseNFTMetadata(content)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.TokenURI = tokenURI
+	if metadata.Image != "" && !strings.HasPrefix(metadata.Image, "data:") {
+		metadata.Image = f.ResolveURI(metadata.Image)
+	}
+	if metadata.AnimationURL != "" && !strings.HasPrefix(metadata.AnimationURL, "data:") {
+		metadata.AnimationURL = f.ResolveURI(metadata.AnimationURL)
+	}
+
+	return metadata, nil
+}
+
+// fetchHTTP downloads a metadata document over HTTP(S)
+func (f *NFTMetadataFetcher) fetchHTTP(ctx context.Context, client *http.Client, metadataURL string) ([]byte, error) {
+	if !strings.HasPrefix(metadataURL, "http://") && !strings.HasPrefix(metadataURL, "https://") {
+		return nil, fmt.Errorf("unsupported token URI scheme: %s", metadataURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata request returned status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxNFTMetadataSize))
+}
+
+// decodeDataURI decodes an RFC 2397 data: URI (base64 or percent-encoded)
+func decodeDataURI(uri string) ([]byte, error) {
+	comma := strings.Index(uri, ",")
+	if comma < 0 {
+		return nil, fmt.Errorf("malformed data URI")
+	}
+	header := uri[len("data:"):comma]
+	payload := uri[comma+1:]
+
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			// Some contracts omit padding
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data URI: %w", err)
+		}
+		return decoded, nil
+	}
+
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		// Raw JSON with stray '%' characters
+		return []byte(payload), nil
+	}
+	return []byte(decoded), nil
+}
+
+// parseNFTMetadata parses a metadata JSON document, accepting the common variations of the
+// OpenSea/ERC721 metadata schema (image_url, image_data, traits or properties maps)
+func parseNFTMetadata(content []byte) (*NFTMetadata, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid metadata JSON: %w", err)
+	}
+
+	metadata := &NFTMetadata{
+		Name:         stringField(raw, "name"),
+		Description:  stringField(raw, "description"),
+		Image:        stringField(raw, "image", "image_url"),
+		AnimationURL: stringField(raw, "animation_url"),
+		ExternalURL:  stringField(raw, "external_url"),
+	}
+	if metadata.Image == "" {
+		if svg := stringField(raw, "image_data"); strings.HasPrefix(strings.TrimSpace(svg), "<svg") {
+			metadata.Image = "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(svg))
+		}
+	}
+
+	switch attributes := firstPresent(raw, "attributes", "traits").(type) {
+	case []interface{}:
+		for _, item := range attributes {
+			attribute, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			trait := NFTAttribute{
+				TraitType:   stringField(attribute, "trait_type", "key", "name"),
+				Value:       formatTraitValue(attribute["value"]),
+				DisplayType: stringField(attribute, "display_type"),
+			}
+			if trait.Value != "" {
+				metadata.Traits = append(metadata.Traits, trait)
+			}
+		}
+	case map[string]interface{}:
+		metadata.Traits = traitsFromMap(attributes)
+	}
+
+	if len(metadata.Traits) == 0 {
+		if properties, ok := raw["properties"].(map[string]interface{}); ok {
+			metadata.Traits = traitsFromMap(properties)
+		}
+	}
+
+	return metadata, nil
+}
+
+// traitsFromMap converts {"Background": "Blue"} style traits into a sorted list
+func traitsFromMap(values map[string]interface{}) []NFTAttribute {
+	var traits []NFTAttribute
+	for key, value := range values {
+		// ERC1155 "properties" entries are sometimes objects with their own value
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = nested["value"]
+		}
+		if formatted := formatTraitValue(value); formatted != "" {
+			traits = append(traits, NFTAttribute{TraitType: key, Value: formatted})
+		}
+	}
+	sort.Slice(traits, func(i, j int) bool {
+		return traits[i].TraitType < traits[j].TraitType
+	})
+	return traits
+}
+
+// formatTraitValue renders a JSON trait value as text
+func formatTraitValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%g", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	}
+	return ""
+}
+
+// stringField returns the first non-empty string among the given keys
+func stringField(values map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := values[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// firstPresent returns the value of the first key that exists
+func firstPresent(values map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+func TestNFTMetadataResolveURI(t *testing.T) {
+	t.Setenv("IPFS_GATEWAY_URL", "http://localhost:8080/ipfs")
+	t.Setenv("ARWEAVE_GATEWAY_URL", "")
+	fetcher := NewNFTMetadataFetcher(false)
+
+	cid := "QmeSjSinHpPnmXmspMjwiXyN6zS4E9zccariGR3jxcaWtq"
+	require.Equal(t, "http://localhost:8080/ipfs/"+cid+"/1", fetcher.ResolveURI("ipfs://"+cid+"/1"))
+	require.Equal(t, "http://localhost:8080/ipfs/"+cid+"/1", fetcher.ResolveURI("ipfs://ipfs/"+cid+"/1"))
+	require.Equal(t, "http://localhost:8080/ipfs/"+cid, fetcher.ResolveURI(cid))
+	require.Equal(t, "http://localhost:8080/ipfs/"+cid+"/2", fetcher.ResolveURI("https://gateway.pinata.cloud/ipfs/"+cid+"/2"))
+	require.Equal(t, "https://arweave.net/abc123", fetcher.ResolveURI("ar://abc123"))
+	require.Equal(t, "https://example.com/meta/1", fetcher.ResolveURI("https://example.com/meta/1"))
+}
+
+func TestNFTMetadataFetchDataURI(t *testing.T) {
+	fetcher := NewNFTMetadataFetcher(false)
+	doc := `{"name":"Loot #1","image_data":"<svg xmlns='http://www.w3.org/2000/svg'></svg>","attributes":[{"trait_type":"Weapon","value":"Katana"},{"trait_type":"Level","value":3,"display_type":"number"}]}`
+
+	metadata, err := fetcher.FetchMetadata(context.Background(), "data:application/json;base64,"+base64.StdEncoding.EncodeToString([]byte(doc)))
+	require.NoError(t, err)
+	require.Equal(t, "Loot #1", metadata.Name)
+	require.Contains(t, metadata.Image, "data:image/svg+xml;base64,")
+	require.Equal(t, []NFTAttribute{
+		{TraitType: "Weapon", Value: "Katana"},
+		{TraitType: "Level", Value: "3", DisplayType: "number"},
+	}, metadata.Traits)
+
+	metadata, err = fetcher.FetchMetadata(context.Background(), `data:application/json,{"name":"Plain%20%231","properties":{"Color":"Red"}}`)
+	require.NoError(t, err)
+	require.Equal(t, "Plain #1", metadata.Name)
+	require.Equal(t, []NFTAttribute{{TraitType: "Color", Value: "Red"}}, metadata.Traits)
+}
+
+func TestNFTMetadataFetchHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ipfs/QmeSjSinHpPnmXmspMjwiXyN6zS4E9zccariGR3jxcaWtq/42", r.URL.Path)
+		w.Write([]byte(`{"name":"Ape #42","image":"ipfs://QmImage/42.png","attributes":[{"trait_type":"Fur","value":"Gold"}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("IPFS_GATEWAY_URL", server.URL+"/ipfs/")
+	fetcher := NewNFTMetadataFetcher(false)
+
+	metadata, err := fetcher.FetchMetadata(context.Background(), "ipfs://QmeSjSinHpPnmXmspMjwiXyN6zS4E9zccariGR3jxcaWtq/42")
+	require.NoError(t, err)
+	require.Equal(t, "Ape #42", metadata.Name)
+	require.Equal(t, server.URL+"/ipfs/QmImage/42.png", metadata.Image)
+	require.Equal(t, "ipfs://QmeSjSinHpPnmXmspMjwiXyN6zS4E9zccariGR3jxcaWtq/42", metadata.TokenURI)
+
+	_, err = fetcher.FetchMetadata(context.Background(), "ftp://example.com/1")
+	require.Error(t, err)
+}
+
+func TestNFTMetadataRefusesInternalHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"Internal"}`))
+	}))
+	defer server.Close()
+
+	// The gateway is configured by the operator and may be a local node; token URIs are chosen by the contract
+	t.Setenv("IPFS_GATEWAY_URL", server.URL+"/ipfs/")
+	fetcher := NewNFTMetadataFetcher(false)
+	_, err := fetcher.FetchMetadata(context.Background(), "ipfs://QmeSjSinHpPnmXmspMjwiXyN6zS4E9zccariGR3jxcaWtq/1")
+	require.NoError(t, err)
+
+	_, err = fetcher.FetchMetadata(context.Background(), server.URL+"/1")
+	require.ErrorContains(t, err, "non-public address")
+	_, err = fetcher.FetchMetadata(context.Background(), "http://localhost:"+server.URL[len("http://127.0.0.1:"):]+"/1")
+	require.ErrorContains(t, err, "non-public address", "names are checked after resolution")
+
+	// The check sits in the dialer, so the hops of a redirect from a public host are refused the same way
+	_, err = fetcher.publicClient.Get("http://169.254.169.254/latest/meta-data/")
+	require.ErrorContains(t, err, "non-public address")
+}
+
+func TestNFTDecoderFetchesMetadataConcurrently(t *testing.T) {
+	const networkID = 99008
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"name":"Token ` + strings.TrimPrefix(r.URL.Path, "/ipfs/") + `"}`))
+	}))
+	defer gateway.Close()
+	t.Setenv("IPFS_GATEWAY_URL", gateway.URL+"/ipfs/")
+
+	// tokenURI(id) returns ipfs://<id>
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int                      `json:"id"`
+			Params []map[string]interface{} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		id := new(big.Int).SetBytes(newABIData(request.Params[0]["data"].(string)[10:]).wordAt(0)).String()
+		uri := hex.EncodeToString([]byte("ipfs://" + id))
+		result := "0x" + abiWord("20") + encodeTail(uri)
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	defer node.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: node.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+
+	var transfers []NFTTransfer
+	for id := 1; id <= 10; id++ {
+		transfers = append(transfers, NFTTransfer{Type: "ERC721", Contract: testCollection, From: testSeller, To: testBuyer, TokenID: strconv.Itoa(id)})
+	}
+	started := time.Now()
+	metadata := NewNFTDecoder(nil, false, client).enrichTokenMetadata(context.Background(), map[string]interface{}{}, transfers)
+	require.Less(t, time.Since(started), 1500*time.Millisecond, "ten 200ms fetches run on a bounded pool")
+	require.Len(t, metadata, 10)
+	require.Equal(t, "Token 7", transfers[6].TokenName)
+}
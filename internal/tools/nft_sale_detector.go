@@ -0,0 +1,995 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// Marketplace events recognised by the sale detector. All are decoded from raw topics and data so
+// they work whether or not the marketplace contract is verified.
+var (
+	seaportOrderFulfilledTopic = rpc.GenerateEventSignature("OrderFulfilled(bytes32,address,address,address,(uint8,address,uint256,uint256)[],(uint8,address,uint256,uint256,address)[])")
+
+	blurOrdersMatchedTopic        = rpc.GenerateEventSignature("OrdersMatched(address,address,(address,uint8,address,address,uint256,uint256,address,uint256,uint256,uint256,(uint16,address)[],uint256,bytes),bytes32,(address,uint8,address,address,uint256,uint256,address,uint256,uint256,uint256,(uint16,address)[],uint256,bytes),bytes32)")
+	blurExecution721Topic         = rpc.GenerateEventSignature("Execution721Packed(bytes32,uint256,uint256)")
+	blurExecution721TakerFeeTopic = rpc.GenerateEventSignature("Execution721TakerFeePacked(bytes32,uint256,uint256,uint256)")
+	blurExecution721MakerFeeTopic = rpc.GenerateEventSignature("Execution721MakerFeePacked(bytes32,uint256,uint256,uint256)")
+
+	looksRareV1TakerBidTopic       = rpc.GenerateEventSignature("TakerBid(bytes32,uint256,address,address,address,address,address,uint256,uint256,uint256)")
+	looksRareV1TakerAskTopic       = rpc.GenerateEventSignature("TakerAsk(bytes32,uint256,address,address,address,address,address,uint256,uint256,uint256)")
+	looksRareV1RoyaltyPaymentTopic = rpc.GenerateEventSignature("RoyaltyPayment(address,uint256,address,address,uint256)")
+	looksRareV2TakerBidTopic       = rpc.GenerateEventSignature("TakerBid((bytes32,uint256,bool),address,address,uint256,address,address,uint256[],uint256[],address[2],uint256[3])")
+	looksRareV2TakerAskTopic       = rpc.GenerateEventSignature("TakerAsk((bytes32,uint256,bool),address,address,uint256,address,address,uint256[],uint256[],address[2],uint256[3])")
+
+	x2y2EvInventoryTopic = rpc.GenerateEventSignature("EvInventory(bytes32,address,address,uint256,uint256,uint256,uint256,uint256,address,bytes,(uint256,bytes),(uint8,uint256,uint256,uint256,bytes32,address,bytes,uint256,uint256,uint256,(uint256,address)[]))")
+
+	erc20TransferTopic = rpc.GenerateEventSignature("Transfer(address,address,uint256)")
+)
+
+// Marketplace fee recipients, used to split Seaport and X2Y2 consideration items into marketplace
+// fees and creator royalties
+var marketplaceFeeRecipients = map[string]string{
+	"0x0000a26b00c1f0df003000390027140000faa719": "OpenSea",
+	"0x5b3256965e7c3cf26e11fcaf296dfc8807c01073": "OpenSea",
+	"0x8de9c5a032463c561423387a9648c5c7bcc5bc90": "OpenSea",
+	"0xd823c605807cc5e6bd6fc0d7e4eea50d3e2d66cd": "X2Y2",
+}
+
+// blurPoolAddress is Blur's ETH deposit token used for bids; it is redeemable 1:1 for ETH
+const blurPoolAddress = "0x0000000000a39bb272e79075ade125fd351887ac"
+
+// nativePaymentToken marks sales paid in the chain's native currency, matching DetectedAmount.TokenContract
+const nativePaymentToken = "native"
+
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// NFTSale is a single NFT purchase: one collection changing hands between a seller and a buyer for a price
+type NFTSale struct {
+	Marketplace    string   `json:"marketplace,omitempty"` // "OpenSea", "Seaport", "Blur", "LooksRare", "X2Y2"; empty for pattern matches
+	Collection     string   `json:"collection"`            // NFT contract address
+	CollectionName string   `json:"collection_name,omitempty"`
+	TokenIDs       []string `json:"token_ids"`
+	Buyer          string   `json:"buyer"`
+	Seller         string   `json:"seller"`
+
+	PaymentToken    string `json:"payment_token"` // "native" or the ERC20 contract address
+	PaymentSymbol   string `json:"payment_symbol,omitempty"`
+	PaymentDecimals int    `json:"payment_decimals"`
+
+	// Raw amounts in the payment token's smallest unit, as decimal strings
+	Price          string `json:"price"`
+	MarketplaceFee string `json:"marketplace_fee,omitempty"`
+	Royalty        string `json:"royalty,omitempty"`
+	SellerProceeds string `json:"seller_proceeds,omitempty"`
+
+	Detection string `json:"detection"` // "event" when decoded from a marketplace event, "transfer-pattern" otherwise
+
+	// Filled in by the monetary value enricher when prices are available
+	PriceFormatted string  `json:"price_formatted,omitempty"`
+	PriceUSD       float64 `json:"price_usd,omitempty"`
+}
+
+// NFTSaleDetector finds NFT marketplace sales by decoding Seaport, Blur, LooksRare and X2Y2 events,
+// falling back to matching NFT transfers against payments flowing the other way
+type NFTSaleDetector struct {
+	verbose bool
+}
+
+// NewNFTSaleDetector creates a new NFT sale detector
+func NewNFTSaleDetector(verbose bool) *NFTSaleDetector {
+	return &NFTSaleDetector{
+		verbose: verbose,
+	}
+}
+
+// Name returns the processor name
+func (d *NFTSaleDetector) Name() string {
+	return "nft_sale_detector"
+}
+
+// Description returns the processor description
+func (d *NFTSaleDetector) Description() string {
+	return "Detects NFT marketplace sales with price, marketplace fee and royalty breakdowns"
+}
+
+// Dependencies returns the tools this processor depends on
+func (d *NFTSaleDetector) Dependencies() []string {
+	return []string{"log_decoder", "nft_decoder", "token_metadata_enricher"}
+}
+
+// Process detects NFT sales and stores them in baggage["nft_sales"]
+func (d *NFTSaleDetector) Process(ctx context.Context, baggage map[string]interface{}) error {
+	nftTransfers, _ := GetNFTTransfers(baggage)
+	if len(nftTransfers) == 0 {
+		return nil // No NFT moved, so nothing was sold
+	}
+
+	if progressTracker, ok := baggage["progress_tracker"].(*models.ProgressTracker); ok {
+		progressTracker.UpdateComponent("nft_sale_detector", models.ComponentGroupEnrichment, "Detecting NFT Sales", models.ComponentStatusRunning, "Matching NFT transfers with marketplace payments...")
+	}
+
+	events, _ := baggage["events"].([]models.Event)
+	sales := d.detectSales(events, nftTransfers, transactionSender(baggage), nativeTransfersFromTrace(baggage))
+
+	tokenMetadata, _ := baggage["token_metadata"].(map[string]*TokenMetadata)
+	for i := range sales {
+		sale := &sales[i]
+		if sale.PaymentToken == nativePaymentToken {
+			sale.PaymentDecimals = 18
+			continue
+		}
+		if metadata, ok := tokenMetadata[sale.PaymentToken]; ok {
+			sale.PaymentSymbol = metadata.Symbol
+			sale.PaymentDecimals = metadata.Decimals
+		}
+	}
+
+	if d.verbose || os.Getenv("DEBUG") == "true" {
+		fmt.Printf("🛒 NFT SALE DETECTOR: found %d sales\n", len(sales))
+		for _, sale := range sales {
+			fmt.Printf("   %s %s #%s: %s -> %s for %s %s (%s)\n", sale.Marketplace, sale.Collection, strings.Join(sale.TokenIDs, ","), sale.Seller, sale.Buyer, sale.Price, sale.PaymentToken, sale.Detection)
+		}
+	}
+
+	if len(sales) > 0 {
+		baggage["nft_sales"] = sales
+	}
+	return nil
+}
+
+// detectSales decodes marketplace events first, then pairs any remaining NFT transfers with payments
+func (d *NFTSaleDetector) detectSales(events []models.Event, nftTransfers []NFTTransfer, txSender string, nativeTransfers []paymentFlow) []NFTSale {
+	var sales []NFTSale
+	for i, event := range events {
+		if len(event.Topics) == 0 {
+			continue
+		}
+		switch strings.ToLower(event.Topics[0]) {
+		case seaportOrderFulfilledTopic:
+			if sale := decodeSeaportOrderFulfilled(event); sale != nil {
+				sales = append(sales, *sale)
+			}
+		case blurOrdersMatchedTopic:
+			if sale := decodeBlurOrdersMatched(event); sale != nil {
+				sales = append(sales, *sale)
+			}
+		case blurExecution721Topic, blurExecution721TakerFeeTopic, blurExecution721MakerFeeTopic:
+			if sale := decodeBlurExecution(event); sale != nil {
+				sales = append(sales, *sale)
+			}
+		case looksRareV1TakerBidTopic, looksRareV1TakerAskTopic:
+			if sale := decodeLooksRareV1(event, events[:i]); sale != nil {
+				sales = append(sales, *sale)
+			}
+		case looksRareV2TakerBidTopic, looksRareV2TakerAskTopic:
+			if sale := decodeLooksRareV2(event); sale != nil {
+				sales = append(sales, *sale)
+			}
+		case x2y2EvInventoryTopic:
+			if sale := decodeX2Y2Inventory(event); sale != nil {
+				sales = append(sales, *sale)
+			}
+		}
+	}
+
+	sales = mergeSales(sales)
+	completeSalesFromTransfers(sales, nftTransfers, txSender)
+
+	payments := append(erc20TransfersFromEvents(events), nativeTransfers...)
+	sales = append(sales, detectSalesFromTransfers(sales, nftTransfers, payments)...)
+
+	// Attach collection names for display
+	for i := range sales {
+		for _, transfer := range nftTransfers {
+			if strings.EqualFold(transfer.Contract, sales[i].Collection) {
+				if transfer.CollectionName != "" {
+					sales[i].CollectionName = transfer.CollectionName
+				} else {
+					sales[i].CollectionName = transfer.Name
+				}
+				break
+			}
+		}
+	}
+
+	return sales
+}
+
+// seaportItem is a SpentItem or ReceivedItem from a Seaport OrderFulfilled event
+type seaportItem struct {
+	itemType   int // 0 native, 1 ERC20, 2 ERC721, 3 ERC1155, 4/5 criteria-based ERC721/ERC1155
+	token      string
+	identifier *big.Int
+	amount     *big.Int
+	recipient  string
+}
+
+// decodeSeaportOrderFulfilled decodes OrderFulfilled(orderHash, offerer indexed, zone indexed, recipient, offer, consideration).
+// A listing offers the NFT and asks for payment; an accepted offer is the reverse.
+func decodeSeaportOrderFulfilled(event models.Event) *NFTSale {
+	if len(event.Topics) < 3 {
+		return nil
+	}
+	data := newABIData(event.Data)
+	offerer := topicAddress(event.Topics[1])
+	recipient := data.addressAt(32)
+
+	offerOffset, ok := data.intAt(64)
+	if !ok {
+		return nil
+	}
+	considerationOffset, ok := data.intAt(96)
+	if !ok {
+		return nil
+	}
+	offer := data.seaportItems(offerOffset, 4)
+	consideration := data.seaportItems(considerationOffset, 5)
+
+	offerNFTs, offerPayments := splitSeaportItems(offer)
+	considerationNFTs, considerationPayments := splitSeaportItems(consideration)
+
+	sale := &NFTSale{Marketplace: "Seaport", Detection: "event"}
+	var nfts, payments []seaportItem
+	switch {
+	case len(offerNFTs) > 0 && len(considerationPayments) > 0:
+		// Listing: the offerer sells, payments go to the offerer and fee recipients
+		sale.Seller, sale.Buyer = offerer, recipient
+		nfts, payments = offerNFTs, considerationPayments
+	case len(considerationNFTs) > 0 && len(offerPayments) > 0:
+		// Accepted offer: the offerer pays; consideration payments are fees taken from the seller's proceeds
+		sale.Buyer, sale.Seller = offerer, recipient
+		nfts, payments = considerationNFTs, considerationPayments
+	default:
+		return nil // NFT-for-NFT swaps and pure token trades are not sales
+	}
+
+	sale.Collection = nfts[0].token
+	for _, item := range nfts {
+		if item.token == sale.Collection {
+			sale.TokenIDs = append(sale.TokenIDs, item.identifier.String())
+		}
+	}
+
+	price := new(big.Int)
+	fee := new(big.Int)
+	royalty := new(big.Int)
+	source := payments
+	if len(offerPayments) > 0 && len(offerNFTs) == 0 {
+		source = offerPayments
+	}
+	sale.PaymentToken = seaportPaymentToken(source[0])
+	for _, item := range source {
+		price.Add(price, item.amount)
+	}
+	for _, item := range payments {
+		if item.recipient == sale.Seller && len(offerNFTs) > 0 {
+			continue // Seller proceeds on a listing
+		}
+		if marketplace, ok := marketplaceFeeRecipients[item.recipient]; ok {
+			sale.Marketplace = marketplace
+			fee.Add(fee, item.amount)
+		} else if item.recipient != sale.Buyer {
+			royalty.Add(royalty, item.amount)
+		}
+	}
+
+	sale.setAmounts(price, fee, royalty)
+	return sale
+}
+
+// splitSeaportItems separates NFT items from native/ERC20 payment items
+func splitSeaportItems(items []seaportItem) (nfts, payments []seaportItem) {
+	for _, item := range items {
+		if item.itemType >= 2 {
+			nfts = append(nfts, item)
+		} else {
+			payments = append(payments, item)
+		}
+	}
+	return nfts, payments
+}
+
+func seaportPaymentToken(item seaportItem) string {
+	if item.itemType == 0 {
+		return nativePaymentToken
+	}
+	return item.token
+}
+
+// decodeBlurOrdersMatched decodes Blur v1 OrdersMatched(maker indexed, taker indexed, sell, sellHash, buy, buyHash).
+// Royalties are the fee entries of the sell order, in basis points of the price.
+func decodeBlurOrdersMatched(event models.Event) *NFTSale {
+	data := newABIData(event.Data)
+	sellOffset, ok := data.intAt(0)
+	if !ok {
+		return nil
+	}
+	buyOffset, ok := data.intAt(64)
+	if !ok {
+		return nil
+	}
+
+	// Order head: trader, side, matchingPolicy, collection, tokenId, amount, paymentToken, price, listingTime, expirationTime, fees, ...
+	price := data.uintAt(sellOffset + 7*32)
+	collection := data.addressAt(sellOffset + 3*32)
+	if price == nil || collection == "" {
+		return nil
+	}
+
+	sale := &NFTSale{
+		Marketplace:  "Blur",
+		Collection:   collection,
+		TokenIDs:     []string{data.uintAt(sellOffset + 4*32).String()},
+		Seller:       data.addressAt(sellOffset),
+		Buyer:        data.addressAt(buyOffset),
+		PaymentToken: blurPaymentToken(data.addressAt(sellOffset + 6*32)),
+		Detection:    "event",
+	}
+
+	royalty := new(big.Int)
+	if feesOffset, ok := data.intAt(sellOffset + 10*32); ok {
+		feesStart := sellOffset + feesOffset
+		count, _ := data.intAt(feesStart)
+		for i := 0; i < count; i++ {
+			rate := data.uintAt(feesStart + 32 + i*64)
+			if rate == nil {
+				break
+			}
+			royalty.Add(royalty, basisPoints(price, rate))
+		}
+	}
+
+	sale.setAmounts(price, new(big.Int), royalty)
+	return sale
+}
+
+// decodeBlurExecution decodes the packed Blur v2 Execution721 events:
+//
+//	tokenIdListingIndexTrader = tokenId << 168 | listingIndex << 160 | trader
+//	collectionPriceSide       = orderType << 248 | price << 160 | collection
+//	feeRecipientRate          = rate << 160 | recipient
+//
+// Asks are paid in ETH and bids in Blur Pool. Blur takes no marketplace fee; the maker/taker fee
+// words carry creator royalties.
+func decodeBlurExecution(event models.Event) *NFTSale {
+	data := newABIData(event.Data)
+	tokenWord := data.wordAt(32)
+	collectionWord := data.wordAt(64)
+	if tokenWord == nil || collectionWord == nil {
+		return nil
+	}
+
+	trader := "0x" + hex.EncodeToString(tokenWord[12:])
+	price := new(big.Int).SetBytes(collectionWord[1:12])
+	sale := &NFTSale{
+		Marketplace:  "Blur",
+		Collection:   "0x" + hex.EncodeToString(collectionWord[12:]),
+		TokenIDs:     []string{new(big.Int).SetBytes(tokenWord[:11]).String()},
+		PaymentToken: nativePaymentToken,
+		Detection:    "event",
+	}
+	if collectionWord[0] == 0 {
+		sale.Seller = trader // ASK: the listing's maker sells
+	} else {
+		sale.Buyer = trader // BID: the bidder buys
+	}
+
+	royalty := new(big.Int)
+	if feeWord := data.wordAt(96); feeWord != nil {
+		rate := new(big.Int).SetBytes(feeWord[10:12])
+		royalty.Add(royalty, basisPoints(price, rate))
+	}
+
+	sale.setAmounts(price, new(big.Int), royalty)
+	return sale
+}
+
+// blurPaymentToken maps ETH and Blur Pool payments to the native token
+func blurPaymentToken(token string) string {
+	if token == "" || token == zeroAddress || token == blurPoolAddress {
+		return nativePaymentToken
+	}
+	return token
+}
+
+// decodeLooksRareV1 decodes TakerBid/TakerAsk(orderHash, orderNonce, taker indexed, maker indexed, strategy indexed,
+// currency, collection, tokenId, amount, price). The royalty is reported by a RoyaltyPayment event emitted just before,
+// so preceding is scanned backwards up to the previous sale of a batch.
+func decodeLooksRareV1(event models.Event, preceding []models.Event) *NFTSale {
+	if len(event.Topics) < 4 {
+		return nil
+	}
+	data := newABIData(event.Data)
+	price := data.uintAt(6 * 32)
+	if price == nil {
+		return nil
+	}
+
+	taker := topicAddress(event.Topics[1])
+	maker := topicAddress(event.Topics[2])
+	sale := &NFTSale{
+		Marketplace:  "LooksRare",
+		Collection:   data.addressAt(3 * 32),
+		TokenIDs:     []string{data.uintAt(4 * 32).String()},
+		PaymentToken: data.addressAt(2 * 32),
+		Detection:    "event",
+	}
+	if strings.EqualFold(event.Topics[0], looksRareV1TakerBidTopic) {
+		sale.Buyer, sale.Seller = taker, maker
+	} else {
+		sale.Buyer, sale.Seller = maker, taker
+	}
+	if sale.PaymentToken == zeroAddress {
+		sale.PaymentToken = nativePaymentToken
+	}
+
+	royalty := new(big.Int)
+	for i := len(preceding) - 1; i >= 0; i-- {
+		candidate := preceding[i]
+		if len(candidate.Topics) == 0 {
+			continue
+		}
+		topic := strings.ToLower(candidate.Topics[0])
+		if topic == looksRareV1TakerBidTopic || topic == looksRareV1TakerAskTopic {
+			break // Previous sale in a batch
+		}
+		if topic != looksRareV1RoyaltyPaymentTopic || len(candidate.Topics) < 3 || topicAddress(candidate.Topics[1]) != sale.Collection {
+			continue
+		}
+		if tokenID := newABIData(candidate.Topics[2]).uintAt(0); tokenID != nil && tokenID.String() == sale.TokenIDs[0] {
+			if amount := newABIData(candidate.Data).uintAt(32); amount != nil {
+				royalty.Add(royalty, amount)
+			}
+		}
+	}
+
+	sale.setAmounts(price, new(big.Int), royalty)
+	return sale
+}
+
+// decodeLooksRareV2 decodes TakerBid/TakerAsk(nonceInvalidationParameters, user, recipient, strategyId, currency,
+// collection, itemIds, amounts, feeRecipients[2], feeAmounts[3]) where feeAmounts are seller proceeds,
+// creator royalty and protocol fee.
+func decodeLooksRareV2(event models.Event) *NFTSale {
+	data := newABIData(event.Data)
+	proceeds := data.uintAt(12 * 32)
+	royalty := data.uintAt(13 * 32)
+	fee := data.uintAt(14 * 32)
+	if proceeds == nil || royalty == nil || fee == nil {
+		return nil
+	}
+
+	sale := &NFTSale{
+		Marketplace:  "LooksRare",
+		Collection:   data.addressAt(7 * 32),
+		PaymentToken: data.addressAt(6 * 32),
+		Detection:    "event",
+	}
+	if strings.EqualFold(event.Topics[0], looksRareV2TakerBidTopic) {
+		sale.Buyer = data.addressAt(3 * 32)
+		sale.Seller = data.addressAt(10 * 32)
+	} else {
+		sale.Seller = data.addressAt(3 * 32)
+		sale.Buyer = data.addressAt(4 * 32)
+	}
+	if sale.PaymentToken == zeroAddress {
+		sale.PaymentToken = nativePaymentToken
+	}
+
+	if idsOffset, ok := data.intAt(8 * 32); ok {
+		count, _ := data.intAt(idsOffset)
+		for i := 0; i < count; i++ {
+			tokenID := data.uintAt(idsOffset + 32 + i*32)
+			if tokenID == nil {
+				break
+			}
+			sale.TokenIDs = append(sale.TokenIDs, tokenID.String())
+		}
+	}
+
+	price := new(big.Int).Add(proceeds, royalty)
+	price.Add(price, fee)
+	sale.setAmounts(price, fee, royalty)
+	return sale
+}
+
+// decodeX2Y2Inventory decodes EvInventory(itemHash indexed, maker, taker, orderSalt, settleSalt, intent, delegateType,
+// deadline, currency, dataMask, item, detail). The NFT itself is only in the order's encoded data, so the collection
+// and token are taken from the matching NFT transfer afterwards.
+func decodeX2Y2Inventory(event models.Event) *NFTSale {
+	data := newABIData(event.Data)
+	itemOffset, ok := data.intAt(9 * 32)
+	if !ok {
+		return nil
+	}
+	price := data.uintAt(itemOffset)
+	if price == nil {
+		return nil
+	}
+
+	maker := data.addressAt(0)
+	taker := data.addressAt(32)
+	sale := &NFTSale{
+		Marketplace:  "X2Y2",
+		PaymentToken: data.addressAt(7 * 32),
+		Detection:    "event",
+	}
+	intent := data.uintAt(4 * 32)
+	if intent != nil && intent.Int64() == 3 {
+		sale.Buyer, sale.Seller = maker, taker // INTENT_BUY: the maker placed an offer
+	} else {
+		sale.Seller, sale.Buyer = maker, taker // INTENT_SELL / INTENT_AUCTION
+	}
+	if sale.PaymentToken == zeroAddress {
+		sale.PaymentToken = nativePaymentToken
+	}
+
+	// Fee percentages are scaled by 1e6
+	fee := new(big.Int)
+	royalty := new(big.Int)
+	if detailOffset, ok := data.intAt(10 * 32); ok {
+		if feesOffset, ok := data.intAt(detailOffset + 10*32); ok {
+			feesStart := detailOffset + feesOffset
+			count, _ := data.intAt(feesStart)
+			for i := 0; i < count; i++ {
+				percentage := data.uintAt(feesStart + 32 + i*64)
+				recipient := data.addressAt(feesStart + 64 + i*64)
+				if percentage == nil {
+					break
+				}
+				amount := new(big.Int).Mul(price, percentage)
+				amount.Div(amount, big.NewInt(1_000_000))
+				if _, ok := marketplaceFeeRecipients[recipient]; ok {
+					fee.Add(fee, amount)
+				} else {
+					royalty.Add(royalty, amount)
+				}
+			}
+		}
+	}
+
+	sale.setAmounts(price, fee, royalty)
+	return sale
+}
+
+// setAmounts records the price and fee breakdown; seller proceeds are whatever the fees leave
+func (s *NFTSale) setAmounts(price, fee, royalty *big.Int) {
+	s.Price = price.String()
+	if fee.Sign() > 0 {
+		s.MarketplaceFee = fee.String()
+	}
+	if royalty.Sign() > 0 {
+		s.Royalty = royalty.String()
+	}
+	proceeds := new(big.Int).Sub(price, fee)
+	proceeds.Sub(proceeds, royalty)
+	if proceeds.Sign() >= 0 {
+		s.SellerProceeds = proceeds.String()
+	}
+}
+
+// basisPoints returns amount * rate / 10000
+func basisPoints(amount, rate *big.Int) *big.Int {
+	result := new(big.Int).Mul(amount, rate)
+	return result.Div(result, big.NewInt(10000))
+}
+
+// saleKey identifies a sale by collection and token IDs
+func saleKey(collection string, tokenIDs []string) string {
+	ids := append([]string(nil), tokenIDs...)
+	sort.Strings(ids)
+	return strings.ToLower(collection) + ":" + strings.Join(ids, ",")
+}
+
+// mergeSales collapses sales reported twice for the same tokens (e.g. both sides of a Seaport matchOrders),
+// keeping the first and filling in parties it is missing
+func mergeSales(sales []NFTSale) []NFTSale {
+	var merged []NFTSale
+	index := make(map[string]int)
+	for _, sale := range sales {
+		if sale.Collection == "" {
+			merged = append(merged, sale) // X2Y2 sales get their collection later
+			continue
+		}
+		key := saleKey(sale.Collection, sale.TokenIDs)
+		if i, ok := index[key]; ok {
+			existing := &merged[i]
+			if isEmptyAddress(existing.Buyer) {
+				existing.Buyer = sale.Buyer
+			}
+			if isEmptyAddress(existing.Seller) {
+				existing.Seller = sale.Seller
+			}
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, sale)
+	}
+	return merged
+}
+
+// completeSalesFromTransfers fills in parties and tokens the marketplace event did not carry using the NFT transfers
+func completeSalesFromTransfers(sales []NFTSale, nftTransfers []NFTTransfer, txSender string) {
+	for i := range sales {
+		sale := &sales[i]
+
+		if sale.Collection == "" {
+			// Match the transfer between the two parties
+			for _, transfer := range nftTransfers {
+				if strings.EqualFold(transfer.From, sale.Seller) && strings.EqualFold(transfer.To, sale.Buyer) {
+					if sale.Collection == "" {
+						sale.Collection = strings.ToLower(transfer.Contract)
+					}
+					if strings.EqualFold(transfer.Contract, sale.Collection) {
+						sale.TokenIDs = append(sale.TokenIDs, transfer.TokenID)
+					}
+				}
+			}
+		}
+
+		if !isEmptyAddress(sale.Buyer) && !isEmptyAddress(sale.Seller) {
+			continue
+		}
+		for _, transfer := range nftTransfers {
+			if !strings.EqualFold(transfer.Contract, sale.Collection) || !containsString(sale.TokenIDs, transfer.TokenID) {
+				continue
+			}
+			if isEmptyAddress(sale.Buyer) && !isEmptyAddress(transfer.To) {
+				sale.Buyer = strings.ToLower(transfer.To)
+			}
+			if isEmptyAddress(sale.Seller) && !isEmptyAddress(transfer.From) {
+				sale.Seller = strings.ToLower(transfer.From)
+			}
+		}
+		if isEmptyAddress(sale.Buyer) {
+			sale.Buyer = txSender
+		}
+	}
+}
+
+// paymentFlow is a native or ERC20 value transfer considered as payment for NFTs
+type paymentFlow struct {
+	token  string // "native" or ERC20 contract
+	from   string
+	to     string
+	amount *big.Int
+}
+
+// detectSalesFromTransfers pairs NFT transfers that no marketplace event explained with payments moving the other
+// way: what the recipient paid is the price, what the sender received is the proceeds, and the difference went to fees
+func detectSalesFromTransfers(known []NFTSale, nftTransfers []NFTTransfer, payments []paymentFlow) []NFTSale {
+	covered := make(map[string]bool)
+	for _, sale := range known {
+		for _, tokenID := range sale.TokenIDs {
+			covered[strings.ToLower(sale.Collection)+":"+tokenID] = true
+		}
+	}
+
+	var order []string
+	groups := make(map[string]*NFTSale)
+	for _, transfer := range nftTransfers {
+		if isEmptyAddress(transfer.From) || isEmptyAddress(transfer.To) {
+			continue // Mints and burns
+		}
+		contract := strings.ToLower(transfer.Contract)
+		if covered[contract+":"+transfer.TokenID] {
+			continue
+		}
+		seller, buyer := strings.ToLower(transfer.From), strings.ToLower(transfer.To)
+		key := contract + ":" + seller + ":" + buyer
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+			groups[key] = &NFTSale{
+				Collection: contract,
+				Seller:     seller,
+				Buyer:      buyer,
+				Detection:  "transfer-pattern",
+			}
+		}
+		groups[key].TokenIDs = append(groups[key].TokenIDs, transfer.TokenID)
+	}
+
+	var sales []NFTSale
+	for _, key := range order {
+		sale := *groups[key]
+
+		// Net token flows for the buyer and seller
+		paid := make(map[string]*big.Int)
+		received := make(map[string]*big.Int)
+		var tokens []string
+		for _, payment := range payments {
+			if _, ok := paid[payment.token]; !ok {
+				paid[payment.token] = new(big.Int)
+				received[payment.token] = new(big.Int)
+				tokens = append(tokens, payment.token)
+			}
+			switch {
+			case payment.from == sale.Buyer:
+				paid[payment.token].Add(paid[payment.token], payment.amount)
+			case payment.to == sale.Buyer:
+				paid[payment.token].Sub(paid[payment.token], payment.amount)
+			}
+			switch {
+			case payment.to == sale.Seller:
+				received[payment.token].Add(received[payment.token], payment.amount)
+			case payment.from == sale.Seller:
+				received[payment.token].Sub(received[payment.token], payment.amount)
+			}
+		}
+
+		for _, token := range tokens {
+			price, proceeds := paid[token], received[token]
+			if price.Sign() <= 0 {
+				if proceeds.Sign() <= 0 {
+					continue
+				}
+				price = proceeds // The buyer paid through a router; only the seller's side is visible
+			}
+			sale.PaymentToken = token
+			sale.Price = price.String()
+			if proceeds.Sign() > 0 && proceeds.Cmp(price) <= 0 {
+				sale.SellerProceeds = proceeds.String()
+			}
+			sales = append(sales, sale)
+			break
+		}
+	}
+	return sales
+}
+
+// erc20TransfersFromEvents collects ERC20 Transfer logs; ERC721 transfers share the topic but index the token ID
+func erc20TransfersFromEvents(events []models.Event) []paymentFlow {
+	var flows []paymentFlow
+	for _, event := range events {
+		if len(event.Topics) != 3 || strings.ToLower(event.Topics[0]) != erc20TransferTopic {
+			continue
+		}
+		amount := newABIData(event.Data).uintAt(0)
+		if amount == nil || amount.Sign() == 0 {
+			continue
+		}
+		flows = append(flows, paymentFlow{
+			token:  strings.ToLower(event.Contract),
+			from:   topicAddress(event.Topics[1]),
+			to:     topicAddress(event.Topics[2]),
+			amount: amount,
+		})
+	}
+	return flows
+}
+
+// nativeTransfersFromTrace walks the call trace for value transfers
+func nativeTransfersFromTrace(baggage map[string]interface{}) []paymentFlow {
+	rawData, ok := baggage["raw_data"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	trace, ok := rawData["trace"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var flows []paymentFlow
+	var walk func(frame map[string]interface{})
+	walk = func(frame map[string]interface{}) {
+		callType, _ := frame["type"].(string)
+		value, _ := frame["value"].(string)
+		from, _ := frame["from"].(string)
+		to, _ := frame["to"].(string)
+		if !strings.EqualFold(callType, "DELEGATECALL") && value != "" {
+			if amount, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16); ok && amount.Sign() > 0 {
+				flows = append(flows, paymentFlow{
+					token:  nativePaymentToken,
+					from:   strings.ToLower(from),
+					to:     strings.ToLower(to),
+					amount: amount,
+				})
+			}
+		}
+		if calls, ok := frame["calls"].([]interface{}); ok {
+			for _, call := range calls {
+				if child, ok := call.(map[string]interface{}); ok {
+					walk(child)
+				}
+			}
+		}
+	}
+	walk(trace)
+	return flows
+}
+
+// transactionSender returns the lower-cased transaction sender from the receipt
+func transactionSender(baggage map[string]interface{}) string {
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+			if from, ok := receipt["from"].(string); ok {
+				return strings.ToLower(from)
+			}
+		}
+	}
+	return ""
+}
+
+// abiData is an ABI-encoded log data payload read by byte offset
+type abiData []byte
+
+func newABIData(hexData string) abiData {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(hexData, "0x"))
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// wordAt returns the 32-byte word at a byte offset, or nil when out of range
+func (d abiData) wordAt(offset int) []byte {
+	if offset < 0 || offset+32 > len(d) {
+		return nil
+	}
+	return d[offset : offset+32]
+}
+
+func (d abiData) uintAt(offset int) *big.Int {
+	word := d.wordAt(offset)
+	if word == nil {
+		return nil
+	}
+	return new(big.Int).SetBytes(word)
+}
+
+func (d abiData) addressAt(offset int) string {
+	word := d.wordAt(offset)
+	if word == nil {
+		return ""
+	}
+	return "0x" + hex.EncodeToString(word[12:])
+}
+
+// intAt reads an offset or length word, rejecting values that point outside the payload
+func (d abiData) intAt(offset int) (int, bool) {
+	value := d.uintAt(offset)
+	if value == nil || !value.IsInt64() || value.Int64() > int64(len(d)) {
+		return 0, false
+	}
+	return int(value.Int64()), true
+}
+
+// seaportItems decodes an array of SpentItem (4 words) or ReceivedItem (5 words) at a byte offset
+func (d abiData) seaportItems(offset, words int) []seaportItem {
+	count, ok := d.intAt(offset)
+	if !ok {
+		return nil
+	}
+	var items []seaportItem
+	for i := 0; i < count; i++ {
+		base := offset + 32 + i*words*32
+		itemType := d.uintAt(base)
+		amount := d.uintAt(base + 96)
+		if itemType == nil || amount == nil {
+			break
+		}
+		item := seaportItem{
+			itemType:   int(itemType.Int64()),
+			token:      d.addressAt(base + 32),
+			identifier: d.uintAt(base + 64),
+			amount:     amount,
+		}
+		if words == 5 {
+			item.recipient = d.addressAt(base + 128)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// topicAddress extracts the address from an indexed address topic
+func topicAddress(topic string) string {
+	topic = strings.ToLower(strings.TrimPrefix(topic, "0x"))
+	if len(topic) < 40 {
+		return ""
+	}
+	return "0x" + topic[len(topic)-40:]
+}
+
+func isEmptyAddress(address string) bool {
+	return address == "" || strings.EqualFold(address, zeroAddress)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNFTSales is a helper function to get detected NFT sales from baggage
+func GetNFTSales(baggage map[string]interface{}) ([]NFTSale, bool) {
+	if sales, ok := baggage["nft_sales"].([]NFTSale); ok {
+		return sales, true
+	}
+	return nil, false
+}
+
+// GetPromptContext describes each sale in one line plus its fee breakdown
+func (d *NFTSaleDetector) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	sales, ok := GetNFTSales(baggage)
+	if !ok || len(sales) == 0 {
+		return ""
+	}
+
+	var contextParts []string
+	contextParts = append(contextParts, "### NFT Sales:")
+	contextParts = append(contextParts, "These NFTs were BOUGHT, not just transferred - describe them as purchases with the price paid.")
+
+	for i, sale := range sales {
+		collection := sale.CollectionName
+		if collection == "" {
+			collection = sale.Collection
+		}
+		marketplace := sale.Marketplace
+		if marketplace == "" {
+			marketplace = "unknown marketplace (matched from transfers and payments)"
+		}
+
+		saleInfo := fmt.Sprintf("\nSale #%d:", i+1)
+		saleInfo += fmt.Sprintf("\n- %s bought %s #%s from %s", sale.Buyer, collection, strings.Join(sale.TokenIDs, ", #"), sale.Seller)
+		saleInfo += fmt.Sprintf("\n- Price: %s", sale.formatAmount(sale.Price))
+		if sale.PriceUSD > 0 {
+			saleInfo += fmt.Sprintf(" ($%.2f)", sale.PriceUSD)
+		}
+		saleInfo += fmt.Sprintf("\n- Marketplace: %s", marketplace)
+		if sale.MarketplaceFee != "" {
+			saleInfo += fmt.Sprintf("\n- Marketplace fee: %s", sale.formatAmount(sale.MarketplaceFee))
+		}
+		if sale.Royalty != "" {
+			saleInfo += fmt.Sprintf("\n- Creator royalty: %s", sale.formatAmount(sale.Royalty))
+		}
+		if sale.SellerProceeds != "" && sale.SellerProceeds != sale.Price {
+			saleInfo += fmt.Sprintf("\n- Seller received: %s", sale.formatAmount(sale.SellerProceeds))
+		}
+		saleInfo += fmt.Sprintf("\n- Collection contract: %s", sale.Collection)
+		contextParts = append(contextParts, saleInfo)
+	}
+
+	return strings.Join(contextParts, "\n")
+}
+
+// formatAmount renders a raw amount of the sale's payment token
+func (s NFTSale) formatAmount(raw string) string {
+	amount, ok := new(big.Float).SetString(raw)
+	if !ok {
+		return raw
+	}
+	decimals := s.PaymentDecimals
+	if decimals == 0 && s.PaymentToken == nativePaymentToken {
+		decimals = 18
+	}
+	if decimals > 0 {
+		amount.Quo(amount, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	}
+
+	symbol := s.PaymentSymbol
+	if symbol == "" {
+		if s.PaymentToken == nativePaymentToken {
+			symbol = "native token"
+		} else {
+			symbol = "of token " + s.PaymentToken
+		}
+	}
+	return strings.TrimRight(strings.TrimRight(amount.Text('f', 6), "0"), ".") + " " + symbol
+}
+
+// GetRagContext provides RAG context for NFT sales (none - sales are transaction specific)
+func (d *NFTSaleDetector) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
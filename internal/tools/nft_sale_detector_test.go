@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+func abiWord(hexValue string) string {
+	hexValue = strings.TrimPrefix(hexValue, "0x")
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+const (
+	testSeller     = "0x1111111111111111111111111111111111111111"
+	testBuyer      = "0x2222222222222222222222222222222222222222"
+	testCollection = "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d"
+	testCreator    = "0x3333333333333333333333333333333333333333"
+	testWETH       = "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2"
+)
+
+func TestDecodeSeaportListing(t *testing.T) {
+	// Offer: 1 ERC721; consideration: 9.75 ETH to seller, 0.25 ETH OpenSea fee
+	data := abiWord("ab") + // orderHash
+		abiWord(testBuyer) + // recipient
+		abiWord("80") + // offer offset
+		abiWord("120") + // consideration offset
+		abiWord("1") + abiWord("2") + abiWord(testCollection) + abiWord("2a") + abiWord("1") +
+		abiWord("2") +
+		abiWord("0") + abiWord("0") + abiWord("0") + abiWord("874ef557a00f0000") + abiWord(testSeller) +
+		abiWord("0") + abiWord("0") + abiWord("0") + abiWord("3782dace9d90000") + abiWord("0000a26b00c1f0df003000390027140000faa719")
+
+	event := models.Event{
+		Topics: []string{seaportOrderFulfilledTopic, "0x" + abiWord(testSeller), "0x" + abiWord("0")},
+		Data:   "0x" + data,
+	}
+
+	sale := decodeSeaportOrderFulfilled(event)
+	require.NotNil(t, sale)
+	require.Equal(t, "OpenSea", sale.Marketplace)
+	require.Equal(t, testCollection, sale.Collection)
+	require.Equal(t, []string{"42"}, sale.TokenIDs)
+	require.Equal(t, testSeller, sale.Seller)
+	require.Equal(t, testBuyer, sale.Buyer)
+	require.Equal(t, nativePaymentToken, sale.PaymentToken)
+	require.Equal(t, "10000000000000000000", sale.Price)
+	require.Equal(t, "250000000000000000", sale.MarketplaceFee)
+	require.Empty(t, sale.Royalty)
+	require.Equal(t, "9750000000000000000", sale.SellerProceeds)
+}
+
+func TestDecodeBlurExecution(t *testing.T) {
+	// ASK for token 7 of the collection at 1 ETH with a 0.5% creator fee
+	tokenWord := abiWord("7")[42:] + "00" + strings.TrimPrefix(testSeller, "0x")
+	collectionWord := "00" + abiWord("de0b6b3a7640000")[42:] + strings.TrimPrefix(testCollection, "0x")
+	feeWord := strings.Repeat("0", 20) + "0032" + strings.TrimPrefix(testCreator, "0x")
+
+	sales := NewNFTSaleDetector(false).detectSales([]models.Event{{
+		Topics: []string{blurExecution721TakerFeeTopic},
+		Data:   "0x" + abiWord("ab") + tokenWord + collectionWord + feeWord,
+	}}, []NFTTransfer{{Type: "ERC721", Contract: testCollection, From: testSeller, To: testBuyer, TokenID: "7", Name: "BoredApeYachtClub"}}, testBuyer, nil)
+
+	require.Len(t, sales, 1)
+	require.Equal(t, "Blur", sales[0].Marketplace)
+	require.Equal(t, []string{"7"}, sales[0].TokenIDs)
+	require.Equal(t, testSeller, sales[0].Seller)
+	require.Equal(t, testBuyer, sales[0].Buyer, "buyer comes from the NFT transfer")
+	require.Equal(t, "1000000000000000000", sales[0].Price)
+	require.Equal(t, "5000000000000000", sales[0].Royalty)
+	require.Equal(t, "BoredApeYachtClub", sales[0].CollectionName)
+}
+
+func TestDetectSalesFromTransferPattern(t *testing.T) {
+	transfers := []NFTTransfer{
+		{Type: "ERC721", Contract: testCollection, From: testSeller, To: testBuyer, TokenID: "1"},
+		{Type: "ERC721", Contract: testCollection, From: testSeller, To: testBuyer, TokenID: "2"},
+		{Type: "ERC721", Contract: testCollection, From: zeroAddress, To: testBuyer, TokenID: "3"}, // mint
+	}
+	// Buyer pays 2 WETH; the seller receives 1.9 WETH and 0.1 WETH goes to the creator
+	events := []models.Event{
+		{Contract: testWETH, Topics: []string{erc20TransferTopic, "0x" + abiWord(testBuyer), "0x" + abiWord(testSeller)}, Data: "0x" + abiWord("1a5e27eef13e0000")},
+		{Contract: testWETH, Topics: []string{erc20TransferTopic, "0x" + abiWord(testBuyer), "0x" + abiWord(testCreator)}, Data: "0x" + abiWord("16345785d8a0000")},
+	}
+
+	baggage := map[string]interface{}{
+		"events":        events,
+		"nft_transfers": transfers,
+		"token_metadata": map[string]*TokenMetadata{
+			testWETH: {Symbol: "WETH", Decimals: 18},
+		},
+	}
+	detector := NewNFTSaleDetector(false)
+	require.NoError(t, detector.Process(context.Background(), baggage))
+
+	sales, ok := GetNFTSales(baggage)
+	require.True(t, ok)
+	require.Len(t, sales, 1)
+	require.Equal(t, "transfer-pattern", sales[0].Detection)
+	require.Equal(t, []string{"1", "2"}, sales[0].TokenIDs)
+	require.Equal(t, testWETH, sales[0].PaymentToken)
+	require.Equal(t, "2000000000000000000", sales[0].Price)
+	require.Equal(t, "1900000000000000000", sales[0].SellerProceeds)
+
+	prompt := detector.GetPromptContext(context.Background(), baggage)
+	require.Contains(t, prompt, "Price: 2 WETH")
+	require.Contains(t, prompt, "Seller received: 1.9 WETH")
+}
+
+func TestDecodeLooksRareV1BatchRoyalties(t *testing.T) {
+	royalty := func(tokenID, amount string) models.Event {
+		return models.Event{
+			Topics: []string{looksRareV1RoyaltyPaymentTopic, "0x" + abiWord(testCollection), "0x" + abiWord(tokenID), "0x" + abiWord(testCreator)},
+			Data:   "0x" + abiWord("0") + abiWord(amount),
+		}
+	}
+	takerBid := func(tokenID, price string) models.Event {
+		return models.Event{
+			Topics: []string{looksRareV1TakerBidTopic, "0x" + abiWord(testBuyer), "0x" + abiWord(testSeller), "0x" + abiWord("5")},
+			Data: "0x" + abiWord("ab") + abiWord("1") + abiWord("0") + abiWord(testCollection) +
+				abiWord(tokenID) + abiWord("1") + abiWord(price),
+		}
+	}
+	// Each sale pays its royalty before emitting TakerBid: 0.02 ETH on 1 ETH, then 0.05 ETH on 2.5 ETH
+	sales := NewNFTSaleDetector(false).detectSales([]models.Event{
+		royalty("1", "470de4df820000"), takerBid("1", "de0b6b3a7640000"),
+		royalty("2", "b1a2bc2ec50000"), takerBid("2", "22b1c8c1227a0000"),
+	}, nil, testBuyer, nil)
+
+	require.Len(t, sales, 2)
+	require.Equal(t, "LooksRare", sales[0].Marketplace)
+	require.Equal(t, []string{"1"}, sales[0].TokenIDs)
+	require.Equal(t, testBuyer, sales[0].Buyer)
+	require.Equal(t, testSeller, sales[0].Seller)
+	require.Equal(t, nativePaymentToken, sales[0].PaymentToken)
+	require.Equal(t, "1000000000000000000", sales[0].Price)
+	require.Equal(t, "20000000000000000", sales[0].Royalty)
+	require.Equal(t, []string{"2"}, sales[1].TokenIDs)
+	require.Equal(t, "2500000000000000000", sales[1].Price)
+	require.Equal(t, "50000000000000000", sales[1].Royalty)
+}
@@ -333,10 +333,16 @@ func (p *BaggagePipeline) getToolGroup(toolName string) models.ComponentGroup {
 		"log_decoder":              models.ComponentGroupDecoding,
 		"signature_resolver":       models.ComponentGroupDecoding,
 		"token_transfer_extractor": models.ComponentGroupDecoding,
+		"revert_decoder":           models.ComponentGroupDecoding,
+		"call_unwrapper":           models.ComponentGroupDecoding,
+		"user_operation_decoder":   models.ComponentGroupDecoding,
+		"safe_transaction_decoder": models.ComponentGroupDecoding,
 
 		// Enrichment phase
 		"nft_decoder":             models.ComponentGroupEnrichment,
 		"token_metadata_enricher": models.ComponentGroupEnrichment,
+		"pool_resolver":           models.ComponentGroupEnrichment,
+		"nft_sale_detector":       models.ComponentGroupEnrichment,
 		"amounts_finder":          models.ComponentGroupEnrichment,
 		"icon_resolver":           models.ComponentGroupEnrichment,
 		"erc20_price_lookup":      models.ComponentGroupEnrichment,
@@ -344,10 +350,15 @@ func (p *BaggagePipeline) getToolGroup(toolName string) models.ComponentGroup {
 		"ens_resolver":            models.ComponentGroupEnrichment,
 
 		// Analysis phase
-		"address_role_resolver": models.ComponentGroupAnalysis,
-		"protocol_resolver":     models.ComponentGroupAnalysis,
-		"tag_resolver":          models.ComponentGroupAnalysis,
-		"transaction_explainer": models.ComponentGroupAnalysis,
+		"address_role_resolver":      models.ComponentGroupAnalysis,
+		"protocol_resolver":          models.ComponentGroupAnalysis,
+		"tag_resolver":               models.ComponentGroupAnalysis,
+		"action_builder":             models.ComponentGroupAnalysis,
+		"approval_analyzer":          models.ComponentGroupAnalysis,
+		"address_poisoning_detector": models.ComponentGroupAnalysis,
+		"address_screener":           models.ComponentGroupAnalysis,
+		"mev_detector":               models.ComponentGroupAnalysis,
+		"transaction_explainer":      models.ComponentGroupAnalysis,
 
 		// Finishing phase
 		"annotation_generator": models.ComponentGroupFinishing,
@@ -369,8 +380,14 @@ func (p *BaggagePipeline) getToolTitle(toolName string) string {
 		"log_decoder":                  "Decoding Events",
 		"signature_resolver":           "Resolving Method Signatures",
 		"token_transfer_extractor":     "Extracting Token Transfers",
+		"revert_decoder":               "Decoding Revert Reasons",
+		"call_unwrapper":               "Unwrapping Batched Calls",
+		"user_operation_decoder":       "Decoding UserOperations",
+		"safe_transaction_decoder":     "Recovering Safe Signers",
 		"nft_decoder":                  "Processing NFT Data",
 		"token_metadata_enricher":      "Fetching Token Metadata",
+		"pool_resolver":                "Identifying Liquidity Pools",
+		"nft_sale_detector":            "Detecting NFT Sales",
 		"amounts_finder":               "Detecting Transaction Amounts",
 		"icon_resolver":                "Loading Token Icons",
 		"erc20_price_lookup":           "Fetching Token Prices",
@@ -379,6 +396,11 @@ func (p *BaggagePipeline) getToolTitle(toolName string) string {
 		"address_role_resolver":        "Analyzing Address Roles",
 		"protocol_resolver":            "Identifying Protocols",
 		"tag_resolver":                 "Generating Tags",
+		"action_builder":               "Building Actions",
+		"approval_analyzer":            "Analyzing Approvals",
+		"address_poisoning_detector":   "Detecting Address Poisoning",
+		"address_screener":             "Screening Addresses",
+		"mev_detector":                 "Detecting MEV",
 		"transaction_explainer":        "Generating AI Explanation",
 		"annotation_generator":         "Creating Annotations",
 	}
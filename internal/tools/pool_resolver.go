@@ -0,0 +1,384 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// Pool kinds, decided by the factory or registry that knows the pool
+const (
+	PoolKindUniswapV2 = "uniswap_v2" // factory() + getPair(token0, token1)
+	PoolKindUniswapV3 = "uniswap_v3" // factory() + getPool(token0, token1, fee)
+	PoolKindCurve     = "curve"      // get_coins(pool) on a Curve factory or registry
+)
+
+// poolEventNames are the events emitted by AMM pools regardless of protocol
+var poolEventNames = map[string]bool{"Swap": true, "Sync": true, "Mint": true, "Burn": true}
+
+var (
+	selectorFactory  = functionSelector("factory()")
+	selectorToken0   = functionSelector("token0()")
+	selectorToken1   = functionSelector("token1()")
+	selectorFee      = functionSelector("fee()")
+	selectorGetPair  = functionSelector("getPair(address,address)")
+	selectorGetPool  = functionSelector("getPool(address,address,uint24)")
+	selectorGetCoins = functionSelector("get_coins(address)")
+)
+
+// PoolInfo describes a liquidity pool whose factory or registry confirmed it on-chain
+type PoolInfo struct {
+	Address     string   `json:"address"`
+	Kind        string   `json:"kind"`
+	Protocol    string   `json:"protocol"`
+	Version     string   `json:"version,omitempty"`
+	Factory     string   `json:"factory"`            // Factory or registry that knows the pool
+	FactoryName string   `json:"factory_name"`       // e.g. "Uniswap V3 Factory"
+	Tokens      []string `json:"tokens"`             // token0, token1, ... (lowercase)
+	FeeTier     uint32   `json:"fee_tier,omitempty"` // Uniswap V3 fee in hundredths of a basis point (500 = 0.05%)
+
+	// Filled per transaction from token metadata
+	Symbols []string `json:"symbols,omitempty"`
+	Label   string   `json:"label,omitempty"` // e.g. "Uniswap V3 USDC/WETH 0.05% pool"
+}
+
+// Fee returns the fee tier as a percentage string (e.g. "0.05%"), or "" if the pool has none
+func (pi *PoolInfo) Fee() string {
+	if pi.FeeTier == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(pi.FeeTier)/10000, 'f', -1, 64) + "%"
+}
+
+// PoolResolver identifies the contracts emitting Swap/Sync/Mint/Burn events as pools of known protocols by
+// asking their factory (or a Curve registry) whether it created them. A pool's own factory() answer is not
+// trusted on its own: the factory has to map the pool's tokens back to the pool's address.
+type PoolResolver struct {
+	rpcClient      *rpc.Client
+	staticProvider *StaticContextProvider
+	cache          Cache
+	verbose        bool
+}
+
+// NewPoolResolver creates a new pool resolver
+func NewPoolResolver(rpcClient *rpc.Client, staticProvider *StaticContextProvider, cache Cache, verbose bool) *PoolResolver {
+	return &PoolResolver{
+		rpcClient:      rpcClient,
+		staticProvider: staticProvider,
+		cache:          cache,
+		verbose:        verbose,
+	}
+}
+
+// Name returns the processor name
+func (pr *PoolResolver) Name() string {
+	return "pool_resolver"
+}
+
+// Description returns the processor description
+func (pr *PoolResolver) Description() string {
+	return "Identifies liquidity pools by verifying them against known factories and registries"
+}
+
+// Dependencies returns the tools this processor depends on
+func (pr *PoolResolver) Dependencies() []string {
+	return []string{"log_decoder", "token_metadata_enricher"}
+}
+
+// Process verifies the pools behind pool events and stores them in baggage["pools"] by lowercase address
+func (pr *PoolResolver) Process(ctx context.Context, baggage map[string]interface{}) error {
+	if pr.rpcClient == nil || pr.staticProvider == nil {
+		return nil
+	}
+	events, ok := baggage["events"].([]models.Event)
+	if !ok || len(events) == 0 {
+		return nil
+	}
+
+	networkID := int64(1)
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if nid, ok := rawData["network_id"].(float64); ok {
+			networkID = int64(nid)
+		}
+	}
+
+	// Candidate pools, and whether they emitted a Curve event (old Curve pools have no factory())
+	candidates := make(map[string]bool)
+	var order []string
+	for _, event := range events {
+		contract := strings.ToLower(event.Contract)
+		isCandidate, curve := poolEventNames[event.Name], false
+		if len(event.Topics) > 0 {
+			for _, fingerprint := range pr.staticProvider.LookupEventFingerprints(event.Topics[0]) {
+				if fingerprint.Emitter == FingerprintEmitterPool {
+					isCandidate = true
+					curve = curve || strings.EqualFold(fingerprint.Protocol, "Curve")
+				}
+			}
+		}
+		if !isCandidate || !isAddress(contract) {
+			continue
+		}
+		if _, seen := candidates[contract]; !seen {
+			order = append(order, contract)
+		}
+		candidates[contract] = candidates[contract] || curve
+	}
+
+	pools := make(map[string]*PoolInfo)
+	for _, address := range order {
+		pool := pr.lookupPool(ctx, networkID, address, candidates[address])
+		if pool == nil {
+			continue
+		}
+		pr.labelPool(pool, networkID, baggage)
+		pools[address] = pool
+
+		if pr.verbose {
+			fmt.Printf("🏊 Pool %s: %s (via %s)\n", address, pool.Label, pool.FactoryName)
+		}
+	}
+
+	if len(pools) > 0 {
+		baggage["pools"] = pools
+	}
+	return nil
+}
+
+// lookupPool returns the verified pool at an address from the cache or the chain, or nil if no known factory
+// or registry confirms it. Only confirmed pools are cached, so pools of factories added later are found.
+func (pr *PoolResolver) lookupPool(ctx context.Context, networkID int64, address string, curve bool) *PoolInfo {
+	cacheKey := fmt.Sprintf(PoolKeyPattern, networkID, address)
+	if pr.cache != nil {
+		var cached PoolInfo
+		if err := pr.cache.GetJSON(ctx, cacheKey, &cached); err == nil && cached.Address != "" {
+			return &cached
+		}
+	}
+
+	pool := pr.discoverPool(ctx, networkID, address, curve)
+	if pool != nil && pr.cache != nil {
+		if err := pr.cache.SetJSON(ctx, cacheKey, pool, &PoolTTLDuration); err != nil && pr.verbose {
+			fmt.Printf("⚠️  Failed to cache pool %s: %v\n", address, err)
+		}
+	}
+	return pool
+}
+
+// discoverPool asks the pool for its factory and has that factory confirm the pool. Pools of Curve's older
+// registries are looked up in the registries instead.
+func (pr *PoolResolver) discoverPool(ctx context.Context, networkID int64, address string, curve bool) *PoolInfo {
+	if factory := pr.callAddress(ctx, address, selectorFactory); factory != "" {
+		if deployment, ok := pr.staticProvider.LookupProtocolDeployment(networkID, factory); ok {
+			if pool := pr.verifyPool(ctx, deployment, address); pool != nil {
+				return pool
+			}
+		}
+	}
+
+	if !curve {
+		return nil
+	}
+	for _, deployment := range pr.staticProvider.GetProtocolDeployments("Curve", networkID) {
+		if !strings.Contains(strings.ToLower(deployment.Contract), "registry") {
+			continue
+		}
+		if pool := pr.verifyPool(ctx, deployment, address); pool != nil {
+			return pool
+		}
+	}
+	return nil
+}
+
+// verifyPool checks that a factory or registry deployment knows the pool
+func (pr *PoolResolver) verifyPool(ctx context.Context, deployment ProtocolDeployment, address string) *PoolInfo {
+	pool := &PoolInfo{
+		Address:     address,
+		Protocol:    deployment.Protocol,
+		Version:     deployment.Version,
+		Factory:     deployment.Address,
+		FactoryName: deployment.Contract,
+	}
+
+	switch {
+	case strings.EqualFold(deployment.Protocol, "Curve"):
+		pool.Kind = PoolKindCurve
+		pool.Tokens = decodeAddressList(pr.call(ctx, deployment.Address, selectorGetCoins+encodeAddressArg(address)))
+		if len(pool.Tokens) < 2 {
+			return nil
+		}
+		return pool
+
+	case deployment.Version == "v2" || deployment.Version == "v3":
+		token0 := pr.callAddress(ctx, address, selectorToken0)
+		token1 := pr.callAddress(ctx, address, selectorToken1)
+		if token0 == "" || token1 == "" {
+			return nil
+		}
+		pool.Tokens = []string{token0, token1}
+
+		data := selectorGetPair + encodeAddressArg(token0) + encodeAddressArg(token1)
+		pool.Kind = PoolKindUniswapV2
+		if deployment.Version == "v3" {
+			fee, ok := decodeUint(pr.call(ctx, address, selectorFee))
+			if !ok || fee == 0 || fee > 1_000_000 {
+				return nil
+			}
+			pool.FeeTier = uint32(fee)
+			data = selectorGetPool + encodeAddressArg(token0) + encodeAddressArg(token1) + fmt.Sprintf("%064x", fee)
+			pool.Kind = PoolKindUniswapV3
+		}
+
+		// The factory maps the tokens back to the pool only if it deployed it
+		if pr.callAddress(ctx, deployment.Address, data) != address {
+			return nil
+		}
+		return pool
+	}
+	return nil
+}
+
+// labelPool names a pool after its protocol and tokens, e.g. "Uniswap V3 USDC/WETH 0.05% pool"
+func (pr *PoolResolver) labelPool(pool *PoolInfo, networkID int64, baggage map[string]interface{}) {
+	tokenMetadata, _ := baggage["token_metadata"].(map[string]*TokenMetadata)
+
+	pool.Symbols = nil
+	for _, token := range pool.Tokens {
+		symbol := ""
+		if metadata, ok := tokenMetadata[token]; ok && metadata.Symbol != "" {
+			symbol = metadata.Symbol
+		} else if entry, ok := pr.staticProvider.LookupToken(networkID, token); ok && entry.Symbol != "" {
+			symbol = entry.Symbol
+		} else {
+			symbol = token[:6] + "…" + token[len(token)-4:]
+		}
+		pool.Symbols = append(pool.Symbols, symbol)
+	}
+
+	parts := []string{pool.Protocol}
+	if pool.Version != "" {
+		parts = append(parts, strings.ToUpper(pool.Version))
+	}
+	parts = append(parts, strings.Join(pool.Symbols, "/"))
+	if fee := pool.Fee(); fee != "" {
+		parts = append(parts, fee)
+	}
+	pool.Label = strings.Join(parts, " ") + " pool"
+}
+
+// call makes an eth_call and returns the raw result, or "" on failure
+func (pr *PoolResolver) call(ctx context.Context, to, data string) string {
+	result, err := pr.rpcClient.CallContractAt(ctx, to, data, "latest")
+	if err != nil {
+		return ""
+	}
+	return result
+}
+
+// callAddress makes an eth_call returning a single address, or "" on failure or the zero address
+func (pr *PoolResolver) callAddress(ctx context.Context, to, data string) string {
+	result := pr.call(ctx, to, data)
+	if len(result) < 66 {
+		return ""
+	}
+	address := strings.ToLower("0x" + result[26:66])
+	if address == "0x0000000000000000000000000000000000000000" {
+		return ""
+	}
+	return address
+}
+
+// GetPromptContext lists the verified pools for the LLM
+func (pr *PoolResolver) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	pools, ok := baggage["pools"].(map[string]*PoolInfo)
+	if !ok || len(pools) == 0 {
+		return ""
+	}
+
+	lines := []string{"### Liquidity Pools (verified with their factory or registry):"}
+	for address, pool := range pools {
+		lines = append(lines, fmt.Sprintf("- %s: %s (tokens: %s, created by %s %s)",
+			address, pool.Label, strings.Join(pool.Tokens, ", "), pool.FactoryName, pool.Factory))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for verified pools
+func (pr *PoolResolver) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	ragContext := NewRagContext()
+
+	pools, ok := baggage["pools"].(map[string]*PoolInfo)
+	if !ok {
+		return ragContext
+	}
+	for address, pool := range pools {
+		ragContext.AddItem(RagContextItem{
+			ID:      fmt.Sprintf("pool_%s", address),
+			Type:    "address",
+			Title:   pool.Label,
+			Content: fmt.Sprintf("%s is the %s, created by the %s", address, pool.Label, pool.FactoryName),
+			Metadata: map[string]interface{}{
+				"address":  address,
+				"protocol": pool.Protocol,
+				"tokens":   pool.Tokens,
+			},
+			Keywords:  append([]string{pool.Protocol, "pool", address}, pool.Symbols...),
+			Relevance: 1.0,
+		})
+	}
+	return ragContext
+}
+
+// functionSelector returns the 4-byte selector of a canonical function signature
+func functionSelector(signature string) string {
+	return eventTopic(signature)[:10]
+}
+
+// encodeAddressArg ABI-encodes an address argument (without 0x)
+func encodeAddressArg(address string) string {
+	return fmt.Sprintf("%064s", strings.TrimPrefix(strings.ToLower(address), "0x"))
+}
+
+// decodeUint decodes a single uint256 return value that fits in 64 bits
+func decodeUint(result string) (uint64, bool) {
+	if len(result) < 66 {
+		return 0, false
+	}
+	value, ok := new(big.Int).SetString(result[2:66], 16)
+	if !ok || !value.IsUint64() {
+		return 0, false
+	}
+	return value.Uint64(), true
+}
+
+// decodeAddressList decodes a get_coins result up to the first zero address. Both fixed-size arrays
+// (address[8], returned inline) and dynamic arrays (offset, length, items) are accepted.
+func decodeAddressList(result string) []string {
+	data := strings.TrimPrefix(result, "0x")
+	var words []string
+	for i := 0; i+64 <= len(data); i += 64 {
+		words = append(words, data[i:i+64])
+	}
+	if len(words) >= 2 {
+		offset, _ := new(big.Int).SetString(words[0], 16)
+		length, _ := new(big.Int).SetString(words[1], 16)
+		if offset != nil && length != nil && offset.Int64() == 32 && length.Int64() <= int64(len(words)-2) {
+			words = words[2 : 2+length.Int64()]
+		}
+	}
+
+	var addresses []string
+	for _, word := range words {
+		address := "0x" + word[24:]
+		if address == "0x0000000000000000000000000000000000000000" || strings.Trim(word[:24], "0") != "" {
+			break
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
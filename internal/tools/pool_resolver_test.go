@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+const (
+	testUSDC      = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	testV3Pool    = "0x88e6a0c2ddd26feeb64f039a2c41296fcb3f5640"
+	testV3Factory = "0x1f98431c8ad98523631ae4a59f267346ea31f984"
+	testDAI       = "0x6b175474e89094c44da98b954eedeac495271d0f"
+)
+
+func TestPoolHelpers(t *testing.T) {
+	require.Equal(t, "0x1698ee82", selectorGetPool)
+	require.Equal(t, "0xe6a43905", selectorGetPair)
+	require.Equal(t, "0xc45a0155", selectorFactory)
+
+	require.Equal(t, "0.05%", (&PoolInfo{FeeTier: 500}).Fee())
+	require.Equal(t, "1%", (&PoolInfo{FeeTier: 10000}).Fee())
+	require.Equal(t, "", (&PoolInfo{}).Fee())
+
+	word := func(address string) string { return encodeAddressArg(address) }
+	zero := strings.Repeat("0", 64)
+
+	// Fixed-size address[8] stops at the first empty slot
+	fixed := "0x" + word(testDAI) + word(testUSDC) + zero + zero
+	require.Equal(t, []string{testDAI, testUSDC}, decodeAddressList(fixed))
+
+	// Dynamic arrays carry an offset and a length
+	dynamic := "0x" + strings.Repeat("0", 62) + "20" + strings.Repeat("0", 63) + "2" + word(testUSDC) + word(testWETH)
+	require.Equal(t, []string{testUSDC, testWETH}, decodeAddressList(dynamic))
+	require.Empty(t, decodeAddressList("0x"))
+}
+
+func TestVerifiedPoolsAreLabeledAndAttributed(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ProtocolDeploymentsFile), "protocol,chain_id,address,contract,version,type\n"+
+		"Uniswap,1,"+testV2Router+",Uniswap V2 Router 02,v2,DEX\n"+
+		"Uniswap,1,"+testV3Factory+",Uniswap V3 Factory,v3,DEX\n")
+	provider := NewStaticContextProvider(dir, false)
+
+	pool := &PoolInfo{
+		Address: testV3Pool, Kind: PoolKindUniswapV3, Protocol: "Uniswap", Version: "v3",
+		Factory: testV3Factory, FactoryName: "Uniswap V3 Factory", Tokens: []string{testUSDC, testWETH}, FeeTier: 500,
+	}
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(1),
+			"receipt":    map[string]interface{}{"to": testV2Router, "from": "0x00000000000000000000000000000000000000aa"},
+		},
+		"contract_addresses": []string{testV2Router, testUSDC, testWETH, testV3Pool},
+		"token_metadata": map[string]*TokenMetadata{
+			testUSDC: {Name: "USD Coin", Symbol: "USDC", Type: "ERC20"},
+			testWETH: {Name: "Wrapped Ether", Symbol: "WETH", Type: "ERC20"},
+		},
+		"events": []models.Event{{Contract: testV3Pool, Name: "Swap", Topics: []string{testV3SwapTopic}}},
+	}
+
+	NewPoolResolver(nil, provider, nil, false).labelPool(pool, 1, baggage)
+	require.Equal(t, "Uniswap V3 USDC/WETH 0.05% pool", pool.Label)
+	baggage["pools"] = map[string]*PoolInfo{testV3Pool: pool}
+
+	// The router is a known deployment and the pool is verified, so the LLM (nil here) is not needed
+	resolver := NewProtocolResolver(nil, false, 0.6)
+	resolver.SetStaticContextProvider(provider)
+	require.NoError(t, resolver.Process(context.Background(), baggage))
+	protocols := baggage["protocols"].([]ProbabilisticProtocol)
+	require.Len(t, protocols, 1)
+	require.Equal(t, []string{testV2Router, testV3Pool}, protocols[0].Contracts)
+	require.Equal(t, "", protocols[0].Version, "router and pool are different versions")
+
+	network, _ := models.GetNetwork(1)
+	participants := NewAddressRoleResolver(nil, false, nil).buildParticipants([]string{testV3Pool}, map[string]string{testV3Pool: "Contract"},
+		map[string]map[string]string{}, baggage, network)
+	require.Equal(t, "Uniswap V3 USDC/WETH 0.05% pool", participants[0].Name)
+	require.Equal(t, testUSDC, participants[0].Metadata["token0"])
+	require.Equal(t, testWETH, participants[0].Metadata["token1"])
+	require.Equal(t, uint32(500), participants[0].Metadata["fee_tier"])
+}
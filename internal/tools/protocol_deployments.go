@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ProtocolDeploymentsFile maps protocols to their contract addresses on each chain (in the static data directory)
+const ProtocolDeploymentsFile = "protocol_deployments.csv"
+
+// ProtocolDeployment is one contract a protocol has deployed on one chain
+type ProtocolDeployment struct {
+	Protocol string `json:"protocol"` // Matches the name column of protocols.csv
+	ChainID  int64  `json:"chain_id"`
+	Address  string `json:"address"`
+	Contract string `json:"contract,omitempty"` // e.g. "Uniswap V3 Factory"
+	Version  string `json:"version,omitempty"`  // e.g. "v3"
+	Type     string `json:"type,omitempty"`     // DEX, Lending, Aggregator, ...
+}
+
+// ReadProtocolDeployments reads a deployments CSV (protocol, chain_id, address, contract, version, type).
+// Columns are located by header name; rows without a valid chain ID or address are skipped.
+func ReadProtocolDeployments(r io.Reader) ([]ProtocolDeployment, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for _, required := range []string{"protocol", "chain_id", "address"} {
+		if findColumnIndex(header, required) == -1 {
+			return nil, fmt.Errorf("deployments CSV has no %s column", required)
+		}
+	}
+
+	var deployments []ProtocolDeployment
+	for _, record := range records[1:] {
+		deployment := ProtocolDeployment{
+			Protocol: csvField(header, record, "protocol"),
+			Address:  strings.ToLower(csvField(header, record, "address")),
+			Contract: csvField(header, record, "contract"),
+			Version:  csvField(header, record, "version"),
+			Type:     csvField(header, record, "type"),
+		}
+		chainID, err := strconv.ParseInt(csvField(header, record, "chain_id"), 10, 64)
+		if err != nil || chainID <= 0 {
+			continue
+		}
+		deployment.ChainID = chainID
+		if deployment.Protocol == "" || !isAddress(deployment.Address) {
+			continue
+		}
+		deployments = append(deployments, deployment)
+	}
+	return deployments, nil
+}
+
+// csvField returns a trimmed column value by header name, or "" if the row has no such column
+func csvField(header, record []string, column string) string {
+	i := findColumnIndex(header, column)
+	if i == -1 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// isAddress reports whether s is a 0x-prefixed 20-byte hex address
+func isAddress(s string) bool {
+	return len(s) == 42 && strings.HasPrefix(s, "0x") && isHexString(s[2:])
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testV2Router = "0x7a250d5630b4cf539739df2c5dacb4c659f2488d"
+
+func TestReadProtocolDeploymentsSkipsInvalidRows(t *testing.T) {
+	deployments, err := ReadProtocolDeployments(strings.NewReader("protocol,chain_id,address,contract,version,type\n" +
+		"Uniswap,1,0x7A250D5630B4CF539739DF2C5DACB4C659F2488D,Uniswap V2 Router 02,v2,DEX\n" +
+		"Uniswap,,0x7a250d5630b4cf539739df2c5dacb4c659f2488d,No chain,v2,DEX\n" +
+		"Uniswap,1,0x1234,Short address,v2,DEX\n"))
+	require.NoError(t, err)
+	require.Equal(t, []ProtocolDeployment{{
+		Protocol: "Uniswap", ChainID: 1, Address: testV2Router, Contract: "Uniswap V2 Router 02", Version: "v2", Type: "DEX",
+	}}, deployments)
+
+	_, err = ReadProtocolDeployments(strings.NewReader("protocol,address\nUniswap," + testV2Router + "\n"))
+	require.Error(t, err, "chain_id column is required")
+}
+
+func TestStaticAddressesAreChainAware(t *testing.T) {
+	provider := NewStaticContextProvider(t.TempDir(), false)
+	provider.addAddress(0, "0x000000000000000000000000000000000000dead", "Dead Address", "system", "", "", "test")
+	provider.addAddress(1, testV2Router, "Uniswap V2 Router", "dex", "", "", "test")
+	provider.addProtocolDeployments([]ProtocolDeployment{
+		{Protocol: "Uniswap", ChainID: 1, Address: testV2Router, Contract: "Uniswap V2 Router 02", Version: "v2", Type: "DEX"},
+		{Protocol: "Uniswap", ChainID: 42161, Address: "0xe592427a0aece92de3edee1f18e0157c05861564", Contract: "Uniswap V3 SwapRouter", Version: "v3", Type: "DEX"},
+	})
+
+	item, ok := provider.GetAddressInfo(1, testV2Router)
+	require.True(t, ok)
+	require.Equal(t, "Uniswap V2 Router", item.Name, "curated label wins over the deployment contract name")
+	_, ok = provider.GetAddressInfo(137, testV2Router)
+	require.False(t, ok, "mainnet label must not apply to the same address on Polygon")
+	_, ok = provider.GetAddressInfo(137, "0x000000000000000000000000000000000000DEAD")
+	require.True(t, ok, "labels without a chain apply everywhere")
+	item, ok = provider.GetAddressInfo(42161, "0xe592427a0aece92de3edee1f18e0157c05861564")
+	require.True(t, ok, "deployments become known addresses")
+	require.Equal(t, "Uniswap V3 SwapRouter", item.Name)
+
+	deployment, ok := provider.LookupProtocolDeployment(1, testV2Router)
+	require.True(t, ok)
+	require.Equal(t, "Uniswap", deployment.Protocol)
+	_, ok = provider.LookupProtocolDeployment(42161, testV2Router)
+	require.False(t, ok)
+	require.Len(t, provider.GetProtocolDeployments("uniswap", 0), 2)
+	require.Len(t, provider.GetProtocolDeployments("Uniswap", 42161), 1)
+
+	search := NewRAGSearchService(provider, false)
+	ctx := context.Background()
+
+	addresses, err := search.SearchAddresses(ctx, 137, testV2Router)
+	require.NoError(t, err)
+	require.Zero(t, addresses.Found)
+	addresses, err = search.SearchAddresses(ctx, 1, testV2Router)
+	require.NoError(t, err)
+	require.Equal(t, 1, addresses.Found)
+	require.Equal(t, int64(1), addresses.Results[0].ChainID)
+
+	protocols, err := search.SearchProtocols(ctx, 1, "0x7A250D5630B4CF539739DF2C5DACB4C659F2488D")
+	require.NoError(t, err)
+	require.Equal(t, 1, protocols.Found)
+	require.Equal(t, 1.0, protocols.Results[0].Confidence)
+	require.Equal(t, "Uniswap V2 Router 02", protocols.Results[0].Contract)
+	protocols, err = search.SearchProtocols(ctx, 42161, testV2Router)
+	require.NoError(t, err)
+	require.Zero(t, protocols.Found, "address is not a Uniswap deployment on Arbitrum")
+
+	result, err := search.HandleFunctionCall(ctx, 42161, "search_addresses", map[string]interface{}{"address": testV2Router})
+	require.NoError(t, err)
+	require.Zero(t, result.(*SearchAddressesResult).Found)
+}
@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ProtocolFingerprintsFile maps protocol event signatures to protocols (in the static data directory)
+const ProtocolFingerprintsFile = "protocol_fingerprints.csv"
+
+// Who emits a fingerprinted event
+const (
+	FingerprintEmitterPool       = "pool"       // Pools created by one of the protocol's factories
+	FingerprintEmitterDeployment = "deployment" // One of the protocol's listed deployments
+)
+
+// ProtocolFingerprint is an event signature characteristic of a protocol. Forks usually keep the same
+// events, so a fingerprint alone only suggests a protocol family; the emitting contract decides.
+type ProtocolFingerprint struct {
+	Protocol string `json:"protocol"` // Matches the name column of protocols.csv
+	Version  string `json:"version,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Event    string `json:"event"`   // Canonical signature, e.g. "Sync(uint112,uint112)"
+	Topic    string `json:"topic"`   // keccak256 of Event
+	Emitter  string `json:"emitter"` // FingerprintEmitterPool or FingerprintEmitterDeployment
+}
+
+// Name returns the event name without its parameters
+func (f ProtocolFingerprint) Name() string {
+	if i := strings.Index(f.Event, "("); i != -1 {
+		return f.Event[:i]
+	}
+	return f.Event
+}
+
+// ReadProtocolFingerprints reads a fingerprints CSV (protocol, version, type, event, emitter).
+// Columns are located by header name; rows without a protocol or a parseable event signature are skipped.
+func ReadProtocolFingerprints(r io.Reader) ([]ProtocolFingerprint, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for _, required := range []string{"protocol", "event"} {
+		if findColumnIndex(header, required) == -1 {
+			return nil, fmt.Errorf("fingerprints CSV has no %s column", required)
+		}
+	}
+
+	var fingerprints []ProtocolFingerprint
+	for _, record := range records[1:] {
+		fingerprint := ProtocolFingerprint{
+			Protocol: csvField(header, record, "protocol"),
+			Version:  csvField(header, record, "version"),
+			Type:     csvField(header, record, "type"),
+			Event:    strings.ReplaceAll(csvField(header, record, "event"), " ", ""),
+			Emitter:  strings.ToLower(csvField(header, record, "emitter")),
+		}
+		if fingerprint.Protocol == "" || !strings.HasSuffix(fingerprint.Event, ")") || !strings.Contains(fingerprint.Event, "(") {
+			continue
+		}
+		if fingerprint.Emitter != FingerprintEmitterPool {
+			fingerprint.Emitter = FingerprintEmitterDeployment
+		}
+		fingerprint.Topic = eventTopic(fingerprint.Event)
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return fingerprints, nil
+}
+
+// eventTopic returns the topic0 hash of a canonical event signature
+func eventTopic(signature string) string {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(signature))
+	return fmt.Sprintf("0x%x", hasher.Sum(nil))
+}
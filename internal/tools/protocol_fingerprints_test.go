@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+const (
+	testV2SwapTopic = "0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822"
+	testV3SwapTopic = "0xc42079f94a6350d7e6235f29174924f928cc2ac818eb64fed8004e115fbcca67"
+	testV2Pair      = "0xb4e16d0168e52d35cacd2c6185b44281ec28c9dc"
+)
+
+func TestReadProtocolFingerprintsComputesTopics(t *testing.T) {
+	fingerprints, err := ReadProtocolFingerprints(strings.NewReader("protocol,version,type,event,emitter\n" +
+		"Uniswap,v2,DEX,\"Swap(address, uint256,uint256,uint256,uint256,address)\",pool\n" +
+		"Uniswap,v3,DEX,\"Swap(address,address,int256,int256,uint160,uint128,int24)\",POOL\n" +
+		"Aave,v3,Lending,\"Supply(address,address,address,uint256,uint16)\",\n" +
+		"Broken,,,Swap,pool\n"))
+	require.NoError(t, err)
+	require.Len(t, fingerprints, 3)
+	require.Equal(t, testV2SwapTopic, fingerprints[0].Topic, "whitespace in the signature is ignored")
+	require.Equal(t, testV3SwapTopic, fingerprints[1].Topic)
+	require.Equal(t, FingerprintEmitterPool, fingerprints[1].Emitter)
+	require.Equal(t, FingerprintEmitterDeployment, fingerprints[2].Emitter)
+	require.Equal(t, "Supply", fingerprints[2].Name())
+}
+
+func TestProtocolResolverSkipsLLMWhenFingerprintsCoverTransaction(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ProtocolDeploymentsFile), "protocol,chain_id,address,contract,version,type\n"+
+		"Uniswap,1,"+testV2Router+",Uniswap V2 Router 02,v2,DEX\n")
+	writeTestFile(t, filepath.Join(dir, ProtocolFingerprintsFile), "protocol,version,type,event,emitter\n"+
+		"Uniswap,v2,DEX,\"Swap(address,uint256,uint256,uint256,uint256,address)\",pool\n"+
+		"SushiSwap,v2,DEX,\"Swap(address,uint256,uint256,uint256,uint256,address)\",pool\n")
+
+	// No LLM configured: calling it would panic
+	resolver := NewProtocolResolver(nil, false, 0.6)
+	resolver.SetStaticContextProvider(NewStaticContextProvider(dir, false))
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"network_id": float64(1),
+			"receipt":    map[string]interface{}{"to": "0x7A250D5630B4CF539739DF2C5DACB4C659F2488D"},
+		},
+		"contract_addresses": []string{testV2Router, testWETH},
+		"token_metadata":     map[string]*TokenMetadata{testWETH: {Symbol: "WETH"}},
+	}
+	require.NoError(t, resolver.Process(context.Background(), baggage))
+	protocols := baggage["protocols"].([]ProbabilisticProtocol)
+	require.Len(t, protocols, 1)
+	require.Equal(t, "Uniswap", protocols[0].Name)
+	require.Equal(t, 1.0, protocols[0].Confidence)
+
+	// A pool no factory has confirmed is left to the LLM, with its event as a hint
+	baggage["contract_addresses"] = []string{testV2Router, testWETH, testV2Pair}
+	baggage["events"] = []models.Event{{Contract: testV2Pair, Name: "Swap", Topics: []string{testV2SwapTopic}}}
+	networkID, interacted := interactedContracts(baggage)
+	found := newProtocolMatches()
+	resolver.matchDeployments(networkID, interacted, found)
+	hints := resolver.matchEventFingerprints(baggage, found)
+	require.Len(t, hints, 1)
+	require.Contains(t, hints[0], "Uniswap v2 / SushiSwap v2")
+	require.Equal(t, []string{testV2Pair}, unidentifiedContracts(baggage, interacted, found))
+
+	// Transfers of a token keep the LLM out, calling the token itself does not
+	baggage["raw_data"].(map[string]interface{})["receipt"] = map[string]interface{}{"to": testWETH}
+	networkID, interacted = interactedContracts(baggage)
+	found = newProtocolMatches()
+	resolver.matchDeployments(networkID, interacted, found)
+	require.Equal(t, []string{testWETH, testV2Pair}, unidentifiedContracts(baggage, interacted, found))
+}
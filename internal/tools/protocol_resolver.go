@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/txplain/txplain/internal/models"
@@ -17,7 +19,9 @@ type ProtocolResolver struct {
 	llm                 llms.Model
 	verbose             bool
 	confidenceThreshold float64             // Minimum confidence to include a protocol
-	protocolKnowledge   []ProtocolKnowledge // RAG data from protocols.csv
+	protocolKnowledge   []ProtocolKnowledge // RAG data from protocols.csv, loaded on first use without a static provider
+	staticProvider      *StaticContextProvider
+	loadOnce            sync.Once
 }
 
 // ProtocolKnowledge represents protocol information from CSV for RAG
@@ -50,15 +54,31 @@ func NewProtocolResolver(llm llms.Model, verbose bool, confidenceThreshold float
 		protocolKnowledge:   []ProtocolKnowledge{},
 	}
 
-	// Load protocol knowledge from CSV for RAG
-	if err := resolver.loadProtocolKnowledge(); err != nil {
-		// Log error but don't fail - AI can still work without CSV data
-		if resolver.verbose {
-			fmt.Printf("Warning: Failed to load protocol knowledge from CSV: %v\n", err)
-		}
+	return resolver
+}
+
+// SetStaticContextProvider makes the resolver use the shared (hot-reloaded) protocol list and enables exact
+// protocol identification from known per-chain deployment addresses
+func (p *ProtocolResolver) SetStaticContextProvider(provider *StaticContextProvider) {
+	p.staticProvider = provider
+}
+
+// knowledge returns the curated protocols from the static provider, or from protocols.csv if none is set
+func (p *ProtocolResolver) knowledge() []ProtocolKnowledge {
+	if p.staticProvider != nil {
+		return p.staticProvider.Protocols()
 	}
 
-	return resolver
+	p.loadOnce.Do(func() {
+		// Load protocol knowledge from CSV for RAG
+		if err := p.loadProtocolKnowledge(); err != nil {
+			// Log error but don't fail - AI can still work without CSV data
+			if p.verbose {
+				fmt.Printf("Warning: Failed to load protocol knowledge from CSV: %v\n", err)
+			}
+		}
+	})
+	return p.protocolKnowledge
 }
 
 // Name returns the tool name
@@ -73,7 +93,7 @@ func (p *ProtocolResolver) Description() string {
 
 // Dependencies returns the tools this processor depends on
 func (p *ProtocolResolver) Dependencies() []string {
-	return []string{"abi_resolver", "token_transfer_extractor", "token_metadata_enricher"}
+	return []string{"abi_resolver", "token_transfer_extractor", "token_metadata_enricher", "pool_resolver"}
 }
 
 // Process identifies protocols probabilistically from transaction data
@@ -81,7 +101,7 @@ func (p *ProtocolResolver) Process(ctx context.Context, baggage map[string]inter
 	if p.verbose {
 		fmt.Println("\n" + strings.Repeat("🏛️", 60))
 		fmt.Printf("🔍 PROTOCOL RESOLVER: Starting AI-powered protocol detection (threshold: %.1f%%)\n", p.confidenceThreshold*100)
-		fmt.Printf("📚 Knowledge base: %d protocols loaded\n", len(p.protocolKnowledge))
+		fmt.Printf("📚 Knowledge base: %d protocols loaded\n", len(p.knowledge()))
 		fmt.Println(strings.Repeat("🏛️", 60))
 	}
 
@@ -97,6 +117,28 @@ func (p *ProtocolResolver) Process(ctx context.Context, baggage map[string]inter
 		}
 	}
 
+	// Known deployments, verified pools and protocol events are matched before asking the LLM
+	networkID, interacted := interactedContracts(baggage)
+	found := newProtocolMatches()
+	p.matchDeployments(networkID, interacted, found)
+	p.matchPools(baggage, found)
+	hints := p.matchEventFingerprints(baggage, found)
+	remaining := unidentifiedContracts(baggage, interacted, found)
+
+	if len(found.protocols) > 0 {
+		var lines []string
+		for _, protocol := range found.protocols {
+			lines = append(lines, fmt.Sprintf("- %s (%.0f%%): %s", protocol.Name, protocol.Confidence*100, strings.Join(protocol.Evidence, "; ")))
+		}
+		additionalContext = append(additionalContext, "### PROTOCOL FINGERPRINT MATCHES (include these):\n"+strings.Join(lines, "\n"))
+	}
+	if len(hints) > 0 {
+		additionalContext = append(additionalContext, "### PROTOCOL EVENT SIGNATURES (forks reuse these events, confirm with other evidence):\n"+strings.Join(hints, "\n"))
+	}
+	if len(found.protocols) > 0 && len(remaining) > 0 {
+		additionalContext = append(additionalContext, "### CONTRACTS STILL TO IDENTIFY (the matches above are settled, only look for protocols behind these):\n- "+strings.Join(remaining, "\n- "))
+	}
+
 	// Combine all context for AI analysis
 	contextData := strings.Join(additionalContext, "\n\n")
 
@@ -104,25 +146,36 @@ func (p *ProtocolResolver) Process(ctx context.Context, baggage map[string]inter
 		fmt.Printf("📊 Built analysis context: %d characters\n", len(contextData))
 	}
 
-	// Use AI to identify protocols with context from previous tools
-	protocols, err := p.identifyProtocolsWithAI(ctx, contextData)
-	if err != nil {
-		// Update progress tracker to show the error while continuing with fallback
-		if progressTracker, ok := baggage["progress_tracker"].(*models.ProgressTracker); ok {
-			progressTracker.UpdateComponent("protocol_resolver", models.ComponentGroupAnalysis, "Identifying Protocols", models.ComponentStatusRunning, fmt.Sprintf("AI detection failed, using fallback: %v", err))
-		}
+	var protocols []ProbabilisticProtocol
+	if len(found.protocols) > 0 && len(remaining) == 0 {
+		// Every contract is accounted for with certainty - nothing left for the LLM to guess
 		if p.verbose {
-			fmt.Printf("❌ AI protocol detection failed: %v\n", err)
-			fmt.Println("⚠️  Falling back to empty protocol list")
+			fmt.Println("⏭️  All contracts identified by fingerprint, skipping AI protocol detection")
+		}
+	} else {
+		// Use AI to identify protocols with context from previous tools
+		var err error
+		protocols, err = p.identifyProtocolsWithAI(ctx, contextData)
+		if err != nil {
+			// Update progress tracker to show the error while continuing with fallback
+			if progressTracker, ok := baggage["progress_tracker"].(*models.ProgressTracker); ok {
+				progressTracker.UpdateComponent("protocol_resolver", models.ComponentGroupAnalysis, "Identifying Protocols", models.ComponentStatusRunning, fmt.Sprintf("AI detection failed, using fallback: %v", err))
+			}
+			if p.verbose {
+				fmt.Printf("❌ AI protocol detection failed: %v\n", err)
+				fmt.Println("⚠️  Falling back to empty protocol list")
+			}
+			// Fall back to empty list - don't fail the whole pipeline
+			protocols = []ProbabilisticProtocol{}
 		}
-		// Fall back to empty list - don't fail the whole pipeline
-		protocols = []ProbabilisticProtocol{}
 	}
 
 	if p.verbose && len(protocols) > 0 {
 		fmt.Printf("🧠 AI detected %d potential protocols\n", len(protocols))
 	}
 
+	protocols = mergeFingerprintMatches(found.protocols, protocols)
+
 	// Filter by confidence threshold
 	var highConfidenceProtocols []ProbabilisticProtocol
 	for _, protocol := range protocols {
@@ -154,6 +207,226 @@ func (p *ProtocolResolver) Process(ctx context.Context, baggage map[string]inter
 	return nil
 }
 
+// protocolMatches collects protocols identified without the LLM
+type protocolMatches struct {
+	protocols []ProbabilisticProtocol
+	index     map[string]int  // lowercase protocol name -> position in protocols
+	certain   map[string]bool // contracts whose protocol is known with full confidence
+}
+
+func newProtocolMatches() *protocolMatches {
+	return &protocolMatches{index: make(map[string]int), certain: make(map[string]bool)}
+}
+
+// add records that a contract belongs to a protocol. Matches for the same protocol are combined and keep the
+// highest confidence.
+func (m *protocolMatches) add(protocol ProbabilisticProtocol, address string) {
+	if protocol.Confidence >= 1.0 {
+		m.certain[address] = true
+	}
+
+	key := strings.ToLower(protocol.Name)
+	i, exists := m.index[key]
+	if !exists {
+		m.index[key] = len(m.protocols)
+		m.protocols = append(m.protocols, protocol)
+		return
+	}
+
+	existing := &m.protocols[i]
+	existing.Contracts = append(existing.Contracts, protocol.Contracts...)
+	existing.Evidence = append(existing.Evidence, protocol.Evidence...)
+	if existing.Version != protocol.Version {
+		existing.Version = "" // Several versions involved
+	}
+	if protocol.Confidence > existing.Confidence {
+		existing.Confidence = protocol.Confidence
+	}
+}
+
+// interactedContracts returns the transaction's chain and the contracts it called or that emitted events
+func interactedContracts(baggage map[string]interface{}) (int64, []string) {
+	networkID := int64(1)
+	var addresses []string
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if nid, ok := rawData["network_id"].(float64); ok {
+			networkID = int64(nid)
+		}
+		if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+			if to, ok := receipt["to"].(string); ok && to != "" {
+				addresses = append(addresses, to)
+			}
+		}
+	}
+	if contractAddresses, ok := baggage["contract_addresses"].([]string); ok {
+		addresses = append(addresses, contractAddresses...)
+	}
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		address = strings.ToLower(address)
+		if !seen[address] {
+			seen[address] = true
+			unique = append(unique, address)
+		}
+	}
+	return networkID, unique
+}
+
+// matchDeployments identifies protocols whose known contracts on the transaction's chain were called or
+// emitted events. Matches are certain, so they get full confidence.
+func (p *ProtocolResolver) matchDeployments(networkID int64, addresses []string, found *protocolMatches) {
+	if p.staticProvider == nil {
+		return
+	}
+
+	matched := 0
+	for _, address := range addresses {
+		deployment, ok := p.staticProvider.LookupProtocolDeployment(networkID, address)
+		if !ok {
+			continue
+		}
+
+		found.add(p.protocolMatch(deployment.Protocol, deployment.Type, deployment.Version, 1.0, address,
+			fmt.Sprintf("%s is the %s on chain %d (known deployment)", address, deployment.Contract, networkID)), address)
+		matched++
+	}
+
+	if p.verbose && matched > 0 {
+		fmt.Printf("🎯 Matched %d contracts by deployment address\n", matched)
+	}
+}
+
+// matchPools attributes pools verified by their factory or registry (see PoolResolver) to the factory's
+// protocol with full confidence
+func (p *ProtocolResolver) matchPools(baggage map[string]interface{}, found *protocolMatches) {
+	pools, ok := baggage["pools"].(map[string]*PoolInfo)
+	if !ok || p.staticProvider == nil {
+		return
+	}
+
+	addresses := make([]string, 0, len(pools))
+	for address := range pools {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses) // Stable evidence order
+
+	for _, address := range addresses {
+		pool := pools[address]
+		if found.certain[address] {
+			continue
+		}
+		found.add(p.protocolMatch(pool.Protocol, "DEX", pool.Version, 1.0, address,
+			fmt.Sprintf("%s is the %s, confirmed by the %s (%s)", address, pool.Label, pool.FactoryName, pool.Factory)), address)
+	}
+}
+
+// matchEventFingerprints looks up the events of contracts that are not identified yet. Forks reuse protocol
+// events, so these are only returned as hints for the LLM.
+func (p *ProtocolResolver) matchEventFingerprints(baggage map[string]interface{}, found *protocolMatches) []string {
+	if p.staticProvider == nil {
+		return nil
+	}
+	events, ok := baggage["events"].([]models.Event)
+	if !ok {
+		return nil
+	}
+
+	var hints []string
+	checked := make(map[string]bool) // contract:topic pairs already handled
+	for _, event := range events {
+		if len(event.Topics) == 0 {
+			continue
+		}
+		contract, topic := strings.ToLower(event.Contract), strings.ToLower(event.Topics[0])
+		if found.certain[contract] || checked[contract+":"+topic] {
+			continue
+		}
+		checked[contract+":"+topic] = true
+
+		fingerprints := p.staticProvider.LookupEventFingerprints(topic)
+		if len(fingerprints) == 0 {
+			continue
+		}
+
+		var names []string
+		for _, fingerprint := range fingerprints {
+			names = append(names, strings.TrimSpace(fingerprint.Protocol+" "+fingerprint.Version))
+		}
+		hints = append(hints, fmt.Sprintf("- %s emits %s, the %s event of %s", contract, fingerprints[0].Event, fingerprints[0].Name(), strings.Join(names, " / ")))
+	}
+	return hints
+}
+
+// protocolMatch builds a protocol detection for a matched contract
+func (p *ProtocolResolver) protocolMatch(name, protocolType, version string, confidence float64, address, evidence string) ProbabilisticProtocol {
+	protocol := ProbabilisticProtocol{
+		Name:       name,
+		Type:       protocolType,
+		Version:    version,
+		Confidence: confidence,
+		Evidence:   []string{evidence},
+		Contracts:  []string{address},
+		Category:   "DeFi",
+	}
+	if info, ok := p.staticProvider.GetProtocolInfo(name); ok {
+		protocol.Website = info.Link
+	}
+	return protocol
+}
+
+// unidentifiedContracts returns the interacted contracts the LLM still has to explain: everything not matched
+// with certainty, except tokens that were only transferred (the called contract is always kept)
+func unidentifiedContracts(baggage map[string]interface{}, interacted []string, found *protocolMatches) []string {
+	tokens := make(map[string]bool)
+	if metadata, ok := baggage["token_metadata"].(map[string]*TokenMetadata); ok {
+		for address := range metadata {
+			tokens[strings.ToLower(address)] = true
+		}
+	}
+
+	called := ""
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if receipt, ok := rawData["receipt"].(map[string]interface{}); ok {
+			to, _ := receipt["to"].(string)
+			called = strings.ToLower(to)
+		}
+	}
+
+	var remaining []string
+	for _, address := range interacted {
+		if found.certain[address] || (tokens[address] && address != called) {
+			continue
+		}
+		remaining = append(remaining, address)
+	}
+	return remaining
+}
+
+// mergeFingerprintMatches puts fingerprint matches first and drops AI detections of the same protocols
+func mergeFingerprintMatches(matches, detected []ProbabilisticProtocol) []ProbabilisticProtocol {
+	if len(matches) == 0 {
+		return detected
+	}
+
+	merged := append([]ProbabilisticProtocol(nil), matches...)
+	for _, protocol := range detected {
+		duplicate := false
+		for _, match := range matches {
+			name, matchName := strings.ToLower(protocol.Name), strings.ToLower(match.Name)
+			if strings.Contains(name, matchName) || strings.Contains(matchName, name) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, protocol)
+		}
+	}
+	return merged
+}
+
 // loadProtocolKnowledge loads protocol data from protocols.csv for RAG context
 func (p *ProtocolResolver) loadProtocolKnowledge() error {
 	// Try multiple possible CSV locations
@@ -299,7 +572,7 @@ func (p *ProtocolResolver) buildProtocolAnalysisPrompt(contextData string) strin
 	var knowledgeContext strings.Builder
 	knowledgeContext.WriteString("CURATED PROTOCOL KNOWLEDGE (use as reference):\n")
 
-	for _, knowledge := range p.protocolKnowledge {
+	for _, knowledge := range p.knowledge() {
 		knowledgeContext.WriteString(fmt.Sprintf("- %s (%s): %s [Website: %s]\n",
 			knowledge.Name, knowledge.Type, knowledge.Description, knowledge.Website))
 	}
@@ -30,12 +30,15 @@ type SearchProtocolsResult struct {
 }
 
 type ProtocolResult struct {
-	Name        string  `json:"name"`
-	Type        string  `json:"type,omitempty"`
-	Website     string  `json:"website,omitempty"`
-	Description string  `json:"description,omitempty"`
-	Icon        string  `json:"icon,omitempty"`
-	Confidence  float64 `json:"confidence"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Website     string   `json:"website,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	Contract    string   `json:"contract,omitempty"`    // Set for exact deployment address matches
+	Version     string   `json:"version,omitempty"`     // Set for exact deployment address matches
+	Deployments []string `json:"deployments,omitempty"` // Protocol contracts on the searched chain
+	Confidence  float64  `json:"confidence"`
 }
 
 // SearchTokensResult represents the result of a token search
@@ -47,6 +50,7 @@ type SearchTokensResult struct {
 
 type TokenResult struct {
 	Address     string  `json:"address"`
+	ChainID     int64   `json:"chain_id,omitempty"`
 	Symbol      string  `json:"symbol"`
 	Name        string  `json:"name"`
 	Decimals    string  `json:"decimals,omitempty"`
@@ -64,6 +68,7 @@ type SearchAddressesResult struct {
 
 type AddressResult struct {
 	Address     string  `json:"address"`
+	ChainID     int64   `json:"chain_id,omitempty"` // 0 for labels that apply to every chain
 	Name        string  `json:"name"`
 	Type        string  `json:"type,omitempty"`
 	Description string  `json:"description,omitempty"`
@@ -71,14 +76,19 @@ type AddressResult struct {
 	Confidence  float64 `json:"confidence"`
 }
 
-// SearchProtocols performs fuzzy search for protocol information
-// This function is called autonomously by the LLM when it encounters unknown protocols
-func (r *RAGSearchService) SearchProtocols(ctx context.Context, query string) (*SearchProtocolsResult, error) {
+// SearchProtocols performs fuzzy search for protocol information on a chain (0 = any chain)
+// This function is called autonomously by the LLM when it encounters unknown protocols.
+// A full contract address is matched exactly against the protocol deployments of the chain instead.
+func (r *RAGSearchService) SearchProtocols(ctx context.Context, chainID int64, query string) (*SearchProtocolsResult, error) {
 	if r.verbose {
-		fmt.Printf("RAG Search: Looking for protocols matching '%s'\n", query)
+		fmt.Printf("RAG Search: Looking for protocols matching '%s' (chain %d)\n", query, chainID)
+	}
+
+	queryLower := strings.ToLower(strings.TrimSpace(query))
+	if isAddress(queryLower) {
+		return r.searchProtocolDeployment(chainID, queryLower), nil
 	}
 
-	queryLower := strings.ToLower(query)
 	var results []ProtocolResult
 
 	// Get RAG context from static provider
@@ -107,12 +117,20 @@ func (r *RAGSearchService) SearchProtocols(ctx context.Context, query string) (*
 				icon = iconVal
 			}
 
+			var deployments []string
+			if chainID != 0 {
+				for _, deployment := range r.staticProvider.GetProtocolDeployments(name, chainID) {
+					deployments = append(deployments, deployment.Address)
+				}
+			}
+
 			results = append(results, ProtocolResult{
 				Name:        name,
 				Type:        "DeFi Protocol", // Could be enhanced with more specific types
 				Website:     website,
 				Description: r.extractDescription(item.Content),
 				Icon:        icon,
+				Deployments: deployments,
 				Confidence:  confidence,
 			})
 		}
@@ -148,9 +166,9 @@ func (r *RAGSearchService) SearchProtocols(ctx context.Context, query string) (*
 	return result, nil
 }
 
-// SearchTokens performs fuzzy search for token information
+// SearchTokens performs fuzzy search for token information on a chain (0 = any chain)
 // This function is called autonomously by the LLM when it encounters unknown tokens
-func (r *RAGSearchService) SearchTokens(ctx context.Context, addressOrSymbol string) (*SearchTokensResult, error) {
+func (r *RAGSearchService) SearchTokens(ctx context.Context, chainID int64, addressOrSymbol string) (*SearchTokensResult, error) {
 	if r.verbose {
 		fmt.Printf("RAG Search: Looking for tokens matching '%s'\n", addressOrSymbol)
 	}
@@ -163,7 +181,7 @@ func (r *RAGSearchService) SearchTokens(ctx context.Context, addressOrSymbol str
 
 	// Search through token knowledge
 	for _, item := range ragContext.Items {
-		if item.Type != "token" {
+		if item.Type != "token" || !itemOnChain(item, chainID) {
 			continue
 		}
 
@@ -192,8 +210,10 @@ func (r *RAGSearchService) SearchTokens(ctx context.Context, addressOrSymbol str
 				icon = iconVal
 			}
 
+			chain, _ := item.Metadata["chain_id"].(int64)
 			results = append(results, TokenResult{
 				Address:     address,
+				ChainID:     chain,
 				Symbol:      symbol,
 				Name:        name,
 				Decimals:    decimals,
@@ -234,9 +254,9 @@ func (r *RAGSearchService) SearchTokens(ctx context.Context, addressOrSymbol str
 	return result, nil
 }
 
-// SearchAddresses performs fuzzy search for well-known address information
+// SearchAddresses performs fuzzy search for well-known address information on a chain (0 = any chain)
 // This function is called autonomously by the LLM when it encounters unknown addresses
-func (r *RAGSearchService) SearchAddresses(ctx context.Context, address string) (*SearchAddressesResult, error) {
+func (r *RAGSearchService) SearchAddresses(ctx context.Context, chainID int64, address string) (*SearchAddressesResult, error) {
 	if r.verbose {
 		fmt.Printf("RAG Search: Looking for addresses matching '%s'\n", address)
 	}
@@ -249,7 +269,7 @@ func (r *RAGSearchService) SearchAddresses(ctx context.Context, address string)
 
 	// Search through address knowledge
 	for _, item := range ragContext.Items {
-		if item.Type != "address" {
+		if item.Type != "address" || !itemOnChain(item, chainID) {
 			continue
 		}
 
@@ -274,8 +294,10 @@ func (r *RAGSearchService) SearchAddresses(ctx context.Context, address string)
 				explorer = explorerVal
 			}
 
+			chain, _ := item.Metadata["chain_id"].(int64)
 			results = append(results, AddressResult{
 				Address:     itemAddress,
+				ChainID:     chain,
 				Name:        name,
 				Type:        addressType,
 				Description: r.extractDescription(item.Content),
@@ -315,6 +337,49 @@ func (r *RAGSearchService) SearchAddresses(ctx context.Context, address string)
 	return result, nil
 }
 
+// searchProtocolDeployment resolves a contract address to the protocol deployed there. Exact matches are
+// returned with full confidence; an address that is not a known deployment on the chain yields no results,
+// even if the same address belongs to a protocol elsewhere.
+func (r *RAGSearchService) searchProtocolDeployment(chainID int64, address string) *SearchProtocolsResult {
+	result := &SearchProtocolsResult{Query: address}
+
+	deployment, found := r.staticProvider.LookupProtocolDeployment(chainID, address)
+	if !found {
+		if r.verbose {
+			fmt.Printf("RAG Search: %s is not a known protocol deployment on chain %d\n", address, chainID)
+		}
+		return result
+	}
+
+	protocol := ProtocolResult{
+		Name:       deployment.Protocol,
+		Type:       deployment.Type,
+		Contract:   deployment.Contract,
+		Version:    deployment.Version,
+		Confidence: 1.0,
+	}
+	if info, ok := r.staticProvider.GetProtocolInfo(deployment.Protocol); ok {
+		protocol.Website = info.Link
+		protocol.Description = info.Description
+		protocol.Icon = info.Icon
+	}
+
+	result.Results = []ProtocolResult{protocol}
+	result.Found = 1
+
+	if r.verbose {
+		fmt.Printf("RAG Search: %s is %s (%s) on chain %d\n", address, deployment.Contract, deployment.Protocol, deployment.ChainID)
+	}
+	return result
+}
+
+// itemOnChain reports whether a RAG item applies to a chain. Items without a chain ID (or chain 0) apply to
+// every chain, and chain 0 in the query matches every item.
+func itemOnChain(item RagContextItem, chainID int64) bool {
+	itemChainID, ok := item.Metadata["chain_id"].(int64)
+	return chainID == 0 || !ok || itemChainID == 0 || itemChainID == chainID
+}
+
 // calculateFuzzyMatch computes fuzzy match score between query and knowledge item
 func (r *RAGSearchService) calculateFuzzyMatch(query string, item RagContextItem) float64 {
 	score := 0.0
@@ -440,8 +505,9 @@ func (r *RAGSearchService) GetLangChainGoTools() []llms.Tool {
 	}
 }
 
-// HandleFunctionCall processes LLM function calls and returns results
-func (r *RAGSearchService) HandleFunctionCall(ctx context.Context, functionName string, arguments map[string]interface{}) (interface{}, error) {
+// HandleFunctionCall processes LLM function calls and returns results. Searches are restricted to the
+// transaction's chain so a label for the same address on another chain is never returned.
+func (r *RAGSearchService) HandleFunctionCall(ctx context.Context, chainID int64, functionName string, arguments map[string]interface{}) (interface{}, error) {
 	if r.verbose {
 		fmt.Printf("RAG Function Call: %s with args %+v\n", functionName, arguments)
 	}
@@ -449,19 +515,19 @@ func (r *RAGSearchService) HandleFunctionCall(ctx context.Context, functionName
 	switch functionName {
 	case "search_protocols":
 		if query, ok := arguments["query"].(string); ok {
-			return r.SearchProtocols(ctx, query)
+			return r.SearchProtocols(ctx, chainID, query)
 		}
 		return nil, fmt.Errorf("search_protocols requires 'query' parameter")
 
 	case "search_tokens":
 		if addressOrSymbol, ok := arguments["address_or_symbol"].(string); ok {
-			return r.SearchTokens(ctx, addressOrSymbol)
+			return r.SearchTokens(ctx, chainID, addressOrSymbol)
 		}
 		return nil, fmt.Errorf("search_tokens requires 'address_or_symbol' parameter")
 
 	case "search_addresses":
 		if address, ok := arguments["address"].(string); ok {
-			return r.SearchAddresses(ctx, address)
+			return r.SearchAddresses(ctx, chainID, address)
 		}
 		return nil, fmt.Errorf("search_addresses requires 'address' parameter")
 
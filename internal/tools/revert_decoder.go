@@ -0,0 +1,450 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/sigdb"
+)
+
+// Selectors of the revert payloads the compiler emits itself
+const (
+	errorStringSelector = "0x08c379a0" // Error(string)
+	panicSelector       = "0x4e487b71" // Panic(uint256)
+)
+
+// panicReasons explains the Solidity Panic(uint256) codes
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "corrupted storage byte array",
+	0x31: "pop() on an empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory (too much memory allocated)",
+	0x51: "call to an uninitialized internal function",
+}
+
+// RevertDecoder explains failed calls: Error(string) and Panic(uint256) payloads, and custom errors matched
+// against the resolved ABIs and the signature database. It also works out why the transaction failed.
+type RevertDecoder struct {
+	db      *sigdb.DB
+	verbose bool
+}
+
+// NewRevertDecoder creates a new revert decoder
+func NewRevertDecoder(verbose bool) *RevertDecoder {
+	return &RevertDecoder{
+		db:      sigdb.Default(),
+		verbose: verbose,
+	}
+}
+
+// Name returns the processor name
+func (r *RevertDecoder) Name() string {
+	return "revert_decoder"
+}
+
+// Description returns the processor description
+func (r *RevertDecoder) Description() string {
+	return "Decodes revert reasons, panics and custom errors of failed calls"
+}
+
+// Dependencies returns the tools this processor depends on
+func (r *RevertDecoder) Dependencies() []string {
+	return []string{"trace_decoder", "abi_resolver"}
+}
+
+// Process decodes the revert data of failed calls and stores the transaction's failure in baggage["failure"]
+func (r *RevertDecoder) Process(ctx context.Context, baggage map[string]interface{}) error {
+	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
+
+	calls, _ := baggage["calls"].([]models.Call)
+	for i, call := range calls {
+		if call.Success || call.RevertData == "" {
+			continue
+		}
+		if failure := r.decodeRevert(call.Contract, call.RevertData, resolvedContracts); failure != nil {
+			calls[i].ErrorReason = failure.Reason
+		}
+	}
+
+	if !transactionFailed(baggage) {
+		return nil
+	}
+
+	failure := r.explainFailure(calls, resolvedContracts)
+	if r.verbose {
+		fmt.Printf("💥 Transaction failed: %s (%s)\n", failure.Reason, failure.Kind)
+	}
+	baggage["failure"] = failure
+	return nil
+}
+
+// transactionFailed reports whether the receipt status marks the transaction as reverted
+func transactionFailed(baggage map[string]interface{}) bool {
+	rawData, ok := baggage["raw_data"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	receipt, ok := rawData["receipt"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	status, _ := receipt["status"].(string)
+	return status == "0x0" || status == "0"
+}
+
+// explainFailure finds the frame where the revert originated: the deepest failed call whose revert data
+// bubbled up unchanged to the top-level call
+func (r *RevertDecoder) explainFailure(calls []models.Call, resolvedContracts map[string]*ContractInfo) *models.Failure {
+	var root *models.Call
+	for i := range calls {
+		if !calls[i].Success && calls[i].Depth == 0 {
+			root = &calls[i]
+			break
+		}
+	}
+	if root == nil {
+		return &models.Failure{
+			Kind:   models.FailureKindUnknown,
+			Reason: "transaction reverted (no trace available to decode why)",
+		}
+	}
+
+	origin := root
+	for i := range calls {
+		call := &calls[i]
+		if !call.Success && call.RevertData == root.RevertData && call.Depth > origin.Depth {
+			origin = call
+		}
+	}
+
+	failure := r.decodeRevert(origin.Contract, origin.RevertData, resolvedContracts)
+	if failure == nil {
+		failure = &models.Failure{Kind: models.FailureKindEmpty, Reason: "reverted without a reason"}
+		if origin.ErrorReason != "" && origin.ErrorReason != "execution reverted" {
+			// Errors reported by the tracer itself: out of gas, invalid opcode, stack overflow...
+			failure.Kind = models.FailureKindExecutionError
+			failure.Reason = origin.ErrorReason
+		}
+	}
+	failure.Contract = origin.Contract
+	failure.Method = origin.Method
+	failure.Depth = origin.Depth
+	return failure
+}
+
+// decodeRevert decodes revert data raised by a contract, or returns nil if there is none
+func (r *RevertDecoder) decodeRevert(contract, data string, resolvedContracts map[string]*ContractInfo) *models.Failure {
+	if failure, ok := decodeStandardRevert(data); ok {
+		return failure
+	}
+	data = strings.ToLower(data)
+	if len(data) < 10 {
+		return nil
+	}
+
+	failure := &models.Failure{Kind: models.FailureKindCustomError, Data: data}
+	selector := data[:10]
+	if signature, names := r.lookupCustomError(contract, selector, data, resolvedContracts); signature != "" {
+		failure.Error = signature
+		failure.Arguments = decodeArguments(signature, names, data)
+		failure.Reason = formatCustomError(signature, names, failure.Arguments)
+		return failure
+	}
+	failure.Reason = fmt.Sprintf("custom error %s (unknown signature)", selector)
+	return failure
+}
+
+// lookupCustomError finds a custom error by selector in the resolved ABIs (with parameter names), then in
+// the signature database. The reverting contract's own ABI is checked first; the others follow in address
+// order so that a selector several ABIs share always resolves the same way.
+func (r *RevertDecoder) lookupCustomError(contract, selector, data string, resolvedContracts map[string]*ContractInfo) (string, []string) {
+	contract = strings.ToLower(contract)
+	addresses := make([]string, 0, len(resolvedContracts))
+	for address := range resolvedContracts {
+		if strings.ToLower(address) != contract {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	if _, ok := resolvedContracts[contract]; ok {
+		addresses = append([]string{contract}, addresses...)
+	}
+
+	for _, address := range addresses {
+		info := resolvedContracts[address]
+		if info == nil {
+			continue
+		}
+		for _, method := range info.ParsedABI {
+			if method.Type != "error" || method.Hash != selector {
+				continue
+			}
+			var names []string
+			for _, input := range method.Inputs {
+				names = append(names, input.Name)
+			}
+			return method.Signature, names
+		}
+	}
+
+	if r.db == nil {
+		return "", nil
+	}
+	var signatures []string
+	for _, entry := range r.db.LookupFunction(selector) {
+		signatures = append(signatures, entry.TextSignature)
+	}
+	if ranked := sigdb.RankFunctionCandidates(signatures, data); len(ranked) > 0 && ranked[0].Score > 0 {
+		return ranked[0].TextSignature, nil
+	}
+	return "", nil
+}
+
+// decodeStandardRevert decodes the Error(string) and Panic(uint256) payloads emitted by the compiler
+func decodeStandardRevert(data string) (*models.Failure, bool) {
+	data = strings.ToLower(data)
+	if len(data) < 10 {
+		return nil, false
+	}
+	args, err := hex.DecodeString(data[10:])
+	if err != nil {
+		return nil, false
+	}
+
+	switch data[:10] {
+	case errorStringSelector:
+		message, ok := abiString(args, 0)
+		if !ok {
+			return nil, false
+		}
+		return &models.Failure{
+			Kind:      models.FailureKindErrorString,
+			Reason:    message,
+			Error:     "Error(string)",
+			Arguments: map[string]interface{}{"message": message},
+			Data:      data,
+		}, true
+
+	case panicSelector:
+		if len(args) < 32 {
+			return nil, false
+		}
+		code := new(big.Int).SetBytes(args[:32])
+		meaning := "unknown panic code"
+		if code.IsUint64() {
+			if known, exists := panicReasons[code.Uint64()]; exists {
+				meaning = known
+			}
+		}
+		return &models.Failure{
+			Kind:      models.FailureKindPanic,
+			Reason:    fmt.Sprintf("panic 0x%02x: %s", code, meaning),
+			Error:     "Panic(uint256)",
+			Arguments: map[string]interface{}{"code": fmt.Sprintf("0x%02x", code)},
+			Data:      data,
+		}, true
+	}
+	return nil, false
+}
+
+// decodeArguments decodes the arguments of a custom error or function call by name (arg0, arg1, ... when names
+// are unknown). data starts with the 4-byte selector. Static types, string/bytes and arrays of static
+// elements are decoded; other dynamic types are left as their raw head word.
+func decodeArguments(signature string, names []string, data string) map[string]interface{} {
+	if len(data) < 10 {
+		return nil
+	}
+	args, err := hex.DecodeString(strings.TrimPrefix(data, "0x")[8:])
+	if err != nil {
+		return nil
+	}
+	return decodeArgumentBytes(signature, names, args)
+}
+
+// decodeArgumentBytes decodes ABI-encoded arguments that are not prefixed by a selector
+func decodeArgumentBytes(signature string, names []string, args []byte) map[string]interface{} {
+	open := strings.Index(signature, "(")
+	if open == -1 {
+		return nil
+	}
+
+	arguments := make(map[string]interface{})
+	for i, paramType := range sigdb.SplitParams(signature[open+1 : len(signature)-1]) {
+		name := fmt.Sprintf("arg%d", i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		if len(args) < (i+1)*32 {
+			break
+		}
+		head := i * 32
+
+		switch {
+		case paramType == "string":
+			if value, ok := abiString(args, head); ok {
+				arguments[name] = value
+			}
+		case paramType == "bytes":
+			if value, ok := abiBytes(args, head); ok {
+				arguments[name] = "0x" + hex.EncodeToString(value)
+			}
+		case strings.HasSuffix(paramType, "[]") && isStaticWordType(strings.TrimSuffix(paramType, "[]")):
+			if words, ok := abiWords(args, head); ok {
+				values := make([]interface{}, 0, len(words))
+				for _, word := range words {
+					values = append(values, decodeWord(strings.TrimSuffix(paramType, "[]"), word))
+				}
+				arguments[name] = values
+			}
+		default:
+			arguments[name] = decodeWord(paramType, args[head:head+32])
+		}
+	}
+	return arguments
+}
+
+// isStaticWordType reports whether a type is encoded in a single word
+func isStaticWordType(paramType string) bool {
+	return paramType == "address" || paramType == "bool" || strings.HasPrefix(paramType, "uint") ||
+		strings.HasPrefix(paramType, "int") || (strings.HasPrefix(paramType, "bytes") && paramType != "bytes")
+}
+
+// decodeWord decodes a single-word value; types it does not know are returned as hex
+func decodeWord(paramType string, word []byte) interface{} {
+	switch {
+	case paramType == "address":
+		return "0x" + hex.EncodeToString(word[12:])
+	case paramType == "bool":
+		return word[31] == 1
+	case strings.HasPrefix(paramType, "uint"):
+		return new(big.Int).SetBytes(word).String()
+	case strings.HasPrefix(paramType, "int"):
+		value := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return value.String()
+	}
+	return "0x" + hex.EncodeToString(word)
+}
+
+// formatCustomError renders a custom error like "InsufficientBalance(available: 5, required: 10)"
+func formatCustomError(signature string, names []string, arguments map[string]interface{}) string {
+	open := strings.Index(signature, "(")
+	if open == -1 {
+		return signature
+	}
+
+	var parts []string
+	for i := range sigdb.SplitParams(signature[open+1 : len(signature)-1]) {
+		name := fmt.Sprintf("arg%d", i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		if value, exists := arguments[name]; exists {
+			parts = append(parts, fmt.Sprintf("%s: %v", name, value))
+		}
+	}
+	return fmt.Sprintf("%s(%s)", signature[:open], strings.Join(parts, ", "))
+}
+
+// abiString decodes a dynamic string whose offset is stored at head
+func abiString(args []byte, head int) (string, bool) {
+	value, ok := abiBytes(args, head)
+	return string(value), ok
+}
+
+// abiBytes decodes dynamic bytes whose offset is stored at head
+func abiBytes(args []byte, head int) ([]byte, bool) {
+	start, length, ok := abiDynamic(args, head, 1)
+	if !ok {
+		return nil, false
+	}
+	return args[start : start+length], true
+}
+
+// abiWords decodes a dynamic array of single-word elements whose offset is stored at head
+func abiWords(args []byte, head int) ([][]byte, bool) {
+	start, length, ok := abiDynamic(args, head, 32)
+	if !ok {
+		return nil, false
+	}
+	words := make([][]byte, 0, length)
+	for i := 0; i < length; i++ {
+		words = append(words, args[start+i*32:start+(i+1)*32])
+	}
+	return words, true
+}
+
+// abiDynamic follows the offset stored at head to a length-prefixed value and returns where its content
+// starts and how many elements of elementSize bytes it has, rejecting values that overrun the data
+func abiDynamic(args []byte, head, elementSize int) (int, int, bool) {
+	if len(args) < head+32 {
+		return 0, 0, false
+	}
+	offset := new(big.Int).SetBytes(args[head : head+32])
+	if !offset.IsInt64() || offset.Int64() > int64(len(args)-32) {
+		return 0, 0, false
+	}
+	start := int(offset.Int64())
+	length := new(big.Int).SetBytes(args[start : start+32])
+	if !length.IsInt64() || length.Int64() > int64(len(args)-start-32)/int64(elementSize) {
+		return 0, 0, false
+	}
+	return start + 32, int(length.Int64()), true
+}
+
+// GetPromptContext explains why the transaction failed
+func (r *RevertDecoder) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	failure, ok := baggage["failure"].(*models.Failure)
+	if !ok || failure == nil {
+		return ""
+	}
+
+	lines := []string{"### TRANSACTION FAILURE (explain why it failed):", "- Reason: " + failure.Reason}
+	if failure.Error != "" {
+		lines = append(lines, "- Error: "+failure.Error)
+	}
+	if failure.Contract != "" {
+		location := fmt.Sprintf("- Reverted in: %s (call depth %d)", failure.Contract, failure.Depth)
+		if failure.Method != "" {
+			location = fmt.Sprintf("- Reverted in: %s.%s (call depth %d)", failure.Contract, failure.Method, failure.Depth)
+		}
+		lines = append(lines, location)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for the failure
+func (r *RevertDecoder) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	ragContext := NewRagContext()
+
+	failure, ok := baggage["failure"].(*models.Failure)
+	if !ok || failure == nil {
+		return ragContext
+	}
+	ragContext.AddItem(RagContextItem{
+		ID:      "transaction_failure",
+		Type:    "failure",
+		Title:   "Transaction Failure",
+		Content: fmt.Sprintf("The transaction reverted: %s", failure.Reason),
+		Metadata: map[string]interface{}{
+			"kind":     failure.Kind,
+			"error":    failure.Error,
+			"contract": failure.Contract,
+		},
+		Keywords:  []string{"failed", "reverted", failure.Kind},
+		Relevance: 1.0,
+	})
+	return ragContext
+}
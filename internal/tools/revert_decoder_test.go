@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/sigdb"
+)
+
+// encodeErrorString builds an Error(string) revert payload
+func encodeErrorString(message string) string {
+	padded := make([]byte, (len(message)+31)/32*32)
+	copy(padded, message)
+	return errorStringSelector + fmt.Sprintf("%064x%064x", 32, len(message)) + hex.EncodeToString(padded)
+}
+
+func TestDecodeStandardRevert(t *testing.T) {
+	failure, ok := decodeStandardRevert(encodeErrorString("STF"))
+	require.True(t, ok)
+	require.Equal(t, models.FailureKindErrorString, failure.Kind)
+	require.Equal(t, "STF", failure.Reason)
+
+	failure, ok = decodeStandardRevert(panicSelector + fmt.Sprintf("%064x", 0x11))
+	require.True(t, ok)
+	require.Equal(t, models.FailureKindPanic, failure.Kind)
+	require.Equal(t, "panic 0x11: arithmetic overflow or underflow", failure.Reason)
+
+	_, ok = decodeStandardRevert(errorStringSelector + fmt.Sprintf("%064x", 4096))
+	require.False(t, ok, "out of range string offset")
+	_, ok = decodeStandardRevert("0x")
+	require.False(t, ok)
+}
+
+func TestRevertDecoderExplainsFailure(t *testing.T) {
+	const (
+		router = "0x1111111111111111111111111111111111111111"
+		vault  = "0x2222222222222222222222222222222222222222"
+	)
+	selector := functionSelector("InsufficientBalance(uint256,uint256)")
+	customData := selector + fmt.Sprintf("%064x%064x", 5, 10)
+
+	// Custom error names come from the ABI when the contract is verified
+	decoder := &RevertDecoder{db: sigdb.New()}
+	resolved := map[string]*ContractInfo{vault: {ParsedABI: []ABIMethod{{
+		Name: "InsufficientBalance", Type: "error", Signature: "InsufficientBalance(uint256,uint256)", Hash: selector,
+		Inputs: []ABIInput{{Name: "available", Type: "uint256"}, {Name: "required", Type: "uint256"}},
+	}}}}
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{"receipt": map[string]interface{}{"status": "0x0"}},
+		"calls": []models.Call{
+			{Contract: router, Depth: 0, ErrorReason: "execution reverted", RevertData: customData},
+			{Contract: vault, Depth: 1, ErrorReason: "execution reverted", RevertData: customData},
+			{Contract: vault, Depth: 1, Success: true},
+		},
+		"resolved_contracts": resolved,
+	}
+	require.NoError(t, decoder.Process(context.Background(), baggage))
+
+	failure := baggage["failure"].(*models.Failure)
+	require.Equal(t, models.FailureKindCustomError, failure.Kind)
+	require.Equal(t, "InsufficientBalance(available: 5, required: 10)", failure.Reason)
+	require.Equal(t, vault, failure.Contract, "the revert originated in the vault and bubbled up")
+	require.Equal(t, 1, failure.Depth)
+	require.Equal(t, failure.Reason, baggage["calls"].([]models.Call)[0].ErrorReason)
+	require.Contains(t, decoder.GetPromptContext(context.Background(), baggage), "Reverted in: "+vault)
+
+	// A selector several ABIs define resolves against the reverting contract first, then in address order
+	shared := map[string]*ContractInfo{
+		"0x0000000000000000000000000000000000000001": {ParsedABI: []ABIMethod{{Type: "error", Signature: "Collision(uint256,uint256)", Hash: selector}}},
+		"0x0000000000000000000000000000000000000002": {ParsedABI: []ABIMethod{{Type: "error", Signature: "Other(uint256,uint256)", Hash: selector}}},
+		vault: resolved[vault],
+	}
+	for i := 0; i < 20; i++ {
+		require.Equal(t, "InsufficientBalance(uint256,uint256)", decoder.decodeRevert(vault, customData, shared).Error)
+		require.Equal(t, "Collision(uint256,uint256)", decoder.decodeRevert(router, customData, shared).Error)
+	}
+
+	// Without an ABI the signature database supplies the error, with positional names
+	require.NoError(t, decoder.db.Add("InsufficientBalance(uint256,uint256)", sigdb.KindFunction, 1))
+	failure = decoder.decodeRevert(vault, customData, nil)
+	require.Equal(t, "InsufficientBalance(arg0: 5, arg1: 10)", failure.Reason)
+	require.Equal(t, "custom error 0xdeadbeef (unknown signature)", decoder.decodeRevert(vault, "0xdeadbeef", nil).Reason)
+
+	// Out of gas carries no revert data, only the tracer's error
+	failure = decoder.explainFailure([]models.Call{{Contract: router, ErrorReason: "out of gas"}}, nil)
+	require.Equal(t, models.FailureKindExecutionError, failure.Kind)
+	require.Equal(t, "out of gas", failure.Reason)
+
+	// Successful transactions have no failure even when an inner call reverted
+	baggage["raw_data"] = map[string]interface{}{"receipt": map[string]interface{}{"status": "0x1"}}
+	delete(baggage, "failure")
+	require.NoError(t, decoder.Process(context.Background(), baggage))
+	require.NotContains(t, baggage, "failure")
+}
+
+func TestTraceDecoderKeepsFailedCalls(t *testing.T) {
+	decoder := NewTraceDecoder(nil, false)
+	var calls []models.Call
+	require.NoError(t, decoder.decodeCallTracerResult(context.Background(), map[string]interface{}{
+		"type":   "CALL",
+		"to":     "0x1111111111111111111111111111111111111111",
+		"value":  "0x0",
+		"error":  "execution reverted",
+		"output": encodeErrorString("Too little received"),
+	}, &calls, 0))
+
+	require.Len(t, calls, 1, "failed calls without value are kept")
+	require.False(t, calls[0].Success)
+	require.Equal(t, "Too little received", calls[0].ErrorReason)
+	require.NotEmpty(t, calls[0].RevertData)
+}
@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// Safe typehashes (Safe v1.3+ binds the chain id into the domain, and before v1.0 baseGas was named dataGas)
+var (
+	safeDomainTypehash       = keccak256([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	safeLegacyDomainTypehash = keccak256([]byte("EIP712Domain(address verifyingContract)"))
+	safeTxTypehash           = keccak256([]byte("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 baseGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"))
+	safeLegacyTxTypehash     = keccak256([]byte("SafeTx(address to,uint256 value,bytes data,uint8 operation,uint256 safeTxGas,uint256 dataGas,uint256 gasPrice,address gasToken,address refundReceiver,uint256 nonce)"))
+)
+
+// Safe events carrying the hash of the executed Safe transaction and the gas payment
+var (
+	safeExecutionSuccessTopic = eventTopic("ExecutionSuccess(bytes32,uint256)")
+	safeExecutionFailureTopic = eventTopic("ExecutionFailure(bytes32,uint256)")
+)
+
+// SafeTransactionDecoder explains Safe multisig executions: the inner transaction execTransaction runs, the
+// owners whose signatures approved it (recovered from the packed signatures), the Safe's threshold and
+// owners before execution, and whether the transaction was submitted by a relayer rather than an owner.
+type SafeTransactionDecoder struct {
+	rpcClient *rpc.Client
+	unwrapper *CallUnwrapper
+	verbose   bool
+}
+
+// NewSafeTransactionDecoder creates a new Safe transaction decoder. Without an RPC client owners and
+// threshold are not reported.
+func NewSafeTransactionDecoder(rpcClient *rpc.Client, verbose bool) *SafeTransactionDecoder {
+	return &SafeTransactionDecoder{
+		rpcClient: rpcClient,
+		unwrapper: NewCallUnwrapper(false),
+		verbose:   verbose,
+	}
+}
+
+// Name returns the processor name
+func (d *SafeTransactionDecoder) Name() string {
+	return "safe_transaction_decoder"
+}
+
+// Description returns the processor description
+func (d *SafeTransactionDecoder) Description() string {
+	return "Decodes Safe execTransaction calls with the owners who signed them, the threshold and the relayer"
+}
+
+// Dependencies returns the tools this processor depends on
+func (d *SafeTransactionDecoder) Dependencies() []string {
+	return []string{"abi_resolver", "log_decoder", "trace_decoder"}
+}
+
+// Process decodes the Safe executions and stores them in baggage["safe_transactions"]
+func (d *SafeTransactionDecoder) Process(ctx context.Context, baggage map[string]interface{}) error {
+	calls := execTransactionCalls(baggage)
+	if len(calls) == 0 {
+		return nil
+	}
+	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
+	events, _ := baggage["events"].([]models.Event)
+	parentBlock, chainID := safeCallContext(baggage)
+
+	// Execution events are matched to calls in order, per Safe
+	used := make(map[int]bool)
+	nextExecutionEvent := func(safe string) (models.Event, bool) {
+		for i, event := range events {
+			if used[i] || len(event.Topics) == 0 || !strings.EqualFold(event.Contract, safe) {
+				continue
+			}
+			if topic := strings.ToLower(event.Topics[0]); topic == safeExecutionSuccessTopic || topic == safeExecutionFailureTopic {
+				used[i] = true
+				return event, true
+			}
+		}
+		return models.Event{}, false
+	}
+
+	var transactions []models.SafeTransaction
+	for _, call := range calls {
+		args, err := hex.DecodeString(call.input[10:])
+		if err != nil || len(args) < 10*32 {
+			continue
+		}
+		transaction := models.SafeTransaction{Safe: call.to, Executor: call.from, Success: !transactionFailed(baggage)}
+		if decoded := d.unwrapper.decodeCall(call.to, call.input, "", "call", resolvedContracts, 0); len(decoded.Calls) == 1 {
+			transaction.Call = &decoded.Calls[0]
+		}
+		transaction.Owners, transaction.Threshold = d.safeOwners(ctx, call.to, parentBlock)
+
+		var hash []byte
+		if event, ok := nextExecutionEvent(call.to); ok {
+			data := newABIData(event.Data)
+			hash = data.wordAt(0)
+			transaction.Success = strings.ToLower(event.Topics[0]) == safeExecutionSuccessTopic
+			if payment := data.uintAt(32); payment != nil && payment.Sign() > 0 {
+				transaction.Payment = payment.String()
+			}
+		} else {
+			hash = d.computeSafeTxHash(ctx, call.to, args, chainID, parentBlock)
+		}
+		if hash != nil {
+			transaction.SafeTxHash = "0x" + hex.EncodeToString(hash)
+		}
+
+		if signatures, ok := abiBytes(args, 9*32); ok {
+			transaction.Signers = parseSafeSignatures(hash, signatures, transaction.Threshold)
+		}
+		for i, signer := range transaction.Signers {
+			transaction.Signers[i].IsOwner = containsString(transaction.Owners, signer.Owner)
+		}
+		if len(transaction.Owners) > 0 {
+			transaction.Relayed = !containsString(transaction.Owners, transaction.Executor)
+		} else {
+			transaction.Relayed = true
+			for _, signer := range transaction.Signers {
+				if signer.Owner == transaction.Executor {
+					transaction.Relayed = false
+				}
+			}
+		}
+
+		if d.verbose {
+			fmt.Printf("🔐 Safe %s executed with %d signatures (threshold %d)\n", transaction.Safe, len(transaction.Signers), transaction.Threshold)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if len(transactions) > 0 {
+		baggage["safe_transactions"] = transactions
+	}
+	return nil
+}
+
+type safeExecCall struct {
+	from, to, input string
+}
+
+// execTransactionCalls finds execTransaction calls in the trace (skipping the proxy's delegatecall into the
+// singleton, which repeats the input), or else the transaction's own call
+func execTransactionCalls(baggage map[string]interface{}) []safeExecCall {
+	selector := functionSelector(safeExecTransaction.signature)
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	trace, ok := rawData["trace"].(map[string]interface{})
+	if !ok {
+		to, input, _ := topLevelCall(baggage)
+		if strings.HasPrefix(input, selector) {
+			return []safeExecCall{{from: transactionSender(baggage), to: to, input: input}}
+		}
+		return nil
+	}
+
+	var calls []safeExecCall
+	var walk func(frame map[string]interface{})
+	walk = func(frame map[string]interface{}) {
+		callType, _ := frame["type"].(string)
+		input, _ := frame["input"].(string)
+		input = strings.ToLower(input)
+		if !strings.EqualFold(callType, "DELEGATECALL") && strings.HasPrefix(input, selector) {
+			from, _ := frame["from"].(string)
+			to, _ := frame["to"].(string)
+			calls = append(calls, safeExecCall{from: strings.ToLower(from), to: strings.ToLower(to), input: input})
+		}
+		if children, ok := frame["calls"].([]interface{}); ok {
+			for _, child := range children {
+				if childFrame, ok := child.(map[string]interface{}); ok {
+					walk(childFrame)
+				}
+			}
+		}
+	}
+	walk(trace)
+	return calls
+}
+
+// safeCallContext returns the block before the transaction (the Safe's state when it was signed) and the
+// chain id
+func safeCallContext(baggage map[string]interface{}) (string, int64) {
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	var chainID int64
+	if networkID, ok := rawData["network_id"].(float64); ok {
+		chainID = int64(networkID)
+	}
+	receipt, _ := rawData["receipt"].(map[string]interface{})
+	blockNumber, _ := receipt["blockNumber"].(string)
+	block, err := strconv.ParseUint(strings.TrimPrefix(blockNumber, "0x"), 16, 64)
+	if err != nil || block == 0 {
+		return "latest", chainID
+	}
+	return fmt.Sprintf("0x%x", block-1), chainID
+}
+
+// safeOwners reads getOwners() and getThreshold() from the Safe
+func (d *SafeTransactionDecoder) safeOwners(ctx context.Context, safe, block string) ([]string, int) {
+	if d.rpcClient == nil {
+		return nil, 0
+	}
+	var owners []string
+	if result, err := d.rpcClient.CallContractAt(ctx, safe, functionSelector("getOwners()"), block); err == nil {
+		if words, ok := abiWords(newABIData(result), 0); ok {
+			for _, word := range words {
+				owners = append(owners, "0x"+hex.EncodeToString(word[12:]))
+			}
+		}
+	}
+	threshold := 0
+	if result, err := d.rpcClient.CallContractAt(ctx, safe, functionSelector("getThreshold()"), block); err == nil {
+		if value, ok := decodeUint(result); ok {
+			threshold = int(value)
+		}
+	}
+	return owners, threshold
+}
+
+// safeVersion reads the major and minor version from the Safe's VERSION(), e.g. "1.3.0+L2"
+func (d *SafeTransactionDecoder) safeVersion(ctx context.Context, safe, block string) (int, int, bool) {
+	result, err := d.rpcClient.CallContractAt(ctx, safe, functionSelector("VERSION()"), block)
+	if err != nil {
+		return 0, 0, false
+	}
+	version, ok := abiString(newABIData(result), 0)
+	if !ok {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// computeSafeTxHash rebuilds the EIP-712 Safe transaction hash from execTransaction's arguments and the
+// Safe's nonce before execution, for when no execution event carries it (a reverted transaction). The domain
+// and typehash depend on the Safe's version; without a readable version the hash is not guessed.
+func (d *SafeTransactionDecoder) computeSafeTxHash(ctx context.Context, safe string, args []byte, chainID int64, block string) []byte {
+	if d.rpcClient == nil {
+		return nil
+	}
+	major, minor, ok := d.safeVersion(ctx, safe, block)
+	if !ok {
+		return nil
+	}
+	chainBound := major > 1 || (major == 1 && minor >= 3)
+	if chainBound && chainID == 0 {
+		return nil
+	}
+	result, err := d.rpcClient.CallContractAt(ctx, safe, functionSelector("nonce()"), block)
+	if err != nil {
+		return nil
+	}
+	nonce := newABIData(result).wordAt(0)
+	data, ok := abiBytes(args, 2*32)
+	if nonce == nil || !ok {
+		return nil
+	}
+
+	safeWord := make([]byte, 32)
+	if address, err := hex.DecodeString(strings.TrimPrefix(safe, "0x")); err == nil && len(address) == 20 {
+		copy(safeWord[12:], address)
+	}
+	domainSeparator := keccak256(safeLegacyDomainTypehash, safeWord)
+	if chainBound {
+		domainSeparator = keccak256(safeDomainTypehash, big.NewInt(chainID).FillBytes(make([]byte, 32)), safeWord)
+	}
+	typehash := safeTxTypehash
+	if major == 0 {
+		typehash = safeLegacyTxTypehash
+	}
+
+	// to, value, keccak(data), operation, safeTxGas, baseGas, gasPrice, gasToken, refundReceiver, nonce
+	structHash := keccak256(typehash, args[:64], keccak256(data), args[3*32:9*32], nonce)
+	return keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+}
+
+// parseSafeSignatures recovers the owners from execTransaction's packed 65-byte signatures. The v byte selects
+// the type: 0 a contract signature (r holds the owner, s the offset of its EIP-1271 data after the static
+// part), 1 an approved hash (r holds the owner), above 30 an eth_sign signature with v+4, otherwise an
+// ECDSA signature of the Safe transaction hash. Without the hash ECDSA signers cannot be recovered.
+func parseSafeSignatures(hash, signatures []byte, threshold int) []models.SafeSignature {
+	count := len(signatures) / 65
+	if threshold > 0 && threshold < count {
+		count = threshold
+	}
+
+	var signers []models.SafeSignature
+	for i := 0; i < count; i++ {
+		signature := signatures[i*65 : (i+1)*65]
+		r, v := signature[:32], signature[64]
+		signer := models.SafeSignature{Owner: "0x" + hex.EncodeToString(r[12:])}
+		switch {
+		case v == 0:
+			signer.Type = models.SafeSignatureContract
+			// The dynamic data starts after the last static signature
+			if offset := new(big.Int).SetBytes(signature[32:64]); offset.IsInt64() && offset.Int64()/65 < int64(count) {
+				count = int(offset.Int64() / 65)
+			}
+		case v == 1:
+			signer.Type = models.SafeSignatureApprovedHash
+		case hash == nil:
+			continue
+		case v > 30:
+			adjusted := append(append([]byte{}, signature[:64]...), v-4)
+			owner, ok := recoverSigner(ethSignedMessageHash(hash), adjusted)
+			if !ok {
+				continue
+			}
+			signer.Owner, signer.Type = owner, models.SafeSignatureEthSign
+		default:
+			owner, ok := recoverSigner(hash, signature)
+			if !ok {
+				continue
+			}
+			signer.Owner, signer.Type = owner, models.SafeSignatureECDSA
+		}
+		signers = append(signers, signer)
+	}
+	return signers
+}
+
+// GetSafeTransactions returns the decoded Safe executions from baggage
+func GetSafeTransactions(baggage map[string]interface{}) ([]models.SafeTransaction, bool) {
+	transactions, ok := baggage["safe_transactions"].([]models.SafeTransaction)
+	return transactions, ok && len(transactions) > 0
+}
+
+// GetPromptContext describes each Safe execution as its owners approving the inner transaction
+func (d *SafeTransactionDecoder) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	transactions, ok := GetSafeTransactions(baggage)
+	if !ok {
+		return ""
+	}
+
+	lines := []string{
+		"### SAFE MULTISIG EXECUTIONS:",
+		`Describe each as the Safe's owners approving the inner transaction, e.g. "3 of 5 signers approved sending 1M USDC to X", not as the executor calling execTransaction.`,
+	}
+	for _, transaction := range transactions {
+		approval := fmt.Sprintf("approved by %d signers", len(transaction.Signers))
+		if len(transaction.Owners) > 0 {
+			approval = fmt.Sprintf("approved by %d of %d owners", len(transaction.Signers), len(transaction.Owners))
+		}
+		if transaction.Threshold > 0 {
+			approval += fmt.Sprintf(" (threshold %d)", transaction.Threshold)
+		}
+		lines = append(lines, fmt.Sprintf("- Safe %s: %s", transaction.Safe, approval))
+
+		if len(transaction.Signers) > 0 {
+			signers := make([]string, 0, len(transaction.Signers))
+			for _, signer := range transaction.Signers {
+				signers = append(signers, fmt.Sprintf("%s (%s)", signer.Owner, strings.ReplaceAll(signer.Type, "_", " ")))
+			}
+			lines = append(lines, "  Signers: "+strings.Join(signers, ", "))
+		}
+		if transaction.Relayed {
+			lines = append(lines, fmt.Sprintf("  Submitted by relayer %s (not an owner)", transaction.Executor))
+		} else {
+			lines = append(lines, fmt.Sprintf("  Submitted by owner %s", transaction.Executor))
+		}
+		if !transaction.Success {
+			lines = append(lines, "  The inner transaction failed (the Safe emitted ExecutionFailure or the transaction reverted)")
+		}
+		if transaction.Call != nil {
+			lines = append(lines, "  Inner transaction:")
+			lines = append(lines, renderDecodedCall(*transaction.Call, 2)...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for Safe executions (none - they are transaction specific)
+func (d *SafeTransactionDecoder) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
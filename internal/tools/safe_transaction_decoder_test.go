@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// testKey is the private key whose scalar is n
+func testKey(t *testing.T, n int64) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(new(big.Int).SetInt64(n).FillBytes(make([]byte, 32)))
+	require.NoError(t, err)
+	return key
+}
+
+// testSign signs a hash with a private key, returning r || s || v with v 27/28 as ecrecover expects
+func testSign(t *testing.T, hash []byte, privateKey int64) []byte {
+	signature, err := crypto.Sign(hash, testKey(t, privateKey))
+	require.NoError(t, err)
+	signature[64] += 27
+	return signature
+}
+
+func testAddressOf(t *testing.T, privateKey int64) string {
+	return strings.ToLower(crypto.PubkeyToAddress(testKey(t, privateKey).PublicKey).Hex())
+}
+
+func TestRecoverSigner(t *testing.T) {
+	// The well-known address of private key 1
+	require.Equal(t, "0x7e5f4552091a69125d5dfcb7b8c2659029395bdf", testAddressOf(t, 1))
+
+	hash := keccak256([]byte("txplain"))
+	signer, ok := recoverSigner(hash, testSign(t, hash, 1))
+	require.True(t, ok)
+	require.Equal(t, testAddressOf(t, 1), signer)
+
+	signer, ok = recoverSigner(hash, testSign(t, hash, 0xbeef))
+	require.True(t, ok)
+	require.Equal(t, testAddressOf(t, 0xbeef), signer)
+
+	// go-ethereum's published ecrecover vector, with v as 0/1 and as 27/28
+	message, _ := hex.DecodeString("ce0677bb30baa8cf067c88db9811f4333d131bf8bcf12fe7065d211dce971008")
+	signature, _ := hex.DecodeString("90f27b8b488db00b00606796d2987f6a5f59ae62ea05effe84fef5b8b0e549984a691139ad57a3f0b906637673aa2f63d1f55cb1a69199d4009eea23ceaddc9301")
+	publicKey, _ := hex.DecodeString("e32df42865e97135acfb65f3bae71bdc86f4d49150ad6a440b6f15878109880a0a2b2667f7e725ceea70c673093bf67663e0312623c8e091b13cf2c0f11ef652")
+	expected := "0x" + hex.EncodeToString(keccak256(publicKey)[12:])
+	signer, ok = recoverSigner(message, signature)
+	require.True(t, ok)
+	require.Equal(t, expected, signer)
+	signature[64] += 27
+	signer, ok = recoverSigner(message, signature)
+	require.True(t, ok)
+	require.Equal(t, expected, signer)
+
+	_, ok = recoverSigner(hash, make([]byte, 65))
+	require.False(t, ok, "zero r and s are invalid")
+}
+
+func TestSafeTransactionDecoderRecoversSigners(t *testing.T) {
+	safeTxHash := keccak256([]byte("safe transaction"))
+	ownerA, ownerB := testAddressOf(t, 11), testAddressOf(t, 22)
+
+	// ECDSA, eth_sign (v + 4) and an approved hash from a third owner, sorted as the Safe requires
+	ethSign := testSign(t, ethSignedMessageHash(safeTxHash), 22)
+	ethSign[64] += 4
+	approved, _ := hex.DecodeString(abiWord(testTrader) + abiWord("0") + "01")
+	signatures := hex.EncodeToString(testSign(t, safeTxHash, 11)) + hex.EncodeToString(ethSign) + hex.EncodeToString(approved)
+
+	transfer := encodeCall("transfer(address,uint256)", testRouter, fmt.Sprintf("%x", 1_000_000_000000))
+	execTransaction := functionSelector(safeExecTransaction.signature) +
+		abiWord(testUSDC) + abiWord("0") + abiWord(fmt.Sprintf("%x", 10*32)) + abiWord("0") +
+		abiWord("0") + abiWord("0") + abiWord("0") + abiWord("0") + abiWord("0")
+	dataTail := encodeTail(transfer)
+	execTransaction += abiWord(fmt.Sprintf("%x", 10*32+len(dataTail)/2)) + dataTail + encodeTail(signatures)
+
+	relayer := "0x2222222222222222222222222222222222222222"
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt": map[string]interface{}{"from": relayer, "to": testSafe, "status": "0x1"},
+			"trace": map[string]interface{}{
+				"type": "CALL", "from": relayer, "to": testSafe, "input": execTransaction,
+				"calls": []interface{}{
+					// The proxy delegates to the singleton with the same input
+					map[string]interface{}{"type": "DELEGATECALL", "from": testSafe, "to": "0x3333333333333333333333333333333333333333", "input": execTransaction},
+				},
+			},
+		},
+		"events": []models.Event{
+			testLog(testUSDC, "Transfer(address,address,uint256)", []string{testSafe, testRouter}, fmt.Sprintf("%x", 1_000_000_000000)),
+			testLog(testSafe, "ExecutionSuccess(bytes32,uint256)", nil, hex.EncodeToString(safeTxHash), "0"),
+		},
+	}
+
+	decoder := NewSafeTransactionDecoder(nil, false)
+	require.NoError(t, decoder.Process(context.Background(), baggage))
+
+	transactions, ok := GetSafeTransactions(baggage)
+	require.True(t, ok)
+	require.Len(t, transactions, 1, "the singleton delegatecall is not a second execution")
+	transaction := transactions[0]
+	require.Equal(t, testSafe, transaction.Safe)
+	require.Equal(t, "0x"+hex.EncodeToString(safeTxHash), transaction.SafeTxHash)
+	require.True(t, transaction.Success)
+	require.Equal(t, relayer, transaction.Executor)
+	require.True(t, transaction.Relayed, "the executor did not sign")
+	require.Equal(t, []models.SafeSignature{
+		{Owner: ownerA, Type: models.SafeSignatureECDSA},
+		{Owner: ownerB, Type: models.SafeSignatureEthSign},
+		{Owner: testTrader, Type: models.SafeSignatureApprovedHash},
+	}, transaction.Signers)
+
+	require.NotNil(t, transaction.Call)
+	require.Equal(t, testUSDC, transaction.Call.Contract)
+	require.Equal(t, "transfer", transaction.Call.Method)
+
+	prompt := decoder.GetPromptContext(context.Background(), baggage)
+	require.Contains(t, prompt, "- Safe "+testSafe+": approved by 3 signers")
+	require.Contains(t, prompt, "Submitted by relayer "+relayer+" (not an owner)")
+	require.Contains(t, prompt, "    - call transfer(")
+
+	// The Safe, not the relayer, sent the USDC
+	baggage["token_metadata"] = map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}}
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), baggage))
+	actions, _ := GetActions(baggage)
+	require.Len(t, actions, 1)
+	require.Equal(t, testSafe, actions[0].Actor)
+	require.Equal(t, "1000000", actions[0].TokensOut[0].Amount)
+}
+
+func TestParseSafeSignaturesStopsAtContractSignatureData(t *testing.T) {
+	// A contract signature whose EIP-1271 data follows the single static signature
+	static, _ := hex.DecodeString(abiWord(testSafe) + abiWord(fmt.Sprintf("%x", 65)) + "00")
+	dynamic, _ := hex.DecodeString(strings.Repeat("ab", 100))
+	signers := parseSafeSignatures(nil, append(static, dynamic...), 0)
+	require.Equal(t, []models.SafeSignature{{Owner: testSafe, Type: models.SafeSignatureContract}}, signers)
+}
+
+func TestSafeTransactionDecoderHashesRevertedTransactionsPerVersion(t *testing.T) {
+	const networkID = 99006
+	owner := testAddressOf(t, 33)
+	transfer := encodeCall("transfer(address,uint256)", testRouter, "64")
+	args := abiWord(testUSDC) + abiWord("0") + abiWord(fmt.Sprintf("%x", 10*32)) + abiWord("0") +
+		abiWord("0") + abiWord("0") + abiWord("0") + abiWord("0") + abiWord("0")
+	dataTail := encodeTail(transfer)
+	args += abiWord(fmt.Sprintf("%x", 10*32+len(dataTail)/2)) + dataTail
+
+	// A Safe v1.1.1 signs over a domain without the chain id
+	encoded, _ := hex.DecodeString(args)
+	data, _ := hex.DecodeString(strings.TrimPrefix(transfer, "0x"))
+	safeWord, _ := hex.DecodeString(abiWord(testSafe))
+	nonce, _ := hex.DecodeString(abiWord("5"))
+	domainSeparator := keccak256(keccak256([]byte("EIP712Domain(address verifyingContract)")), safeWord)
+	structHash := keccak256(safeTxTypehash, encoded[:64], keccak256(data), encoded[3*32:9*32], nonce)
+	safeTxHash := keccak256([]byte{0x19, 0x01}, domainSeparator, structHash)
+	execTransaction := functionSelector(safeExecTransaction.signature) + args + encodeTail(hex.EncodeToString(testSign(t, safeTxHash, 33)))
+
+	version := "1.1.1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     int           `json:"id"`
+			Params []interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		var result string
+		switch request.Params[0].(map[string]interface{})["data"] {
+		case functionSelector("getOwners()"):
+			result = "0x" + abiWord("20") + abiWord("1") + abiWord(owner)
+		case functionSelector("getThreshold()"):
+			result = "0x" + abiWord("1")
+		case functionSelector("VERSION()"):
+			result = "0x" + abiWord("20") + encodeTail(hex.EncodeToString([]byte(version)))
+		case functionSelector("nonce()"):
+			result = "0x" + abiWord("5")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	defer server.Close()
+
+	if models.SupportedNetworks == nil {
+		models.InitializeNetworks()
+	}
+	models.SupportedNetworks[networkID] = models.Network{ID: networkID, Name: "Test", RPCUrl: server.URL}
+	defer delete(models.SupportedNetworks, networkID)
+	client, err := rpc.NewClient(networkID)
+	require.NoError(t, err)
+
+	decode := func() models.SafeTransaction {
+		baggage := map[string]interface{}{
+			"raw_data": map[string]interface{}{
+				"network_id": float64(1),
+				"receipt":    map[string]interface{}{"from": owner, "to": testSafe, "status": "0x0", "blockNumber": "0x10"},
+				"trace":      map[string]interface{}{"type": "CALL", "from": owner, "to": testSafe, "input": execTransaction},
+			},
+		}
+		require.NoError(t, NewSafeTransactionDecoder(client, false).Process(context.Background(), baggage))
+		transactions, ok := GetSafeTransactions(baggage)
+		require.True(t, ok)
+		require.Len(t, transactions, 1)
+		return transactions[0]
+	}
+
+	transaction := decode()
+	require.False(t, transaction.Success)
+	require.Equal(t, "0x"+hex.EncodeToString(safeTxHash), transaction.SafeTxHash)
+	require.Equal(t, []models.SafeSignature{{Owner: owner, Type: models.SafeSignatureECDSA, IsOwner: true}}, transaction.Signers)
+
+	// The same signature does not match the chain-bound domain of a v1.3 Safe
+	version = "1.3.0+L2"
+	transaction = decode()
+	require.NotEqual(t, "0x"+hex.EncodeToString(safeTxHash), transaction.SafeTxHash)
+	require.Len(t, transaction.Signers, 1)
+	require.False(t, transaction.Signers[0].IsOwner)
+
+	// Without a version the hash is not guessed
+	version = ""
+	transaction = decode()
+	require.Empty(t, transaction.SafeTxHash)
+}
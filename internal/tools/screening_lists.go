@@ -0,0 +1,323 @@
+package tools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ScreeningDir is the folder of the static data directory holding one CSV per screening list (<list>.csv)
+const ScreeningDir = "screening"
+
+// Screening lists
+const (
+	ScreeningListSanctions = "sanctions" // OFAC SDN digital currency addresses
+	ScreeningListScam      = "scam"      // Phishing and scam addresses
+	ScreeningListDenylist  = "denylist"  // Addresses the team chooses to flag
+)
+
+// ScreeningLists are the lists addresses are screened against
+var ScreeningLists = []string{ScreeningListSanctions, ScreeningListScam, ScreeningListDenylist}
+
+// screeningCSVHeader is the column layout of screening CSVs. Readers locate columns by header name, so
+// hand-written denylists may only have an address column.
+var screeningCSVHeader = []string{"address", "chain_id", "label", "source", "reference"}
+
+// sdnAddressPattern finds Ethereum-format addresses in the remarks of the OFAC SDN list (sdn.csv), e.g.
+// "Digital Currency Address - ETH 0x8589427373d6d84e98730d7795d8f6f8731fda16;"
+var sdnAddressPattern = regexp.MustCompile(`Digital Currency Address - [A-Za-z0-9]+ (0x[0-9a-fA-F]{40})`)
+
+// ScreeningEntry is one address on a screening list
+type ScreeningEntry struct {
+	List      string `json:"list"` // ScreeningList*
+	Address   string `json:"address"`
+	ChainID   int64  `json:"chain_id,omitempty"`  // 0 applies to every chain
+	Label     string `json:"label,omitempty"`     // Who the address belongs to, e.g. the SDN name
+	Source    string `json:"source"`              // File or URL the entry was imported from
+	Reference string `json:"reference,omitempty"` // Identifier in the source, e.g. the SDN entry number
+}
+
+// ReadScreeningCSV reads a screening CSV. Files with a header row need an address column and may have
+// chain_id, label (or name), source and reference columns; files without one list an address per line.
+// Rows without a valid address are skipped.
+func ReadScreeningCSV(r io.Reader, list, source string) ([]ScreeningEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := map[string]int{"address": 0}
+	if !isScreeningAddress(strings.TrimSpace(records[0][0])) {
+		columns = make(map[string]int)
+		for i, name := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, ok := columns["address"]; !ok {
+			return nil, fmt.Errorf("screening CSV has no address column")
+		}
+		records = records[1:]
+	}
+	field := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var entries []ScreeningEntry
+	for _, record := range records {
+		entry := ScreeningEntry{
+			List:      list,
+			Address:   strings.ToLower(field(record, "address")),
+			Label:     field(record, "label", "name"),
+			Source:    field(record, "source"),
+			Reference: field(record, "reference"),
+		}
+		if chainID := field(record, "chain_id", "chainid"); chainID != "" {
+			if entry.ChainID, err = strconv.ParseInt(chainID, 10, 64); err != nil {
+				continue
+			}
+		}
+		if entry.Source == "" {
+			entry.Source = source
+		}
+		if isScreeningAddress(entry.Address) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// ParseScreeningJSON parses a JSON address list: an array of addresses (as published for the OFAC list and by
+// scam trackers), an array of objects with address, chain_id, label or name and reference, or either of those
+// under an "addresses" key
+func ParseScreeningJSON(data []byte, list, source string) ([]ScreeningEntry, error) {
+	var wrapped struct {
+		Addresses json.RawMessage `json:"addresses"`
+	}
+	if json.Unmarshal(data, &wrapped) == nil && wrapped.Addresses != nil {
+		data = wrapped.Addresses
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid screening list: %w", err)
+	}
+	var entries []ScreeningEntry
+	for _, item := range items {
+		entry := ScreeningEntry{List: list, Source: source}
+		var object struct {
+			Address   string `json:"address"`
+			ChainID   int64  `json:"chain_id"`
+			ChainId   int64  `json:"chainId"`
+			Label     string `json:"label"`
+			Name      string `json:"name"`
+			Reference string `json:"reference"`
+		}
+		if json.Unmarshal(item, &entry.Address) != nil {
+			if err := json.Unmarshal(item, &object); err != nil {
+				continue
+			}
+			entry.Address, entry.ChainID, entry.Label, entry.Reference = object.Address, object.ChainID, object.Label, object.Reference
+			if entry.ChainID == 0 {
+				entry.ChainID = object.ChainId
+			}
+			if entry.Label == "" {
+				entry.Label = object.Name
+			}
+		}
+		entry.Address = strings.ToLower(strings.TrimSpace(entry.Address))
+		if isScreeningAddress(entry.Address) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// ParseOFACSDN extracts the Ethereum-format digital currency addresses of the OFAC SDN list (sdn.csv, no
+// header: ent_num, SDN_Name, SDN_Type, Program, ..., Remarks). Entries are labeled with the SDN name and
+// program and referenced by entry number. Addresses apply to every chain.
+func ParseOFACSDN(data []byte, source string) ([]ScreeningEntry, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid SDN list: %w", err)
+	}
+
+	var entries []ScreeningEntry
+	for _, record := range records {
+		if len(record) < 4 {
+			continue
+		}
+		label := strings.TrimSpace(record[1])
+		// Multiple programs are separated by "] [", e.g. "CYBER2] [DPRK3"
+		if program := strings.TrimSpace(record[3]); program != "" && program != "-0-" {
+			label += " (" + strings.ReplaceAll(program, "] [", ", ") + ")"
+		}
+		for _, match := range sdnAddressPattern.FindAllStringSubmatch(strings.Join(record[4:], " "), -1) {
+			entries = append(entries, ScreeningEntry{
+				List:      ScreeningListSanctions,
+				Address:   strings.ToLower(match[1]),
+				Label:     label,
+				Source:    source,
+				Reference: "SDN " + strings.TrimSpace(record[0]),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// WriteScreeningCSV writes entries in the screening CSV layout, sorted by address for stable diffs
+func WriteScreeningCSV(w io.Writer, entries []ScreeningEntry) error {
+	sorted := append([]ScreeningEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Address != sorted[j].Address {
+			return sorted[i].Address < sorted[j].Address
+		}
+		return sorted[i].ChainID < sorted[j].ChainID
+	})
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(screeningCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range sorted {
+		chainID := ""
+		if entry.ChainID != 0 {
+			chainID = strconv.FormatInt(entry.ChainID, 10)
+		}
+		if err := writer.Write([]string{entry.Address, chainID, entry.Label, entry.Source, entry.Reference}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportScreeningLists reads address lists from files or http(s) URLs - OFAC's sdn.csv, JSON address arrays,
+// CSVs or one address per line - and writes them into <dir>/<list>.csv. Importing a source again refreshes
+// it: its previous entries are replaced, so addresses taken off a list are removed. Returns the number of
+// entries added and removed.
+func ImportScreeningLists(list string, paths []string, dir string) (int, int, error) {
+	if !containsString(ScreeningLists, list) {
+		return 0, 0, fmt.Errorf("unknown screening list %q (want one of %s)", list, strings.Join(ScreeningLists, ", "))
+	}
+
+	var incoming []ScreeningEntry
+	sources := make(map[string]bool)
+	for _, path := range paths {
+		entries, err := readScreeningSource(path, list)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to import %s: %w", path, err)
+		}
+		sources[filepath.Base(path)] = true
+		for _, entry := range entries {
+			sources[entry.Source] = true
+		}
+		incoming = append(incoming, entries...)
+	}
+
+	filename := filepath.Join(dir, list+".csv")
+	var existing []ScreeningEntry
+	if file, err := os.Open(filename); err == nil {
+		existing, err = ReadScreeningCSV(file, list, "")
+		file.Close()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+	}
+
+	key := func(entry ScreeningEntry) string {
+		return TokenKey(entry.ChainID, entry.Address) + ":" + entry.Source
+	}
+	previous := make(map[string]bool)
+	var merged []ScreeningEntry
+	for _, entry := range existing {
+		if sources[entry.Source] {
+			previous[key(entry)] = true
+		} else {
+			merged = append(merged, entry)
+		}
+	}
+	added := 0
+	seen := make(map[string]bool)
+	for _, entry := range incoming {
+		if seen[key(entry)] {
+			continue
+		}
+		seen[key(entry)] = true
+		if !previous[key(entry)] {
+			added++
+		}
+		merged = append(merged, entry)
+	}
+	removed := 0
+	for entry := range previous {
+		if !seen[entry] {
+			removed++
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, 0, err
+	}
+	// Write to a temp file first so a failed import never leaves a truncated CSV behind
+	tmp := filename + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := WriteScreeningCSV(file, merged); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return 0, 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, 0, err
+	}
+	return added, removed, os.Rename(tmp, filename)
+}
+
+// readScreeningSource loads one address list from disk or over HTTP, recognizing its format by content
+func readScreeningSource(path, list string) ([]ScreeningEntry, error) {
+	data, err := readImportSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := filepath.Base(path)
+	trimmed := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"):
+		return ParseScreeningJSON(data, list, source)
+	case strings.Contains(trimmed, "Digital Currency Address"):
+		if list != ScreeningListSanctions {
+			return nil, fmt.Errorf("the OFAC SDN list belongs in the %s list", ScreeningListSanctions)
+		}
+		return ParseOFACSDN(data, source)
+	default:
+		return ReadScreeningCSV(strings.NewReader(trimmed), list, source)
+	}
+}
+
+// isScreeningAddress reports whether s is a lowercase or checksummed 0x address
+func isScreeningAddress(s string) bool {
+	return len(s) == 42 && strings.HasPrefix(s, "0x") && isHexString(s[2:])
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Default base URLs for the remote signature APIs. Both can be pointed at a local mirror via
+// FOURBYTE_API_URL / OPENCHAIN_API_URL; Sourcify serves the openchain API at https://api.4byte.sourcify.dev
+const (
+	DefaultFourByteAPIURL  = "https://www.4byte.directory"
+	DefaultOpenchainAPIURL = "https://api.openchain.xyz"
+)
+
+// SignatureBackend is a remote API that maps function selectors and event topics to text signatures
+type SignatureBackend interface {
+	Name() string
+	// LookupSignatures returns the candidate text signatures for a hash, most common first.
+	// sigType is "function" or "event".
+	LookupSignatures(ctx context.Context, hexSignature, sigType string) ([]string, error)
+}
+
+// NewSignatureBackendsFromEnv builds the backends listed in SIGNATURE_BACKENDS (default "4byte,openchain")
+func NewSignatureBackendsFromEnv(httpClient *http.Client) []SignatureBackend {
+	names := os.Getenv("SIGNATURE_BACKENDS")
+	if names == "" {
+		names = "4byte,openchain"
+	}
+
+	var backends []SignatureBackend
+	for _, name := range strings.Split(names, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "4byte":
+			backends = append(backends, NewFourByteBackend(os.Getenv("FOURBYTE_API_URL"), httpClient))
+		case "openchain", "sourcify":
+			backends = append(backends, NewOpenchainBackend(os.Getenv("OPENCHAIN_API_URL"), httpClient))
+		}
+	}
+	return backends
+}
+
+// FourByteBackend looks up signatures on 4byte.directory (or a mirror of its API)
+type FourByteBackend struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewFourByteBackend creates a 4byte.directory backend; an empty baseURL uses the public API
+func NewFourByteBackend(baseURL string, httpClient *http.Client) *FourByteBackend {
+	if baseURL == "" {
+		baseURL = DefaultFourByteAPIURL
+	}
+	return &FourByteBackend{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// Name returns the backend name
+func (b *FourByteBackend) Name() string {
+	return "4byte"
+}
+
+// LookupSignatures queries the 4byte.directory signature or event-signature endpoint
+func (b *FourByteBackend) LookupSignatures(ctx context.Context, hexSignature, sigType string) ([]string, error) {
+	endpoint := "/api/v1/signatures/"
+	if sigType == "event" {
+		endpoint = "/api/v1/event-signatures/"
+	}
+
+	params := url.Values{}
+	params.Set("hex_signature", hexSignature)
+
+	body, err := getSignatureAPI(ctx, b.httpClient, b.baseURL+endpoint+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var response FourByteResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var candidates []string
+	for _, result := range response.Results {
+		candidates = append(candidates, result.TextSignature)
+	}
+	return candidates, nil
+}
+
+// OpenchainBackend looks up signatures on the openchain.xyz signature database API, which Sourcify also serves
+type OpenchainBackend struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// OpenchainResponse represents the response from the openchain signature-database lookup API
+type OpenchainResponse struct {
+	OK     bool `json:"ok"`
+	Result struct {
+		Function map[string][]OpenchainSignature `json:"function"`
+		Event    map[string][]OpenchainSignature `json:"event"`
+	} `json:"result"`
+}
+
+// OpenchainSignature is a single candidate in an openchain lookup response
+type OpenchainSignature struct {
+	Name     string `json:"name"`
+	Filtered bool   `json:"filtered"`
+}
+
+// NewOpenchainBackend creates an openchain backend; an empty baseURL uses the public API
+func NewOpenchainBackend(baseURL string, httpClient *http.Client) *OpenchainBackend {
+	if baseURL == "" {
+		baseURL = DefaultOpenchainAPIURL
+	}
+	return &OpenchainBackend{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// Name returns the backend name
+func (b *OpenchainBackend) Name() string {
+	return "openchain"
+}
+
+// LookupSignatures queries the openchain lookup endpoint, skipping signatures it flags as spam
+func (b *OpenchainBackend) LookupSignatures(ctx context.Context, hexSignature, sigType string) ([]string, error) {
+	params := url.Values{}
+	params.Set(sigType, hexSignature)
+	params.Set("filter", "true")
+
+	body, err := getSignatureAPI(ctx, b.httpClient, b.baseURL+"/signature-database/v1/lookup?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var response OpenchainResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if !response.OK {
+		return nil, fmt.Errorf("openchain lookup failed")
+	}
+
+	results := response.Result.Function
+	if sigType == "event" {
+		results = response.Result.Event
+	}
+
+	var candidates []string
+	for hash, signatures := range results {
+		if !strings.EqualFold(hash, hexSignature) {
+			continue
+		}
+		for _, signature := range signatures {
+			if !signature.Filtered && signature.Name != "" {
+				candidates = append(candidates, signature.Name)
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// getSignatureAPI performs a GET against a signature API and returns the body of a 200 response
+func getSignatureAPI(ctx context.Context, httpClient *http.Client, apiURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,338 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/rpc"
+)
+
+// SignatureExplainer finishes a decoded typed-data signature: it formats amounts with token metadata, names
+// spenders and the verifying contract from static knowledge, checks spenders on chain like ApprovalAnalyzer
+// and writes a deterministic summary of what signing would authorize, with its risks.
+type SignatureExplainer struct {
+	rpcClient      *rpc.Client
+	staticProvider *StaticContextProvider
+	approvals      *ApprovalAnalyzer
+	verbose        bool
+}
+
+// NewSignatureExplainer creates a new signature explainer. Without an RPC client spenders are not checked on
+// chain; without a static context provider only token metadata names contracts.
+func NewSignatureExplainer(rpcClient *rpc.Client, staticProvider *StaticContextProvider, verbose bool) *SignatureExplainer {
+	return &SignatureExplainer{
+		rpcClient:      rpcClient,
+		staticProvider: staticProvider,
+		approvals:      NewApprovalAnalyzer(rpcClient, verbose),
+		verbose:        verbose,
+	}
+}
+
+// Name returns the processor name
+func (e *SignatureExplainer) Name() string {
+	return "signature_explainer"
+}
+
+// Description returns the processor description
+func (e *SignatureExplainer) Description() string {
+	return "Explains what an EIP-712 typed-data signature authorizes and flags risky requests"
+}
+
+// Dependencies returns the tools this processor depends on
+func (e *SignatureExplainer) Dependencies() []string {
+	return []string{"typed_data_decoder", "token_metadata_enricher"}
+}
+
+// Process completes baggage["signature_explanation"] with names, formatted amounts, risks and a summary
+func (e *SignatureExplainer) Process(ctx context.Context, baggage map[string]interface{}) error {
+	explanation, ok := GetSignatureExplanation(baggage)
+	if !ok {
+		return fmt.Errorf("no decoded signature to explain")
+	}
+	amounts := &actionContext{}
+	amounts.metadata, _ = baggage["token_metadata"].(map[string]*TokenMetadata)
+	chainID := explanation.Domain.ChainID
+	if chainID == 0 {
+		chainID = explanation.NetworkID
+	}
+
+	explanation.Domain.VerifyingContractName = e.contractName(chainID, explanation.Domain.VerifyingContract, amounts)
+	for i := range explanation.Approvals {
+		approval := &explanation.Approvals[i]
+		approval.Allowance = refreshAmount(amounts, approval.Allowance)
+		if approval.Unlimited {
+			approval.Allowance.Amount = "unlimited"
+			approval.Allowance.AmountUSD = ""
+		}
+		approval.SpenderName = e.contractName(chainID, approval.Spender, amounts)
+	}
+	if order := explanation.Order; order != nil {
+		for _, list := range [][]models.ActionAmount{order.Gives, order.Receives, order.Fees} {
+			for i := range list {
+				list[i] = refreshAmount(amounts, list[i])
+			}
+		}
+	}
+
+	e.checkSpenders(ctx, baggage, explanation)
+	explanation.Risks = append(explanation.Risks, signatureRisks(explanation)...)
+	typedData, _ := baggage["typed_data"].(*models.TypedData)
+	explanation.Summary = describeSignature(explanation, typedData)
+	if e.verbose {
+		fmt.Printf("✍️  %s\n", explanation.Summary)
+	}
+	return nil
+}
+
+// refreshAmount re-formats an amount decoded before token metadata was known
+func refreshAmount(amounts *actionContext, amount models.ActionAmount) models.ActionAmount {
+	raw, ok := new(big.Int).SetString(amount.RawAmount, 10)
+	if !ok {
+		refreshed := amounts.amount(amount.Token, amount.TokenID, nil)
+		refreshed.Amount = amount.Amount
+		return refreshed
+	}
+	return amounts.amount(amount.Token, amount.TokenID, raw)
+}
+
+// contractName names a contract from Permit2, static protocol deployments and address labels, or its token
+// metadata
+func (e *SignatureExplainer) contractName(chainID int64, address string, amounts *actionContext) string {
+	if address == "" {
+		return ""
+	}
+	if address == permit2Address {
+		return "Permit2"
+	}
+	if e.staticProvider != nil {
+		if deployment, ok := e.staticProvider.LookupProtocolDeployment(chainID, address); ok {
+			if deployment.Contract != "" {
+				return deployment.Contract
+			}
+			return strings.TrimSpace(deployment.Protocol + " " + deployment.Version)
+		}
+		if item, ok := e.staticProvider.GetAddressInfo(chainID, address); ok && item.Name != "" {
+			return item.Name
+		}
+	}
+	if metadata, ok := amounts.metadata[address]; ok && metadata.Name != "" {
+		return metadata.Name
+	}
+	return ""
+}
+
+// checkSpenders classifies each spender as an EOA or contract against the latest block, flagging contracts
+// deployed within freshContractAge, and adds the approval risks ApprovalAnalyzer would
+func (e *SignatureExplainer) checkSpenders(ctx context.Context, baggage map[string]interface{}, explanation *models.SignatureExplanation) {
+	if e.rpcClient == nil || len(explanation.Approvals) == 0 {
+		return
+	}
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	if rawData == nil {
+		rawData = make(map[string]interface{})
+		baggage["raw_data"] = rawData
+	}
+	if _, ok := rawData["block"]; !ok {
+		if block, err := e.rpcClient.GetBlockByNumber(ctx, "latest"); err == nil {
+			rawData["block"] = block
+		}
+	}
+	block, freshnessBlock := e.approvals.blocks(ctx, baggage)
+
+	spenders := make(map[string]spenderInfo)
+	for i := range explanation.Approvals {
+		approval := &explanation.Approvals[i]
+		info, seen := spenders[approval.Spender]
+		if !seen {
+			info.name = approval.SpenderName
+			info.kind, info.fresh = e.approvals.classifySpender(ctx, approval.Spender, block, freshnessBlock)
+			spenders[approval.Spender] = info
+		}
+		approval.SpenderType = info.kind
+		approval.Risks = approvalRisks(*approval, info)
+		for _, risk := range approval.Risks {
+			if !containsString(explanation.Risks, risk) {
+				explanation.Risks = append(explanation.Risks, risk)
+			}
+		}
+	}
+}
+
+// signatureRisks flags a domain for another chain and orders that pay the maker nothing or pay someone else
+func signatureRisks(explanation *models.SignatureExplanation) []string {
+	var risks []string
+	if explanation.Domain.ChainID != 0 && explanation.NetworkID != 0 && explanation.Domain.ChainID != explanation.NetworkID {
+		risks = append(risks, fmt.Sprintf("The signature is for chain %d, not the selected network %d", explanation.Domain.ChainID, explanation.NetworkID))
+	}
+	if order := explanation.Order; order != nil {
+		if len(order.Gives) > 0 && len(order.Receives) == 0 {
+			risks = append(risks, fmt.Sprintf("The %s order gives away %s and pays the maker nothing in return", order.Protocol, describeActionAmounts(order.Gives)))
+		}
+		if order.Receiver != "" {
+			risks = append(risks, fmt.Sprintf("The %s order sends its proceeds to %s, not to the signer", order.Protocol, order.Receiver))
+		}
+	}
+	return risks
+}
+
+// describeSignature summarizes what signing authorizes, e.g. "Signing this permit lets Uniswap (0x…) spend an
+// unlimited amount of USDC from 0x…. The signature can be submitted until 2030-01-01 00:00 UTC."
+func describeSignature(explanation *models.SignatureExplanation, typedData *models.TypedData) string {
+	party := func(address, name string) string {
+		if name != "" {
+			return fmt.Sprintf("%s (%s)", name, address)
+		}
+		return address
+	}
+	until := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return " until " + t.UTC().Format("2006-01-02 15:04 UTC")
+	}
+
+	var sentences []string
+	switch explanation.Schema {
+	case models.SignatureSchemaPermit, models.SignatureSchemaPermit2:
+		for _, approval := range explanation.Approvals {
+			owner := approval.Owner
+			if owner == "" {
+				owner = "the signer"
+			}
+			token := describeActionAmounts([]models.ActionAmount{{Token: approval.Allowance.Token, Symbol: approval.Allowance.Symbol}})
+			spender := party(approval.Spender, approval.SpenderName)
+			if approval.Revoked {
+				sentences = append(sentences, fmt.Sprintf("Signing this permit revokes %s's permission to spend %s from %s.", spender, token, owner))
+				continue
+			}
+			amount := "up to " + describeActionAmounts([]models.ActionAmount{approval.Allowance})
+			if approval.Unlimited {
+				amount = "an unlimited amount of " + token
+			}
+			// A permit's allowance does not expire; a Permit2 allowance does
+			via, expiry := "", ""
+			if approval.Kind == models.ApprovalKindPermit2 {
+				via, expiry = " through Permit2", until(approval.Expiration)
+			}
+			sentences = append(sentences, fmt.Sprintf("Signing this permit lets %s spend %s from %s%s%s.", spender, amount, owner, via, expiry))
+		}
+		if len(explanation.Approvals) > 0 && explanation.Approvals[0].Deadline != nil {
+			sentences = append(sentences, fmt.Sprintf("The signature can be submitted%s.", until(explanation.Approvals[0].Deadline)))
+		}
+	case models.SignatureSchemaPermit2Transfer:
+		var tokens []models.ActionAmount
+		for _, approval := range explanation.Approvals {
+			tokens = append(tokens, approval.Allowance)
+		}
+		if len(explanation.Approvals) > 0 {
+			approval := explanation.Approvals[0]
+			owner := approval.Owner
+			if owner == "" {
+				owner = "the signer"
+			}
+			sentence := fmt.Sprintf("Signing this lets %s transfer %s from %s once through Permit2%s", party(approval.Spender, approval.SpenderName), describeActionAmounts(tokens), owner, until(approval.Deadline))
+			if witness := permit2WitnessType(typedData); witness != "" {
+				sentence += fmt.Sprintf(", as payment for the %s it is signed with", witness)
+			}
+			sentences = append(sentences, sentence+".")
+		}
+	case models.SignatureSchemaSeaport, models.SignatureSchemaCowSwap:
+		order := explanation.Order
+		maker := order.Maker
+		if maker == "" {
+			maker = "the signer"
+		}
+		switch {
+		case len(order.Receives) == 0:
+			sentences = append(sentences, fmt.Sprintf("Signing this %s order gives away %s from %s without paying anything in return%s.", order.Protocol, describeActionAmounts(order.Gives), maker, until(order.ValidUntil)))
+		case explanation.Schema == models.SignatureSchemaCowSwap && typedData != nil && typedData.Message["kind"] == "buy":
+			sentences = append(sentences, fmt.Sprintf("Signing this %s order buys %s for at most %s from %s%s.", order.Protocol, describeActionAmounts(order.Receives), describeActionAmounts(order.Gives), maker, until(order.ValidUntil)))
+		case explanation.Schema == models.SignatureSchemaCowSwap:
+			sentences = append(sentences, fmt.Sprintf("Signing this %s order sells %s from %s for at least %s%s.", order.Protocol, describeActionAmounts(order.Gives), maker, describeActionAmounts(order.Receives), until(order.ValidUntil)))
+		default:
+			sentences = append(sentences, fmt.Sprintf("Signing this %s order offers %s from %s in exchange for %s%s.", order.Protocol, describeActionAmounts(order.Gives), maker, describeActionAmounts(order.Receives), until(order.ValidUntil)))
+		}
+		if len(order.Fees) > 0 {
+			sentences = append(sentences, fmt.Sprintf("%s of it goes to fees.", describeActionAmounts(order.Fees)))
+		}
+		if order.Receiver != "" {
+			sentences = append(sentences, fmt.Sprintf("The proceeds go to %s.", order.Receiver))
+		}
+	}
+
+	if len(sentences) == 0 {
+		target := party(explanation.Domain.VerifyingContract, explanation.Domain.VerifyingContractName)
+		if explanation.Domain.Name != "" {
+			target = explanation.Domain.Name + " at " + target
+		}
+		return fmt.Sprintf("Signing this %s message for %s authorizes something that is not a known permit or order; review the message before signing.", explanation.PrimaryType, strings.TrimSuffix(target, " at "))
+	}
+	return strings.Join(sentences, " ")
+}
+
+// permit2WitnessType returns the type of the order a Permit2 witness transfer is signed with, e.g.
+// "ExclusiveDutchOrder"
+func permit2WitnessType(typedData *models.TypedData) string {
+	if typedData == nil {
+		return ""
+	}
+	for _, field := range typedData.Types[typedData.PrimaryType] {
+		if field.Name == "witness" {
+			return field.Type
+		}
+	}
+	return ""
+}
+
+// GetPromptContext describes the decoded signature for the annotation generator
+func (e *SignatureExplainer) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	explanation, ok := GetSignatureExplanation(baggage)
+	if !ok {
+		return ""
+	}
+
+	lines := []string{"### SIGNATURE REQUEST (EIP-712, not yet signed):"}
+	domain := fmt.Sprintf("- Domain: %s", explanation.Domain.Name)
+	if explanation.Domain.VerifyingContract != "" {
+		domain += ", verifying contract " + explanation.Domain.VerifyingContract
+		if explanation.Domain.VerifyingContractName != "" {
+			domain += " (" + explanation.Domain.VerifyingContractName + ")"
+		}
+	}
+	if explanation.Domain.ChainID != 0 {
+		domain += fmt.Sprintf(", chain %d", explanation.Domain.ChainID)
+	}
+	lines = append(lines, domain, fmt.Sprintf("- Message type: %s (%s)", explanation.PrimaryType, strings.ReplaceAll(explanation.Schema, "_", " ")))
+	if explanation.Signer != "" {
+		lines = append(lines, "- Signed by: "+explanation.Signer)
+	}
+	for _, approval := range explanation.Approvals {
+		line := fmt.Sprintf("- %s for spender %s", describeApproval(approval), approval.Spender)
+		if approval.SpenderName != "" {
+			line += " (" + approval.SpenderName + ")"
+		}
+		if approval.SpenderType != "" {
+			line += ", spender is " + map[string]string{models.SpenderTypeEOA: "an EOA", models.SpenderTypeContract: "a contract"}[approval.SpenderType]
+		}
+		lines = append(lines, line)
+	}
+	if order := explanation.Order; order != nil {
+		lines = append(lines, fmt.Sprintf("- %s order by %s: gives %s, receives %s", order.Protocol, order.Maker, describeActionAmounts(order.Gives), describeActionAmounts(order.Receives)))
+		if len(order.Fees) > 0 {
+			lines = append(lines, "  Fees: "+describeActionAmounts(order.Fees))
+		}
+	}
+	for _, risk := range explanation.Risks {
+		lines = append(lines, "  RISK: "+risk)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetRagContext provides RAG context for signatures (none - they are request specific)
+func (e *SignatureExplainer) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
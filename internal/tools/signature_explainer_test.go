@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+// testExplainSignature runs the decoder and explainer on a JSON request, with token metadata standing in for
+// the enricher
+func testExplainSignature(t *testing.T, request string, metadata map[string]*TokenMetadata) *models.SignatureExplanation {
+	var signatureRequest models.SignatureRequest
+	require.NoError(t, json.Unmarshal([]byte(request), &signatureRequest))
+	baggage := map[string]interface{}{
+		"raw_data":   map[string]interface{}{"network_id": float64(signatureRequest.NetworkID)},
+		"typed_data": &signatureRequest.TypedData,
+		"signature":  signatureRequest.Signature,
+	}
+	require.NoError(t, NewTypedDataDecoder(false).Process(context.Background(), baggage))
+	baggage["token_metadata"] = metadata
+	require.NoError(t, NewSignatureExplainer(nil, nil, false).Process(context.Background(), baggage))
+
+	explanation, ok := GetSignatureExplanation(baggage)
+	require.True(t, ok)
+	return explanation
+}
+
+func TestTypedDataHashAndSigner(t *testing.T) {
+	// The example of the EIP-712 specification, passed as the JSON string wallets send
+	typedData := `{"types":{"EIP712Domain":[{"name":"name","type":"string"},{"name":"version","type":"string"},{"name":"chainId","type":"uint256"},{"name":"verifyingContract","type":"address"}],` +
+		`"Person":[{"name":"name","type":"string"},{"name":"wallet","type":"address"}],` +
+		`"Mail":[{"name":"from","type":"Person"},{"name":"to","type":"Person"},{"name":"contents","type":"string"}]},` +
+		`"primaryType":"Mail","domain":{"name":"Ether Mail","version":"1","chainId":1,"verifyingContract":"0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"},` +
+		`"message":{"from":{"name":"Cow","wallet":"0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},"to":{"name":"Bob","wallet":"0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},"contents":"Hello, Bob!"}}`
+	encoded, _ := json.Marshal(typedData)
+	signature := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d" +
+		"07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c"
+
+	explanation := testExplainSignature(t, `{"network_id":1,"signature":"`+signature+`","typed_data":`+string(encoded)+`}`, nil)
+	require.Equal(t, "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2", explanation.Hash)
+	require.Equal(t, "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826", explanation.Signer)
+	require.Equal(t, models.SignatureSchemaUnknown, explanation.Schema)
+	require.Equal(t, "Ether Mail", explanation.Domain.Name)
+	require.Equal(t, "Signing this Mail message for Ether Mail at 0xcccccccccccccccccccccccccccccccccccccccc authorizes something that is not a known permit or order; review the message before signing.", explanation.Summary)
+	require.Empty(t, explanation.Risks)
+}
+
+func TestTypedDataRejectsRecursiveStructs(t *testing.T) {
+	decode := func(request string) error {
+		var signatureRequest models.SignatureRequest
+		require.NoError(t, json.Unmarshal([]byte(request), &signatureRequest))
+		baggage := map[string]interface{}{"typed_data": &signatureRequest.TypedData}
+		return NewTypedDataDecoder(false).Process(context.Background(), baggage)
+	}
+
+	// A self-referencing struct with its value left out used to recurse until the stack overflowed
+	err := decode(`{"network_id":1,"typed_data":{"types":{"A":[{"name":"a","type":"A"}]},"primaryType":"A","domain":{},"message":{}}}`)
+	require.ErrorContains(t, err, "missing value for field")
+
+	// Values nested deeper than maxTypedDataDepth
+	message := "{}"
+	for i := 0; i <= maxTypedDataDepth; i++ {
+		message = `{"a":[` + message + `]}`
+	}
+	err = decode(`{"network_id":1,"typed_data":{"types":{"A":[{"name":"a","type":"A[]"}]},"primaryType":"A","domain":{},"message":` + message + `}}`)
+	require.ErrorContains(t, err, "levels deep")
+}
+
+func TestSignatureExplainerDecodesPermit2(t *testing.T) {
+	request := `{"network_id":137,"typed_data":{
+		"types":{"PermitSingle":[{"name":"details","type":"PermitDetails"},{"name":"spender","type":"address"},{"name":"sigDeadline","type":"uint256"}],
+			"PermitDetails":[{"name":"token","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]},
+		"primaryType":"PermitSingle",
+		"domain":{"name":"Permit2","chainId":"1","verifyingContract":"` + permit2Address + `"},
+		"message":{"details":{"token":"` + testUSDC + `","amount":"1461501637330902918203684832716283019655932542975","expiration":"1893456000","nonce":"0"},
+			"spender":"` + testRouter + `","sigDeadline":1893456000}}}`
+	explanation := testExplainSignature(t, request, map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}})
+
+	require.Equal(t, models.SignatureSchemaPermit2, explanation.Schema)
+	require.Equal(t, "Permit2", explanation.Domain.VerifyingContractName)
+	require.Len(t, explanation.Approvals, 1)
+	approval := explanation.Approvals[0]
+	require.Equal(t, models.ApprovalKindPermit2, approval.Kind)
+	require.Equal(t, testRouter, approval.Spender)
+	require.Equal(t, "signature", approval.Source)
+	require.True(t, approval.Unlimited, "the max uint160 is unlimited")
+	require.Equal(t, "USDC", approval.Allowance.Symbol)
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, expiry, *approval.Expiration)
+
+	require.Equal(t, "Signing this permit lets "+testRouter+" spend an unlimited amount of USDC from the signer through Permit2 until 2030-01-01 00:00 UTC. "+
+		"The signature can be submitted until 2030-01-01 00:00 UTC.", explanation.Summary)
+	require.Equal(t, []string{"The signature is for chain 1, not the selected network 137"}, explanation.Risks)
+}
+
+func TestSignatureExplainerFlagsOrders(t *testing.T) {
+	const nft = "0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d"
+
+	// A Seaport listing of an NFT whose consideration all goes to someone else
+	seaport := `{"network_id":1,"typed_data":{
+		"types":{"OrderComponents":[{"name":"offerer","type":"address"},{"name":"zone","type":"address"},{"name":"offer","type":"OfferItem[]"},{"name":"consideration","type":"ConsiderationItem[]"},
+				{"name":"orderType","type":"uint8"},{"name":"startTime","type":"uint256"},{"name":"endTime","type":"uint256"},{"name":"zoneHash","type":"bytes32"},{"name":"salt","type":"uint256"},{"name":"conduitKey","type":"bytes32"},{"name":"counter","type":"uint256"}],
+			"OfferItem":[{"name":"itemType","type":"uint8"},{"name":"token","type":"address"},{"name":"identifierOrCriteria","type":"uint256"},{"name":"startAmount","type":"uint256"},{"name":"endAmount","type":"uint256"}],
+			"ConsiderationItem":[{"name":"itemType","type":"uint8"},{"name":"token","type":"address"},{"name":"identifierOrCriteria","type":"uint256"},{"name":"startAmount","type":"uint256"},{"name":"endAmount","type":"uint256"},{"name":"recipient","type":"address"}]},
+		"primaryType":"OrderComponents",
+		"domain":{"name":"Seaport","version":"1.6","chainId":1,"verifyingContract":"0x0000000000000068f116a894984e2db1123eb395"},
+		"message":{"offerer":"` + testTrader + `","zone":"` + zeroAddress + `",
+			"offer":[{"itemType":2,"token":"` + nft + `","identifierOrCriteria":"42","startAmount":"1","endAmount":"1"}],
+			"consideration":[{"itemType":0,"token":"` + zeroAddress + `","identifierOrCriteria":"0","startAmount":"1","endAmount":"1","recipient":"` + testRouter + `"}],
+			"orderType":0,"startTime":"0","endTime":"1893456000","zoneHash":"0x` + strings.Repeat("0", 64) + `","salt":"1","conduitKey":"0x` + strings.Repeat("0", 64) + `","counter":"0"}}}`
+	explanation := testExplainSignature(t, seaport, map[string]*TokenMetadata{nft: {Symbol: "BAYC"}})
+
+	require.Equal(t, models.SignatureSchemaSeaport, explanation.Schema)
+	require.NotNil(t, explanation.Order)
+	require.Equal(t, testTrader, explanation.Order.Maker)
+	require.Equal(t, []models.ActionAmount{{Token: nft, Symbol: "BAYC", TokenID: "42", RawAmount: "1"}}, explanation.Order.Gives)
+	require.Empty(t, explanation.Order.Receives)
+	require.Len(t, explanation.Order.Fees, 1)
+	require.Equal(t, []string{"The Seaport order gives away BAYC #42 and pays the maker nothing in return"}, explanation.Risks)
+	require.True(t, strings.HasPrefix(explanation.Summary, "Signing this Seaport order gives away BAYC #42 from "+testTrader+" without paying anything in return"))
+
+	// A CoW Protocol sell order paying out to another address
+	receiver := "0x2222222222222222222222222222222222222222"
+	cowSwap := `{"network_id":1,"typed_data":{
+		"types":{"Order":[{"name":"sellToken","type":"address"},{"name":"buyToken","type":"address"},{"name":"receiver","type":"address"},{"name":"sellAmount","type":"uint256"},
+			{"name":"buyAmount","type":"uint256"},{"name":"validTo","type":"uint32"},{"name":"appData","type":"bytes32"},{"name":"feeAmount","type":"uint256"},{"name":"kind","type":"string"},
+			{"name":"partiallyFillable","type":"bool"},{"name":"sellTokenBalance","type":"string"},{"name":"buyTokenBalance","type":"string"}]},
+		"primaryType":"Order",
+		"domain":{"name":"Gnosis Protocol","version":"v2","chainId":1,"verifyingContract":"0x9008d19f58aabd9ed0d60971565aa8510560ab41"},
+		"message":{"sellToken":"` + testUSDC + `","buyToken":"` + cowSwapNativeToken + `","receiver":"` + receiver + `","sellAmount":"2500000000","buyAmount":"1000000000000000000",
+			"validTo":1893456000,"appData":"0x` + strings.Repeat("0", 64) + `","feeAmount":"0","kind":"sell","partiallyFillable":false,"sellTokenBalance":"erc20","buyTokenBalance":"erc20"}}}`
+	explanation = testExplainSignature(t, cowSwap, map[string]*TokenMetadata{testUSDC: {Symbol: "USDC", Decimals: 6}})
+
+	require.Equal(t, models.SignatureSchemaCowSwap, explanation.Schema)
+	require.Equal(t, receiver, explanation.Order.Receiver)
+	require.Equal(t, "Signing this CoW Protocol order sells 2500 USDC from the signer for at least 1 native until 2030-01-01 00:00 UTC. "+
+		"The proceeds go to "+receiver+".", explanation.Summary)
+	require.Equal(t, []string{"The CoW Protocol order sends its proceeds to " + receiver + ", not to the signer"}, explanation.Risks)
+}
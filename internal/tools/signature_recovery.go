@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/sha3"
+)
+
+// keccak256 hashes the concatenation of its arguments
+func keccak256(data ...[]byte) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	for _, part := range data {
+		hasher.Write(part)
+	}
+	return hasher.Sum(nil)
+}
+
+// recoverSigner recovers the address that signed a 32-byte hash from a 65-byte r || s || v signature, with v
+// either 27/28 or 0/1 - what ecrecover does
+func recoverSigner(hash, signature []byte) (string, bool) {
+	if len(hash) != 32 || len(signature) != 65 {
+		return "", false
+	}
+	v := signature[64]
+	if v >= 27 {
+		v -= 27
+	}
+	// ecrecover accepts high s values, unlike transaction signatures
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:64])
+	if !crypto.ValidateSignatureValues(v, r, s, false) {
+		return "", false
+	}
+
+	normalized := make([]byte, 65)
+	copy(normalized, signature[:64])
+	normalized[64] = v
+	key, err := crypto.SigToPub(hash, normalized)
+	if err != nil {
+		return "", false
+	}
+	return strings.ToLower(crypto.PubkeyToAddress(*key).Hex()), true
+}
+
+// ethSignedMessageHash is the hash eth_sign signs for a 32-byte message
+func ethSignedMessageHash(hash []byte) []byte {
+	return keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash)
+}
@@ -2,24 +2,28 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/txplain/txplain/internal/models"
+	"github.com/txplain/txplain/internal/sigdb"
 )
 
-// SignatureResolver resolves function and event signatures using 4byte.directory API
+// SignatureResolver resolves function and event signatures using the offline signature database and
+// remote signature APIs (4byte.directory, openchain/Sourcify)
 // Acts as a RAG tool to provide human-readable signatures when ABI resolution fails
 type SignatureResolver struct {
 	httpClient *http.Client
 	verbose    bool
-	cache      Cache // Cache for signature lookups
+	cache      Cache     // Cache for signature lookups
+	db         *sigdb.DB // Offline signature database, consulted before any HTTP lookup
+	offline    bool      // When true, never call remote signature APIs
+	backends   []SignatureBackend
 }
 
 // FourByteSignature represents a signature from 4byte.directory API
@@ -41,23 +45,40 @@ type FourByteResponse struct {
 
 // ResolvedSignature contains resolved signature information
 type ResolvedSignature struct {
-	Signature     string `json:"signature"`      // Raw signature hash
-	TextSignature string `json:"text_signature"` // Human-readable signature
-	Type          string `json:"type"`           // "function" or "event"
-	Source        string `json:"source"`         // "abi" or "4byte"
+	Signature     string                 `json:"signature"`              // Raw signature hash
+	TextSignature string                 `json:"text_signature"`         // Human-readable signature
+	Type          string                 `json:"type"`                   // "function" or "event"
+	Source        string                 `json:"source"`                 // "abi", "signature-db" or the answering backends, e.g. "4byte+openchain"
+	Confidence    float64                `json:"confidence,omitempty"`   // Likelihood TextSignature is right, from decoding the data (0-1)
+	Alternatives  []SignatureAlternative `json:"alternatives,omitempty"` // Other signatures with the same hash, best first
+}
+
+// SignatureAlternative is a colliding signature that was not selected
+type SignatureAlternative struct {
+	TextSignature string  `json:"text_signature"`
+	Confidence    float64 `json:"confidence"`
 }
 
 // NewSignatureResolver creates a new signature resolver
 func NewSignatureResolver(cache Cache, verbose bool) *SignatureResolver {
+	httpClient := &http.Client{
+		Timeout: 300 * time.Second, // 5 minutes for signature lookups
+	}
 	return &SignatureResolver{
-		httpClient: &http.Client{
-			Timeout: 300 * time.Second, // 5 minutes for signature lookups
-		},
-		verbose: verbose,
-		cache:   cache,
+		httpClient: httpClient,
+		verbose:    verbose,
+		cache:      cache,
+		db:         sigdb.Default(),
+		offline:    os.Getenv("SIGNATURE_LOOKUP_OFFLINE") == "true",
+		backends:   NewSignatureBackendsFromEnv(httpClient),
 	}
 }
 
+// SetBackends replaces the remote signature backends (e.g. to use only a local mirror)
+func (s *SignatureResolver) SetBackends(backends ...SignatureBackend) {
+	s.backends = backends
+}
+
 // Name returns the tool name
 func (s *SignatureResolver) Name() string {
 	return "signature_resolver"
@@ -65,7 +86,7 @@ func (s *SignatureResolver) Name() string {
 
 // Description returns the tool description
 func (s *SignatureResolver) Description() string {
-	return "Resolves function and event signatures using 4byte.directory when ABI resolution fails"
+	return "Resolves function and event signatures using the signature database, 4byte.directory and openchain when ABI resolution fails"
 }
 
 // Dependencies returns the tools this processor depends on
@@ -101,7 +122,7 @@ func (s *SignatureResolver) Process(ctx context.Context, baggage map[string]inte
 	baggage["resolved_signatures"] = resolvedSignatures
 
 	if s.verbose || os.Getenv("DEBUG") == "true" {
-		fmt.Printf("Resolved %d signatures from 4byte.directory\n", len(resolvedSignatures))
+		fmt.Printf("Resolved %d signatures from signature sources\n", len(resolvedSignatures))
 		for hash, sig := range resolvedSignatures {
 			fmt.Printf("  %s -> %s (%s)\n", hash, sig.TextSignature, sig.Type)
 		}
@@ -121,7 +142,7 @@ func (s *SignatureResolver) resolveEventSignatures(ctx context.Context, baggage
 	// Get resolved contracts to check what we already know
 	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
 
-	unknownSignatures := make(map[string]bool)
+	unknownSignatures := make(map[string]models.Event) // signature -> sample log for candidate ranking
 
 	for _, event := range events {
 		// Skip if we already have a human-readable name from ABI
@@ -146,7 +167,7 @@ func (s *SignatureResolver) resolveEventSignatures(ctx context.Context, baggage
 		if !hasABIResolution && len(event.Topics) > 0 {
 			signature := event.Topics[0] // First topic is event signature
 			if signature != "" && strings.HasPrefix(signature, "0x") {
-				unknownSignatures[signature] = true
+				unknownSignatures[signature] = event
 			}
 		}
 	}
@@ -156,8 +177,8 @@ func (s *SignatureResolver) resolveEventSignatures(ctx context.Context, baggage
 	}
 
 	// Resolve unknown event signatures
-	for signature := range unknownSignatures {
-		if resolvedSig, err := s.lookupEventSignature(ctx, signature); err == nil {
+	for signature, event := range unknownSignatures {
+		if resolvedSig, err := s.lookupEventSignature(ctx, signature, event.Topics, event.Data); err == nil {
 			resolved[signature] = resolvedSig
 		}
 		// Small delay to be respectful to the API
@@ -177,7 +198,7 @@ func (s *SignatureResolver) resolveFunctionSignatures(ctx context.Context, bagga
 	// Get resolved contracts to check what we already know
 	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
 
-	unknownSignatures := make(map[string]bool)
+	unknownSignatures := make(map[string]string) // selector -> sample calldata for candidate ranking
 
 	for _, call := range calls {
 		// Skip if we already have a human-readable method name from ABI
@@ -200,7 +221,7 @@ func (s *SignatureResolver) resolveFunctionSignatures(ctx context.Context, bagga
 		if callData, ok := call.Arguments["input"].(string); ok && len(callData) >= 10 {
 			selector := callData[:10] // First 4 bytes (8 hex chars + 0x)
 			if !hasABIResolution && strings.HasPrefix(selector, "0x") {
-				unknownSignatures[selector] = true
+				unknownSignatures[selector] = callData
 			}
 		}
 	}
@@ -210,8 +231,8 @@ func (s *SignatureResolver) resolveFunctionSignatures(ctx context.Context, bagga
 	}
 
 	// Resolve unknown function signatures
-	for signature := range unknownSignatures {
-		if resolvedSig, err := s.lookupFunctionSignature(ctx, signature); err == nil {
+	for signature, callData := range unknownSignatures {
+		if resolvedSig, err := s.lookupFunctionSignature(ctx, signature, callData); err == nil {
 			resolved[signature] = resolvedSig
 		}
 		// Small delay to be respectful to the API
@@ -221,182 +242,216 @@ func (s *SignatureResolver) resolveFunctionSignatures(ctx context.Context, bagga
 	return nil
 }
 
-// lookupEventSignature looks up an event signature on 4byte.directory
-func (s *SignatureResolver) lookupEventSignature(ctx context.Context, hexSignature string) (*ResolvedSignature, error) {
+// lookupEventSignature looks up an event signature in the offline database, then on the remote backends.
+// topics and data are optional; when present every candidate is decoded against them to pick the best.
+func (s *SignatureResolver) lookupEventSignature(ctx context.Context, hexSignature string, topics []string, data string) (*ResolvedSignature, error) {
+	candidates, source, err := s.findCandidates(ctx, hexSignature, "event")
+	if err != nil {
+		return nil, err
+	}
+	return newResolvedSignature(hexSignature, "event", source, sigdb.RankEventCandidates(candidates, topics, data)), nil
+}
+
+// lookupFunctionSignature looks up a function signature in the offline database, then on the remote backends.
+// callData is optional; when present every candidate is decoded against it to pick the best.
+func (s *SignatureResolver) lookupFunctionSignature(ctx context.Context, hexSignature string, callData string) (*ResolvedSignature, error) {
+	candidates, source, err := s.findCandidates(ctx, hexSignature, "function")
+	if err != nil {
+		return nil, err
+	}
+	return newResolvedSignature(hexSignature, "function", source, sigdb.RankFunctionCandidates(candidates, callData)), nil
+}
+
+// findCandidates returns every known text signature for a hash in popularity order, checking the
+// offline database, then the cache, then the remote backends
+func (s *SignatureResolver) findCandidates(ctx context.Context, hexSignature, sigType string) ([]string, string, error) {
+	// Offline database first - no network needed for common signatures
+	if s.db != nil {
+		var entries []sigdb.Entry
+		if sigType == "event" {
+			entries = s.db.LookupEvent(hexSignature)
+		} else {
+			entries = s.db.LookupFunction(hexSignature)
+		}
+		if len(entries) > 0 {
+			candidates := make([]string, len(entries))
+			for i, entry := range entries {
+				candidates[i] = entry.TextSignature
+			}
+			if s.verbose || os.Getenv("DEBUG") == "true" {
+				fmt.Printf("  ✅ Found %s in signature database: %s -> %s (%d candidates)\n", sigType, hexSignature, candidates[0], len(candidates))
+			}
+			return candidates, "signature-db", nil
+		}
+	}
+
+	keyPattern := FunctionSigKeyPattern
+	if sigType == "event" {
+		keyPattern = EventSigKeyPattern
+	}
+	cacheKey := fmt.Sprintf(keyPattern, hexSignature)
+
 	// Check cache first if available
 	if s.cache != nil {
-		cacheKey := fmt.Sprintf(EventSigKeyPattern, hexSignature)
 		if s.verbose || os.Getenv("DEBUG") == "true" {
-			fmt.Printf("  Checking cache for event signature %s with key: %s\n", hexSignature, cacheKey)
+			fmt.Printf("  Checking cache for %s signature %s with key: %s\n", sigType, hexSignature, cacheKey)
 		}
 
 		var cachedSig ResolvedSignature
 		if err := s.cache.GetJSON(ctx, cacheKey, &cachedSig); err == nil {
 			if s.verbose || os.Getenv("DEBUG") == "true" {
-				fmt.Printf("  ✅ Found cached event signature: %s -> %s\n", hexSignature, cachedSig.TextSignature)
+				fmt.Printf("  ✅ Found cached %s signature: %s -> %s\n", sigType, hexSignature, cachedSig.TextSignature)
 			}
-			return &cachedSig, nil
+			return cachedSig.candidateSignatures(), cachedSig.Source, nil
 		} else if s.verbose || os.Getenv("DEBUG") == "true" {
-			fmt.Printf("  Cache miss for event signature %s: %v\n", hexSignature, err)
+			fmt.Printf("  Cache miss for %s signature %s: %v\n", sigType, hexSignature, err)
 		}
 	}
 
-	baseURL := "https://www.4byte.directory/api/v1/event-signatures/"
-
-	params := url.Values{}
-	params.Set("hex_signature", hexSignature)
-
-	apiURL := baseURL + "?" + params.Encode()
-
-	if s.verbose || os.Getenv("DEBUG") == "true" {
-		fmt.Printf("  Looking up event signature: %s\n", hexSignature)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	if s.offline {
+		return nil, "", fmt.Errorf("signature not in offline database")
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	candidates, source, err := s.queryBackends(ctx, hexSignature, sigType)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var response FourByteResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if len(response.Results) == 0 {
-		return nil, fmt.Errorf("no signatures found")
-	}
-
-	// Return the first (most common) signature
-	result := response.Results[0]
-
-	if s.verbose || os.Getenv("DEBUG") == "true" {
-		fmt.Printf("  ✅ Found event: %s\n", result.TextSignature)
-	}
-
-	resolvedSig := &ResolvedSignature{
-		Signature:     hexSignature,
-		TextSignature: result.TextSignature,
-		Type:          "event",
-		Source:        "4byte",
+		return nil, "", err
 	}
 
-	// Cache successful result if cache is available
+	// Cache every candidate (unranked) so later lookups can re-rank against their own data
 	if s.cache != nil {
-		cacheKey := fmt.Sprintf(EventSigKeyPattern, hexSignature)
+		unranked := sigdb.RankFunctionCandidates(candidates, "")
+		if sigType == "event" {
+			unranked = sigdb.RankEventCandidates(candidates, nil, "")
+		}
+		resolvedSig := newResolvedSignature(hexSignature, sigType, source, unranked)
 		if err := s.cache.SetJSON(ctx, cacheKey, resolvedSig, &SignatureTTLDuration); err != nil {
 			if s.verbose || os.Getenv("DEBUG") == "true" {
-				fmt.Printf("  ⚠️  Failed to cache event signature %s: %v\n", hexSignature, err)
+				fmt.Printf("  ⚠️  Failed to cache %s signature %s: %v\n", sigType, hexSignature, err)
 			}
 		} else if s.verbose || os.Getenv("DEBUG") == "true" {
-			fmt.Printf("  ✅ Cached event signature: %s -> %s\n", hexSignature, result.TextSignature)
+			fmt.Printf("  ✅ Cached %s signature: %s -> %s\n", sigType, hexSignature, candidates[0])
 		}
 	}
 
-	return resolvedSig, nil
+	return candidates, source, nil
 }
 
-// lookupFunctionSignature looks up a function signature on 4byte.directory
-func (s *SignatureResolver) lookupFunctionSignature(ctx context.Context, hexSignature string) (*ResolvedSignature, error) {
-	// Check cache first if available
-	if s.cache != nil {
-		cacheKey := fmt.Sprintf(FunctionSigKeyPattern, hexSignature)
-		if s.verbose || os.Getenv("DEBUG") == "true" {
-			fmt.Printf("  Checking cache for function signature %s with key: %s\n", hexSignature, cacheKey)
-		}
-
-		var cachedSig ResolvedSignature
-		if err := s.cache.GetJSON(ctx, cacheKey, &cachedSig); err == nil {
-			if s.verbose || os.Getenv("DEBUG") == "true" {
-				fmt.Printf("  ✅ Found cached function signature: %s -> %s\n", hexSignature, cachedSig.TextSignature)
-			}
-			return &cachedSig, nil
-		} else if s.verbose || os.Getenv("DEBUG") == "true" {
-			fmt.Printf("  Cache miss for function signature %s: %v\n", hexSignature, err)
-		}
+// queryBackends looks a hash up on every remote backend in parallel and merges the answers.
+// Signatures reported by more backends rank first, then those ranked higher by any backend.
+func (s *SignatureResolver) queryBackends(ctx context.Context, hexSignature, sigType string) ([]string, string, error) {
+	if len(s.backends) == 0 {
+		return nil, "", fmt.Errorf("no signature backends configured")
 	}
 
-	baseURL := "https://www.4byte.directory/api/v1/signatures/"
-
-	params := url.Values{}
-	params.Set("hex_signature", hexSignature)
-
-	apiURL := baseURL + "?" + params.Encode()
-
 	if s.verbose || os.Getenv("DEBUG") == "true" {
-		fmt.Printf("  Looking up function signature: %s\n", hexSignature)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		fmt.Printf("  Looking up %s signature %s on %d backends\n", sigType, hexSignature, len(s.backends))
+	}
+
+	results := make([][]string, len(s.backends))
+	var wg sync.WaitGroup
+	for i, backend := range s.backends {
+		wg.Add(1)
+		go func(i int, backend SignatureBackend) {
+			defer wg.Done()
+			candidates, err := backend.LookupSignatures(ctx, hexSignature, sigType)
+			if err != nil {
+				if s.verbose || os.Getenv("DEBUG") == "true" {
+					fmt.Printf("  ⚠️  %s lookup failed for %s: %v\n", backend.Name(), hexSignature, err)
+				}
+				return
+			}
+			results[i] = candidates
+		}(i, backend)
 	}
+	wg.Wait()
 
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	type mergedCandidate struct {
+		signature string
+		votes     int
+		bestRank  int
+		order     int
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	merged := make(map[string]*mergedCandidate)
+	var sources []string
+	for i, candidates := range results {
+		if len(candidates) == 0 {
+			continue
+		}
+		sources = append(sources, s.backends[i].Name())
+		seen := make(map[string]bool)
+		for rank, signature := range candidates {
+			signature = sigdb.NormalizeSignature(signature)
+			if signature == "" || seen[signature] {
+				continue
+			}
+			seen[signature] = true
+			if existing, ok := merged[signature]; ok {
+				existing.votes++
+				if rank < existing.bestRank {
+					existing.bestRank = rank
+				}
+				continue
+			}
+			merged[signature] = &mergedCandidate{signature: signature, votes: 1, bestRank: rank, order: len(merged)}
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	if len(merged) == 0 {
+		return nil, "", fmt.Errorf("no signatures found")
 	}
 
-	var response FourByteResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	ordered := make([]*mergedCandidate, 0, len(merged))
+	for _, candidate := range merged {
+		ordered = append(ordered, candidate)
 	}
+	sort.Slice(ordered, func(a, b int) bool {
+		if ordered[a].votes != ordered[b].votes {
+			return ordered[a].votes > ordered[b].votes
+		}
+		if ordered[a].bestRank != ordered[b].bestRank {
+			return ordered[a].bestRank < ordered[b].bestRank
+		}
+		return ordered[a].order < ordered[b].order
+	})
 
-	if len(response.Results) == 0 {
-		return nil, fmt.Errorf("no signatures found")
+	candidates := make([]string, len(ordered))
+	for i, candidate := range ordered {
+		candidates[i] = candidate.signature
 	}
 
-	// Return the first (most common) signature
-	result := response.Results[0]
-
 	if s.verbose || os.Getenv("DEBUG") == "true" {
-		fmt.Printf("  ✅ Found function: %s\n", result.TextSignature)
+		fmt.Printf("  ✅ Found %s: %s (%d candidates from %s)\n", sigType, candidates[0], len(candidates), strings.Join(sources, ", "))
 	}
 
+	return candidates, strings.Join(sources, "+"), nil
+}
+
+// newResolvedSignature builds a ResolvedSignature from ranked candidates: the best one is
+// selected and the rest are kept as alternatives
+func newResolvedSignature(hexSignature, sigType, source string, ranked []sigdb.Candidate) *ResolvedSignature {
 	resolvedSig := &ResolvedSignature{
 		Signature:     hexSignature,
-		TextSignature: result.TextSignature,
-		Type:          "function",
-		Source:        "4byte",
-	}
+		TextSignature: ranked[0].TextSignature,
+		Type:          sigType,
+		Source:        source,
+		Confidence:    ranked[0].Confidence,
+	}
+	for _, candidate := range ranked[1:] {
+		resolvedSig.Alternatives = append(resolvedSig.Alternatives, SignatureAlternative{
+			TextSignature: candidate.TextSignature,
+			Confidence:    candidate.Confidence,
+		})
+	}
+	return resolvedSig
+}
 
-	// Cache successful result if cache is available
-	if s.cache != nil {
-		cacheKey := fmt.Sprintf(FunctionSigKeyPattern, hexSignature)
-		if err := s.cache.SetJSON(ctx, cacheKey, resolvedSig, &SignatureTTLDuration); err != nil {
-			if s.verbose || os.Getenv("DEBUG") == "true" {
-				fmt.Printf("  ⚠️  Failed to cache function signature %s: %v\n", hexSignature, err)
-			}
-		} else if s.verbose || os.Getenv("DEBUG") == "true" {
-			fmt.Printf("  ✅ Cached function signature: %s -> %s\n", hexSignature, result.TextSignature)
-		}
+// candidateSignatures returns the selected signature followed by its alternatives
+func (r *ResolvedSignature) candidateSignatures() []string {
+	candidates := []string{r.TextSignature}
+	for _, alternative := range r.Alternatives {
+		candidates = append(candidates, alternative.TextSignature)
 	}
-
-	return resolvedSig, nil
+	return candidates
 }
 
 // GetPromptContext provides context about resolved signatures for LLM
@@ -407,15 +462,23 @@ func (s *SignatureResolver) GetPromptContext(ctx context.Context, baggage map[st
 	}
 
 	var contextParts []string
-	contextParts = append(contextParts, "### 4byte.directory Signature Resolutions:")
+	contextParts = append(contextParts, "### Signature Resolutions (signature database / 4byte.directory / openchain):")
 
 	var eventSigs, functionSigs []string
 
 	for _, sig := range resolvedSignatures {
+		line := fmt.Sprintf("- %s: %s", sig.Signature, sig.TextSignature)
+		if len(sig.Alternatives) > 0 {
+			var alternatives []string
+			for _, alternative := range sig.Alternatives {
+				alternatives = append(alternatives, fmt.Sprintf("%s %.0f%%", alternative.TextSignature, alternative.Confidence*100))
+			}
+			line += fmt.Sprintf(" (confidence %.0f%%; alternatives: %s)", sig.Confidence*100, strings.Join(alternatives, ", "))
+		}
 		if sig.Type == "event" {
-			eventSigs = append(eventSigs, fmt.Sprintf("- %s: %s", sig.Signature, sig.TextSignature))
+			eventSigs = append(eventSigs, line)
 		} else if sig.Type == "function" {
-			functionSigs = append(functionSigs, fmt.Sprintf("- %s: %s", sig.Signature, sig.TextSignature))
+			functionSigs = append(functionSigs, line)
 		}
 	}
 
@@ -433,6 +496,7 @@ func (s *SignatureResolver) GetPromptContext(ctx context.Context, baggage map[st
 
 	contextParts = append(contextParts, "")
 	contextParts = append(contextParts, "Use these human-readable signatures when analyzing transaction calls and events that don't have ABI resolution.")
+	contextParts = append(contextParts, "Where alternatives are listed, the selected signature decoded the data most cleanly; treat low-confidence names with caution.")
 
 	return strings.Join(contextParts, "\n")
 }
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/sigdb"
+)
+
+func TestLookupFunctionSignatureDisambiguates(t *testing.T) {
+	db := sigdb.New()
+	// Both hash to 0xa9059cbb; the vanity one is made more "popular" on purpose
+	require.NoError(t, db.Add("many_msg_babbage(bytes1)", sigdb.KindFunction, 50))
+	require.NoError(t, db.Add("transfer(address,uint256)", sigdb.KindFunction, 1))
+
+	resolver := &SignatureResolver{db: db, offline: true}
+
+	callData := "0xa9059cbb" +
+		"000000000000000000000000d8da6bf26964af9d7eed9e03e53415d37aa96045" +
+		"0000000000000000000000000000000000000000000000000de0b6b3a7640000"
+
+	sig, err := resolver.lookupFunctionSignature(context.Background(), "0xa9059cbb", callData)
+	require.NoError(t, err)
+	require.Equal(t, "transfer(address,uint256)", sig.TextSignature)
+	require.Equal(t, "signature-db", sig.Source)
+	require.Len(t, sig.Alternatives, 1)
+	require.Equal(t, "many_msg_babbage(bytes1)", sig.Alternatives[0].TextSignature)
+	require.Greater(t, sig.Confidence, sig.Alternatives[0].Confidence)
+
+	// Without calldata, popularity decides and confidence is split
+	sig, err = resolver.lookupFunctionSignature(context.Background(), "0xa9059cbb", "")
+	require.NoError(t, err)
+	require.Equal(t, "many_msg_babbage(bytes1)", sig.TextSignature)
+	require.Equal(t, 0.5, sig.Confidence)
+
+	prompt := resolver.GetPromptContext(context.Background(), map[string]interface{}{
+		"resolved_signatures": map[string]*ResolvedSignature{"0xa9059cbb": sig},
+	})
+	require.True(t, strings.Contains(prompt, "alternatives: transfer(address,uint256) 50%"))
+
+	_, err = resolver.lookupFunctionSignature(context.Background(), "0x12345678", "")
+	require.Error(t, err)
+}
+
+func TestQueryBackendsMergesResults(t *testing.T) {
+	fourByte := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/event-signatures/", r.URL.Path)
+		require.Equal(t, "0xabcd", r.URL.Query().Get("hex_signature"))
+		w.Write([]byte(`{"count":2,"results":[{"text_signature":"Spam(uint256)"},{"text_signature":"Swap(address,uint256)"}]}`))
+	}))
+	defer fourByte.Close()
+
+	openchain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/signature-database/v1/lookup", r.URL.Path)
+		require.Equal(t, "0xabcd", r.URL.Query().Get("event"))
+		w.Write([]byte(`{"ok":true,"result":{"event":{"0xabcd":[
+			{"name":"Swap(address,uint256)","filtered":false},
+			{"name":"Junk(bytes)","filtered":true}
+		]},"function":{}}}`))
+	}))
+	defer openchain.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer down.Close()
+
+	resolver := &SignatureResolver{}
+	resolver.SetBackends(
+		NewFourByteBackend(fourByte.URL, http.DefaultClient),
+		NewOpenchainBackend(openchain.URL+"/", http.DefaultClient),
+		NewFourByteBackend(down.URL, http.DefaultClient),
+	)
+
+	candidates, source, err := resolver.queryBackends(context.Background(), "0xabcd", "event")
+	require.NoError(t, err)
+	// Reported by both backends, so it outranks 4byte's first answer; filtered entries are dropped
+	require.Equal(t, []string{"Swap(address,uint256)", "Spam(uint256)"}, candidates)
+	require.Equal(t, "4byte+openchain", source)
+
+	resolver.SetBackends(NewFourByteBackend(down.URL, http.DefaultClient))
+	_, _, err = resolver.queryBackends(context.Background(), "0xabcd", "event")
+	require.Error(t, err)
+}
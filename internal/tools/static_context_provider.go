@@ -6,46 +6,134 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/txplain/txplain/internal/models"
 )
 
-// StaticContextProvider loads context from CSV files for RAG and lightweight prompts
+// DefaultStaticDataDir is used when STATIC_DATA_DIR is not set
+const DefaultStaticDataDir = "data"
+
+// StaticContextProvider loads context from CSV files for RAG and lightweight prompts. One provider is shared
+// by all requests: Reload parses the data directory into a fresh snapshot and swaps it in, so readers never
+// see a half-loaded knowledge base.
 type StaticContextProvider struct {
-	tokens    map[string]models.AnnotationContextItem // address -> token info
+	dir     string
+	verbose bool
+
+	mu sync.RWMutex
+	*staticKnowledge
+	modTimes map[string]time.Time // CSV path -> modification time at the last load
+
+	writeMu sync.Mutex // Serializes edits to the CSV files
+}
+
+// staticKnowledge is one complete load of the static data directory. It is never modified after loading.
+type staticKnowledge struct {
+	tokens    map[string]models.AnnotationContextItem // chainId:address -> token info
 	protocols map[string]models.AnnotationContextItem // name -> protocol info
-	addresses map[string]models.AnnotationContextItem // address -> address info
+	addresses map[string]models.AnnotationContextItem // chainId:address -> address info (chain 0 = every chain)
+
+	tokenEntries   map[string]TokenListEntry // chainId:address -> token list entry
+	addressEntries map[string]StaticAddress  // chainId:address -> row from addresses.csv
+	protocolList   []ProtocolKnowledge       // protocols.csv rows in file order
+	tags           []TagKnowledge            // tags.csv rows in file order
+
+	deployments         map[string]ProtocolDeployment    // chainId:address -> protocol contract
+	protocolDeployments map[string][]ProtocolDeployment  // lowercase protocol name -> contracts on all chains
+	fingerprints        map[string][]ProtocolFingerprint // event topic -> protocols emitting it
+
+	screening map[string][]ScreeningEntry // address -> its entries on the sanctions, scam and denylist lists
 
 	// RAG-specific data
-	ragTokens    map[string]RagContextItem // address -> RAG token data
+	ragTokens    map[string]RagContextItem // chainId:address -> RAG token data
 	ragProtocols map[string]RagContextItem // name -> RAG protocol data
-	ragAddresses map[string]RagContextItem // address -> RAG address data
-
-	verbose bool
+	ragAddresses map[string]RagContextItem // chainId:address -> RAG address data
 }
 
-// NewStaticContextProvider creates a new static context provider
-func NewStaticContextProvider(verbose bool) *StaticContextProvider {
+// NewStaticContextProvider creates a static context provider for a data directory and loads it.
+// An empty dir falls back to STATIC_DATA_DIR and then DefaultStaticDataDir.
+func NewStaticContextProvider(dir string, verbose bool) *StaticContextProvider {
+	if dir == "" {
+		dir = os.Getenv("STATIC_DATA_DIR")
+	}
+	if dir == "" {
+		dir = DefaultStaticDataDir
+	}
+
 	provider := &StaticContextProvider{
+		dir:             dir,
+		verbose:         verbose,
+		staticKnowledge: newStaticKnowledge(),
+	}
+	provider.Reload()
+
+	return provider
+}
+
+// newStaticKnowledge creates an empty snapshot
+func newStaticKnowledge() *staticKnowledge {
+	return &staticKnowledge{
 		tokens:    make(map[string]models.AnnotationContextItem),
 		protocols: make(map[string]models.AnnotationContextItem),
 		addresses: make(map[string]models.AnnotationContextItem),
 
+		tokenEntries:   make(map[string]TokenListEntry),
+		addressEntries: make(map[string]StaticAddress),
+
+		deployments:         make(map[string]ProtocolDeployment),
+		protocolDeployments: make(map[string][]ProtocolDeployment),
+		fingerprints:        make(map[string][]ProtocolFingerprint),
+
+		screening: make(map[string][]ScreeningEntry),
+
 		// Initialize RAG storage
 		ragTokens:    make(map[string]RagContextItem),
 		ragProtocols: make(map[string]RagContextItem),
 		ragAddresses: make(map[string]RagContextItem),
-
-		verbose: verbose,
 	}
+}
 
-	// Load data from CSV files on initialization
-	provider.loadTokens()
-	provider.loadProtocols()
-	provider.loadAddresses()
+// Dir returns the data directory backing the provider
+func (scp *StaticContextProvider) Dir() string {
+	return scp.dir
+}
 
-	return provider
+// Reload re-reads every CSV in the data directory and replaces the current knowledge in one step
+func (scp *StaticContextProvider) Reload() {
+	modTimes := scp.scanModTimes()
+
+	// Load into a separate provider so concurrent readers keep using the previous snapshot
+	loaded := &StaticContextProvider{dir: scp.dir, verbose: scp.verbose, staticKnowledge: newStaticKnowledge()}
+	loaded.loadTokens()
+	loaded.loadProtocols()
+	loaded.loadAddresses()
+	loaded.loadProtocolDeployments()
+	loaded.loadProtocolFingerprints()
+	loaded.loadTags()
+	loaded.loadScreeningLists()
+
+	scp.mu.Lock()
+	scp.staticKnowledge = loaded.staticKnowledge
+	scp.modTimes = modTimes
+	scp.mu.Unlock()
+}
+
+// knowledge returns the current snapshot
+func (scp *StaticContextProvider) knowledge() *staticKnowledge {
+	scp.mu.RLock()
+	defer scp.mu.RUnlock()
+	return scp.staticKnowledge
+}
+
+// path returns the location of a file in the data directory
+func (scp *StaticContextProvider) path(name string) string {
+	return filepath.Join(scp.dir, name)
 }
 
 // Name returns the processor name
@@ -75,17 +163,22 @@ func (scp *StaticContextProvider) Process(ctx context.Context, baggage map[strin
 func (scp *StaticContextProvider) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
 	// Only provide summary statistics and availability info, not full data
 	var contextParts []string
+	knowledge := scp.knowledge()
+
+	if len(knowledge.tokens) > 0 {
+		contextParts = append(contextParts, fmt.Sprintf("### STATIC DATA AVAILABLE:\n- %d known tokens in RAG database", len(knowledge.tokens)))
+	}
 
-	if len(scp.tokens) > 0 {
-		contextParts = append(contextParts, fmt.Sprintf("### STATIC DATA AVAILABLE:\n- %d known tokens in RAG database", len(scp.tokens)))
+	if len(knowledge.protocols) > 0 {
+		contextParts = append(contextParts, fmt.Sprintf("- %d known protocols in RAG database", len(knowledge.protocols)))
 	}
 
-	if len(scp.protocols) > 0 {
-		contextParts = append(contextParts, fmt.Sprintf("- %d known protocols in RAG database", len(scp.protocols)))
+	if len(knowledge.addresses) > 0 {
+		contextParts = append(contextParts, fmt.Sprintf("- %d known addresses in RAG database", len(knowledge.addresses)))
 	}
 
-	if len(scp.addresses) > 0 {
-		contextParts = append(contextParts, fmt.Sprintf("- %d known addresses in RAG database", len(scp.addresses)))
+	if len(knowledge.deployments) > 0 {
+		contextParts = append(contextParts, fmt.Sprintf("- %d known protocol contract deployments", len(knowledge.deployments)))
 	}
 
 	if len(contextParts) > 0 {
@@ -100,19 +193,20 @@ func (scp *StaticContextProvider) GetPromptContext(ctx context.Context, baggage
 // This replaces the heavy data that was previously in GetPromptContext
 func (scp *StaticContextProvider) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
 	ragContext := NewRagContext()
+	knowledge := scp.knowledge()
 
 	// Add all token data to RAG context
-	for _, ragItem := range scp.ragTokens {
+	for _, ragItem := range knowledge.ragTokens {
 		ragContext.AddItem(ragItem)
 	}
 
 	// Add all protocol data to RAG context
-	for _, ragItem := range scp.ragProtocols {
+	for _, ragItem := range knowledge.ragProtocols {
 		ragContext.AddItem(ragItem)
 	}
 
 	// Add all address data to RAG context
-	for _, ragItem := range scp.ragAddresses {
+	for _, ragItem := range knowledge.ragAddresses {
 		ragContext.AddItem(ragItem)
 	}
 
@@ -125,70 +219,88 @@ func (scp *StaticContextProvider) generateID(itemType, key string) string {
 	return fmt.Sprintf("static_%s_%x", itemType, hash[:8])
 }
 
-// loadTokens loads token information from CSV file for both annotation and RAG contexts
+// loadTokens loads token information for both annotation and RAG contexts. Imported per-chain CSVs
+// (tokens/<chainId>.csv) are loaded first and the hand-maintained tokens.csv (mainnet unless it has a
+// chain_id column) last, so manual corrections win over imported token lists.
 func (scp *StaticContextProvider) loadTokens() {
-	filename := "data/tokens.csv"
-	if !scp.fileExists(filename) {
-		if scp.verbose {
-			fmt.Printf("StaticContextProvider: Token file %s not found, skipping\n", filename)
-		}
-		return
+	var entries []TokenListEntry
+
+	files, _ := filepath.Glob(filepath.Join(scp.path("tokens"), "*.csv"))
+	sort.Strings(files)
+	for _, filename := range files {
+		// The file name is the chain ID for rows without a chain_id column
+		chainID, _ := strconv.ParseInt(strings.TrimSuffix(filepath.Base(filename), ".csv"), 10, 64)
+		entries = append(entries, scp.readTokenFile(filename, chainID)...)
+	}
+
+	filename := scp.path("tokens.csv")
+	if scp.fileExists(filename) {
+		entries = append(entries, scp.readTokenFile(filename, 1)...)
+	} else if scp.verbose {
+		fmt.Printf("StaticContextProvider: Token file %s not found, skipping\n", filename)
+	}
+
+	for _, entry := range entries {
+		scp.addToken(entry)
 	}
 
+	if scp.verbose {
+		fmt.Printf("StaticContextProvider: Loaded %d tokens (%d per-chain files, %d RAG items)\n", len(scp.tokens), len(files), len(scp.ragTokens))
+	}
+}
+
+// readTokenFile reads one token CSV, logging and skipping it on errors
+func (scp *StaticContextProvider) readTokenFile(filename string, defaultChainID int64) []TokenListEntry {
 	file, err := os.Open(filename)
 	if err != nil {
 		if scp.verbose {
 			fmt.Printf("StaticContextProvider: Error opening token file: %v\n", err)
 		}
-		return
+		return nil
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	// Expected format: [chain_id,]address,symbol,name,decimals,icon_url,description[,source]
+	entries, err := ReadTokenCSV(file, defaultChainID, "csv_tokens")
 	if err != nil {
 		if scp.verbose {
-			fmt.Printf("StaticContextProvider: Error reading token CSV: %v\n", err)
+			fmt.Printf("StaticContextProvider: Error reading token CSV %s: %v\n", filename, err)
 		}
-		return
+		return nil
 	}
+	return entries
+}
 
-	// Expected format: address,symbol,name,decimals,icon_url,description
-	for i, record := range records {
-		if i == 0 || len(record) < 4 { // Skip header row or incomplete rows
-			continue
-		}
-
-		address := strings.ToLower(record[0])
-		symbol := record[1]
-		name := record[2]
-		decimals := record[3]
-		icon := ""
-		description := ""
-
-		if len(record) > 4 {
-			icon = record[4]
-		}
-		if len(record) > 5 {
-			description = record[5]
-		}
-
-		// Legacy annotation context (kept for backward compatibility)
-		scp.tokens[address] = models.AnnotationContextItem{
-			Type:        "token",
-			Value:       address,
-			Name:        fmt.Sprintf("%s (%s)", name, symbol),
-			Icon:        icon,
-			Description: description,
-			Metadata: map[string]interface{}{
-				"symbol":   symbol,
-				"name":     name,
-				"decimals": decimals,
-			},
-		}
+// addToken stores a token under its chain-aware key, replacing any earlier entry
+func (scp *StaticContextProvider) addToken(entry TokenListEntry) {
+	key := TokenKey(entry.ChainID, entry.Address)
+	address := entry.Address
+	symbol := entry.Symbol
+	name := entry.Name
+	decimals := strconv.Itoa(entry.Decimals)
+	icon := entry.LogoURI
+	description := entry.Description
+
+	scp.tokenEntries[key] = entry
+
+	// Legacy annotation context (kept for backward compatibility)
+	scp.tokens[key] = models.AnnotationContextItem{
+		Type:        "token",
+		Value:       address,
+		Name:        fmt.Sprintf("%s (%s)", name, symbol),
+		Icon:        icon,
+		Description: description,
+		Metadata: map[string]interface{}{
+			"symbol":   symbol,
+			"name":     name,
+			"decimals": decimals,
+			"chain_id": entry.ChainID,
+		},
+	}
 
-		// NEW: RAG context item with rich, searchable content
-		ragContent := fmt.Sprintf(`Token: %s (%s)
+	// NEW: RAG context item with rich, searchable content
+	ragContent := fmt.Sprintf(`Token: %s (%s)
+Chain ID: %d
 Contract Address: %s
 Symbol: %s
 Name: %s
@@ -197,48 +309,49 @@ Description: %s
 Icon: %s
 
 This is a well-known token with established metadata. Use this information for accurate token identification and display.`,
-			name, symbol, address, symbol, name, decimals, description, icon)
+		name, symbol, entry.ChainID, address, symbol, name, decimals, description, icon)
 
-		keywords := []string{
-			strings.ToLower(symbol),
-			strings.ToLower(name),
-			strings.ToLower(address),
-			"token", "erc20",
-		}
+	keywords := []string{
+		strings.ToLower(symbol),
+		strings.ToLower(name),
+		strings.ToLower(address),
+		"token", "erc20",
+	}
 
-		// Add description words as keywords if available
-		if description != "" {
-			descWords := strings.Fields(strings.ToLower(description))
-			keywords = append(keywords, descWords...)
-		}
+	// Add description words as keywords if available
+	if description != "" {
+		descWords := strings.Fields(strings.ToLower(description))
+		keywords = append(keywords, descWords...)
+	}
 
-		scp.ragTokens[address] = RagContextItem{
-			ID:      scp.generateID("token", address),
-			Type:    "token",
-			Title:   fmt.Sprintf("%s (%s) Token", name, symbol),
-			Content: ragContent,
-			Metadata: map[string]interface{}{
-				"address":     address,
-				"symbol":      symbol,
-				"name":        name,
-				"decimals":    decimals,
-				"icon":        icon,
-				"description": description,
-				"source":      "csv_tokens",
-			},
-			Keywords:  keywords,
-			Relevance: 0.8, // High relevance for known tokens
-		}
+	source := entry.Source
+	if source == "" {
+		source = "csv_tokens"
 	}
 
-	if scp.verbose {
-		fmt.Printf("StaticContextProvider: Loaded %d tokens from %s (%d RAG items)\n", len(scp.tokens), filename, len(scp.ragTokens))
+	scp.ragTokens[key] = RagContextItem{
+		ID:      scp.generateID("token", key),
+		Type:    "token",
+		Title:   fmt.Sprintf("%s (%s) Token", name, symbol),
+		Content: ragContent,
+		Metadata: map[string]interface{}{
+			"address":     address,
+			"chain_id":    entry.ChainID,
+			"symbol":      symbol,
+			"name":        name,
+			"decimals":    decimals,
+			"icon":        icon,
+			"description": description,
+			"source":      source,
+		},
+		Keywords:  keywords,
+		Relevance: 0.8, // High relevance for known tokens
 	}
 }
 
 // loadProtocols loads protocol information from CSV file for both annotation and RAG contexts
 func (scp *StaticContextProvider) loadProtocols() {
-	filename := "data/protocols.csv"
+	filename := scp.path("protocols.csv")
 	if !scp.fileExists(filename) {
 		if scp.verbose {
 			fmt.Printf("StaticContextProvider: Protocol file %s not found, skipping\n", filename)
@@ -282,6 +395,14 @@ func (scp *StaticContextProvider) loadProtocols() {
 			description = record[3]
 		}
 
+		scp.protocolList = append(scp.protocolList, ProtocolKnowledge{
+			Name:        name,
+			Type:        "DeFi", // Generic default - let LLM classify based on description
+			Website:     link,
+			Description: description,
+			IconURL:     icon,
+		})
+
 		// Legacy annotation context (kept for backward compatibility)
 		scp.protocols[strings.ToLower(name)] = models.AnnotationContextItem{
 			Type:        "protocol",
@@ -334,9 +455,11 @@ This is a well-known DeFi/Web3 protocol. Use this information for accurate proto
 	}
 }
 
-// loadAddresses loads well-known address information from CSV file for both annotation and RAG contexts
+// loadAddresses loads well-known address information from CSV file for both annotation and RAG contexts.
+// Rows with an empty chain_id (or files without the column) apply to every chain, which suits system
+// addresses like the null address; contract labels should always name their chain.
 func (scp *StaticContextProvider) loadAddresses() {
-	filename := "data/addresses.csv"
+	filename := scp.path("addresses.csv")
 	if !scp.fileExists(filename) {
 		if scp.verbose {
 			fmt.Printf("StaticContextProvider: Address file %s not found, skipping\n", filename)
@@ -354,6 +477,7 @@ func (scp *StaticContextProvider) loadAddresses() {
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		if scp.verbose {
@@ -361,81 +485,294 @@ func (scp *StaticContextProvider) loadAddresses() {
 		}
 		return
 	}
+	if len(records) == 0 {
+		return
+	}
 
-	// Expected format: address,name,type,description,explorer_url
-	for i, record := range records {
-		if i == 0 || len(record) < 3 { // Skip header row or incomplete rows
+	// Expected format: chain_id,address,name,type,description,explorer_url
+	header := records[0]
+	for _, record := range records[1:] {
+		address := strings.ToLower(csvField(header, record, "address"))
+		name := csvField(header, record, "name")
+		if address == "" || name == "" { // Skip incomplete rows
 			continue
 		}
 
-		address := strings.ToLower(record[0])
-		name := record[1]
-		addressType := record[2]
-		description := ""
-		link := ""
-
-		if len(record) > 3 {
-			description = record[3]
-		}
-		if len(record) > 4 {
-			link = record[4]
+		var chainID int64
+		if value := csvField(header, record, "chain_id"); value != "" {
+			if chainID, err = strconv.ParseInt(value, 10, 64); err != nil {
+				continue
+			}
 		}
 
-		// Legacy annotation context (kept for backward compatibility)
-		scp.addresses[address] = models.AnnotationContextItem{
-			Type:        "address",
-			Value:       address,
+		entry := StaticAddress{
+			ChainID:     chainID,
+			Address:     address,
 			Name:        name,
-			Link:        link,
-			Description: description,
-			Metadata: map[string]interface{}{
-				"address_type": addressType,
-			},
+			Type:        csvField(header, record, "type"),
+			Description: csvField(header, record, "description"),
+			ExplorerURL: csvField(header, record, "explorer_url"),
 		}
+		scp.addressEntries[TokenKey(chainID, address)] = entry
+		scp.addAddress(chainID, address, name, entry.Type, entry.Description, entry.ExplorerURL, "csv_addresses")
+	}
 
-		// NEW: RAG context item with rich, searchable content
-		ragContent := fmt.Sprintf(`Address: %s
+	if scp.verbose {
+		fmt.Printf("StaticContextProvider: Loaded %d addresses from %s (%d RAG items)\n", len(scp.addresses), filename, len(scp.ragAddresses))
+	}
+}
+
+// addAddress stores a well-known address under its chain-aware key (chain 0 = every chain)
+func (scp *StaticContextProvider) addAddress(chainID int64, address, name, addressType, description, link, source string) {
+	key := TokenKey(chainID, address)
+
+	// Legacy annotation context (kept for backward compatibility)
+	scp.addresses[key] = models.AnnotationContextItem{
+		Type:        "address",
+		Value:       address,
+		Name:        name,
+		Link:        link,
+		Description: description,
+		Metadata: map[string]interface{}{
+			"address_type": addressType,
+			"chain_id":     chainID,
+		},
+	}
+
+	chain := "all chains"
+	if chainID != 0 {
+		chain = strconv.FormatInt(chainID, 10)
+	}
+
+	// NEW: RAG context item with rich, searchable content
+	ragContent := fmt.Sprintf(`Address: %s
+Chain ID: %s
 Name: %s
 Type: %s
 Description: %s
 Explorer: %s
 
 This is a well-known blockchain address. Use this information for accurate address identification and labeling.`,
-			address, name, addressType, description, link)
+		address, chain, name, addressType, description, link)
 
-		keywords := []string{
-			strings.ToLower(address),
-			strings.ToLower(name),
-			strings.ToLower(addressType),
-			"address", "contract", "wallet",
+	keywords := []string{
+		strings.ToLower(address),
+		strings.ToLower(name),
+		strings.ToLower(addressType),
+		"address", "contract", "wallet",
+	}
+
+	// Add description words as keywords if available
+	if description != "" {
+		descWords := strings.Fields(strings.ToLower(description))
+		keywords = append(keywords, descWords...)
+	}
+
+	scp.ragAddresses[key] = RagContextItem{
+		ID:      scp.generateID("address", key),
+		Type:    "address",
+		Title:   fmt.Sprintf("%s Address (%s)", name, addressType),
+		Content: ragContent,
+		Metadata: map[string]interface{}{
+			"address":     address,
+			"chain_id":    chainID,
+			"name":        name,
+			"type":        addressType,
+			"description": description,
+			"explorer":    link,
+			"source":      source,
+		},
+		Keywords:  keywords,
+		Relevance: 0.7, // Good relevance for known addresses
+	}
+}
+
+// loadProtocolDeployments loads per-chain protocol contract addresses so protocols can be identified by exact
+// address match. Deployments are added to their protocol's RAG item, and contracts missing from addresses.csv
+// become well-known addresses on their chain.
+func (scp *StaticContextProvider) loadProtocolDeployments() {
+	filename := scp.path(ProtocolDeploymentsFile)
+	if !scp.fileExists(filename) {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Deployments file %s not found, skipping\n", filename)
 		}
+		return
+	}
 
-		// Add description words as keywords if available
-		if description != "" {
-			descWords := strings.Fields(strings.ToLower(description))
-			keywords = append(keywords, descWords...)
+	file, err := os.Open(filename)
+	if err != nil {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Error opening deployments file: %v\n", err)
 		}
+		return
+	}
+	defer file.Close()
 
-		scp.ragAddresses[address] = RagContextItem{
-			ID:      scp.generateID("address", address),
-			Type:    "address",
-			Title:   fmt.Sprintf("%s Address (%s)", name, addressType),
-			Content: ragContent,
-			Metadata: map[string]interface{}{
-				"address":     address,
-				"name":        name,
-				"type":        addressType,
-				"description": description,
-				"explorer":    link,
-				"source":      "csv_addresses",
-			},
-			Keywords:  keywords,
-			Relevance: 0.7, // Good relevance for known addresses
+	deployments, err := ReadProtocolDeployments(file)
+	if err != nil {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Error reading deployments CSV: %v\n", err)
 		}
+		return
 	}
 
+	scp.addProtocolDeployments(deployments)
+
 	if scp.verbose {
-		fmt.Printf("StaticContextProvider: Loaded %d addresses from %s (%d RAG items)\n", len(scp.addresses), filename, len(scp.ragAddresses))
+		fmt.Printf("StaticContextProvider: Loaded %d protocol deployments from %s\n", len(scp.deployments), filename)
+	}
+}
+
+// addProtocolDeployments indexes deployments by chain and address and attaches them to their protocols
+func (scp *StaticContextProvider) addProtocolDeployments(deployments []ProtocolDeployment) {
+	for _, deployment := range deployments {
+		key := TokenKey(deployment.ChainID, deployment.Address)
+		scp.deployments[key] = deployment
+		name := strings.ToLower(deployment.Protocol)
+		scp.protocolDeployments[name] = append(scp.protocolDeployments[name], deployment)
+
+		if _, exists := scp.addresses[key]; !exists {
+			contract := deployment.Contract
+			if contract == "" {
+				contract = deployment.Protocol
+			}
+			scp.addAddress(deployment.ChainID, deployment.Address, contract, strings.ToLower(deployment.Type),
+				fmt.Sprintf("%s contract", deployment.Protocol), "", "csv_protocol_deployments")
+		}
+	}
+
+	// Attach the per-chain address sets to the protocol RAG items
+	for name, protocolDeployments := range scp.protocolDeployments {
+		ragItem, exists := scp.ragProtocols[name]
+		if !exists {
+			continue
+		}
+
+		byChain := make(map[int64][]string)
+		for _, deployment := range protocolDeployments {
+			byChain[deployment.ChainID] = append(byChain[deployment.ChainID], deployment.Address)
+		}
+		chainIDs := make([]int64, 0, len(byChain))
+		for chainID := range byChain {
+			chainIDs = append(chainIDs, chainID)
+		}
+		sort.Slice(chainIDs, func(i, j int) bool { return chainIDs[i] < chainIDs[j] })
+
+		var lines []string
+		for _, chainID := range chainIDs {
+			lines = append(lines, fmt.Sprintf("- Chain %d: %s", chainID, strings.Join(byChain[chainID], ", ")))
+		}
+		if i := strings.Index(ragItem.Content, "\n\nDeployments:\n"); i != -1 {
+			ragItem.Content = ragItem.Content[:i] // Rebuilt from all deployments seen so far
+		}
+		ragItem.Content += "\n\nDeployments:\n" + strings.Join(lines, "\n")
+		ragItem.Metadata["deployments"] = byChain
+		ragItem.Metadata["chain_ids"] = chainIDs
+		scp.ragProtocols[name] = ragItem
+	}
+}
+
+// loadProtocolFingerprints loads the event signatures used to recognize protocols before asking the LLM
+func (scp *StaticContextProvider) loadProtocolFingerprints() {
+	filename := scp.path(ProtocolFingerprintsFile)
+	if !scp.fileExists(filename) {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Fingerprints file %s not found, skipping\n", filename)
+		}
+		return
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Error opening fingerprints file: %v\n", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	fingerprints, err := ReadProtocolFingerprints(file)
+	if err != nil {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Error reading fingerprints CSV: %v\n", err)
+		}
+		return
+	}
+	for _, fingerprint := range fingerprints {
+		scp.fingerprints[fingerprint.Topic] = append(scp.fingerprints[fingerprint.Topic], fingerprint)
+	}
+
+	if scp.verbose {
+		fmt.Printf("StaticContextProvider: Loaded %d protocol fingerprints from %s\n", len(fingerprints), filename)
+	}
+}
+
+// loadTags loads the transaction tag vocabulary used by the tag resolver
+func (scp *StaticContextProvider) loadTags() {
+	filename := scp.path("tags.csv")
+	if !scp.fileExists(filename) {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Tag file %s not found, skipping\n", filename)
+		}
+		return
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Error opening tag file: %v\n", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	tags, err := ReadTagKnowledge(file)
+	if err != nil {
+		if scp.verbose {
+			fmt.Printf("StaticContextProvider: Error reading tag CSV: %v\n", err)
+		}
+		return
+	}
+	scp.tags = tags
+
+	if scp.verbose {
+		fmt.Printf("StaticContextProvider: Loaded %d tags from %s\n", len(scp.tags), filename)
+	}
+}
+
+// loadScreeningLists loads the sanctions, scam and denylist addresses written by the screening list importer
+// or maintained by hand
+func (scp *StaticContextProvider) loadScreeningLists() {
+	count := 0
+	for _, list := range ScreeningLists {
+		filename := scp.path(filepath.Join(ScreeningDir, list+".csv"))
+		if !scp.fileExists(filename) {
+			continue
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			if scp.verbose {
+				fmt.Printf("StaticContextProvider: Error opening screening list: %v\n", err)
+			}
+			continue
+		}
+		entries, err := ReadScreeningCSV(file, list, list+".csv")
+		file.Close()
+		if err != nil {
+			if scp.verbose {
+				fmt.Printf("StaticContextProvider: Error reading screening CSV %s: %v\n", filename, err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			scp.screening[entry.Address] = append(scp.screening[entry.Address], entry)
+		}
+		count += len(entries)
+	}
+
+	if scp.verbose && count > 0 {
+		fmt.Printf("StaticContextProvider: Loaded %d screening list entries\n", count)
 	}
 }
 
@@ -447,20 +784,120 @@ func (scp *StaticContextProvider) fileExists(filename string) bool {
 	return true
 }
 
-// GetTokenInfo retrieves token information by address
-func (scp *StaticContextProvider) GetTokenInfo(address string) (models.AnnotationContextItem, bool) {
-	item, exists := scp.tokens[strings.ToLower(address)]
+// GetTokenInfo retrieves token information by chain ID and address
+func (scp *StaticContextProvider) GetTokenInfo(chainID int64, address string) (models.AnnotationContextItem, bool) {
+	item, exists := scp.knowledge().tokens[TokenKey(chainID, address)]
 	return item, exists
 }
 
+// LookupToken returns the token list entry for a token, if it is on an imported or curated list
+func (scp *StaticContextProvider) LookupToken(chainID int64, address string) (TokenListEntry, bool) {
+	entry, exists := scp.knowledge().tokenEntries[TokenKey(chainID, address)]
+	return entry, exists
+}
+
 // GetProtocolInfo retrieves protocol information by name
 func (scp *StaticContextProvider) GetProtocolInfo(name string) (models.AnnotationContextItem, bool) {
-	item, exists := scp.protocols[strings.ToLower(name)]
+	item, exists := scp.knowledge().protocols[strings.ToLower(name)]
 	return item, exists
 }
 
-// GetAddressInfo retrieves address information by address
-func (scp *StaticContextProvider) GetAddressInfo(address string) (models.AnnotationContextItem, bool) {
-	item, exists := scp.addresses[strings.ToLower(address)]
+// GetAddressInfo retrieves address information for an address on a chain, falling back to labels that
+// apply to every chain
+func (scp *StaticContextProvider) GetAddressInfo(chainID int64, address string) (models.AnnotationContextItem, bool) {
+	knowledge := scp.knowledge()
+	if item, exists := knowledge.addresses[TokenKey(chainID, address)]; exists {
+		return item, true
+	}
+	item, exists := knowledge.addresses[TokenKey(0, address)]
 	return item, exists
 }
+
+// LookupProtocolDeployment returns the protocol contract deployed at an address on a chain.
+// Chain 0 matches a deployment on any chain.
+func (scp *StaticContextProvider) LookupProtocolDeployment(chainID int64, address string) (ProtocolDeployment, bool) {
+	knowledge := scp.knowledge()
+	if chainID != 0 {
+		deployment, exists := knowledge.deployments[TokenKey(chainID, address)]
+		return deployment, exists
+	}
+	address = strings.ToLower(address)
+	for _, deployment := range knowledge.deployments {
+		if deployment.Address == address {
+			return deployment, true
+		}
+	}
+	return ProtocolDeployment{}, false
+}
+
+// GetProtocolDeployments returns a protocol's contracts on a chain (every chain if chainID is 0)
+func (scp *StaticContextProvider) GetProtocolDeployments(name string, chainID int64) []ProtocolDeployment {
+	var deployments []ProtocolDeployment
+	for _, deployment := range scp.knowledge().protocolDeployments[strings.ToLower(name)] {
+		if chainID == 0 || deployment.ChainID == chainID {
+			deployments = append(deployments, deployment)
+		}
+	}
+	return deployments
+}
+
+// LookupEventFingerprints returns the protocols known to emit an event (by topic0)
+func (scp *StaticContextProvider) LookupEventFingerprints(topic string) []ProtocolFingerprint {
+	return scp.knowledge().fingerprints[strings.ToLower(topic)]
+}
+
+// Protocols returns the curated protocol list
+func (scp *StaticContextProvider) Protocols() []ProtocolKnowledge {
+	return scp.knowledge().protocolList
+}
+
+// Tags returns the curated transaction tag list
+func (scp *StaticContextProvider) Tags() []TagKnowledge {
+	return scp.knowledge().tags
+}
+
+// ListTokens returns the known tokens on a chain (every chain if chainID is 0), ordered by chain and address
+func (scp *StaticContextProvider) ListTokens(chainID int64) []TokenListEntry {
+	var entries []TokenListEntry
+	for _, entry := range scp.knowledge().tokenEntries {
+		if chainID == 0 || entry.ChainID == chainID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChainID != entries[j].ChainID {
+			return entries[i].ChainID < entries[j].ChainID
+		}
+		return entries[i].Address < entries[j].Address
+	})
+	return entries
+}
+
+// ScreenAddress returns the sanctions, scam and denylist entries for an address on a chain
+func (scp *StaticContextProvider) ScreenAddress(chainID int64, address string) []ScreeningEntry {
+	var matches []ScreeningEntry
+	for _, entry := range scp.knowledge().screening[strings.ToLower(address)] {
+		if entry.ChainID == 0 || entry.ChainID == chainID {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// ListAddresses returns the address labels from addresses.csv that apply to a chain (all of them if chainID
+// is 0), ordered by chain and address
+func (scp *StaticContextProvider) ListAddresses(chainID int64) []StaticAddress {
+	var entries []StaticAddress
+	for _, entry := range scp.knowledge().addressEntries {
+		if chainID == 0 || entry.ChainID == 0 || entry.ChainID == chainID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChainID != entries[j].ChainID {
+			return entries[i].ChainID < entries[j].ChainID
+		}
+		return entries[i].Address < entries[j].Address
+	})
+	return entries
+}
@@ -0,0 +1,402 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticAddress is a well-known address label from addresses.csv
+type StaticAddress struct {
+	ChainID     int64  `json:"chain_id"` // 0 = applies to every chain
+	Address     string `json:"address"`
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	ExplorerURL string `json:"explorer_url,omitempty"`
+}
+
+// ErrInvalidStaticEntry is wrapped by the Save methods when an entry fails validation
+var ErrInvalidStaticEntry = errors.New("invalid entry")
+
+// Column layouts used when a CSV has to be created from scratch
+var (
+	addressCSVHeader  = []string{"chain_id", "address", "name", "type", "description", "explorer_url"}
+	protocolCSVHeader = []string{"name", "icon_url", "website_url", "description"}
+	tagCSVHeader      = []string{"tag", "category", "description", "patterns", "confidence_weight"}
+)
+
+// Watch polls the data directory and reloads the knowledge base whenever a CSV is added, changed or removed.
+// It blocks until the context is cancelled.
+func (scp *StaticContextProvider) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if scp.hasChanged() {
+				fmt.Printf("Static data in %s changed, reloading\n", scp.dir)
+				scp.Reload()
+			}
+		}
+	}
+}
+
+// hasChanged reports whether any CSV file was added, modified or removed since the last load
+func (scp *StaticContextProvider) hasChanged() bool {
+	current := scp.scanModTimes()
+
+	scp.mu.RLock()
+	defer scp.mu.RUnlock()
+
+	if len(current) != len(scp.modTimes) {
+		return true
+	}
+	for path, modTime := range current {
+		if known, exists := scp.modTimes[path]; !exists || !known.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanModTimes collects the modification times of every CSV file under the data directory
+func (scp *StaticContextProvider) scanModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	filepath.WalkDir(scp.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".csv") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+		return nil
+	})
+	return modTimes
+}
+
+// SaveToken adds or replaces a token in the hand-maintained tokens.csv, which overrides imported token lists
+func (scp *StaticContextProvider) SaveToken(entry TokenListEntry) error {
+	entry.Address = strings.ToLower(strings.TrimSpace(entry.Address))
+	if err := validateTokenEntry(entry); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStaticEntry, err)
+	}
+
+	scp.writeMu.Lock()
+	defer scp.writeMu.Unlock()
+
+	filename := scp.path("tokens.csv")
+	entries, err := scp.readTokenCSVFile(filename, 1)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range entries {
+		if TokenKey(entries[i].ChainID, entries[i].Address) == TokenKey(entry.ChainID, entry.Address) {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+
+	if err := writeFileAtomic(filename, func(file *os.File) error { return WriteTokenCSV(file, entries) }); err != nil {
+		return err
+	}
+	scp.Reload()
+	return nil
+}
+
+// DeleteToken removes a token from tokens.csv and from the imported list for its chain
+func (scp *StaticContextProvider) DeleteToken(chainID int64, address string) error {
+	scp.writeMu.Lock()
+	defer scp.writeMu.Unlock()
+
+	key := TokenKey(chainID, address)
+	found := false
+	files := map[string]int64{
+		scp.path("tokens.csv"): 1,
+		filepath.Join(scp.path("tokens"), fmt.Sprintf("%d.csv", chainID)): chainID,
+	}
+	for filename, defaultChainID := range files {
+		entries, err := scp.readTokenCSVFile(filename, defaultChainID)
+		if err != nil {
+			return err
+		}
+
+		var kept []TokenListEntry
+		for _, entry := range entries {
+			if TokenKey(entry.ChainID, entry.Address) != key {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == len(entries) {
+			continue
+		}
+
+		found = true
+		if err := writeFileAtomic(filename, func(file *os.File) error { return WriteTokenCSV(file, kept) }); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("no token %s on chain %d: %w", address, chainID, os.ErrNotExist)
+	}
+
+	scp.Reload()
+	return nil
+}
+
+// readTokenCSVFile reads a token CSV, treating a missing file as empty
+func (scp *StaticContextProvider) readTokenCSVFile(filename string, defaultChainID int64) ([]TokenListEntry, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// No default source, so rewriting the file doesn't stamp one on every row
+	return ReadTokenCSV(file, defaultChainID, "")
+}
+
+// SaveAddress adds or replaces an address label in addresses.csv
+func (scp *StaticContextProvider) SaveAddress(entry StaticAddress) error {
+	entry.Address = strings.ToLower(strings.TrimSpace(entry.Address))
+	if !isAddress(entry.Address) {
+		return fmt.Errorf("%w: invalid address %q", ErrInvalidStaticEntry, entry.Address)
+	}
+	if entry.ChainID < 0 {
+		return fmt.Errorf("%w: invalid chain ID %d", ErrInvalidStaticEntry, entry.ChainID)
+	}
+	if strings.TrimSpace(entry.Name) == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidStaticEntry)
+	}
+
+	chainID := ""
+	if entry.ChainID != 0 {
+		chainID = strconv.FormatInt(entry.ChainID, 10)
+	}
+	row := map[string]string{
+		"chain_id":     chainID,
+		"address":      entry.Address,
+		"name":         entry.Name,
+		"type":         entry.Type,
+		"description":  entry.Description,
+		"explorer_url": entry.ExplorerURL,
+	}
+	_, err := scp.editCSV("addresses.csv", addressCSVHeader, addressRowMatcher(entry.ChainID, entry.Address), row)
+	return err
+}
+
+// DeleteAddress removes an address label from addresses.csv (chain 0 = the label for every chain)
+func (scp *StaticContextProvider) DeleteAddress(chainID int64, address string) error {
+	found, err := scp.editCSV("addresses.csv", addressCSVHeader, addressRowMatcher(chainID, address), nil)
+	if err == nil && !found {
+		err = fmt.Errorf("no address %s on chain %d: %w", address, chainID, os.ErrNotExist)
+	}
+	return err
+}
+
+// addressRowMatcher matches an addresses.csv row by chain and address; an empty chain_id is chain 0
+func addressRowMatcher(chainID int64, address string) func(row map[string]string) bool {
+	address = strings.ToLower(address)
+	return func(row map[string]string) bool {
+		rowChainID, _ := strconv.ParseInt(row["chain_id"], 10, 64)
+		return rowChainID == chainID && strings.ToLower(row["address"]) == address
+	}
+}
+
+// SaveProtocol adds or replaces a protocol in protocols.csv
+func (scp *StaticContextProvider) SaveProtocol(protocol ProtocolKnowledge) error {
+	protocol.Name = strings.TrimSpace(protocol.Name)
+	if protocol.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidStaticEntry)
+	}
+
+	row := map[string]string{
+		"name":        protocol.Name,
+		"icon_url":    protocol.IconURL,
+		"website_url": protocol.Website,
+		"description": protocol.Description,
+	}
+	_, err := scp.editCSV("protocols.csv", protocolCSVHeader, columnMatcher("name", protocol.Name), row)
+	return err
+}
+
+// DeleteProtocol removes a protocol from protocols.csv. Its deployments are left in place.
+func (scp *StaticContextProvider) DeleteProtocol(name string) error {
+	found, err := scp.editCSV("protocols.csv", protocolCSVHeader, columnMatcher("name", name), nil)
+	if err == nil && !found {
+		err = fmt.Errorf("no protocol %q: %w", name, os.ErrNotExist)
+	}
+	return err
+}
+
+// SaveTag adds or replaces a tag in tags.csv
+func (scp *StaticContextProvider) SaveTag(tag TagKnowledge) error {
+	tag.Tag = strings.TrimSpace(tag.Tag)
+	if tag.Tag == "" {
+		return fmt.Errorf("%w: missing tag", ErrInvalidStaticEntry)
+	}
+	if tag.Category == "" || tag.Description == "" {
+		return fmt.Errorf("%w: category and description are required", ErrInvalidStaticEntry)
+	}
+	if tag.ConfidenceWeight < 0 || tag.ConfidenceWeight > 1 {
+		return fmt.Errorf("%w: confidence_weight must be between 0 and 1", ErrInvalidStaticEntry)
+	}
+
+	weight := "" // Empty means the default weight
+	if tag.ConfidenceWeight > 0 {
+		weight = strconv.FormatFloat(tag.ConfidenceWeight, 'f', -1, 64)
+	}
+	row := map[string]string{
+		"tag":               tag.Tag,
+		"category":          tag.Category,
+		"description":       tag.Description,
+		"patterns":          tag.Patterns,
+		"confidence_weight": weight,
+	}
+	_, err := scp.editCSV("tags.csv", tagCSVHeader, columnMatcher("tag", tag.Tag), row)
+	return err
+}
+
+// DeleteTag removes a tag from tags.csv
+func (scp *StaticContextProvider) DeleteTag(tag string) error {
+	found, err := scp.editCSV("tags.csv", tagCSVHeader, columnMatcher("tag", tag), nil)
+	if err == nil && !found {
+		err = fmt.Errorf("no tag %q: %w", tag, os.ErrNotExist)
+	}
+	return err
+}
+
+// columnMatcher matches rows whose column equals value, ignoring case
+func columnMatcher(column, value string) func(row map[string]string) bool {
+	return func(row map[string]string) bool {
+		return strings.EqualFold(row[column], strings.TrimSpace(value))
+	}
+}
+
+// editCSV replaces the rows of a data directory CSV that match with row, or deletes them if row is nil,
+// then reloads the knowledge base. A new row is appended when nothing matches. Existing columns and their
+// order are kept; defaultHeader is used for files that don't exist yet. Reports whether a row matched.
+func (scp *StaticContextProvider) editCSV(name string, defaultHeader []string, match func(row map[string]string) bool, row map[string]string) (bool, error) {
+	scp.writeMu.Lock()
+	defer scp.writeMu.Unlock()
+
+	filename := scp.path(name)
+	header := defaultHeader
+	var records [][]string
+	if file, err := os.Open(filename); err == nil {
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		records, err = reader.ReadAll()
+		file.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if len(records) > 0 {
+			header = records[0]
+			records = records[1:]
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	// Columns the row sets but the file doesn't have yet are appended
+	for _, column := range defaultHeader {
+		if _, ok := row[column]; ok && findColumnIndex(header, column) == -1 {
+			header = append(header, column)
+		}
+	}
+
+	toRecord := func(values map[string]string) []string {
+		record := make([]string, len(header))
+		for i, column := range header {
+			record[i] = values[strings.ToLower(strings.TrimSpace(column))]
+		}
+		return record
+	}
+
+	found := false
+	var updated [][]string
+	for _, record := range records {
+		values := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				values[strings.ToLower(strings.TrimSpace(column))] = strings.TrimSpace(record[i])
+			}
+		}
+		if !match(values) {
+			updated = append(updated, toRecord(values))
+			continue
+		}
+		if !found && row != nil {
+			updated = append(updated, toRecord(row))
+		}
+		found = true
+	}
+	if !found && row == nil {
+		return false, nil
+	}
+	if !found {
+		updated = append(updated, toRecord(row))
+	}
+
+	err := writeFileAtomic(filename, func(file *os.File) error {
+		writer := csv.NewWriter(file)
+		writer.Write(header)
+		writer.WriteAll(updated)
+		return writer.Error()
+	})
+	if err != nil {
+		return found, err
+	}
+
+	scp.Reload()
+	return found, nil
+}
+
+// writeFileAtomic writes a file through a temp file and rename so the watcher never loads a partial file
+func writeFileAtomic(filename string, write func(file *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestStaticKnowledgeEditsAndReloads(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "addresses.csv"), "address,name,type,description,explorer_url\n"+
+		"0x000000000000000000000000000000000000dead,Dead Address,system,Burn address,\n")
+	writeTestFile(t, filepath.Join(dir, "tags.csv"), "tag,category,description,patterns,confidence_weight\n"+
+		"swap,DeFi,Token swaps,\"Swap event,swap\",0.95\n")
+	writeTestFile(t, filepath.Join(dir, "tokens", "137.csv"), "chain_id,address,symbol,name,decimals\n"+
+		"137,"+testWETH+",WETH,Wrapped Ether,18\n")
+
+	store := NewStaticContextProvider(dir, false)
+	require.Len(t, store.ListAddresses(0), 1)
+	require.Equal(t, int64(0), store.ListAddresses(0)[0].ChainID, "file without chain_id column labels every chain")
+	require.Len(t, store.Tags(), 1)
+	require.Len(t, store.ListTokens(137), 1)
+	require.False(t, store.hasChanged())
+
+	// Chain-specific label is written next to the existing layout and picked up without a restart
+	require.NoError(t, store.SaveAddress(StaticAddress{ChainID: 10, Address: testV2Router, Name: "Custom Router", Type: "dex"}))
+	item, ok := store.GetAddressInfo(10, testV2Router)
+	require.True(t, ok)
+	require.Equal(t, "Custom Router", item.Name)
+	_, ok = store.GetAddressInfo(1, testV2Router)
+	require.False(t, ok)
+
+	content, err := os.ReadFile(filepath.Join(dir, "addresses.csv"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(content), "address,name,type,description,explorer_url,chain_id\n"))
+	require.Contains(t, string(content), testV2Router+",Custom Router,dex,,,10\n")
+
+	require.NoError(t, store.SaveAddress(StaticAddress{ChainID: 10, Address: testV2Router, Name: "Renamed Router"}))
+	require.Len(t, store.ListAddresses(10), 2, "saving an existing label replaces it")
+	require.NoError(t, store.DeleteAddress(10, testV2Router))
+	require.True(t, errors.Is(store.DeleteAddress(10, testV2Router), os.ErrNotExist))
+
+	err = store.SaveTag(TagKnowledge{Tag: "bridge"})
+	require.ErrorIs(t, err, ErrInvalidStaticEntry)
+	require.NoError(t, store.SaveTag(TagKnowledge{Tag: "bridge", Category: "Infrastructure", Description: "Cross-chain transfer"}))
+	require.Len(t, store.Tags(), 2)
+	require.Equal(t, 0.8, store.Tags()[1].ConfidenceWeight, "empty weight falls back to the default")
+
+	// Manual token edits go to tokens.csv; deleting also removes the imported row
+	require.NoError(t, store.SaveToken(TokenListEntry{ChainID: 137, Address: testWETH, Symbol: "WETH", Name: "Bridged WETH", Decimals: 18}))
+	entry, ok := store.LookupToken(137, testWETH)
+	require.True(t, ok)
+	require.Equal(t, "Bridged WETH", entry.Name, "tokens.csv overrides imported lists")
+	require.NoError(t, store.DeleteToken(137, testWETH))
+	_, ok = store.LookupToken(137, testWETH)
+	require.False(t, ok)
+
+	// External edits are detected by the watcher
+	require.False(t, store.hasChanged())
+	writeTestFile(t, filepath.Join(dir, "protocols.csv"), "name,icon_url,website_url,description\nUniswap,,https://uniswap.org,DEX\n")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "protocols.csv"), later, later))
+	require.True(t, store.hasChanged())
+	store.Reload()
+	require.Len(t, store.Protocols(), 1)
+	require.False(t, store.hasChanged())
+}
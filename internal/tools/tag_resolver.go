@@ -5,9 +5,11 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tmc/langchaingo/llms"
 	"github.com/txplain/txplain/internal/models"
@@ -18,7 +20,9 @@ type TagResolver struct {
 	llm                 llms.Model
 	verbose             bool
 	confidenceThreshold float64        // Minimum confidence to include a tag
-	tagKnowledge        []TagKnowledge // RAG data from tags.csv
+	tagKnowledge        []TagKnowledge // RAG data from tags.csv, loaded on first use without a static provider
+	staticProvider      *StaticContextProvider
+	loadOnce            sync.Once
 }
 
 // TagKnowledge represents tag information from CSV for RAG
@@ -48,15 +52,30 @@ func NewTagResolver(llm llms.Model, verbose bool, confidenceThreshold float64) *
 		tagKnowledge:        []TagKnowledge{},
 	}
 
-	// Load tag knowledge from CSV for RAG
-	if err := resolver.loadTagKnowledge(); err != nil {
-		// Log error but don't fail - AI can still work without CSV data
-		if resolver.verbose {
-			fmt.Printf("Warning: Failed to load tag knowledge from CSV: %v\n", err)
-		}
+	return resolver
+}
+
+// SetStaticContextProvider makes the resolver use the shared (hot-reloaded) tag list instead of reading tags.csv
+func (t *TagResolver) SetStaticContextProvider(provider *StaticContextProvider) {
+	t.staticProvider = provider
+}
+
+// knowledge returns the tag vocabulary from the static provider, or from tags.csv if none is set
+func (t *TagResolver) knowledge() []TagKnowledge {
+	if t.staticProvider != nil {
+		return t.staticProvider.Tags()
 	}
 
-	return resolver
+	t.loadOnce.Do(func() {
+		// Load tag knowledge from CSV for RAG
+		if err := t.loadTagKnowledge(); err != nil {
+			// Log error but don't fail - AI can still work without CSV data
+			if t.verbose {
+				fmt.Printf("Warning: Failed to load tag knowledge from CSV: %v\n", err)
+			}
+		}
+	})
+	return t.tagKnowledge
 }
 
 // Name returns the tool name
@@ -79,7 +98,7 @@ func (t *TagResolver) Process(ctx context.Context, baggage map[string]interface{
 	if t.verbose {
 		fmt.Println("\n" + strings.Repeat("🏷️", 60))
 		fmt.Printf("🔍 TAG RESOLVER: Starting AI-powered tag detection (threshold: %.1f%%)\n", t.confidenceThreshold*100)
-		fmt.Printf("📚 Knowledge base: %d tags loaded\n", len(t.tagKnowledge))
+		fmt.Printf("📚 Knowledge base: %d tags loaded\n", len(t.knowledge()))
 		fmt.Println(strings.Repeat("🏷️", 60))
 	}
 
@@ -159,7 +178,8 @@ func (t *TagResolver) Process(ctx context.Context, baggage map[string]interface{
 	return nil
 }
 
-// loadTagKnowledge loads tag data from tags.csv for RAG context
+// loadTagKnowledge loads tag data from tags.csv for RAG context. Used when no shared static context
+// provider is set.
 func (t *TagResolver) loadTagKnowledge() error {
 	// Try multiple possible CSV locations
 	csvPaths := []string{
@@ -189,14 +209,28 @@ func (t *TagResolver) loadTagKnowledge() error {
 		fmt.Printf("Loading tag knowledge from: %s\n", csvPath)
 	}
 
-	reader := csv.NewReader(csvFile)
+	t.tagKnowledge, err = ReadTagKnowledge(csvFile)
+	if err != nil {
+		return err
+	}
+
+	if t.verbose {
+		fmt.Printf("Loaded %d tags from CSV for RAG context\n", len(t.tagKnowledge))
+	}
+
+	return nil
+}
+
+// ReadTagKnowledge parses a tags CSV (tag, category, description, patterns[, confidence_weight])
+func ReadTagKnowledge(r io.Reader) ([]TagKnowledge, error) {
+	reader := csv.NewReader(r)
 	records, err := reader.ReadAll()
 	if err != nil {
-		return fmt.Errorf("failed to read CSV: %w", err)
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
 	}
 
 	if len(records) == 0 {
-		return fmt.Errorf("empty CSV file")
+		return nil, fmt.Errorf("empty CSV file")
 	}
 
 	// Parse CSV (expecting header row)
@@ -208,10 +242,11 @@ func (t *TagResolver) loadTagKnowledge() error {
 	confidenceIndex := findColumnIndex(header, "confidence_weight")
 
 	if tagIndex == -1 || categoryIndex == -1 || descriptionIndex == -1 || patternsIndex == -1 {
-		return fmt.Errorf("CSV missing required columns (tag, category, description, patterns)")
+		return nil, fmt.Errorf("CSV missing required columns (tag, category, description, patterns)")
 	}
 
 	// Parse data rows
+	var tags []TagKnowledge
 	for _, record := range records[1:] {
 		if len(record) <= tagIndex || len(record) <= categoryIndex || len(record) <= descriptionIndex || len(record) <= patternsIndex {
 			continue // Skip incomplete rows
@@ -233,15 +268,11 @@ func (t *TagResolver) loadTagKnowledge() error {
 		}
 
 		if knowledge.Tag != "" {
-			t.tagKnowledge = append(t.tagKnowledge, knowledge)
+			tags = append(tags, knowledge)
 		}
 	}
 
-	if t.verbose {
-		fmt.Printf("Loaded %d tags from CSV for RAG context\n", len(t.tagKnowledge))
-	}
-
-	return nil
+	return tags, nil
 }
 
 // identifyTagsWithAI uses LLM to identify tags from context
@@ -300,7 +331,7 @@ func (t *TagResolver) buildTagAnalysisPrompt(contextData string) string {
 
 	// Group by category for better organization
 	categories := make(map[string][]TagKnowledge)
-	for _, knowledge := range t.tagKnowledge {
+	for _, knowledge := range t.knowledge() {
 		categories[knowledge.Category] = append(categories[knowledge.Category], knowledge)
 	}
 
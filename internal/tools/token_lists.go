@@ -0,0 +1,335 @@
+package tools
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTokensDir holds the per-chain token CSVs (<dir>/<chainId>.csv) written by the token list importer
+const DefaultTokensDir = "data/tokens"
+
+// tokenCSVHeader is the column layout of per-chain token CSVs. Readers locate columns by header name,
+// so hand-written files may omit or reorder columns (chain_id falls back to the file's chain).
+var tokenCSVHeader = []string{"chain_id", "address", "symbol", "name", "decimals", "icon_url", "description", "source"}
+
+// TokenListEntry is one token from a token list or token CSV
+type TokenListEntry struct {
+	ChainID     int64  `json:"chainId"`
+	Address     string `json:"address"`
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Decimals    int    `json:"decimals"`
+	LogoURI     string `json:"logoURI,omitempty"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source,omitempty"` // Token list name or CSV file the entry came from
+}
+
+// TokenKey builds the chain-aware key static token data is stored under
+func TokenKey(chainID int64, address string) string {
+	return fmt.Sprintf("%d:%s", chainID, strings.ToLower(address))
+}
+
+// ParseTokenList parses a Uniswap Token List (https://tokenlists.org) document. Entries that fail basic
+// validation (address format, decimals range, empty symbol) are skipped rather than failing the whole list.
+func ParseTokenList(data []byte) ([]TokenListEntry, error) {
+	var list struct {
+		Name   string `json:"name"`
+		Tokens []struct {
+			ChainID  int64  `json:"chainId"`
+			Address  string `json:"address"`
+			Name     string `json:"name"`
+			Symbol   string `json:"symbol"`
+			Decimals int    `json:"decimals"`
+			LogoURI  string `json:"logoURI"`
+		} `json:"tokens"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid token list: %w", err)
+	}
+	if list.Tokens == nil {
+		return nil, fmt.Errorf("invalid token list: no tokens array")
+	}
+
+	var entries []TokenListEntry
+	for _, token := range list.Tokens {
+		entry := TokenListEntry{
+			ChainID:  token.ChainID,
+			Address:  strings.ToLower(token.Address),
+			Symbol:   strings.TrimSpace(token.Symbol),
+			Name:     strings.TrimSpace(token.Name),
+			Decimals: token.Decimals,
+			LogoURI:  token.LogoURI,
+			Source:   list.Name,
+		}
+		if validateTokenEntry(entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// ReadTokenCSV reads a token CSV with a header row. Rows without a chain_id column value get defaultChainID;
+// icon_url may also be called logo_uri. Invalid rows are skipped.
+func ReadTokenCSV(r io.Reader, defaultChainID int64, source string) ([]TokenListEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, fmt.Errorf("token CSV has no address column")
+	}
+	field := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var entries []TokenListEntry
+	for _, record := range records[1:] {
+		entry := TokenListEntry{
+			ChainID:     defaultChainID,
+			Address:     strings.ToLower(field(record, "address")),
+			Symbol:      field(record, "symbol"),
+			Name:        field(record, "name"),
+			LogoURI:     field(record, "icon_url", "logo_uri", "logouri"),
+			Description: field(record, "description"),
+			Source:      field(record, "source"),
+		}
+		if chainID := field(record, "chain_id", "chainid"); chainID != "" {
+			if entry.ChainID, err = strconv.ParseInt(chainID, 10, 64); err != nil {
+				continue
+			}
+		}
+		if entry.Decimals, err = strconv.Atoi(field(record, "decimals")); err != nil {
+			continue
+		}
+		if entry.Source == "" {
+			entry.Source = source
+		}
+		if validateTokenEntry(entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// WriteTokenCSV writes entries in the per-chain CSV layout, sorted by address for stable diffs
+func WriteTokenCSV(w io.Writer, entries []TokenListEntry) error {
+	sorted := append([]TokenListEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ChainID != sorted[j].ChainID {
+			return sorted[i].ChainID < sorted[j].ChainID
+		}
+		return sorted[i].Address < sorted[j].Address
+	})
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tokenCSVHeader); err != nil {
+		return err
+	}
+	for _, entry := range sorted {
+		if err := writer.Write([]string{
+			strconv.FormatInt(entry.ChainID, 10),
+			entry.Address,
+			entry.Symbol,
+			entry.Name,
+			strconv.Itoa(entry.Decimals),
+			entry.LogoURI,
+			entry.Description,
+			entry.Source,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// MergeTokenEntries merges incoming entries into existing ones by chain ID and address. Incoming non-empty
+// fields win, so a re-import refreshes logos and names without erasing hand-written descriptions.
+// Returns the merged entries and how many were new.
+func MergeTokenEntries(existing, incoming []TokenListEntry) ([]TokenListEntry, int) {
+	merged := make(map[string]TokenListEntry, len(existing)+len(incoming))
+	var order []string
+	for _, entry := range existing {
+		key := TokenKey(entry.ChainID, entry.Address)
+		if _, ok := merged[key]; !ok {
+			order = append(order, key)
+		}
+		merged[key] = entry
+	}
+
+	added := 0
+	for _, entry := range incoming {
+		key := TokenKey(entry.ChainID, entry.Address)
+		current, ok := merged[key]
+		if !ok {
+			merged[key] = entry
+			order = append(order, key)
+			added++
+			continue
+		}
+		if entry.Symbol != "" {
+			current.Symbol = entry.Symbol
+		}
+		if entry.Name != "" {
+			current.Name = entry.Name
+		}
+		if entry.LogoURI != "" {
+			current.LogoURI = entry.LogoURI
+		}
+		if entry.Description != "" {
+			current.Description = entry.Description
+		}
+		if entry.Source != "" {
+			current.Source = entry.Source
+		}
+		current.Decimals = entry.Decimals
+		merged[key] = current
+	}
+
+	result := make([]TokenListEntry, 0, len(order))
+	for _, key := range order {
+		result = append(result, merged[key])
+	}
+	return result, added
+}
+
+// ImportTokenLists reads token lists (.json) and token CSVs from files or http(s) URLs and merges them into
+// the per-chain CSVs in dir. defaultChainID applies to CSV rows without a chain_id. Returns the number of
+// new tokens per chain.
+func ImportTokenLists(paths []string, dir string, defaultChainID int64) (map[int64]int, error) {
+	byChain := make(map[int64][]TokenListEntry)
+	for _, path := range paths {
+		entries, err := readTokenSource(path, defaultChainID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			byChain[entry.ChainID] = append(byChain[entry.ChainID], entry)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	added := make(map[int64]int)
+	for chainID, incoming := range byChain {
+		filename := filepath.Join(dir, fmt.Sprintf("%d.csv", chainID))
+
+		var existing []TokenListEntry
+		if file, err := os.Open(filename); err == nil {
+			existing, err = ReadTokenCSV(file, chainID, "")
+			file.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+			}
+		}
+
+		merged, count := MergeTokenEntries(existing, incoming)
+		added[chainID] = count
+
+		// Write to a temp file first so a failed import never leaves a truncated CSV behind
+		tmp := filename + ".tmp"
+		file, err := os.Create(tmp)
+		if err != nil {
+			return nil, err
+		}
+		if err := WriteTokenCSV(file, merged); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp, filename); err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+// readTokenSource loads one token list or CSV from disk or over HTTP
+func readTokenSource(path string, defaultChainID int64) ([]TokenListEntry, error) {
+	data, err := readImportSource(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasSuffix(strings.ToLower(path), ".json") || strings.HasPrefix(trimmed, "{") {
+		return ParseTokenList(data)
+	}
+	return ReadTokenCSV(strings.NewReader(string(data)), defaultChainID, filepath.Base(path))
+}
+
+// readImportSource reads a file to import from disk or, for http(s) URLs, over HTTP
+func readImportSource(path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return os.ReadFile(path)
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+}
+
+// validateTokenEntry rejects entries that cannot be keyed or displayed
+func validateTokenEntry(entry TokenListEntry) error {
+	if entry.ChainID <= 0 {
+		return fmt.Errorf("missing chain ID")
+	}
+	if len(entry.Address) != 42 || !strings.HasPrefix(entry.Address, "0x") {
+		return fmt.Errorf("invalid address %q", entry.Address)
+	}
+	if !isHexString(entry.Address[2:]) {
+		return fmt.Errorf("invalid address %q", entry.Address)
+	}
+	if entry.Symbol == "" {
+		return fmt.Errorf("missing symbol")
+	}
+	if entry.Decimals < 0 || entry.Decimals > 255 {
+		return fmt.Errorf("invalid decimals %d", entry.Decimals)
+	}
+	return nil
+}
+
+// isHexString reports whether s only contains hex digits
+func isHexString(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTokenListSkipsInvalidEntries(t *testing.T) {
+	entries, err := ParseTokenList([]byte(`{"name":"Vetted","tokens":[
+		{"chainId":1,"address":"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2","name":"Wrapped Ether","symbol":"WETH","decimals":18,"logoURI":"https://example.com/weth.png"},
+		{"chainId":1,"address":"0x1234","name":"Short","symbol":"BAD","decimals":18},
+		{"chainId":0,"address":"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2","name":"No chain","symbol":"X","decimals":18}
+	]}`))
+	require.NoError(t, err)
+	require.Equal(t, []TokenListEntry{{
+		ChainID: 1, Address: testWETH, Symbol: "WETH", Name: "Wrapped Ether", Decimals: 18,
+		LogoURI: "https://example.com/weth.png", Source: "Vetted",
+	}}, entries)
+
+	_, err = ParseTokenList([]byte(`[]`))
+	require.Error(t, err)
+}
+
+func TestReadTokenCSVLegacyLayout(t *testing.T) {
+	// Legacy data/tokens.csv has no chain_id column
+	entries, err := ReadTokenCSV(strings.NewReader("address,symbol,name,decimals,icon_url,description\n"+
+		testWETH+",WETH,Wrapped Ether,18,,An ERC-20 compatible version of Ether\n"), 1, "csv_tokens")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(1), entries[0].ChainID)
+	require.Equal(t, "csv_tokens", entries[0].Source)
+	require.Equal(t, "An ERC-20 compatible version of Ether", entries[0].Description)
+}
+
+func TestMergeTokenEntriesKeepsExistingFields(t *testing.T) {
+	existing := []TokenListEntry{{ChainID: 1, Address: testWETH, Symbol: "WETH", Name: "Wrapped Ether", Decimals: 18, Description: "Hand-written"}}
+	incoming := []TokenListEntry{
+		{ChainID: 1, Address: testWETH, Symbol: "WETH", Name: "Wrapped Ether", Decimals: 18, LogoURI: "https://example.com/weth.png", Source: "Vetted"},
+		{ChainID: 137, Address: testWETH, Symbol: "WETH", Name: "Wrapped Ether (PoS)", Decimals: 18, Source: "Vetted"},
+	}
+
+	merged, added := MergeTokenEntries(existing, incoming)
+	require.Equal(t, 1, added, "same address on another chain is a different token")
+	require.Len(t, merged, 2)
+	require.Equal(t, "Hand-written", merged[0].Description)
+	require.Equal(t, "https://example.com/weth.png", merged[0].LogoURI)
+}
+
+func TestTokenMetadataMergesTokenList(t *testing.T) {
+	provider := &StaticContextProvider{staticKnowledge: newStaticKnowledge()}
+	provider.addToken(TokenListEntry{ChainID: 1, Address: testWETH, Symbol: "WETH", Name: "Wrapped Ether", Decimals: 18, LogoURI: "https://example.com/weth.png"})
+
+	enricher := NewTokenMetadataEnricher(nil, false, nil)
+	enricher.SetStaticContextProvider(provider)
+
+	baggage := map[string]interface{}{
+		"raw_data":           map[string]interface{}{"network_id": float64(1)},
+		"contract_addresses": []string{testWETH, testCollection},
+	}
+	require.NoError(t, enricher.Process(context.Background(), baggage))
+
+	metadata := baggage["token_metadata"].(map[string]*TokenMetadata)
+	require.Len(t, metadata, 1, "unlisted contract without RPC data is not a token")
+	require.True(t, metadata[testWETH].Listed)
+	require.Equal(t, "WETH", metadata[testWETH].Symbol)
+	require.Equal(t, 18, metadata[testWETH].Decimals)
+	require.Equal(t, "ERC20", metadata[testWETH].Type)
+
+	// Listing is per chain
+	baggage["raw_data"] = map[string]interface{}{"network_id": float64(137)}
+	delete(baggage, "token_metadata")
+	require.NoError(t, enricher.Process(context.Background(), baggage))
+	require.Nil(t, baggage["token_metadata"])
+}
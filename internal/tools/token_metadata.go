@@ -12,9 +12,10 @@ import (
 
 // TokenMetadataEnricher enriches ERC20 token addresses with metadata
 type TokenMetadataEnricher struct {
-	rpcClient *rpc.Client
-	verbose   bool
-	cache     Cache // Cache for metadata lookups
+	rpcClient      *rpc.Client
+	verbose        bool
+	cache          Cache                  // Cache for metadata lookups
+	staticProvider *StaticContextProvider // Curated and imported token lists (optional)
 }
 
 // TokenMetadata represents metadata for a token
@@ -28,6 +29,7 @@ type TokenMetadata struct {
 	Description string `json:"description,omitempty"`
 	Website     string `json:"website,omitempty"`
 	Category    string `json:"category,omitempty"`
+	Listed      bool   `json:"listed,omitempty"` // On a curated or imported token list for this chain
 }
 
 // NewTokenMetadataEnricher creates a new token metadata enricher
@@ -39,6 +41,11 @@ func NewTokenMetadataEnricher(cache Cache, verbose bool, rpcClient *rpc.Client)
 	}
 }
 
+// SetStaticContextProvider enables merging RPC metadata with the token lists loaded by the provider
+func (t *TokenMetadataEnricher) SetStaticContextProvider(provider *StaticContextProvider) {
+	t.staticProvider = provider
+}
+
 // Name returns the processor name
 func (t *TokenMetadataEnricher) Name() string {
 	return "token_metadata_enricher"
@@ -79,6 +86,13 @@ func (t *TokenMetadataEnricher) Process(ctx context.Context, baggage map[string]
 	// Get resolved contracts data from ABI resolver for additional context
 	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
 
+	networkID := int64(1) // Default to Ethereum mainnet
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if nid, ok := rawData["network_id"].(float64); ok {
+			networkID = int64(nid)
+		}
+	}
+
 	// Create comprehensive contract information map
 	contractMetadata := make(map[string]*TokenMetadata)
 	allContractInfo := make(map[string]map[string]interface{})
@@ -148,13 +162,6 @@ func (t *TokenMetadataEnricher) Process(ctx context.Context, baggage map[string]
 
 		// Check cache first if available
 		if t.cache != nil {
-			networkID := int64(1) // Default to Ethereum mainnet
-			if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
-				if nid, ok := rawData["network_id"].(float64); ok {
-					networkID = int64(nid)
-				}
-			}
-
 			cacheKey := fmt.Sprintf(TokenMetadataKeyPattern, networkID, strings.ToLower(address))
 			if err := t.cache.GetJSON(ctx, cacheKey, &rpcInfo); err == nil {
 				if t.verbose {
@@ -257,6 +264,37 @@ func (t *TokenMetadataEnricher) Process(ctx context.Context, baggage map[string]
 			bestDecimals = rpcDecimals
 		}
 
+		// Merge with token lists. Lists win for display fields (name, symbol, logo) because on-chain strings
+		// are set by the deployer and easily spoofed; decimals come from the chain whenever RPC answered.
+		listed := false
+		if t.staticProvider != nil {
+			if entry, ok := t.staticProvider.LookupToken(networkID, address); ok {
+				listed = true
+				hasAnyTokenLikeData = true
+				if entry.Name != "" {
+					bestName = entry.Name
+				}
+				if entry.Symbol != "" {
+					bestSymbol = entry.Symbol
+				}
+				if entry.LogoURI != "" {
+					bestLogo = entry.LogoURI
+				}
+				if entry.Description != "" {
+					bestDescription = entry.Description
+				}
+
+				if bestDecimals < 0 {
+					bestDecimals = entry.Decimals
+				} else if bestDecimals != entry.Decimals {
+					contractInfo["list_decimals"] = entry.Decimals
+					if t.verbose {
+						fmt.Printf("   ⚠️ %s: token list says %d decimals, contract returns %d (using contract)\n", address, entry.Decimals, bestDecimals)
+					}
+				}
+			}
+		}
+
 		// Determine token type based on available methods and responses
 		tokenType := "Contract"
 		if bestName != "" || bestSymbol != "" {
@@ -283,6 +321,7 @@ func (t *TokenMetadataEnricher) Process(ctx context.Context, baggage map[string]
 				Description: bestDescription,
 				Website:     bestWebsite,
 				Category:    bestCategory,
+				Listed:      listed,
 			}
 			contractMetadata[address] = metadata
 			tokenCount++
@@ -340,6 +379,10 @@ func (t *TokenMetadataEnricher) GetPromptContext(ctx context.Context, baggage ma
 				tokenInfo += fmt.Sprintf(", Type: %s", metadata.Type)
 			}
 
+			if metadata.Listed {
+				tokenInfo += ", on a vetted token list"
+			}
+
 			if metadata.Category != "" {
 				tokenInfo += fmt.Sprintf(", Category: %s", metadata.Category)
 			}
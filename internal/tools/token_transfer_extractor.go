@@ -87,7 +87,8 @@ func (t *TokenTransferExtractor) extractTokenTransfers(events []models.Event) []
 				continue
 			}
 
-			// Skip zero-amount ERC20 transfers (but keep ERC721 transfers)
+			// Skip zero-amount ERC20 transfers (but keep ERC721 transfers). AddressPoisoningDetector reads them
+			// from the events, as address poisoning fakes history with them.
 			if transfer.Type == "ERC20" && t.isZeroAmount(transfer.Amount) {
 				continue
 			}
@@ -300,9 +300,10 @@ func (t *TraceDecoder) decodeCallTracerResult(ctx context.Context, trace map[str
 	// Only process calls that have meaningful data for explanations
 	value, hasValue := trace["value"].(string)
 	to, hasTo := trace["to"].(string)
+	traceError, _ := trace["error"].(string)
 
-	// Skip calls without ETH value or meaningful contract interaction
-	if !hasValue || value == "" || value == "0x" || value == "0x0" {
+	// Skip calls without ETH value or meaningful contract interaction (failed calls always explain something)
+	if (!hasValue || value == "" || value == "0x" || value == "0x0") && traceError == "" {
 		// Still process subcalls in case they have value
 		if subCalls, ok := trace["calls"].([]interface{}); ok {
 			for _, subCallInterface := range subCalls {
@@ -334,10 +335,16 @@ func (t *TraceDecoder) decodeCallTracerResult(ctx context.Context, trace map[str
 	// Don't hardcode method names - let LLM interpret the call based on type and value
 	// The call type and value provide enough context for interpretation
 
-	// Check call success
-	if error, hasError := trace["error"].(string); hasError && error != "" {
+	// Check call success; the tracer only reports a generic error, so decode the revert output when possible
+	if traceError != "" {
 		call.Success = false
-		call.ErrorReason = error
+		call.ErrorReason = traceError
+		if output, ok := trace["output"].(string); ok && len(output) > 2 {
+			call.RevertData = strings.ToLower(output)
+			if failure, ok := decodeStandardRevert(output); ok {
+				call.ErrorReason = failure.Reason
+			}
+		}
 	} else {
 		call.Success = true
 	}
@@ -36,6 +36,9 @@ func (t *TransactionExplainer) Dependencies() []string {
 		"abi_resolver", "log_decoder", "trace_decoder", "ens_resolver",
 		"token_metadata_enricher", "erc20_price_lookup", "monetary_value_enricher",
 		"address_role_resolver", "protocol_resolver", "tag_resolver", "static_context_provider",
+		"nft_sale_detector", "action_builder", "call_unwrapper", "user_operation_decoder",
+		"safe_transaction_decoder", "approval_analyzer", "address_poisoning_detector",
+		"address_screener", "mev_detector",
 	}
 }
 
@@ -74,6 +77,13 @@ func (t *TransactionExplainer) Process(ctx context.Context, baggage map[string]i
 		decodedData.Calls = calls
 	}
 
+	// Network scopes the RAG searches the LLM can make
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if networkID, ok := rawData["network_id"].(float64); ok {
+			decodedData.NetworkID = int64(networkID)
+		}
+	}
+
 	baggage["decoded_data"] = decodedData
 
 	// Send progress update for context collection
@@ -401,6 +411,59 @@ func (t *TransactionExplainer) parseExplanationResponse(ctx context.Context, res
 		result.Participants = []models.AddressParticipant{} // Empty if no participants found
 	}
 
+	// Why a failed transaction reverted (set by RevertDecoder)
+	if failure, ok := baggage["failure"].(*models.Failure); ok {
+		result.Failure = failure
+	}
+
+	// Calls unwrapped from batch calldata (set by CallUnwrapper)
+	if tree, ok := GetCallTree(baggage); ok {
+		result.CallTree = tree
+	}
+
+	// ERC-4337 operations in the bundle (set by UserOperationDecoder)
+	if operations, ok := GetUserOperations(baggage); ok {
+		result.UserOperations = operations
+	}
+
+	// Safe multisig executions with their signers (set by SafeTransactionDecoder)
+	if transactions, ok := GetSafeTransactions(baggage); ok {
+		result.SafeTransactions = transactions
+	}
+
+	// Approvals and permits, whose risk flags become the explanation's risks (set by ApprovalAnalyzer)
+	if approvals, ok := GetApprovals(baggage); ok {
+		result.Approvals = approvals
+		result.Risks = append(result.Risks, ApprovalRisks(approvals)...)
+	}
+
+	// Address poisoning and fake token transfers (set by AddressPoisoningDetector, which also marks participants)
+	if poisoned, ok := GetPoisonedTransfers(baggage); ok {
+		result.Risks = append(result.Risks, PoisoningRisks(poisoned)...)
+	}
+
+	// Sanctions, scam and denylist matches (set by AddressScreener, which also attributes them on participants)
+	if matches, ok := GetScreeningMatches(baggage); ok {
+		result.Risks = append(result.Risks, ScreeningRisks(matches)...)
+	}
+
+	// Sandwiches, arbitrage and JIT liquidity around the transaction; only sandwiches it fell victim to are risks
+	// (set by MEVDetector)
+	if mev, ok := GetMEV(baggage); ok {
+		result.MEV = mev
+		result.Risks = append(result.Risks, MEVRisks(mev)...)
+	}
+
+	// Structured actions (set by ActionBuilder)
+	if actions, ok := GetActions(baggage); ok {
+		result.Actions = actions
+	}
+
+	// Structured NFT sales for clients that render purchase details
+	if sales, ok := GetNFTSales(baggage); ok && len(sales) > 0 {
+		result.Metadata["nft_sales"] = sales
+	}
+
 	// Add address categories to metadata for frontend legend grouping (backward compatibility)
 	if len(result.Participants) > 0 {
 		// Create categories map for frontend
@@ -668,7 +731,7 @@ func (t *TransactionExplainer) processRAGResponse(ctx context.Context, response
 			}
 
 			// Execute the RAG search function - ALWAYS CONTINUE EVEN IF SEARCH FAILS
-			result, err := t.ragService.HandleFunctionCall(ctx, toolCall.FunctionCall.Name, args)
+			result, err := t.ragService.HandleFunctionCall(ctx, decodedData.NetworkID, toolCall.FunctionCall.Name, args)
 			if err != nil {
 				// Log the error but don't fail the entire explanation
 				if t.verbose {
@@ -771,6 +834,7 @@ func (t *TransactionExplainer) buildRAGEnabledPrompt(decodedData *models.Decoded
 - "Approved 🍣 SushiSwap router to spend unlimited PEPE tokens ($0.000012 each) from outta.eth, preparing for future trades + $0.85 gas"
 - "Transferred 57,071 GrowAI tokens ($594.12) from charlie.eth to alice.eth in a single direct transfer + $0.82 gas"
 - "Minted 3 new CryptoPunks NFTs (tokens #1205, #1206, #1207) directly to collector vitalik.eth + $2.10 gas"
+- "Bought Bored Ape #4521 from 0x1234...5678 for 12.5 ETH ($41,250.00) on OpenSea, paying a 0.31 ETH marketplace fee, received by alice.eth + $4.20 gas"
 - "Granted admin role #7 to dao-member.eth on governance contract, expanding permissions + $0.45 gas"
 - "Deposited 2.5 ETH ($8,450.00) into Compound lending pool, receiving 125.8 cETH tokens as collateral proof to 0x5555...6666 + $1.20 gas"
 - "Executed multi-step arbitrage: bought 1000 LINK ($23,400.00) with ETH on 🦄 Uniswap, then sold for 1050 LINK on 🍣 SushiSwap, netting 50 LINK ($1,170.00) profit + $3.40 gas"
@@ -811,6 +875,10 @@ TRANSACTION TYPE DETECTION:
 - **CONTRACT DEPLOYMENT**: Look for contract creation, zero address recipients
 - **ACCESS CONTROL**: Look for permission updates, admin changes, access modifications
 - **NFT OPERATIONS**: Look for ERC721/ERC1155 Transfer events with tokenId parameters
+- **NFT SALES**: When an "NFT Sales" section is present, describe the purchase (buyer, seller, collection, price, marketplace) rather than a plain transfer
+- **SCAMS**: When an "ADDRESS POISONING" section is present, call the transaction a scam: say it is address poisoning or a fake token, name the lookalike address or fake token, and never present the flagged transfers as real payments
+- **SCREENING**: When a "SCREENING MATCHES" section is present, state which address is sanctioned or on a scam or denylist
+- **MEV**: When an "MEV" section is present, name the pattern; for a sandwiched swap, explain that the attacker's front-run worsened its price and state the value the attacker extracted
 
 AUTONOMOUS SEARCH INSTRUCTIONS:
 - When you encounter UNKNOWN protocols, contracts, or addresses, USE the search functions available to you
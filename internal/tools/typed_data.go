@@ -0,0 +1,272 @@
+package tools
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+)
+
+// eip712DomainFields are the EIP712Domain members in their canonical order, used when a payload leaves the
+// domain type out
+var eip712DomainFields = []models.TypedDataField{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+	{Name: "salt", Type: "bytes32"},
+}
+
+// maxTypedDataDepth bounds how deeply structs and arrays may nest in a message, so a hostile payload cannot
+// exhaust the stack
+const maxTypedDataDepth = 32
+
+// typedArrayPattern splits an array type into its element type and optional fixed length
+var typedArrayPattern = regexp.MustCompile(`^(.+)\[(\d*)\]$`)
+
+// typedDataHash returns the EIP-712 digest a wallet signs: keccak256(0x1901 || domainSeparator || hashStruct(message))
+func typedDataHash(typedData *models.TypedData) ([]byte, error) {
+	types := typedData.Types
+	if _, ok := types["EIP712Domain"]; !ok {
+		types = make(map[string][]models.TypedDataField, len(typedData.Types)+1)
+		for name, fields := range typedData.Types {
+			types[name] = fields
+		}
+		var domainFields []models.TypedDataField
+		for _, field := range eip712DomainFields {
+			if _, present := typedData.Domain[field.Name]; present {
+				domainFields = append(domainFields, field)
+			}
+		}
+		types["EIP712Domain"] = domainFields
+	}
+
+	domainSeparator, err := hashTypedStruct(types, "EIP712Domain", typedData.Domain, 0)
+	if err != nil {
+		return nil, fmt.Errorf("domain: %w", err)
+	}
+	if _, ok := types[typedData.PrimaryType]; !ok {
+		return nil, fmt.Errorf("primary type %q is not defined", typedData.PrimaryType)
+	}
+	messageHash, err := hashTypedStruct(types, typedData.PrimaryType, typedData.Message, 0)
+	if err != nil {
+		return nil, fmt.Errorf("message: %w", err)
+	}
+	return keccak256([]byte{0x19, 0x01}, domainSeparator, messageHash), nil
+}
+
+// encodeTypedType returns a struct's type string: its own definition followed by the structs it references,
+// sorted by name
+func encodeTypedType(types map[string][]models.TypedDataField, primaryType string) string {
+	referenced := make(map[string]bool)
+	var collect func(typeName string)
+	collect = func(typeName string) {
+		for _, field := range types[typeName] {
+			base := typedBaseType(field.Type)
+			if _, isStruct := types[base]; isStruct && base != primaryType && !referenced[base] {
+				referenced[base] = true
+				collect(base)
+			}
+		}
+	}
+	collect(primaryType)
+
+	names := make([]string, 0, len(referenced))
+	for name := range referenced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var encoded strings.Builder
+	for _, name := range append([]string{primaryType}, names...) {
+		members := make([]string, 0, len(types[name]))
+		for _, field := range types[name] {
+			members = append(members, field.Type+" "+field.Name)
+		}
+		encoded.WriteString(name + "(" + strings.Join(members, ",") + ")")
+	}
+	return encoded.String()
+}
+
+// hashTypedStruct is EIP-712 hashStruct: keccak256(typeHash || encodeData(value)). depth counts the structs and
+// arrays value is nested in.
+func hashTypedStruct(types map[string][]models.TypedDataField, typeName string, value map[string]interface{}, depth int) ([]byte, error) {
+	if depth > maxTypedDataDepth {
+		return nil, fmt.Errorf("%s is nested more than %d levels deep", typeName, maxTypedDataDepth)
+	}
+	encoded := [][]byte{keccak256([]byte(encodeTypedType(types, typeName)))}
+	for _, field := range types[typeName] {
+		word, err := encodeTypedValue(types, field.Type, value[field.Name], depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", typeName, field.Name, err)
+		}
+		encoded = append(encoded, word)
+	}
+	return keccak256(encoded...), nil
+}
+
+// encodeTypedValue encodes one member as its 32-byte word: structs, arrays, strings and bytes by their hash
+func encodeTypedValue(types map[string][]models.TypedDataField, typeName string, value interface{}, depth int) ([]byte, error) {
+	if depth > maxTypedDataDepth {
+		return nil, fmt.Errorf("%s is nested more than %d levels deep", typeName, maxTypedDataDepth)
+	}
+	if match := typedArrayPattern.FindStringSubmatch(typeName); match != nil {
+		items, ok := value.([]interface{})
+		if !ok && value != nil {
+			return nil, fmt.Errorf("expected an array for %s", typeName)
+		}
+		if match[2] != "" && strconv.Itoa(len(items)) != match[2] {
+			return nil, fmt.Errorf("expected %s items for %s", match[2], typeName)
+		}
+		var encoded [][]byte
+		for _, item := range items {
+			word, err := encodeTypedValue(types, match[1], item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, word)
+		}
+		return keccak256(encoded...), nil
+	}
+
+	if _, isStruct := types[typeName]; isStruct {
+		// A missing struct would otherwise hash as empty, and a self-referencing type would recurse forever
+		if value == nil {
+			return nil, fmt.Errorf("missing value for field of type %s", typeName)
+		}
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object for %s", typeName)
+		}
+		return hashTypedStruct(types, typeName, fields, depth)
+	}
+
+	word := make([]byte, 32)
+	switch {
+	case typeName == "string":
+		text, _ := value.(string)
+		return keccak256([]byte(text)), nil
+	case typeName == "bytes":
+		data, err := typedBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return keccak256(data), nil
+	case typeName == "bool":
+		if typedBool(value) {
+			word[31] = 1
+		}
+	case typeName == "address":
+		address := typedAddress(value)
+		if address == "" {
+			return nil, fmt.Errorf("invalid address %v", value)
+		}
+		data, _ := hex.DecodeString(address[2:])
+		copy(word[12:], data)
+	case strings.HasPrefix(typeName, "bytes"):
+		data, err := typedBytes(value)
+		if err != nil || len(data) > 32 {
+			return nil, fmt.Errorf("invalid %s %v", typeName, value)
+		}
+		copy(word, data)
+	case strings.HasPrefix(typeName, "uint"), strings.HasPrefix(typeName, "int"):
+		number := typedUint(value)
+		if number == nil {
+			return nil, fmt.Errorf("invalid %s %v", typeName, value)
+		}
+		if number.Sign() < 0 {
+			// Two's complement over 256 bits
+			number = new(big.Int).Add(number, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if number.BitLen() > 256 {
+			return nil, fmt.Errorf("%s %v overflows", typeName, value)
+		}
+		number.FillBytes(word)
+	default:
+		return nil, fmt.Errorf("unknown type %s", typeName)
+	}
+	return word, nil
+}
+
+// typedBaseType strips array suffixes from a type
+func typedBaseType(typeName string) string {
+	for {
+		match := typedArrayPattern.FindStringSubmatch(typeName)
+		if match == nil {
+			return typeName
+		}
+		typeName = match[1]
+	}
+}
+
+// typedUint reads an integer member given as a JSON number or a decimal or hex string
+func typedUint(value interface{}) *big.Int {
+	var text string
+	switch v := value.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = strings.TrimSpace(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return big.NewInt(1)
+		}
+		return big.NewInt(0)
+	default:
+		return nil
+	}
+	base := 10
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		text, base = text[2:], 16
+	}
+	number, ok := new(big.Int).SetString(text, base)
+	if !ok {
+		return nil
+	}
+	return number
+}
+
+// typedAddress reads an address member as a lowercase 0x-prefixed string, or "" when invalid
+func typedAddress(value interface{}) string {
+	address, _ := value.(string)
+	address = strings.ToLower(strings.TrimSpace(address))
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return ""
+	}
+	if _, err := hex.DecodeString(address[2:]); err != nil {
+		return ""
+	}
+	return address
+}
+
+// typedBool reads a bool member, also accepting "true" and numbers
+func typedBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			return parsed
+		}
+	}
+	number := typedUint(value)
+	return number != nil && number.Sign() != 0
+}
+
+// typedBytes reads a bytes member given as a hex string
+func typedBytes(value interface{}) ([]byte, error) {
+	text, _ := value.(string)
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")
+	if len(text)%2 == 1 {
+		text = "0" + text
+	}
+	return hex.DecodeString(text)
+}
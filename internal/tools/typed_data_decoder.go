@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/txplain/txplain/internal/models"
+)
+
+// cowSwapNativeToken is how CoW Protocol orders denote the chain's native token
+const cowSwapNativeToken = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+
+// Seaport item types: native token, ERC20, ERC721, ERC1155, and the NFT types matching any token of a criteria
+const (
+	seaportItemNative = iota
+	seaportItemERC20
+	seaportItemERC721
+	seaportItemERC1155
+	seaportItemERC721Criteria
+	seaportItemERC1155Criteria
+)
+
+// TypedDataDecoder decodes an EIP-712 payload before it is signed: it computes the digest, recovers the
+// signer when a signature is given and decodes the message against known schemas - EIP-2612 and DAI
+// permits, Permit2 allowances and signature transfers, Seaport orders and CoW Protocol orders - into
+// approvals and orders with raw amounts, for SignatureExplainer to finish.
+type TypedDataDecoder struct {
+	verbose bool
+}
+
+// NewTypedDataDecoder creates a new typed-data decoder
+func NewTypedDataDecoder(verbose bool) *TypedDataDecoder {
+	return &TypedDataDecoder{
+		verbose: verbose,
+	}
+}
+
+// Name returns the processor name
+func (d *TypedDataDecoder) Name() string {
+	return "typed_data_decoder"
+}
+
+// Description returns the processor description
+func (d *TypedDataDecoder) Description() string {
+	return "Decodes EIP-712 typed data against known permit and order schemas"
+}
+
+// Dependencies returns the tools this processor depends on
+func (d *TypedDataDecoder) Dependencies() []string {
+	return []string{}
+}
+
+// Process decodes baggage["typed_data"] into baggage["signature_explanation"] and lists the tokens and
+// contracts it references in baggage["contract_addresses"] for the token metadata enricher
+func (d *TypedDataDecoder) Process(ctx context.Context, baggage map[string]interface{}) error {
+	typedData, ok := baggage["typed_data"].(*models.TypedData)
+	if !ok || typedData == nil {
+		return fmt.Errorf("no typed data to decode")
+	}
+	if typedData.PrimaryType == "" || len(typedData.Types[typedData.PrimaryType]) == 0 {
+		return fmt.Errorf("typed data has no definition for its primary type %q", typedData.PrimaryType)
+	}
+
+	explanation := &models.SignatureExplanation{
+		Schema:      models.SignatureSchemaUnknown,
+		PrimaryType: typedData.PrimaryType,
+		Domain: models.SignatureDomain{
+			VerifyingContract: typedAddress(typedData.Domain["verifyingContract"]),
+		},
+		Timestamp: time.Now(),
+	}
+	explanation.Domain.Name, _ = typedData.Domain["name"].(string)
+	explanation.Domain.Version, _ = typedData.Domain["version"].(string)
+	if chainID := typedUint(typedData.Domain["chainId"]); chainID != nil && chainID.IsInt64() {
+		explanation.Domain.ChainID = chainID.Int64()
+	}
+	if rawData, ok := baggage["raw_data"].(map[string]interface{}); ok {
+		if networkID, ok := rawData["network_id"].(float64); ok {
+			explanation.NetworkID = int64(networkID)
+		}
+	}
+
+	hash, err := typedDataHash(typedData)
+	if err != nil {
+		return fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	explanation.Hash = "0x" + hex.EncodeToString(hash)
+	if signature, _ := baggage["signature"].(string); signature != "" {
+		if data, err := typedBytes(signature); err == nil {
+			explanation.Signer, _ = recoverSigner(hash, data)
+		}
+	}
+
+	decodeTypedMessage(explanation, typedData.Message)
+	if d.verbose {
+		fmt.Printf("✍️  Typed data %s decoded as %s (%d approvals, order: %t)\n", typedData.PrimaryType, explanation.Schema, len(explanation.Approvals), explanation.Order != nil)
+	}
+
+	var addresses []string
+	addAddress := func(address string) {
+		if address != "" && address != nativePaymentToken && !containsString(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+	addAddress(explanation.Domain.VerifyingContract)
+	for _, approval := range explanation.Approvals {
+		addAddress(approval.Allowance.Token)
+	}
+	if order := explanation.Order; order != nil {
+		for _, amounts := range [][]models.ActionAmount{order.Gives, order.Receives, order.Fees} {
+			for _, amount := range amounts {
+				addAddress(amount.Token)
+			}
+		}
+	}
+	baggage["contract_addresses"] = addresses
+	baggage["signature_explanation"] = explanation
+	return nil
+}
+
+// decodeTypedMessage sets the schema and the approvals or order of a known message
+func decodeTypedMessage(explanation *models.SignatureExplanation, message map[string]interface{}) {
+	amounts := &actionContext{}
+	signer := explanation.Signer
+	token := explanation.Domain.VerifyingContract
+	field := func(value map[string]interface{}, name string) map[string]interface{} {
+		nested, _ := value[name].(map[string]interface{})
+		return nested
+	}
+	list := func(value map[string]interface{}, name string) []map[string]interface{} {
+		items, _ := value[name].([]interface{})
+		var result []map[string]interface{}
+		for _, item := range items {
+			if fields, ok := item.(map[string]interface{}); ok {
+				result = append(result, fields)
+			}
+		}
+		return result
+	}
+
+	switch primaryType := explanation.PrimaryType; {
+	case primaryType == "Permit" && message["holder"] != nil && message["allowed"] != nil:
+		// DAI-style permit(holder, spender, nonce, expiry, allowed) grants all or nothing
+		approval := models.Approval{
+			Kind: models.ApprovalKindPermit, Owner: typedAddress(message["holder"]), Spender: typedAddress(message["spender"]),
+			Allowance: models.ActionAmount{Token: token, Amount: "unlimited"}, Source: "signature",
+			Deadline: unixTime(typedUint(message["expiry"])),
+		}
+		approval.Unlimited = typedBool(message["allowed"])
+		approval.Revoked = !approval.Unlimited
+		explanation.Schema = models.SignatureSchemaPermit
+		explanation.Approvals = []models.Approval{approval}
+	case primaryType == "Permit" && message["spender"] != nil && message["value"] != nil:
+		approval := models.Approval{
+			Kind: models.ApprovalKindPermit, Owner: typedAddress(message["owner"]), Spender: typedAddress(message["spender"]),
+			Source: "signature", Deadline: unixTime(typedUint(message["deadline"])),
+		}
+		if value := typedUint(message["value"]); value != nil {
+			setAllowance(&approval, amounts, token, value)
+		}
+		explanation.Schema = models.SignatureSchemaPermit
+		explanation.Approvals = []models.Approval{approval}
+	case primaryType == "PermitSingle" || primaryType == "PermitBatch":
+		// Permit2 allowance: details (token, amount, expiration, nonce), spender, sigDeadline
+		details := list(message, "details")
+		if primaryType == "PermitSingle" {
+			details = []map[string]interface{}{field(message, "details")}
+		}
+		for _, detail := range details {
+			approval := models.Approval{
+				Kind: models.ApprovalKindPermit2, Owner: signer, Spender: typedAddress(message["spender"]), Source: "signature",
+				Expiration: unixTime(typedUint(detail["expiration"])), Deadline: unixTime(typedUint(message["sigDeadline"])),
+			}
+			if value := typedUint(detail["amount"]); value != nil {
+				setAllowance(&approval, amounts, typedAddress(detail["token"]), value)
+			}
+			explanation.Approvals = append(explanation.Approvals, approval)
+		}
+		explanation.Schema = models.SignatureSchemaPermit2
+	case strings.HasPrefix(primaryType, "Permit") && strings.HasSuffix(primaryType, "TransferFrom"):
+		// Permit2 signature transfer: permitted (token, amount) or a list of them, spender, nonce, deadline,
+		// and for the witness variants an order the transfer pays for
+		permitted := list(message, "permitted")
+		if len(permitted) == 0 {
+			permitted = []map[string]interface{}{field(message, "permitted")}
+		}
+		for _, item := range permitted {
+			approval := models.Approval{
+				Kind: models.ApprovalKindPermit2Transfer, Owner: signer, Spender: typedAddress(message["spender"]),
+				Source: "signature", Deadline: unixTime(typedUint(message["deadline"])),
+			}
+			if value := typedUint(item["amount"]); value != nil {
+				setAllowance(&approval, amounts, typedAddress(item["token"]), value)
+			}
+			explanation.Approvals = append(explanation.Approvals, approval)
+		}
+		explanation.Schema = models.SignatureSchemaPermit2Transfer
+	case primaryType == "OrderComponents" && message["offerer"] != nil:
+		explanation.Schema = models.SignatureSchemaSeaport
+		explanation.Order = decodeSeaportOrder(message, list(message, "offer"), list(message, "consideration"), amounts)
+	case primaryType == "Order" && message["sellToken"] != nil && message["buyToken"] != nil:
+		explanation.Schema = models.SignatureSchemaCowSwap
+		explanation.Order = decodeCowSwapOrder(message, signer, amounts)
+	}
+
+	// Skip what does not decode rather than describe half an approval
+	var approvals []models.Approval
+	for _, approval := range explanation.Approvals {
+		if approval.Spender != "" && approval.Allowance.Token != "" {
+			approvals = append(approvals, approval)
+		}
+	}
+	explanation.Approvals = approvals
+}
+
+// decodeSeaportOrder decodes Seaport OrderComponents: the offerer gives the offer items and receives the
+// consideration items addressed to it; the rest of the consideration goes to fee and royalty recipients
+func decodeSeaportOrder(message map[string]interface{}, offer, consideration []map[string]interface{}, amounts *actionContext) *models.SignedOrder {
+	order := &models.SignedOrder{
+		Protocol:   "Seaport",
+		Maker:      typedAddress(message["offerer"]),
+		ValidFrom:  unixTime(typedUint(message["startTime"])),
+		ValidUntil: unixTime(typedUint(message["endTime"])),
+	}
+	if orderType := typedUint(message["orderType"]); orderType != nil {
+		order.PartiallyFillable = orderType.Bit(0) == 1 // PARTIAL_OPEN and PARTIAL_RESTRICTED
+	}
+	order.Gives = []models.ActionAmount{}
+	order.Receives = []models.ActionAmount{}
+
+	item := func(fields map[string]interface{}) models.ActionAmount {
+		itemType := typedUint(fields["itemType"])
+		token := typedAddress(fields["token"])
+		amount := typedUint(fields["startAmount"])
+		if itemType == nil || amount == nil {
+			return models.ActionAmount{}
+		}
+		switch itemType.Int64() {
+		case seaportItemNative:
+			return amounts.amount(nativePaymentToken, "", amount)
+		case seaportItemERC721, seaportItemERC1155:
+			tokenID := typedUint(fields["identifierOrCriteria"])
+			if tokenID == nil {
+				return models.ActionAmount{}
+			}
+			return amounts.amount(token, tokenID.String(), amount)
+		case seaportItemERC721Criteria, seaportItemERC1155Criteria:
+			// Any token of the collection that matches the criteria
+			result := amounts.amount(token, "", nil)
+			result.Amount = "any"
+			if amount.Cmp(big.NewInt(1)) != 0 {
+				result.Amount = amount.String() + " x any"
+			}
+			return result
+		}
+		return amounts.amount(token, "", amount)
+	}
+
+	for _, fields := range offer {
+		if amount := item(fields); amount.Token != "" {
+			order.Gives = append(order.Gives, amount)
+		}
+	}
+	for _, fields := range consideration {
+		amount := item(fields)
+		if amount.Token == "" {
+			continue
+		}
+		if typedAddress(fields["recipient"]) == order.Maker {
+			order.Receives = append(order.Receives, amount)
+		} else {
+			order.Fees = append(order.Fees, amount)
+		}
+	}
+	return order
+}
+
+// decodeCowSwapOrder decodes a CoW Protocol GPv2Order. A sell order receives at least buyAmount, a buy order
+// spends at most sellAmount; the fee comes on top of the sell amount.
+func decodeCowSwapOrder(message map[string]interface{}, signer string, amounts *actionContext) *models.SignedOrder {
+	token := func(value interface{}) string {
+		address := typedAddress(value)
+		if address == cowSwapNativeToken {
+			return nativePaymentToken
+		}
+		return address
+	}
+	order := &models.SignedOrder{
+		Protocol:          "CoW Protocol",
+		Maker:             signer,
+		ValidUntil:        unixTime(typedUint(message["validTo"])),
+		PartiallyFillable: typedBool(message["partiallyFillable"]),
+		Gives:             []models.ActionAmount{},
+		Receives:          []models.ActionAmount{},
+	}
+	if receiver := typedAddress(message["receiver"]); receiver != "" && receiver != zeroAddress && receiver != signer {
+		order.Receiver = receiver
+	}
+	if sellAmount := typedUint(message["sellAmount"]); sellAmount != nil {
+		order.Gives = append(order.Gives, amounts.amount(token(message["sellToken"]), "", sellAmount))
+	}
+	if buyAmount := typedUint(message["buyAmount"]); buyAmount != nil {
+		order.Receives = append(order.Receives, amounts.amount(token(message["buyToken"]), "", buyAmount))
+	}
+	if fee := typedUint(message["feeAmount"]); fee != nil && fee.Sign() > 0 {
+		order.Fees = append(order.Fees, amounts.amount(token(message["sellToken"]), "", fee))
+	}
+	return order
+}
+
+// GetSignatureExplanation returns the signature explanation from baggage
+func GetSignatureExplanation(baggage map[string]interface{}) (*models.SignatureExplanation, bool) {
+	explanation, ok := baggage["signature_explanation"].(*models.SignatureExplanation)
+	return explanation, ok && explanation != nil
+}
+
+// GetPromptContext provides no context: SignatureExplainer describes the decoded signature
+func (d *TypedDataDecoder) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	return ""
+}
+
+// GetRagContext provides RAG context for typed data (none - it is request specific)
+func (d *TypedDataDecoder) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
@@ -0,0 +1,351 @@
+package tools
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/txplain/txplain/internal/models"
+)
+
+// entryPoints are the canonical ERC-4337 EntryPoint deployments by version
+var entryPoints = map[string]string{
+	"0x5ff137d4b0fdcd49dca30c7cf57e578a026d2789": "v0.6",
+	"0x0000000071727de22e5e9d8baf0edac6f37da032": "v0.7",
+	"0x4337084d9e255ff0702461cf8895ce9e3b5ff108": "v0.8",
+}
+
+// handleOpsLayout locates the fields of a UserOperation tuple in an EntryPoint's handleOps calldata
+type handleOpsLayout struct {
+	signature        string
+	paymasterAndData int // Tuple field index of paymasterAndData
+}
+
+// handleOpsLayouts are handleOps(ops, beneficiary) by selector: v0.6 takes the full UserOperation, v0.7 and
+// later the PackedUserOperation with gas limits and fees packed into bytes32 words. Both start with sender,
+// nonce, initCode and callData.
+var handleOpsLayouts = func() map[string]handleOpsLayout {
+	index := make(map[string]handleOpsLayout)
+	for _, layout := range []handleOpsLayout{
+		{signature: "handleOps((address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)[],address)", paymasterAndData: 9},
+		{signature: "handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)", paymasterAndData: 7},
+	} {
+		index[functionSelector(layout.signature)] = layout
+	}
+	return index
+}()
+
+// EntryPoint events, identical across versions
+var (
+	userOperationEventTopic        = eventTopic("UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)")
+	userOperationRevertReasonTopic = eventTopic("UserOperationRevertReason(bytes32,address,uint256,bytes)")
+	accountDeployedTopic           = eventTopic("AccountDeployed(bytes32,address,address,address)")
+)
+
+// UserOperationDecoder decodes ERC-4337 bundles: the UserOperations passed to an EntryPoint's handleOps,
+// with each operation's callData unwrapped through the smart account's execute/executeBatch, and their
+// outcome and gas cost from the EntryPoint's UserOperationEvent logs. This lets the explanation describe
+// what each smart account did instead of "a bundler called the EntryPoint".
+type UserOperationDecoder struct {
+	unwrapper *CallUnwrapper
+	verbose   bool
+}
+
+// NewUserOperationDecoder creates a new UserOperation decoder
+func NewUserOperationDecoder(verbose bool) *UserOperationDecoder {
+	return &UserOperationDecoder{
+		unwrapper: NewCallUnwrapper(false),
+		verbose:   verbose,
+	}
+}
+
+// Name returns the processor name
+func (d *UserOperationDecoder) Name() string {
+	return "user_operation_decoder"
+}
+
+// Description returns the processor description
+func (d *UserOperationDecoder) Description() string {
+	return "Decodes ERC-4337 UserOperations bundled through an EntryPoint into each smart account's calls"
+}
+
+// Dependencies returns the tools this processor depends on
+func (d *UserOperationDecoder) Dependencies() []string {
+	return []string{"abi_resolver", "log_decoder", "trace_decoder"}
+}
+
+// Process decodes the bundled UserOperations and stores them in baggage["user_operations"]
+func (d *UserOperationDecoder) Process(ctx context.Context, baggage map[string]interface{}) error {
+	resolvedContracts, _ := baggage["resolved_contracts"].(map[string]*ContractInfo)
+	events, _ := baggage["events"].([]models.Event)
+	bundler := transactionSender(baggage)
+
+	var operations []models.UserOperation
+	var handlers []string
+	for _, call := range handleOpsCalls(baggage) {
+		operations = append(operations, d.decodeHandleOps(call.to, call.input, bundler, resolvedContracts)...)
+		handlers = append(handlers, call.to)
+	}
+	operations = applyUserOperationEvents(operations, events, bundler, handlers)
+	if len(operations) == 0 {
+		return nil
+	}
+
+	if d.verbose {
+		for _, operation := range operations {
+			fmt.Printf("🪪 UserOperation from %s (nonce %s, success %t)\n", operation.Sender, operation.Nonce, operation.Success)
+		}
+	}
+	baggage["user_operations"] = operations
+	return nil
+}
+
+type entryPointCall struct {
+	to    string
+	input string
+}
+
+// handleOpsCalls finds the handleOps calls in the transaction: usually the transaction itself, but bundlers
+// may also go through a contract of their own, so the trace is searched as well
+func handleOpsCalls(baggage map[string]interface{}) []entryPointCall {
+	isHandleOps := func(input string) bool {
+		_, ok := handleOpsLayouts[strings.ToLower(input[:min(len(input), 10)])]
+		return ok
+	}
+
+	to, input, _ := topLevelCall(baggage)
+	if isHandleOps(input) {
+		return []entryPointCall{{to: to, input: input}}
+	}
+
+	rawData, _ := baggage["raw_data"].(map[string]interface{})
+	trace, ok := rawData["trace"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var calls []entryPointCall
+	var walk func(frame map[string]interface{})
+	walk = func(frame map[string]interface{}) {
+		input, _ := frame["input"].(string)
+		to, _ := frame["to"].(string)
+		if isHandleOps(input) {
+			calls = append(calls, entryPointCall{to: strings.ToLower(to), input: strings.ToLower(input)})
+			return
+		}
+		if children, ok := frame["calls"].([]interface{}); ok {
+			for _, child := range children {
+				if childFrame, ok := child.(map[string]interface{}); ok {
+					walk(childFrame)
+				}
+			}
+		}
+	}
+	walk(trace)
+	return calls
+}
+
+// decodeHandleOps decodes handleOps(ops, beneficiary) into UserOperations with their callData decoded
+// against the sender account
+func (d *UserOperationDecoder) decodeHandleOps(entryPoint, input, bundler string, resolvedContracts map[string]*ContractInfo) []models.UserOperation {
+	layout := handleOpsLayouts[input[:10]]
+	args, err := hex.DecodeString(input[10:])
+	if err != nil || len(args) < 64 {
+		return nil
+	}
+	elements, ok := abiElements(args, 0)
+	if !ok {
+		return nil
+	}
+	beneficiary := "0x" + hex.EncodeToString(args[32+12:64])
+
+	operations := make([]models.UserOperation, 0, len(elements))
+	for _, element := range elements {
+		tuple := args[element:]
+		if len(tuple) < (layout.paymasterAndData+1)*32 {
+			return nil
+		}
+		initCode, okInit := abiBytes(tuple, 2*32)
+		callData, okCall := abiBytes(tuple, 3*32)
+		paymasterAndData, okPaymaster := abiBytes(tuple, layout.paymasterAndData*32)
+		if !okInit || !okCall || !okPaymaster {
+			return nil
+		}
+
+		operation := models.UserOperation{
+			EntryPoint:  entryPoint,
+			Sender:      "0x" + hex.EncodeToString(tuple[12:32]),
+			Nonce:       new(big.Int).SetBytes(tuple[32:64]).String(),
+			Bundler:     bundler,
+			Beneficiary: beneficiary,
+		}
+		if len(initCode) >= 20 {
+			operation.Factory = "0x" + hex.EncodeToString(initCode[:20])
+		}
+		if len(paymasterAndData) >= 20 {
+			operation.Paymaster = "0x" + hex.EncodeToString(paymasterAndData[:20])
+		}
+		if len(callData) >= 4 {
+			call := d.unwrapper.decodeCall(operation.Sender, "0x"+hex.EncodeToString(callData), "", "call", resolvedContracts, 0)
+			operation.Call = &call
+		}
+		operations = append(operations, operation)
+	}
+	return operations
+}
+
+// applyUserOperationEvents completes the operations with the EntryPoint's logs, matched by sender and nonce:
+// the operation hash, success and gas cost from UserOperationEvent, revert reasons and deployments. Operations
+// only seen in logs (no decodable handleOps calldata) are added from the events alone. Any contract can emit
+// look-alike events, so only those from a known EntryPoint or a handleOps target are trusted.
+func applyUserOperationEvents(operations []models.UserOperation, events []models.Event, bundler string, handlers []string) []models.UserOperation {
+	trusted := func(event models.Event) bool {
+		emitter := strings.ToLower(event.Contract)
+		_, known := entryPoints[emitter]
+		return known || containsString(handlers, emitter)
+	}
+	find := func(sender, nonce string) int {
+		for i, operation := range operations {
+			if operation.Sender == sender && operation.Nonce == nonce {
+				return i
+			}
+		}
+		return -1
+	}
+	byHash := func(hash string) int {
+		for i, operation := range operations {
+			if operation.Hash == hash {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, event := range events {
+		if len(event.Topics) < 4 || strings.ToLower(event.Topics[0]) != userOperationEventTopic || !trusted(event) {
+			continue
+		}
+		data := newABIData(event.Data)
+		nonce, success, gasCost, gasUsed := data.uintAt(0), data.uintAt(32), data.uintAt(64), data.uintAt(96)
+		if nonce == nil || success == nil || gasCost == nil || gasUsed == nil {
+			continue
+		}
+		sender := topicAddress(event.Topics[2])
+		index := find(sender, nonce.String())
+		if index < 0 {
+			operations = append(operations, models.UserOperation{
+				EntryPoint: strings.ToLower(event.Contract),
+				Sender:     sender,
+				Nonce:      nonce.String(),
+				Bundler:    bundler,
+			})
+			index = len(operations) - 1
+		}
+		operation := &operations[index]
+		operation.Hash = strings.ToLower(event.Topics[1])
+		if paymaster := topicAddress(event.Topics[3]); paymaster != zeroAddress {
+			operation.Paymaster = paymaster
+		}
+		operation.Success = success.Sign() > 0
+		operation.ActualGasCost = gasCost.String()
+		operation.ActualGasUsed = gasUsed.String()
+	}
+
+	for _, event := range events {
+		if len(event.Topics) < 3 || !trusted(event) {
+			continue
+		}
+		index := byHash(strings.ToLower(event.Topics[1]))
+		if index < 0 {
+			continue
+		}
+		data := newABIData(event.Data)
+		switch strings.ToLower(event.Topics[0]) {
+		case userOperationRevertReasonTopic:
+			reason, ok := abiBytes(data, 32)
+			if !ok {
+				continue
+			}
+			revertData := "0x" + hex.EncodeToString(reason)
+			if failure, decoded := decodeStandardRevert(revertData); decoded {
+				operations[index].RevertReason = failure.Reason
+			} else if len(reason) > 0 {
+				operations[index].RevertReason = revertData
+			}
+		case accountDeployedTopic:
+			if factory := data.addressAt(0); factory != "" {
+				operations[index].Factory = factory
+			}
+		}
+	}
+	return operations
+}
+
+// GetUserOperations returns the decoded UserOperations from baggage
+func GetUserOperations(baggage map[string]interface{}) ([]models.UserOperation, bool) {
+	operations, ok := baggage["user_operations"].([]models.UserOperation)
+	return operations, ok && len(operations) > 0
+}
+
+// GetPromptContext describes each UserOperation as its smart account's action, with the calls it made
+func (d *UserOperationDecoder) GetPromptContext(ctx context.Context, baggage map[string]interface{}) string {
+	operations, ok := GetUserOperations(baggage)
+	if !ok {
+		return ""
+	}
+
+	lines := []string{
+		"### ERC-4337 USER OPERATIONS (account abstraction bundle):",
+		"Describe each operation as an action of its smart account (the sender), not as the bundler calling the EntryPoint. The bundler only relayed them and was repaid for gas.",
+	}
+	for i, operation := range operations {
+		status := "succeeded"
+		if !operation.Success {
+			status = "failed"
+			if operation.RevertReason != "" {
+				status += ": " + operation.RevertReason
+			}
+		}
+		entryPoint := "EntryPoint " + operation.EntryPoint
+		if version, ok := entryPoints[operation.EntryPoint]; ok {
+			entryPoint = "EntryPoint " + version
+		}
+		lines = append(lines, fmt.Sprintf("- Operation %d by smart account %s (nonce %s, via %s) %s", i+1, operation.Sender, operation.Nonce, entryPoint, status))
+		if operation.ActualGasCost != "" {
+			payer := "paid by the account"
+			if operation.Paymaster != "" {
+				payer = "sponsored by paymaster " + operation.Paymaster
+			}
+			lines = append(lines, fmt.Sprintf("  Gas cost: %s native token, %s", formatWei(operation.ActualGasCost), payer))
+		} else if operation.Paymaster != "" {
+			lines = append(lines, "  Gas sponsored by paymaster "+operation.Paymaster)
+		}
+		if operation.Factory != "" {
+			lines = append(lines, "  Deployed the account through factory "+operation.Factory)
+		}
+		if operation.Bundler != "" {
+			lines = append(lines, "  Relayed by bundler "+operation.Bundler)
+		}
+		if operation.Call != nil {
+			lines = append(lines, "  Calls:")
+			lines = append(lines, renderDecodedCall(*operation.Call, 2)...)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatWei renders a decimal wei amount in whole native token units
+func formatWei(wei string) string {
+	amount, ok := new(big.Float).SetString(wei)
+	if !ok {
+		return wei
+	}
+	amount.Quo(amount, big.NewFloat(1e18))
+	return strings.TrimRight(strings.TrimRight(amount.Text('f', 8), "0"), ".")
+}
+
+// GetRagContext provides RAG context for UserOperations (none - they are transaction specific)
+func (d *UserOperationDecoder) GetRagContext(ctx context.Context, baggage map[string]interface{}) *RagContext {
+	return NewRagContext()
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/txplain/txplain/internal/models"
+)
+
+const (
+	testEntryPoint   = "0x0000000071727de22e5e9d8baf0edac6f37da032"
+	testSmartAccount = "0x7777777777777777777777777777777777777777"
+	testPaymaster    = "0x8888888888888888888888888888888888888888"
+	testBundler      = "0x9999999999999999999999999999999999999999"
+)
+
+// encodePackedUserOperation ABI-encodes an EntryPoint v0.7 PackedUserOperation tuple
+func encodePackedUserOperation(sender, nonce, callData, paymasterAndData string) string {
+	tails := []string{encodeTail(""), encodeTail(callData), encodeTail(paymasterAndData), encodeTail("aa")}
+	offset := 9 * 32
+	var offsets []string
+	for _, tail := range tails {
+		offsets = append(offsets, abiWord(fmt.Sprintf("%x", offset)))
+		offset += len(tail) / 2
+	}
+	head := abiWord(sender) + abiWord(nonce) + offsets[0] + offsets[1] +
+		abiWord("1") + abiWord("1") + abiWord("1") + offsets[2] + offsets[3]
+	return head + strings.Join(tails, "")
+}
+
+func TestUserOperationDecoderDecodesHandleOps(t *testing.T) {
+	transfer := encodeCall("transfer(address,uint256)", testTrader, fmt.Sprintf("%x", 1000_000000))
+	// SimpleAccount execute(dest, value, func)
+	execute := encodeCall("execute(address,uint256,bytes)", testUSDC, "0", "60") + encodeTail(transfer)
+	operation := encodePackedUserOperation(testSmartAccount, "5", execute, strings.TrimPrefix(testPaymaster, "0x")+"0102")
+	handleOps := functionSelector("handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)") +
+		abiWord("40") + abiWord(testBundler) + abiWord("1") + abiWord("20") + operation
+
+	opHash := "0x" + strings.Repeat("ab", 32)
+	events := []models.Event{
+		testLog(testUSDC, "Transfer(address,address,uint256)", []string{testSmartAccount, testTrader}, fmt.Sprintf("%x", 1000_000000)),
+		testLog(testEntryPoint, "UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)",
+			[]string{opHash, testSmartAccount, testPaymaster}, "5", "1", fmt.Sprintf("%x", uint64(3e14)), "186a0"),
+	}
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt":     map[string]interface{}{"from": testBundler, "to": testEntryPoint, "status": "0x1"},
+			"transaction": map[string]interface{}{"to": testEntryPoint, "input": handleOps},
+		},
+		"events": events,
+	}
+
+	decoder := NewUserOperationDecoder(false)
+	require.NoError(t, decoder.Process(context.Background(), baggage))
+
+	operations, ok := GetUserOperations(baggage)
+	require.True(t, ok)
+	require.Len(t, operations, 1)
+	op := operations[0]
+	require.Equal(t, testSmartAccount, op.Sender)
+	require.Equal(t, "5", op.Nonce)
+	require.Equal(t, testEntryPoint, op.EntryPoint)
+	require.Equal(t, testPaymaster, op.Paymaster)
+	require.Equal(t, testBundler, op.Bundler)
+	require.Equal(t, opHash, op.Hash)
+	require.True(t, op.Success)
+	require.Equal(t, "300000000000000", op.ActualGasCost)
+	require.Equal(t, "100000", op.ActualGasUsed)
+
+	require.NotNil(t, op.Call)
+	require.Equal(t, testSmartAccount, op.Call.Contract)
+	require.Equal(t, models.BatchAccount, op.Call.Batch)
+	require.Len(t, op.Call.Calls, 1)
+	require.Equal(t, testUSDC, op.Call.Calls[0].Contract)
+	require.Equal(t, "transfer", op.Call.Calls[0].Method)
+
+	prompt := decoder.GetPromptContext(context.Background(), baggage)
+	require.Contains(t, prompt, "- Operation 1 by smart account "+testSmartAccount+" (nonce 5, via EntryPoint v0.7) succeeded")
+	require.Contains(t, prompt, "Gas cost: 0.0003 native token, sponsored by paymaster "+testPaymaster)
+
+	// The smart account, not the bundler, is the actor; the gas prefund sent to the EntryPoint is not an action
+	actionBaggage := testActionBaggage(events, map[string]interface{}{
+		"type": "CALL", "from": testBundler, "to": testEntryPoint, "calls": []interface{}{
+			map[string]interface{}{"type": "CALL", "from": testSmartAccount, "to": testEntryPoint, "value": "0x10"},
+		},
+	})
+	actionBaggage["user_operations"] = operations
+	require.NoError(t, NewActionBuilder(nil, false).Process(context.Background(), actionBaggage))
+	actions, _ := GetActions(actionBaggage)
+	require.Len(t, actions, 1)
+	require.Equal(t, models.ActionTypeTransfer, actions[0].Type)
+	require.Equal(t, testSmartAccount, actions[0].Actor)
+	require.Equal(t, testTrader, actions[0].Counterparty)
+}
+
+func TestUserOperationDecoderUsesEventsWithoutCalldata(t *testing.T) {
+	opHash := "0x" + strings.Repeat("cd", 32)
+	revert := functionSelector("Error(string)") + abiWord("20") + encodeTail(fmt.Sprintf("%x", "insufficient balance"))
+	reasonEvent := testLog(testEntryPoint, "UserOperationRevertReason(bytes32,address,uint256,bytes)", []string{opHash, testSmartAccount}, "9", "40")
+	reasonEvent.Data += encodeTail(revert)
+	events := []models.Event{
+		reasonEvent,
+		testLog(testEntryPoint, "UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)",
+			[]string{opHash, testSmartAccount, zeroAddress}, "9", "0", "1", "1"),
+	}
+
+	baggage := map[string]interface{}{
+		"raw_data": map[string]interface{}{
+			"receipt": map[string]interface{}{"from": testBundler, "to": testEntryPoint, "status": "0x1"},
+		},
+		"events": events,
+	}
+	require.NoError(t, NewUserOperationDecoder(false).Process(context.Background(), baggage))
+
+	operations, ok := GetUserOperations(baggage)
+	require.True(t, ok)
+	require.Len(t, operations, 1)
+	require.False(t, operations[0].Success)
+	require.Empty(t, operations[0].Paymaster, "the zero paymaster means the account paid")
+	require.Equal(t, "insufficient balance", operations[0].RevertReason)
+	require.Nil(t, operations[0].Call)
+
+	// Look-alike events from any other contract are ignored
+	for i := range events {
+		events[i].Contract = testRouter
+	}
+	delete(baggage, "user_operations")
+	require.NoError(t, NewUserOperationDecoder(false).Process(context.Background(), baggage))
+	_, ok = GetUserOperations(baggage)
+	require.False(t, ok)
+}